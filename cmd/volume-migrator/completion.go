@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"volume-migrator/internal/docker"
+)
+
+// completeMigrationArgs completes container names, or volume names with --by-volume
+func completeMigrationArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if byVolume {
+		return completeVolumeNames(cmd, args, toComplete)
+	}
+	return completeContainerNames(cmd, args, toComplete)
+}
+
+// completeContainerNames completes the names of running local containers not already given
+func completeContainerNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(cmd.Context(), args, toComplete, func(c *docker.Client) ([]string, error) {
+		return c.ListContainers([]string{"status=running"})
+	})
+}
+
+// completeVolumeNames completes the names of local volumes not already given
+func completeVolumeNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(cmd.Context(), args, toComplete, (*docker.Client).ListVolumeNames)
+}
+
+// completeNames offers the names returned by list that start with toComplete and are not in args
+// Docker errors yield no suggestions, since completion must never print errors into the shell
+func completeNames(ctx context.Context, args []string, toComplete string, list func(*docker.Client) ([]string, error)) ([]string, cobra.ShellCompDirective) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	dockerClient, err := docker.NewClient(ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := list(dockerClient)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	given := make(map[string]bool, len(args))
+	for _, arg := range args {
+		given[arg] = true
+	}
+
+	var suggestions []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !given[name] {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerCompletions hooks dynamic completion into the commands
+// It must run after the flags are defined
+func registerCompletions() {
+	rootCmd.ValidArgsFunction = completeMigrationArgs
+	rootCmd.RegisterFlagCompletionFunc("exclude-volume", completeVolumeNames)
+
+	listCmd.ValidArgsFunction = completeMigrationArgs
+	listCmd.RegisterFlagCompletionFunc("exclude-volume", completeVolumeNames)
+
+	verifyCmd.ValidArgsFunction = completeVolumeNames
+	diffCmd.ValidArgsFunction = completeVolumeNames
+}
@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
 	"volume-migrator/internal/migrator"
+	"volume-migrator/internal/ssh"
+	"volume-migrator/internal/ui"
+	"volume-migrator/internal/utils"
 )
 
 // Version information (injected at build time via ldflags)
@@ -23,7 +33,8 @@ var (
 	// CLI flags
 	remoteHost            string
 	interactive           bool
-	sshKeyPath            string
+	assumeYes             bool
+	sshKeyPaths           []string
 	sshPort               string
 	tempDir               string
 	remoteTempDir         string
@@ -31,15 +42,88 @@ var (
 	dryRun                bool
 	noCleanup             bool
 	showProgress          bool
+	plainProgress         bool
+	progressInterval      time.Duration
 	strictHostKeyChecking bool
 	acceptHostKey         bool
 	knownHostsFile        string
 	validateOnly          bool
 	force                 bool
+	dbMode                string
+	quiesce               string
+	helperImage           string
+	helperImageTar        string
+	preserveXattrs        bool
+	preserveACLs          bool
+	sparse                bool
+	uidMap                []string
+	gidMap                []string
+	numericOwner          bool
+	excludeVolumes        []string
+	minSize               string
+	maxSize               string
+	allContainers         bool
+	containerFilters      []string
+	byVolume              bool
+	anonymousVolumes      string
+	chunkSize             string
+	maxArchiveSize        string
+	importMethod          string
+	incremental           bool
+	stateDir              string
+	dedup                 bool
+	archiveFormat         string
+	compressionLevel      int
+	autoCompress          bool
+	adaptiveCompression   bool
+	continueOnError       bool
+	forceLock             bool
+	forceInUse            bool
+	stopRemoteConsumers   bool
+	deepVerify            bool
+	signManifest          string
+	listOutput            string
+	verifyChecksums       bool
+	profileName           string
+	configFile            string
+	noColor               bool
+	sshOptions            []string
+	gssapi                bool
+	proxyCommand          string
+	remoteSudoPassword    bool
+	localEscalation       string
+	remoteEscalation      string
+	minLocalVersion       string
+	minRemoteVersion      string
+	helperRegistry        string
+	helperBinary          string
+	manifestFile          string
+	composeFile           string
+	printRunCommands      bool
+	startRemote           bool
+	healthTimeout         time.Duration
+	watch                 bool
+	watchInterval         time.Duration
+	standbyInterval       time.Duration
+	sshTimeout            time.Duration
+	commandTimeout        time.Duration
+	transferStallTimeout  time.Duration
+	transferStallRestarts int
+	minFreeSpace          string
+	bufferSize            string
+	parallelStreams       int
+	maxSessions           int
+	transferBackend       string
+	trace                 bool
+	logFile               string
+	savePlan              string
+	sizeTolerance         float64
+	keepRemoteArchives    int
+	remoteArchiveDir      string
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "volume-migrator [container1] [container2...]",
+	Use:   "volume-migrator [container1] [container2...] | --all | --by-volume [volume1] [volume2...]",
 	Short: "Migrate Docker volumes from local containers to a remote machine",
 	Long: `Volume Migrator is a CLI tool for migrating Docker volumes from local containers to a remote Linux machine.
 
@@ -54,58 +138,343 @@ It automatically detects sudo requirements, supports interactive volume selectio
   volume-migrator web-app db-server --remote user@host --ssh-key ~/.ssh/deploy_key
 
   # Verbose mode with dry-run
-  volume-migrator app --remote user@host --verbose --dry-run`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runMigration,
+  volume-migrator app --remote user@host --verbose --dry-run
+
+  # Volumes by name, without inspecting any container
+  volume-migrator --by-volume shared_data orphaned_cache --remote user@host
+
+  # Every running container carrying a label
+  volume-migrator --all --filter label=com.example.stack=prod --filter status=running --remote user@host`,
+	Args:    cobra.ArbitraryArgs,
+	PreRunE: pickRemoteHost,
+	RunE:    runMigration,
 }
 
 func init() {
+	// Profiles apply to every command, before required flags are checked
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Use the settings of a named profile from the config file (flags given on the command line win)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file with named profiles, volume hooks and telemetry settings (default: ~/.volume-migrator/config.json)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colors in logs, prompts and the volume selector (also set by the NO_COLOR environment variable)")
+	rootCmd.PersistentPreRunE = setup
+
 	// Required flags
-	rootCmd.Flags().StringVarP(&remoteHost, "remote", "r", "", "Remote host in format user@host[:port] (required)")
+	rootCmd.Flags().StringVarP(&remoteHost, "remote", "r", "", "Remote host in format user@host[:port] (required, with --interactive picked from ~/.ssh/config when omitted)")
 	rootCmd.MarkFlagRequired("remote")
 
 	// Optional flags
 	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Display volumes and let user select which to migrate")
-	rootCmd.Flags().StringVar(&sshKeyPath, "ssh-key", "", "Path to SSH private key (default: auto-detect)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Start the migration without asking for confirmation (required when stdin is not a terminal)")
+	rootCmd.Flags().StringArrayVar(&sshKeyPaths, "ssh-key", nil, sshKeyUsage)
 	rootCmd.Flags().StringVar(&sshPort, "ssh-port", "22", "SSH port")
-	rootCmd.Flags().StringVar(&tempDir, "temp-dir", "", "Local temporary directory (default: /tmp/volume-migration-{timestamp})")
-	rootCmd.Flags().StringVar(&remoteTempDir, "remote-temp-dir", "", "Remote temporary directory (default: /tmp/volume-migration-{timestamp})")
+	rootCmd.Flags().StringVar(&tempDir, "temp-dir", "", "Local temporary directory (default: volume-migration-{timestamp} in the system temp directory, unless it is on tmpfs or nearly full)")
+	rootCmd.Flags().StringVar(&remoteTempDir, "remote-temp-dir", "", "Remote temporary directory (default: volume-migration-{timestamp} in the remote directory with the most free space)")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.Flags().BoolVar(&trace, "trace", false, "Log every docker and SSH command line run, with its exit code and duration (implies --verbose)")
+	rootCmd.Flags().StringVar(&logFile, "log-file", "", "Also append the log, without colors, to this file")
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without doing it")
 	rootCmd.Flags().BoolVar(&validateOnly, "validate-only", false, "Validate configuration without running migration")
-	rootCmd.Flags().BoolVar(&force, "force", false, "Skip disk space validation checks")
+	rootCmd.Flags().BoolVar(&force, "force", false, "Skip disk space validation checks and continue past environment incompatibilities")
+	rootCmd.Flags().BoolVar(&forceLock, "force-lock", false, "Take over the run lock left by a migration that is no longer running")
+	rootCmd.Flags().BoolVar(&forceInUse, "force-inuse", false, "Import into remote volumes even while running containers mount them")
+	rootCmd.Flags().StringVar(&signManifest, "sign-manifest", "", "Sign manifest.json with this SSH private key and verify each uploaded archive against it before import")
+	rootCmd.Flags().BoolVar(&deepVerify, "deep-verify", false, "Hash every file before export and check the hashes on the remote after import")
+	rootCmd.Flags().BoolVar(&stopRemoteConsumers, "stop-remote-consumers", false, "Stop remote containers mounting the target volumes during the migration and start them again afterwards")
 	rootCmd.Flags().BoolVar(&noCleanup, "no-cleanup", false, "Keep temporary files for debugging")
-	rootCmd.Flags().BoolVarP(&showProgress, "progress", "p", true, "Show progress bars during transfer")
+	rootCmd.Flags().IntVar(&keepRemoteArchives, "keep-remote-archives", 0, "Keep the archives of this many runs on the remote host as rollback points instead of deleting them")
+	rootCmd.Flags().StringVar(&remoteArchiveDir, "remote-archive-dir", "", "Remote directory for --keep-remote-archives (default: "+migrator.DefaultRemoteArchiveDir+")")
+	rootCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Keep migrating the remaining volumes when one fails, then report every failure (exit code 2)")
+	addProgressFlags(rootCmd)
+	rootCmd.Flags().StringVar(&helperImage, "helper-image", "", "Image used to run tar on both hosts, must provide tar (default: alpine pinned by digest)")
+	rootCmd.Flags().StringVar(&helperRegistry, "helper-registry", "", helperRegistryUsage)
+	rootCmd.Flags().StringVar(&helperBinary, "helper-binary", "", "Build the helper image from a static busybox binary (path, or \"embedded\") instead of pulling one")
+	rootCmd.Flags().StringVar(&savePlan, "save-plan", "", "Write the resolved configuration and selected volumes to this YAML file, for review and replay")
+	rootCmd.Flags().StringVar(&manifestFile, "manifest", "", "Write a JSON manifest of the run (helper image, environments, volumes) to this file")
+	rootCmd.Flags().StringVar(&composeFile, "compose-file", "", "After import, write a compose file recreating the source containers on the migrated volumes to this file")
+	rootCmd.Flags().BoolVar(&printRunCommands, "print-run-commands", false, "After import, print a docker run command recreating each source container on the migrated volumes")
+	rootCmd.Flags().BoolVar(&startRemote, "start-remote", false, startRemoteUsage)
+	rootCmd.Flags().BoolVar(&watch, "watch", false, "After the migration, keep pushing incremental changes to the remote until interrupted (requires --incremental)")
+	rootCmd.Flags().DurationVar(&watchInterval, "watch-interval", 0, "How often --watch checks the volumes for changes, e.g. 30s (default: 10s)")
+	rootCmd.Flags().DurationVar(&standbyInterval, "standby-interval", 0, "Repeat the migration this often until interrupted, keeping a warm standby copy, e.g. 15m (requires --incremental and --yes)")
+	rootCmd.Flags().DurationVar(&healthTimeout, "health-timeout", 0, "How long --start-remote waits for the containers to become healthy or reachable, e.g. 5m (default: 2m)")
+	rootCmd.Flags().StringVar(&helperImageTar, "helper-image-tar", "", "Image archive (docker save) loaded on hosts where the helper image is missing")
+	rootCmd.Flags().BoolVar(&preserveXattrs, "preserve-xattrs", false, "Preserve extended attributes (uses GNU tar)")
+	rootCmd.Flags().BoolVar(&preserveACLs, "preserve-acls", false, "Preserve POSIX ACLs (uses GNU tar)")
+	rootCmd.Flags().BoolVar(&sparse, "sparse", false, "Store sparse files efficiently (uses GNU tar)")
+	rootCmd.Flags().StringArrayVar(&uidMap, "uid-map", nil, "Remap file owner UID during import, format from:to (repeatable)")
+	rootCmd.Flags().StringArrayVar(&gidMap, "gid-map", nil, "Remap file group GID during import, format from:to (repeatable)")
+	rootCmd.Flags().BoolVar(&numericOwner, "numeric-owner", false, "Restore numeric UIDs/GIDs instead of mapping owners by name")
+	rootCmd.Flags().BoolVar(&allContainers, "all", false, "Migrate the named volumes of every local container instead of listing containers")
+	rootCmd.Flags().StringArrayVar(&containerFilters, "filter", nil, "Only enumerate containers matching a docker ps filter with --all, e.g. label=key=value or status=running (repeatable)")
+	rootCmd.Flags().BoolVar(&byVolume, "by-volume", false, "Treat arguments as volume names instead of container names")
+	rootCmd.Flags().StringVar(&anonymousVolumes, "anonymous-volumes", "include", "Handling of anonymous (64-hex) volumes: include, skip, or rename to <container>-<mount-path>")
+	rootCmd.Flags().StringArrayVar(&excludeVolumes, "exclude-volume", nil, "Skip volumes matching a name or glob pattern (repeatable)")
+	rootCmd.Flags().StringVar(&minSize, "min-size", "", minSizeUsage)
+	rootCmd.Flags().StringVar(&maxSize, "max-size", "", maxSizeUsage)
+	rootCmd.Flags().StringVar(&chunkSize, "chunk-size", "", "Upload archives larger than this in resumable, checksummed parts (e.g. 2GB)")
+	rootCmd.Flags().StringVar(&maxArchiveSize, "max-archive-size", "", "Split archives into numbered part files of at most this size on both hosts (e.g. 4000MB for FAT32 disks)")
+	rootCmd.Flags().StringVar(&importMethod, "import-method", migrator.ImportMethodArchive, "How archives reach the remote volume: archive (upload, then extract), stream (pipe into tar, no remote temp space) or cp (pipe into docker cp on a paused container)")
+	rootCmd.Flags().BoolVar(&incremental, "incremental", false, "Only send changes since the last run to this host (uses GNU tar, remote volume becomes an exact mirror)")
+	rootCmd.Flags().StringVar(&stateDir, "state-dir", "", "Directory for incremental snapshots (default: ~/.volume-migrator/state)")
+	rootCmd.Flags().StringVar(&archiveFormat, "format", migrator.FormatTarGz, formatUsage)
+	rootCmd.Flags().IntVar(&compressionLevel, "compression-level", migrator.DefaultCompressionLevel, "Compression level from 1 (fastest) to 9 (smallest archives) for gzip and squashfs, up to 19 for zstd")
+	rootCmd.Flags().BoolVar(&autoCompress, "auto-compress", false, "Skip compression for volumes whose data is already compressed (sampled at the start of each archive)")
+	rootCmd.Flags().BoolVar(&adaptiveCompression, "adaptive-compression", false, "Benchmark compressing each volume against the upload rate and pick the fastest gzip level (an explicit --compression-level wins)")
+	rootCmd.Flags().BoolVar(&dedup, "dedup", false, "Send files with identical content only once per run, across all volumes")
+	rootCmd.Flags().StringVar(&dbMode, "db-mode", "", "Export recognized database volumes as logical dumps (postgres|mysql)")
+	rootCmd.Flags().StringVar(&quiesce, "quiesce", migrator.QuiesceOff, "Put postgres, mysql, redis and mongodb containers, detected by image, into a backup-safe state during export (off|auto)")
 
 	// SSH security flags
 	rootCmd.Flags().BoolVar(&strictHostKeyChecking, "strict-host-key-checking", true, "Verify SSH host keys against known_hosts")
 	rootCmd.Flags().BoolVar(&acceptHostKey, "accept-host-key", false, "Automatically accept and add unknown host keys (DANGEROUS - use only in trusted environments)")
 	rootCmd.Flags().StringVar(&knownHostsFile, "known-hosts-file", "", "Path to known_hosts file (default: ~/.ssh/known_hosts)")
+	rootCmd.Flags().StringArrayVar(&sshOptions, "ssh-option", nil, sshOptionUsage)
+	rootCmd.Flags().BoolVar(&gssapi, "gssapi", false, gssapiUsage)
+	rootCmd.Flags().StringVar(&proxyCommand, "proxy-command", "", proxyCommandUsage)
+	rootCmd.Flags().BoolVar(&remoteSudoPassword, "remote-sudo-password", false, remoteSudoPasswordUsage)
+	rootCmd.Flags().StringVar(&localEscalation, "local-escalation", "auto", localEscalationUsage)
+	rootCmd.Flags().StringVar(&remoteEscalation, "remote-escalation", "auto", remoteEscalationUsage)
+	rootCmd.Flags().DurationVar(&sshTimeout, "ssh-timeout", 0, sshTimeoutUsage)
+	rootCmd.Flags().DurationVar(&commandTimeout, "command-timeout", 0, commandTimeoutUsage)
+	rootCmd.Flags().DurationVar(&transferStallTimeout, "transfer-stall-timeout", 0, "Abort uploads and streamed imports that move no data for this long, e.g. 5m (default: never)")
+	rootCmd.Flags().IntVar(&transferStallRestarts, "transfer-stall-restarts", ssh.DefaultStallRestarts, transferStallRestartsUsage)
+	rootCmd.Flags().StringVar(&bufferSize, "buffer-size", "32KB", bufferSizeUsage)
+	rootCmd.Flags().IntVar(&parallelStreams, "parallel-streams", 1, parallelStreamsUsage)
+	rootCmd.Flags().IntVar(&maxSessions, "max-sessions", ssh.DefaultMaxSessions, maxSessionsUsage)
+	rootCmd.Flags().StringVar(&minFreeSpace, "min-free-space", migrator.DefaultMinFreeSpace, minFreeSpaceUsage)
+	rootCmd.Flags().StringVar(&transferBackend, "transfer-backend", ssh.TransferBackendAuto, "How archives are uploaded: auto (SFTP when the remote offers it), sftp, shell (cat over SSH) or the name of a "+ssh.PluginBackendPrefix+"<name> plugin on PATH")
+	rootCmd.Flags().StringVar(&minLocalVersion, "min-local-docker-version", migrator.DefaultMinDockerVersion, "Oldest local Docker engine accepted, checked before anything else (empty for no minimum)")
+	rootCmd.Flags().StringVar(&minRemoteVersion, "min-remote-docker-version", migrator.DefaultMinDockerVersion, minRemoteVersionUsage)
 }
 
-func runMigration(cmd *cobra.Command, args []string) error {
-	// Create context with cancellation support (Ctrl+C)
+// minSizeUsage is the help text of --min-size
+const minSizeUsage = "Skip volumes smaller than this size, e.g. 1MB (binary units)"
+
+// maxSizeUsage is the help text of --max-size
+const maxSizeUsage = "Skip volumes larger than this size, e.g. 50GB (binary units)"
+
+// bufferSizeUsage is the help text of --buffer-size
+const bufferSizeUsage = "Size of each SFTP packet and download copy, 1KB to 255KB; larger sizes speed up fast links but exceed what some SFTP servers accept"
+
+// parallelStreamsUsage is the help text of --parallel-streams
+const parallelStreamsUsage = "Upload each archive of 32MB or more over up to this many SFTP sessions at once, verified by sha256 afterwards"
+
+// transferStallRestartsUsage is the help text of --transfer-stall-restarts
+const transferStallRestartsUsage = "Restart a transfer stopped by --transfer-stall-timeout this many times, resuming where it stopped, before failing"
+
+// maxSessionsUsage is the help text of --max-sessions
+const maxSessionsUsage = "Remote commands run at once over the SSH connection; more wait for one to finish. Keep below the server's MaxSessions"
+
+// minFreeSpaceUsage is the help text of --min-free-space
+const minFreeSpaceUsage = "Abort the migration when either temp directory has less free space than this while volumes migrate, 0 to never check"
+
+// formatUsage is the help text of --format
+const formatUsage = "Archive format: tar (uncompressed), tar.gz, tar.zst or squashfs (tar.zst and squashfs need a --helper-image with zstd or squashfs-tools)"
+
+// sshKeyUsage is the help text of --ssh-key
+const sshKeyUsage = "Path to SSH private key, tried in the order given (repeatable, default: auto-detect)"
+
+// sshOptionUsage is the help text of --ssh-option
+const sshOptionUsage = "SSH setting as Key=Value: Ciphers, MACs, KexAlgorithms, HostKeyAlgorithms, ConnectTimeout, ServerAliveInterval, HashKnownHosts, ForwardAgent, IdentitiesOnly (repeatable)"
+
+// gssapiUsage is the help text of --gssapi
+const gssapiUsage = "Authenticate with GSSAPI/Kerberos (gssapi-with-mic) using the current ticket, before keys"
+
+// remoteSudoPasswordUsage is the help text of --remote-sudo-password
+const remoteSudoPasswordUsage = "Prompt once for the remote sudo password when Docker needs sudo and passwordless sudo is not allowed"
+
+// localEscalationUsage is the help text of --local-escalation
+const localEscalationUsage = "How local docker commands get root privileges: auto (docker, then sudo -n, then doas -n), none, sudo or doas"
+
+// remoteEscalationUsage is the help text of --remote-escalation
+const remoteEscalationUsage = "How remote docker commands get root privileges: auto (docker, then sudo -n, then doas -n), none, sudo or doas"
+
+// startRemoteUsage is the help text of --start-remote
+const startRemoteUsage = "After import, recreate the source containers on the remote host and fail unless they come up (healthy, or running with reachable published ports)"
+
+// sshTimeoutUsage is the help text of --ssh-timeout
+const sshTimeoutUsage = "Timeout for connecting to the remote host and the SSH handshake, e.g. 10s (default: 30s)"
+
+// commandTimeoutUsage is the help text of --command-timeout
+const commandTimeoutUsage = "Abort any single remote or local docker command running longer than this, including extraction, e.g. 2h (default: none)"
+
+// minRemoteVersionUsage is the help text of --min-remote-docker-version
+const minRemoteVersionUsage = "Oldest remote Docker engine accepted, checked right after connecting (empty for no minimum)"
+
+// helperRegistryUsage is the help text of --helper-registry
+const helperRegistryUsage = "Docker Hub mirror (host[:port][/path]) to pull the default helper images from"
+
+// proxyCommandUsage is the help text of --proxy-command
+const proxyCommandUsage = "Command to connect through, as OpenSSH ProxyCommand; %h, %p and %r expand to host, port and user"
+
+// progressFlag is the value of --progress: a boolean, or plain for timestamped progress lines
+type progressFlag struct{}
+
+func (progressFlag) String() string {
+	if plainProgress {
+		return "plain"
+	}
+	return fmt.Sprint(showProgress)
+}
+
+func (progressFlag) Set(value string) error {
+	if value == "plain" {
+		showProgress, plainProgress = true, true
+		return nil
+	}
+	show, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("must be true, false or plain")
+	}
+	showProgress, plainProgress = show, false
+	return nil
+}
+
+// Type is bool so that the help shows --progress like the other switches
+func (progressFlag) Type() string {
+	return "bool"
+}
+
+// addProgressFlags adds --progress and --progress-interval to a command that migrates
+func addProgressFlags(cmd *cobra.Command) {
+	showProgress = true
+	cmd.Flags().VarP(progressFlag{}, "progress", "p", "Show progress bars during export, transfer and import; plain prints a timestamped line per transfer instead, as when stderr is not a terminal")
+	cmd.Flags().Lookup("progress").NoOptDefVal = "true"
+	cmd.Flags().DurationVar(&progressInterval, "progress-interval", utils.DefaultPlainProgressInterval, "How often plain progress prints a line for each transfer")
+}
+
+// setup runs before every command: it applies the selected profile, then the color and progress settings
+func setup(cmd *cobra.Command, args []string) error {
+	if err := applyProfile(cmd, args); err != nil {
+		return migerrors.NewConfigError(err)
+	}
+	disabled := utils.NoColor(noColor)
+	utils.SetNoColor(disabled)
+	ui.SetNoColor(disabled)
+
+	if progressInterval <= 0 {
+		return migerrors.NewConfigError(fmt.Errorf("--progress-interval must be positive, got %s", progressInterval))
+	}
+	if utils.PlainProgress(plainProgress) {
+		utils.SetPlainProgress(progressInterval)
+	}
+	return nil
+}
+
+// pickRemoteHost lets the user pick --remote from the hosts of ~/.ssh/config in interactive mode
+// The host's existing IdentityFile keys are used as --ssh-key, unless keys were given
+func pickRemoteHost(cmd *cobra.Command, args []string) error {
+	if !interactive || remoteHost != "" {
+		return nil
+	}
+
+	hosts, err := ssh.LoadConfigHosts(ssh.DefaultConfigPath())
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	if len(hosts) == 0 {
+		return nil // Reported as a missing --remote
+	}
+
+	host, err := ui.SelectHost(hosts)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Flags().Set("remote", host.Remote()); err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("ssh-key") {
+		for _, keyPath := range host.IdentityFiles {
+			if _, err := os.Stat(keyPath); err != nil {
+				continue // ssh skips missing identity files too
+			}
+			if err := cmd.Flags().Set("ssh-key", keyPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// configFilePath returns the config file given with --config, or the default one
+func configFilePath() string {
+	if configFile == "" {
+		return migrator.DefaultConfigFile()
+	}
+	return configFile
+}
+
+// applyProfile sets the flags of the selected profile that were not given on the command line
+// Profile settings for flags the command does not have (e.g. --remote for list) are ignored
+func applyProfile(cmd *cobra.Command, args []string) error {
+	if profileName == "" {
+		return nil
+	}
+
+	profile, err := migrator.LoadProfile(configFilePath(), profileName)
+	if err != nil {
+		return err
+	}
+	values, err := profile.Values()
+	if err != nil {
+		return fmt.Errorf("profile '%s': %w", profileName, err)
+	}
+
+	// Record explicit flags first: setting a repeatable flag from the profile marks it changed too
+	explicit := make(map[string]bool)
+	for _, v := range values {
+		explicit[v.Flag] = cmd.Flags().Changed(v.Flag)
+	}
+
+	for _, v := range values {
+		if cmd.Flags().Lookup(v.Flag) == nil || explicit[v.Flag] {
+			continue
+		}
+		if err := cmd.Flags().Set(v.Flag, v.Value); err != nil {
+			return fmt.Errorf("profile '%s': invalid value for --%s: %w", profileName, v.Flag, err)
+		}
+	}
+	return nil
+}
+
+// interrupted is set once Ctrl+C or SIGTERM was received, whatever error the run then ends with
+var interrupted atomic.Bool
+
+// interruptContext returns a context cancelled on Ctrl+C or SIGTERM, so a migration cleans up before exiting
+func interruptContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
-	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigChan
 		fmt.Println("\n\nReceived interrupt signal. Cleaning up...")
+		interrupted.Store(true)
 		cancel()
 	}()
+	return ctx, cancel
+}
+
+func runMigration(cmd *cobra.Command, args []string) error {
+	// Create context with cancellation support (Ctrl+C)
+	ctx, cancel := interruptContext()
+	defer cancel()
 
 	// Create migration config
+	containers, volumes := args, []string(nil)
+	if byVolume {
+		containers, volumes = nil, args
+	}
+
 	config := &migrator.Config{
-		Containers:            args,
+		Containers:            containers,
 		RemoteHost:            remoteHost,
-		SSHKeyPath:            sshKeyPath,
+		SSHKeyPaths:           sshKeyPaths,
 		SSHPort:               sshPort,
 		TempDir:               tempDir,
 		RemoteTempDir:         remoteTempDir,
 		Interactive:           interactive,
+		AssumeYes:             assumeYes,
+		SavePlan:              savePlan,
 		Verbose:               verbose,
 		DryRun:                dryRun,
 		NoCleanup:             noCleanup,
@@ -114,21 +483,99 @@ func runMigration(cmd *cobra.Command, args []string) error {
 		AcceptHostKey:         acceptHostKey,
 		KnownHostsFile:        knownHostsFile,
 		Force:                 force,
+		DBMode:                dbMode,
+		Quiesce:               quiesce,
+		HelperImage:           helperImage,
+		HelperRegistry:        helperRegistry,
+		HelperImageTar:        helperImageTar,
+		PreserveXattrs:        preserveXattrs,
+		PreserveACLs:          preserveACLs,
+		Sparse:                sparse,
+		UIDMap:                uidMap,
+		GIDMap:                gidMap,
+		NumericOwner:          numericOwner,
+		ExcludeVolumes:        excludeVolumes,
+		MinSize:               minSize,
+		MaxSize:               maxSize,
+		AllContainers:         allContainers,
+		ContainerFilters:      containerFilters,
+		ByVolume:              byVolume,
+		Volumes:               volumes,
+		AnonymousVolumes:      anonymousVolumes,
+		ChunkSize:             chunkSize,
+		MaxArchiveSize:        maxArchiveSize,
+		ImportMethod:          importMethod,
+		KeepRemoteArchives:    keepRemoteArchives,
+		RemoteArchiveDir:      remoteArchiveDir,
+		Incremental:           incremental,
+		StateDir:              stateDir,
+		Dedup:                 dedup,
+		ArchiveFormat:         archiveFormat,
+		CompressionLevel:      compressionLevel,
+		AutoCompress:          autoCompress,
+		AdaptiveCompression:   adaptiveCompression && !cmd.Flags().Changed("compression-level"),
+		ContinueOnError:       continueOnError,
+		ForceLock:             forceLock,
+		ForceInUse:            forceInUse,
+		StopRemoteConsumers:   stopRemoteConsumers,
+		DeepVerify:            deepVerify,
+		SignKey:               signManifest,
+		SSHOptions:            sshOptions,
+		GSSAPI:                gssapi,
+		ProxyCommand:          proxyCommand,
+		RemoteSudoPassword:    remoteSudoPassword,
+		LocalEscalation:       localEscalation,
+		RemoteEscalation:      remoteEscalation,
+		MinLocalVersion:       minLocalVersion,
+		MinRemoteVersion:      minRemoteVersion,
+		ManifestFile:          manifestFile,
+		ComposeFile:           composeFile,
+		PrintRunCommands:      printRunCommands,
+		StartRemote:           startRemote,
+		HealthTimeout:         healthTimeout,
+		Watch:                 watch,
+		WatchInterval:         watchInterval,
+		StandbyInterval:       standbyInterval,
+		HelperBinary:          helperBinary,
+		SSHTimeout:            sshTimeout,
+		CommandTimeout:        commandTimeout,
+		TransferStallTimeout:  transferStallTimeout,
+		TransferStallRestarts: transferStallRestarts,
+		MinFreeSpace:          minFreeSpace,
+		BufferSize:            bufferSize,
+		ParallelStreams:       parallelStreams,
+		MaxSessions:           maxSessions,
+		TransferBackend:       transferBackend,
+		Trace:                 trace,
+		LogFile:               logFile,
 	}
+	migrator.TranslateWSLPaths(config)
+
+	hooks, err := migrator.LoadPreExportHooks(configFilePath())
+	if err != nil {
+		return migerrors.NewConfigError(err)
+	}
+	config.PreExportHooks = hooks
 
 	// Validate configuration
 	if err := migrator.ValidateConfig(config); err != nil {
-		return fmt.Errorf("configuration validation failed: %w", err)
+		return migerrors.NewConfigError(fmt.Errorf("configuration validation failed: %w", err))
 	}
 
 	// If validate-only mode, exit after successful validation
 	if validateOnly {
 		fmt.Println("✓ Configuration is valid")
-		fmt.Printf("  Containers: %v\n", config.Containers)
+		if config.ByVolume {
+			fmt.Printf("  Volumes: %v\n", config.Volumes)
+		} else if config.AllContainers {
+			fmt.Printf("  Containers: all (filters: %v)\n", config.ContainerFilters)
+		} else {
+			fmt.Printf("  Containers: %v\n", config.Containers)
+		}
 		fmt.Printf("  Remote Host: %s\n", config.RemoteHost)
 		fmt.Printf("  SSH Port: %s\n", config.SSHPort)
-		if config.SSHKeyPath != "" {
-			fmt.Printf("  SSH Key: %s\n", config.SSHKeyPath)
+		if len(config.SSHKeyPaths) > 0 {
+			fmt.Printf("  SSH Keys: %s\n", strings.Join(config.SSHKeyPaths, ", "))
 		}
 		if config.TempDir != "" {
 			fmt.Printf("  Temp Directory: %s\n", config.TempDir)
@@ -137,6 +584,22 @@ func runMigration(cmd *cobra.Command, args []string) error {
 			fmt.Printf("  Remote Temp Directory: %s\n", config.RemoteTempDir)
 		}
 		fmt.Printf("  Strict Host Key Checking: %v\n", config.StrictHostKeyChecking)
+		if config.HelperImage != "" {
+			fmt.Printf("  Helper Image: %s\n", config.HelperImage)
+		}
+		if config.DBMode != "" {
+			fmt.Printf("  Database Dump Mode: %s\n", config.DBMode)
+		}
+		if len(config.ExcludeVolumes) > 0 {
+			fmt.Printf("  Excluded Volumes: %v\n", config.ExcludeVolumes)
+		}
+		return nil
+	}
+
+	if config.StandbyInterval > 0 {
+		if err := migrator.Standby(ctx, config); err != nil {
+			return fmt.Errorf("standby sync failed: %w", err)
+		}
 		return nil
 	}
 
@@ -147,13 +610,105 @@ func runMigration(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run migration
-	if err := m.Migrate(); err != nil {
+	started := time.Now()
+	err = m.Migrate()
+	reportTelemetry(m, started, err)
+	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	return nil
 }
 
+var applyCmd = &cobra.Command{
+	Use:   "apply PLAN",
+	Short: "Run a migration plan saved with --save-plan",
+	Long: `Run the migration recorded in a plan file, non-interactively and without asking for confirmation.
+Before anything is exported, every planned volume must still exist locally with about the size it had
+when the plan was saved (see --size-tolerance).`,
+	Example: `  volume-migrator app --remote user@host --interactive --dry-run --save-plan plan.yaml
+  volume-migrator apply plan.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApply,
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	if sizeTolerance < 0 {
+		return migerrors.NewConfigError(fmt.Errorf("--size-tolerance must not be negative, got: %g", sizeTolerance))
+	}
+	plan, err := migrator.LoadPlan(args[0])
+	if err != nil {
+		return migerrors.NewConfigError(err)
+	}
+	if host, err := os.Hostname(); err == nil && plan.Source != "" && plan.Source != host {
+		utils.GetLogger().Warnf("Plan was saved on %s, applying it on %s", plan.Source, host)
+	}
+
+	// Settings of this run only, never part of a plan
+	config := plan.Config
+	config.TempDir = tempDir
+	config.RemoteTempDir = remoteTempDir
+	config.Verbose = verbose
+	config.Trace = trace
+	config.LogFile = logFile
+	config.DryRun = dryRun
+	config.NoCleanup = noCleanup
+	config.ShowProgress = showProgress
+	config.ForceLock = forceLock
+	config.AssumeYes = true
+	config.PlannedVolumes = plan.Volumes
+	config.SizeTolerance = sizeTolerance
+
+	if err := migrator.ValidateConfig(config); err != nil {
+		return migerrors.NewConfigError(fmt.Errorf("plan validation failed: %w", err))
+	}
+
+	m, err := migrator.NewMigrator(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create migrator: %w", err)
+	}
+	started := time.Now()
+	err = m.Migrate()
+	reportTelemetry(m, started, err)
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	return nil
+}
+
+// reportTelemetry posts the anonymized statistics of a finished run when telemetry was opted
+// into; a collector that cannot be reached only shows in the debug log
+func reportTelemetry(m *migrator.Migrator, started time.Time, err error) {
+	settings, loadErr := migrator.LoadTelemetrySettings(configFilePath())
+	if loadErr != nil {
+		utils.GetLogger().WithError(loadErr).Warn("Ignoring telemetry settings")
+		return
+	}
+	if !settings.Active() {
+		return
+	}
+	report := m.TelemetryReport(version, time.Since(started), err)
+	if err := migrator.SendTelemetry(context.Background(), settings.Endpoint, report); err != nil {
+		utils.GetLogger().WithError(err).Debug("Failed to send telemetry")
+	}
+}
+
+func init() {
+	applyCmd.Flags().Float64Var(&sizeTolerance, "size-tolerance", migrator.DefaultSizeTolerance, "Percent a volume may have grown or shrunk since the plan was saved")
+	applyCmd.Flags().StringVar(&tempDir, "temp-dir", "", "Local temporary directory (default: volume-migration-{timestamp} in the system temp directory, unless it is on tmpfs or nearly full)")
+	applyCmd.Flags().StringVar(&remoteTempDir, "remote-temp-dir", "", "Remote temporary directory (default: volume-migration-{timestamp} in the remote directory with the most free space)")
+	applyCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	applyCmd.Flags().BoolVar(&trace, "trace", false, "Log every docker and SSH command line run, with its exit code and duration (implies --verbose)")
+	applyCmd.Flags().StringVar(&logFile, "log-file", "", "Also append the log, without colors, to this file")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check the plan against the environment without migrating")
+	applyCmd.Flags().BoolVar(&noCleanup, "no-cleanup", false, "Keep temporary files for debugging")
+	addProgressFlags(applyCmd)
+	applyCmd.Flags().BoolVar(&forceLock, "force-lock", false, "Take over the run lock left by a migration that is no longer running")
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
@@ -167,13 +722,372 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that a remote host is ready for migrations",
+	Long: `Verify SSH connectivity and host key, remote Docker access (direct or via sudo) and version,
+helper image, a writable remote temp directory and free disk space, without migrating anything.`,
+	Example: `  volume-migrator check --remote user@host`,
+	Args:    cobra.NoArgs,
+	RunE:    runCheck,
+	// Failed checks are not usage errors
+	SilenceUsage: true,
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	if helperImage != "" {
+		if err := migrator.ValidateHelperImageReference(helperImage); err != nil {
+			return migerrors.NewConfigError(fmt.Errorf("configuration validation failed: %w", err))
+		}
+	}
+	if err := migrator.ValidateHelperRegistry(helperRegistry); err != nil {
+		return migerrors.NewConfigError(fmt.Errorf("configuration validation failed: %w", err))
+	}
+
+	config := &migrator.Config{
+		RemoteHost:            remoteHost,
+		SSHKeyPaths:           sshKeyPaths,
+		RemoteTempDir:         remoteTempDir,
+		StrictHostKeyChecking: strictHostKeyChecking,
+		AcceptHostKey:         acceptHostKey,
+		KnownHostsFile:        knownHostsFile,
+		HelperImage:           helperImage,
+		HelperRegistry:        helperRegistry,
+		SSHOptions:            sshOptions,
+		GSSAPI:                gssapi,
+		ProxyCommand:          proxyCommand,
+		RemoteSudoPassword:    remoteSudoPassword,
+		RemoteEscalation:      remoteEscalation,
+		MinRemoteVersion:      minRemoteVersion,
+		SSHTimeout:            sshTimeout,
+		CommandTimeout:        commandTimeout,
+	}
+
+	results := migrator.RunChecks(cmd.Context(), config)
+	for _, r := range results {
+		mark := "✓"
+		if !r.Passed {
+			mark = "✗"
+		}
+		fmt.Printf("%s %s: %s\n", mark, r.Name, r.Detail)
+	}
+
+	if failed := migrator.FailedChecks(results); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// addRemoteFlags registers the remote host and SSH flags on a subcommand
+func addRemoteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&remoteHost, "remote", "r", "", "Remote host in format user@host[:port] (required)")
+	cmd.MarkFlagRequired("remote")
+	cmd.Flags().StringArrayVar(&sshKeyPaths, "ssh-key", nil, sshKeyUsage)
+	cmd.Flags().BoolVar(&strictHostKeyChecking, "strict-host-key-checking", true, "Verify SSH host keys against known_hosts")
+	cmd.Flags().BoolVar(&acceptHostKey, "accept-host-key", false, "Automatically accept and add unknown host keys (DANGEROUS - use only in trusted environments)")
+	cmd.Flags().StringVar(&knownHostsFile, "known-hosts-file", "", "Path to known_hosts file (default: ~/.ssh/known_hosts)")
+	cmd.Flags().StringVar(&helperImage, "helper-image", "", "Image used to run tar on both hosts, must provide tar (default: alpine pinned by digest)")
+	cmd.Flags().StringVar(&helperRegistry, "helper-registry", "", helperRegistryUsage)
+	cmd.Flags().StringArrayVar(&sshOptions, "ssh-option", nil, sshOptionUsage)
+	cmd.Flags().BoolVar(&gssapi, "gssapi", false, gssapiUsage)
+	cmd.Flags().StringVar(&proxyCommand, "proxy-command", "", proxyCommandUsage)
+	cmd.Flags().BoolVar(&remoteSudoPassword, "remote-sudo-password", false, remoteSudoPasswordUsage)
+	cmd.Flags().StringVar(&remoteEscalation, "remote-escalation", "auto", remoteEscalationUsage)
+	cmd.Flags().DurationVar(&sshTimeout, "ssh-timeout", 0, sshTimeoutUsage)
+	cmd.Flags().DurationVar(&commandTimeout, "command-timeout", 0, commandTimeoutUsage)
+}
+
+func init() {
+	addRemoteFlags(checkCmd)
+	checkCmd.Flags().StringVar(&minRemoteVersion, "min-remote-docker-version", migrator.DefaultMinDockerVersion, minRemoteVersionUsage)
+	checkCmd.Flags().StringVar(&remoteTempDir, "remote-temp-dir", "", "Remote temporary directory to check (default: the remote directory with the most free space)")
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify volume1 [volume2...]",
+	Short: "Compare local volumes with their remote copies",
+	Long: `Compare each local volume with the remote volume of the same name: file counts, total size and
+the size of every file, plus a sha256 of every file with --checksums. Run it after a migration to
+confirm integrity before decommissioning the source.`,
+	Example: `  volume-migrator verify app_data --remote user@host --checksums`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runVerify,
+	// Differences are not usage errors
+	SilenceUsage: true,
+}
+
+// verifyListLimit is how many differing paths are printed per category
+const verifyListLimit = 10
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	config, err := volumeComparisonConfig(args)
+	if err != nil {
+		return err
+	}
+
+	results, err := migrator.VerifyVolumes(cmd.Context(), config, verifyChecksums)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	differing := 0
+	for _, r := range results {
+		if r.OK() {
+			fmt.Printf("✓ %s: %d files, %s match\n", r.Volume, r.LocalFiles, utils.FormatBytes(r.LocalBytes))
+			continue
+		}
+
+		differing++
+		fmt.Printf("✗ %s: local %d files (%s), remote %d files (%s)\n", r.Volume,
+			r.LocalFiles, utils.FormatBytes(r.LocalBytes), r.RemoteFiles, utils.FormatBytes(r.RemoteBytes))
+		printPaths("missing on remote", r.Missing)
+		printPaths("only on remote", r.Extra)
+		printPaths("different", r.Different)
+	}
+
+	if differing > 0 {
+		return migerrors.NewVerificationError(fmt.Errorf("%d of %d volumes differ", differing, len(results)))
+	}
+	return nil
+}
+
+// volumeComparisonConfig returns the configuration of verify and diff, which compare the named
+// local volumes with the remote volumes of the same name
+func volumeComparisonConfig(volumes []string) (*migrator.Config, error) {
+	if helperImage != "" {
+		if err := migrator.ValidateHelperImageReference(helperImage); err != nil {
+			return nil, migerrors.NewConfigError(fmt.Errorf("configuration validation failed: %w", err))
+		}
+	}
+	if err := migrator.ValidateHelperRegistry(helperRegistry); err != nil {
+		return nil, migerrors.NewConfigError(fmt.Errorf("configuration validation failed: %w", err))
+	}
+
+	return &migrator.Config{
+		Volumes:               volumes,
+		RemoteHost:            remoteHost,
+		SSHKeyPaths:           sshKeyPaths,
+		StrictHostKeyChecking: strictHostKeyChecking,
+		AcceptHostKey:         acceptHostKey,
+		KnownHostsFile:        knownHostsFile,
+		HelperImage:           helperImage,
+		HelperRegistry:        helperRegistry,
+		SSHOptions:            sshOptions,
+		GSSAPI:                gssapi,
+		ProxyCommand:          proxyCommand,
+		RemoteSudoPassword:    remoteSudoPassword,
+		LocalEscalation:       localEscalation,
+		RemoteEscalation:      remoteEscalation,
+		SSHTimeout:            sshTimeout,
+		CommandTimeout:        commandTimeout,
+	}, nil
+}
+
+// printPaths prints up to verifyListLimit paths under a label
+func printPaths(label string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Printf("    %d %s\n", len(paths), label)
+	for i, p := range paths {
+		if i == verifyListLimit {
+			fmt.Printf("      ... and %d more\n", len(paths)-verifyListLimit)
+			break
+		}
+		fmt.Printf("      %s\n", p)
+	}
+}
+
+func init() {
+	addRemoteFlags(verifyCmd)
+	verifyCmd.Flags().StringVar(&localEscalation, "local-escalation", "auto", localEscalationUsage)
+	verifyCmd.Flags().BoolVar(&verifyChecksums, "checksums", false, "Also compare a sha256 of every file (reads all data on both hosts)")
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff volume1 [volume2...]",
+	Short: "List the files that differ between local volumes and their remote copies",
+	Long: `List every file added (+), removed (-) or changed (~) in each local volume since its remote copy
+was made, without migrating anything. Files are compared by size and modification time, and also
+by a sha256 of their contents with --checksums. Use it to decide whether a volume needs re-syncing.`,
+	Example: `  volume-migrator diff app_data --remote user@host`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runDiff,
+	// Differences are not usage errors
+	SilenceUsage: true,
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	config, err := volumeComparisonConfig(args)
+	if err != nil {
+		return err
+	}
+
+	results, err := migrator.DiffVolumes(cmd.Context(), config, verifyChecksums)
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+
+	differing := 0
+	for _, r := range results {
+		if r.OK() {
+			fmt.Printf("%s: no differences (%d files, %s)\n", r.Volume, r.LocalFiles, utils.FormatBytes(r.LocalBytes))
+			continue
+		}
+
+		differing++
+		fmt.Printf("%s: %d added, %d removed, %d changed\n", r.Volume, len(r.Missing), len(r.Extra), len(r.Different))
+		for _, line := range migrator.DiffLines(r) {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	if differing > 0 {
+		return migerrors.NewVerificationError(fmt.Errorf("%d of %d volumes differ; migrate them again to re-sync", differing, len(results)))
+	}
+	return nil
+}
+
+func init() {
+	addRemoteFlags(diffCmd)
+	diffCmd.Flags().StringVar(&localEscalation, "local-escalation", "auto", localEscalationUsage)
+	diffCmd.Flags().BoolVar(&verifyChecksums, "checksums", false, "Also compare a sha256 of every file, to find changes that keep size and modification time")
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list [container1] [container2...]",
+	Short: "List the local volumes that can be migrated",
+	Long: `List the volumes of the given containers (or of every local container) as migration would
+discover them, including --exclude-volume and --anonymous-volumes handling. Nothing is migrated
+and no remote host is needed.`,
+	Example: `  # Every local container
+  volume-migrator list
+
+  # Machine-readable output for scripts
+  volume-migrator list web db --output json`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runList,
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if listOutput != "table" && listOutput != "json" {
+		return migerrors.NewConfigError(fmt.Errorf("invalid output format '%s': must be table or json", listOutput))
+	}
+
+	config := &migrator.Config{
+		Containers:       args,
+		AllContainers:    len(args) == 0 && !byVolume,
+		ContainerFilters: containerFilters,
+		ExcludeVolumes:   excludeVolumes,
+		MinSize:          minSize,
+		MaxSize:          maxSize,
+		AnonymousVolumes: anonymousVolumes,
+		LocalEscalation:  localEscalation,
+	}
+	if byVolume {
+		config.Containers, config.ByVolume, config.Volumes = nil, true, args
+	}
+
+	if len(config.ContainerFilters) > 0 && !config.AllContainers {
+		return migerrors.NewConfigError(fmt.Errorf("--filter only applies when no containers are given"))
+	}
+	if config.ByVolume && len(config.Volumes) == 0 {
+		return migerrors.NewConfigError(fmt.Errorf("no volumes specified"))
+	}
+	for _, err := range []error{
+		migrator.ValidateContainerFilters(config.ContainerFilters),
+		migrator.ValidateExcludePatterns(config.ExcludeVolumes),
+		migrator.ValidateSizeFilters(config.MinSize, config.MaxSize),
+		migrator.ValidateAnonymousMode(config.AnonymousVolumes),
+	} {
+		if err != nil {
+			return migerrors.NewConfigError(err)
+		}
+	}
+
+	// Keep stdout parseable: discovery logs go to stderr
+	if listOutput == "json" {
+		utils.GetLogger().SetOutput(os.Stderr)
+	}
+
+	volumes, unmigrated, err := migrator.ListVolumes(cmd.Context(), config)
+	if err != nil {
+		return fmt.Errorf("failed to discover volumes: %w", err)
+	}
+
+	if listOutput == "json" {
+		if volumes == nil {
+			volumes = []docker.VolumeInfo{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(volumes)
+	}
+	ui.DisplayVolumeTable(volumes)
+	ui.DisplayUnmigratedMounts(unmigrated)
+	return nil
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table or json")
+	listCmd.Flags().StringArrayVar(&containerFilters, "filter", nil, "Only list containers matching a docker ps filter when no containers are given (repeatable)")
+	listCmd.Flags().BoolVar(&byVolume, "by-volume", false, "Treat arguments as volume names instead of container names")
+	listCmd.Flags().StringArrayVar(&excludeVolumes, "exclude-volume", nil, "Skip volumes matching a name or glob pattern (repeatable)")
+	listCmd.Flags().StringVar(&minSize, "min-size", "", minSizeUsage)
+	listCmd.Flags().StringVar(&maxSize, "max-size", "", maxSizeUsage)
+	listCmd.Flags().StringVar(&localEscalation, "local-escalation", "auto", localEscalationUsage)
+	listCmd.Flags().StringVar(&anonymousVolumes, "anonymous-volumes", "include", "Handling of anonymous (64-hex) volumes: include, skip, or rename to <container>-<mount-path>")
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(applyCmd)
+	registerCompletions()
+	markStarted(rootCmd)
 }
 
+// started is set once a command's RunE begins: cobra rejects flags, arguments and missing
+// required flags before that, which makes any earlier error a configuration error
+var started bool
+
+// markStarted wraps the RunE of cmd and its subcommands to set started
+func markStarted(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			started = true
+			return run(cmd, args)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markStarted(sub)
+	}
+}
+
+// main exits with the codes documented under "Exit Codes" in the README, see migerrors.ExitCode
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+	report := migerrors.NewReport(err)
+	if interrupted.Load() {
+		report.Code, report.ExitCode = migerrors.CodeCancelled, migerrors.ExitCancelled
+	} else if report.ExitCode == migerrors.ExitFailure && !started {
+		report.Code, report.ExitCode = migerrors.CodeConfigInvalid, migerrors.ExitConfig
+	}
+	// Scripts reading JSON from stdout get the error in place of the result
+	if output := cmd.Flags().Lookup("output"); output != nil && output.Value.String() == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Error migerrors.Report `json:"error"`
+		}{report})
 	}
+	os.Exit(report.ExitCode)
 }
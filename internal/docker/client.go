@@ -6,7 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os/exec"
 	"strings"
+	"sync"
+	"time"
+
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/utils"
 )
 
 var (
@@ -21,6 +29,7 @@ var (
 type ContainerInfo struct {
 	ID     string
 	Name   string
+	Labels map[string]string
 	Mounts []MountInfo
 }
 
@@ -30,32 +39,54 @@ type MountInfo struct {
 	Name        string // Volume name (for named volumes)
 	Source      string
 	Destination string
+	ReadOnly    bool
+}
+
+// VolumeDetails holds the settings a volume was created with
+type VolumeDetails struct {
+	Driver  string
+	Options map[string]string // --opt values given to docker volume create
+	Labels  map[string]string
 }
 
 // Client wraps Docker operations
 type Client struct {
-	sudo *SudoDetector
-	ctx  context.Context
+	escalation *EscalationDetector
+	ctx        context.Context
+
+	commandTimeout time.Duration // Bounds ExecCommand and ExecCommandWithOutput, 0 for none
+
+	desktopOnce sync.Once
+	desktop     bool // Set by IsDockerDesktop
 }
 
-// NewClient creates a new Docker client
+// NewClient creates a new Docker client, running docker directly or through sudo or doas
 func NewClient(ctx context.Context) (*Client, error) {
-	sudo := NewSudoDetector()
+	methods, _ := shell.ParseEscalation(shell.EscalationAuto)
+	return NewClientWithEscalation(ctx, methods)
+}
+
+// NewClientWithEscalation creates a new Docker client using the first of methods that can run docker
+func NewClientWithEscalation(ctx context.Context, methods []shell.Escalation) (*Client, error) {
+	escalation := NewEscalationDetector(methods)
+	if utils.IsWSL2() {
+		escalation.host = wslDockerHost(socketExists)
+	}
 
-	// Detect sudo requirement
-	if err := sudo.Detect(ctx); err != nil {
+	// Detect how docker can be run
+	if err := escalation.Detect(ctx); err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		sudo: sudo,
-		ctx:  ctx,
+		escalation: escalation,
+		ctx:        ctx,
 	}, nil
 }
 
 // InspectContainer retrieves detailed information about a container
 func (c *Client) InspectContainer(name string) (*ContainerInfo, error) {
-	cmd := c.sudo.WrapCommand(c.ctx, "inspect", name)
+	cmd := c.escalation.WrapCommand(c.ctx, "inspect", name)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -73,6 +104,9 @@ func (c *Client) InspectContainer(name string) (*ContainerInfo, error) {
 	var inspectData []struct {
 		ID     string `json:"Id"`
 		Name   string `json:"Name"`
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
 		Mounts []struct {
 			Type        string `json:"Type"`
 			Name        string `json:"Name"`
@@ -91,8 +125,9 @@ func (c *Client) InspectContainer(name string) (*ContainerInfo, error) {
 
 	data := inspectData[0]
 	info := &ContainerInfo{
-		ID:   data.ID,
-		Name: strings.TrimPrefix(data.Name, "/"),
+		ID:     data.ID,
+		Name:   strings.TrimPrefix(data.Name, "/"),
+		Labels: data.Config.Labels,
 	}
 
 	for _, m := range data.Mounts {
@@ -107,6 +142,33 @@ func (c *Client) InspectContainer(name string) (*ContainerInfo, error) {
 	return info, nil
 }
 
+// InspectVolume returns the driver, options and labels of a volume
+func (c *Client) InspectVolume(volumeName string) (*VolumeDetails, error) {
+	output, err := c.ExecCommand("volume", "inspect", volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect volume %s: %w", volumeName, err)
+	}
+	return parseVolumeInspect(output)
+}
+
+// parseVolumeInspect parses the output of docker volume inspect for a single volume
+func parseVolumeInspect(output string) (*VolumeDetails, error) {
+	var inspectData []struct {
+		Driver  string            `json:"Driver"`
+		Options map[string]string `json:"Options"`
+		Labels  map[string]string `json:"Labels"`
+	}
+	if err := json.Unmarshal([]byte(output), &inspectData); err != nil {
+		return nil, fmt.Errorf("failed to parse volume inspect output: %w", err)
+	}
+	if len(inspectData) == 0 {
+		return nil, fmt.Errorf("volume inspect returned no volumes")
+	}
+
+	data := inspectData[0]
+	return &VolumeDetails{Driver: data.Driver, Options: data.Options, Labels: data.Labels}, nil
+}
+
 // ListVolumes returns a list of volume names used by a container
 func (c *Client) ListVolumes(containerName string) ([]string, error) {
 	info, err := c.InspectContainer(containerName)
@@ -125,16 +187,53 @@ func (c *Client) ListVolumes(containerName string) ([]string, error) {
 	return volumes, nil
 }
 
+// ListContainers returns the names of all local containers (running or stopped)
+// matching the given `docker ps` filters (e.g. "label=com.example.app=web", "status=running")
+func (c *Client) ListContainers(filters []string) ([]string, error) {
+	args := []string{"ps", "-a", "--format", "{{.Names}}"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+
+	output, err := c.ExecCommand(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	return parseNames(output), nil
+}
+
+// ListVolumeNames returns the names of all local volumes
+func (c *Client) ListVolumeNames() ([]string, error) {
+	output, err := c.ExecCommand("volume", "ls", "--format", "{{.Name}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	return parseNames(output), nil
+}
+
+// parseNames splits one-name-per-line output (docker ps or volume ls with --format) into names
+func parseNames(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // ValidateVolume checks if a volume exists
 func (c *Client) ValidateVolume(volumeName string) error {
-	cmd := c.sudo.WrapCommand(c.ctx, "volume", "inspect", volumeName)
+	cmd := c.escalation.WrapCommand(c.ctx, "volume", "inspect", volumeName)
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
 		if strings.Contains(stderr.String(), "No such volume") {
-			return fmt.Errorf("volume %s not found", volumeName)
+			return migerrors.NewVolumeNotFoundError(volumeName, nil)
 		}
 		return fmt.Errorf("failed to inspect volume: %w", err)
 	}
@@ -142,21 +241,55 @@ func (c *Client) ValidateVolume(volumeName string) error {
 	return nil
 }
 
-// RequiresSudo returns whether Docker commands require sudo
-func (c *Client) RequiresSudo() bool {
-	return c.sudo.IsRequired()
+// DaemonHost returns the daemon address passed to docker, empty when the CLI default is used
+func (c *Client) DaemonHost() string {
+	return c.escalation.host
+}
+
+// RequiresEscalation returns whether Docker commands run through sudo or doas
+func (c *Client) RequiresEscalation() bool {
+	return c.escalation.IsRequired()
+}
+
+// Escalation returns how Docker commands get root privileges
+func (c *Client) Escalation() shell.Escalation {
+	return c.escalation.Method()
+}
+
+// SetCommandTimeout bounds every ExecCommand and ExecCommandWithOutput call; 0 removes the bound.
+// ExecCommandStream is not bounded, since it carries whole exports.
+func (c *Client) SetCommandTimeout(timeout time.Duration) {
+	c.commandTimeout = timeout
+}
+
+// commandContext returns the context bounding one command
+func (c *Client) commandContext() (context.Context, context.CancelFunc) {
+	if c.commandTimeout <= 0 {
+		return context.WithCancel(c.ctx)
+	}
+	return context.WithTimeout(c.ctx, c.commandTimeout)
+}
+
+// timeoutError reports a command killed by the command timeout as such
+func (c *Client) timeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && c.ctx.Err() == nil {
+		return fmt.Errorf("timed out after %s (--command-timeout)", c.commandTimeout)
+	}
+	return err
 }
 
 // ExecCommand executes a Docker command and returns stdout
 func (c *Client) ExecCommand(args ...string) (string, error) {
-	cmd := c.sudo.WrapCommand(c.ctx, args...)
+	ctx, cancel := c.commandContext()
+	defer cancel()
+	cmd := c.escalation.WrapCommand(ctx, args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("docker command failed: %w, stderr: %s", err, stderr.String())
+	if err := runTraced(cmd); err != nil {
+		return "", fmt.Errorf("docker command failed: %w, stderr: %s", c.timeoutError(ctx, err), stderr.String())
 	}
 
 	return stdout.String(), nil
@@ -164,9 +297,52 @@ func (c *Client) ExecCommand(args ...string) (string, error) {
 
 // ExecCommandWithOutput executes a Docker command and streams output
 func (c *Client) ExecCommandWithOutput(stdout, stderr *bytes.Buffer, args ...string) error {
-	cmd := c.sudo.WrapCommand(c.ctx, args...)
+	ctx, cancel := c.commandContext()
+	defer cancel()
+	cmd := c.escalation.WrapCommand(ctx, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return c.timeoutError(ctx, runTraced(cmd))
+}
+
+// ExecCommandStream executes a Docker command and streams stdout to an arbitrary writer
+// Use this for commands whose output is too large to buffer in memory (e.g. database dumps)
+func (c *Client) ExecCommandStream(stdout io.Writer, stderr *bytes.Buffer, args ...string) error {
+	cmd := c.escalation.WrapCommand(c.ctx, args...)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
-	return cmd.Run()
+	return runTraced(cmd)
+}
+
+// runTraced runs cmd, recording its command line, exit code and duration at Trace level
+func runTraced(cmd *exec.Cmd) error {
+	start := time.Now()
+	err := cmd.Run()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+
+	args := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = shell.ShellEscape(arg)
+	}
+	utils.TraceCommand("local", strings.Join(args, " "), exitCode, time.Since(start))
+	return err
+}
+
+// WithoutCancel returns a client whose commands are not interrupted when the context is
+// cancelled, for cleanup that must still run after Ctrl+C
+func (c *Client) WithoutCancel() *Client {
+	return &Client{
+		escalation:     c.escalation,
+		ctx:            context.WithoutCancel(c.ctx),
+		commandTimeout: c.commandTimeout,
+	}
 }
@@ -2,146 +2,142 @@ package docker
 
 import (
 	"context"
+	"strings"
 	"testing"
-)
-
-func TestClient_RequiresSudo(t *testing.T) {
-	tests := []struct {
-		name         string
-		requiresSudo bool
-		expected     bool
-	}{
-		{
-			name:         "requires sudo",
-			requiresSudo: true,
-			expected:     true,
-		},
-		{
-			name:         "does not require sudo",
-			requiresSudo: false,
-			expected:     false,
-		},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			detector := &SudoDetector{
-				required: tt.requiresSudo,
-			}
-			client := &Client{
-				sudo: detector,
-			}
-
-			if client.RequiresSudo() != tt.expected {
-				t.Errorf("RequiresSudo() = %v, want %v", client.RequiresSudo(), tt.expected)
-			}
-		})
-	}
-}
+	"volume-migrator/internal/shell"
+)
 
-func TestSudoDetector_IsRequired(t *testing.T) {
+func TestClient_RequiresEscalation(t *testing.T) {
 	tests := []struct {
 		name     string
-		required bool
+		method   shell.Escalation
 		expected bool
 	}{
 		{
-			name:     "sudo required",
-			required: true,
+			name:     "requires sudo",
+			method:   shell.EscalationSudo,
+			expected: true,
+		},
+		{
+			name:     "requires doas",
+			method:   shell.EscalationDoas,
 			expected: true,
 		},
 		{
-			name:     "sudo not required",
-			required: false,
+			name:     "does not require escalation",
+			method:   shell.EscalationNone,
 			expected: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			detector := &SudoDetector{
-				required: tt.required,
+			detector := &EscalationDetector{
+				method: tt.method,
+			}
+			client := &Client{
+				escalation: detector,
 			}
 
-			if detector.IsRequired() != tt.expected {
-				t.Errorf("IsRequired() = %v, want %v", detector.IsRequired(), tt.expected)
+			if client.RequiresEscalation() != tt.expected {
+				t.Errorf("RequiresEscalation() = %v, want %v", client.RequiresEscalation(), tt.expected)
+			}
+			if client.Escalation().Name != tt.method.Name {
+				t.Errorf("Escalation() = %v, want %v", client.Escalation().Name, tt.method.Name)
 			}
 		})
 	}
 }
 
-func TestSudoDetector_WrapCommand(t *testing.T) {
+func TestEscalationDetector_WrapCommand(t *testing.T) {
 	tests := []struct {
 		name     string
-		required bool
+		method   shell.Escalation
 		args     []string
-		wantCmd  string
+		wantArgs string
 	}{
 		{
-			name:     "without sudo",
-			required: false,
+			name:     "without escalation",
+			method:   shell.EscalationNone,
 			args:     []string{"ps", "-a"},
-			wantCmd:  "docker",
+			wantArgs: "docker ps -a",
 		},
 		{
 			name:     "with sudo",
-			required: true,
+			method:   shell.EscalationSudo,
 			args:     []string{"ps", "-a"},
-			wantCmd:  "sudo",
+			wantArgs: "sudo docker ps -a",
 		},
 		{
-			name:     "volume command without sudo",
-			required: false,
+			name:     "volume command with sudo",
+			method:   shell.EscalationSudo,
 			args:     []string{"volume", "ls"},
-			wantCmd:  "docker",
+			wantArgs: "sudo docker volume ls",
 		},
 		{
-			name:     "volume command with sudo",
-			required: true,
+			name:     "volume command with doas",
+			method:   shell.EscalationDoas,
 			args:     []string{"volume", "ls"},
-			wantCmd:  "sudo",
+			wantArgs: "doas docker volume ls",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			detector := &SudoDetector{
-				required: tt.required,
-			}
-
-			ctx := context.Background()
-			cmd := detector.WrapCommand(ctx, tt.args...)
-
-			if cmd.Path != tt.wantCmd && cmd.Args[0] != tt.wantCmd {
-				t.Errorf("WrapCommand() command = %v, want %v", cmd.Args[0], tt.wantCmd)
+			detector := &EscalationDetector{
+				method: tt.method,
 			}
 
-			// Verify args are properly included
-			if tt.required {
-				// With sudo: sudo docker <args>
-				if len(cmd.Args) < 2 {
-					t.Error("Expected sudo command to have docker in args")
-				}
-			} else {
-				// Without sudo: docker <args>
-				if len(cmd.Args) < len(tt.args) {
-					t.Error("Expected docker command to have all args")
-				}
+			cmd := detector.WrapCommand(context.Background(), tt.args...)
+			if got := strings.Join(cmd.Args, " "); got != tt.wantArgs {
+				t.Errorf("WrapCommand() args = %q, want %q", got, tt.wantArgs)
 			}
 		})
 	}
 }
 
-func TestNewSudoDetector(t *testing.T) {
-	detector := NewSudoDetector()
+func TestEscalationDetector_DetectCommand(t *testing.T) {
+	detector := NewEscalationDetector(nil)
+	cmd := detector.command(context.Background(), shell.EscalationDoas, true, "ps")
+	if got := strings.Join(cmd.Args, " "); got != "doas -n docker ps" {
+		t.Errorf("command() args = %q, want the non-interactive flag", got)
+	}
+}
+
+func TestNewEscalationDetector(t *testing.T) {
+	detector := NewEscalationDetector([]shell.Escalation{shell.EscalationSudo})
 
 	if detector == nil {
-		t.Fatal("NewSudoDetector() returned nil")
+		t.Fatal("NewEscalationDetector() returned nil")
+	}
+
+	// Should start without escalation (will be detected later)
+	if detector.IsRequired() {
+		t.Error("New EscalationDetector should start without escalation")
+	}
+}
+
+func TestParseVolumeInspect(t *testing.T) {
+	output := `[{"CreatedAt":"2024-01-01T00:00:00Z","Driver":"local","Labels":{"com.docker.compose.project":"web"},"Mountpoint":"/var/lib/docker/volumes/data/_data","Name":"data","Options":{"device":":/exports/data","o":"addr=10.0.0.5","type":"nfs"},"Scope":"local"}]`
+
+	details, err := parseVolumeInspect(output)
+	if err != nil {
+		t.Fatalf("parseVolumeInspect() error = %v", err)
+	}
+	if details.Driver != "local" {
+		t.Errorf("Driver = %q, want local", details.Driver)
+	}
+	if details.Options["type"] != "nfs" || details.Options["device"] != ":/exports/data" {
+		t.Errorf("Options = %v, want the nfs options", details.Options)
+	}
+
+	if details.Labels["com.docker.compose.project"] != "web" {
+		t.Errorf("Labels = %v, want the compose project label", details.Labels)
 	}
 
-	// Should start with required = false (will be detected later)
-	if detector.required {
-		t.Error("New SudoDetector should start with required = false")
+	if _, err := parseVolumeInspect("[]"); err == nil {
+		t.Error("parseVolumeInspect() expected error for empty output")
 	}
 }
 
@@ -226,3 +222,95 @@ func TestMountInfo_Structure(t *testing.T) {
 		t.Errorf("Source = %v, want %v", mount.Source, "/var/lib/docker/volumes/data-volume/_data")
 	}
 }
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"empty output", "", nil},
+		{"single container", "web\n", []string{"web"}},
+		{"multiple containers", "web\ndb\ncache\n", []string{"web", "db", "cache"}},
+		{"blank lines and whitespace", "\n  web  \n\ndb\r\n", []string{"web", "db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseNames(tt.output)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseNames() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("parseNames()[%d] = %q, want %q", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsDockerDesktopOS(t *testing.T) {
+	tests := []struct {
+		name string
+		os   string
+		want bool
+	}{
+		{"macOS and Windows", "Docker Desktop\n", true},
+		{"Linux Docker Desktop", "Docker Desktop 4.30.0 (149282)", true},
+		{"Ubuntu engine", "Ubuntu 22.04.4 LTS", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDockerDesktopOS(tt.os); got != tt.want {
+				t.Errorf("isDockerDesktopOS(%q) = %v, want %v", tt.os, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWSLDockerHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	shared := wslDesktopSockets[0]
+
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"default socket linked", []string{defaultDockerSocket, shared}, ""},
+		{"only shared socket", []string{shared}, "unix://" + shared},
+		{"no socket", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists := func(path string) bool {
+				for _, p := range tt.existing {
+					if p == path {
+						return true
+					}
+				}
+				return false
+			}
+			if got := wslDockerHost(exists); got != tt.want {
+				t.Errorf("wslDockerHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	if got := wslDockerHost(func(string) bool { return true }); got != "" {
+		t.Errorf("wslDockerHost() = %q, want DOCKER_HOST respected", got)
+	}
+}
+
+func TestEscalationDetector_DockerArgs(t *testing.T) {
+	detector := &EscalationDetector{host: "unix:///mnt/wsl/docker.sock"}
+	cmd := detector.WrapCommand(context.Background(), "ps")
+	if strings.Join(cmd.Args, " ") != "docker -H unix:///mnt/wsl/docker.sock ps" {
+		t.Errorf("WrapCommand() args = %v, want the daemon host first", cmd.Args)
+	}
+}
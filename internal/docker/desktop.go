@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"strings"
+)
+
+// dockerDesktopOS is reported by `docker info` as the OperatingSystem of Docker Desktop daemons
+const dockerDesktopOS = "Docker Desktop"
+
+// IsDockerDesktop reports whether the local daemon is Docker Desktop (macOS, Windows or Linux).
+// Its volumes live inside a VM, so they can only be reached through containers, never host paths.
+// The result is detected on first use and cached.
+func (c *Client) IsDockerDesktop() bool {
+	c.desktopOnce.Do(func() {
+		output, err := c.ExecCommand("info", "--format", "{{.OperatingSystem}}")
+		c.desktop = err == nil && isDockerDesktopOS(output)
+	})
+	return c.desktop
+}
+
+// isDockerDesktopOS reports whether a `docker info` OperatingSystem value is Docker Desktop
+func isDockerDesktopOS(operatingSystem string) bool {
+	return strings.Contains(strings.TrimSpace(operatingSystem), dockerDesktopOS)
+}
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+
+	"volume-migrator/internal/shell"
+)
+
+// EscalationDetector finds and caches how Docker commands get root privileges (directly, sudo or doas)
+type EscalationDetector struct {
+	candidates []shell.Escalation // Methods tried in order by Detect
+	method     shell.Escalation
+	checked    bool
+	host       string // Daemon address passed with -H, empty for the CLI default
+	mu         sync.Mutex
+}
+
+// NewEscalationDetector creates a detector trying the given methods in order
+func NewEscalationDetector(candidates []shell.Escalation) *EscalationDetector {
+	return &EscalationDetector{candidates: candidates, method: shell.EscalationNone}
+}
+
+// Detect runs "docker ps" with each candidate method, non-interactively, and keeps the first that works
+func (ed *EscalationDetector) Detect(ctx context.Context) error {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	if ed.checked {
+		return nil
+	}
+
+	for _, method := range ed.candidates {
+		cmd := ed.command(ctx, method, true, "ps")
+		cmd.Stdout = nil
+		cmd.Stderr = nil
+
+		if err := runTraced(cmd); err == nil {
+			ed.method = method
+			ed.checked = true
+			return nil
+		}
+	}
+	return ErrDockerNotAccessible
+}
+
+// Method returns the detected escalation method
+func (ed *EscalationDetector) Method() shell.Escalation {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	return ed.method
+}
+
+// IsRequired returns whether Docker commands run through sudo or doas
+func (ed *EscalationDetector) IsRequired() bool {
+	return ed.Method().Required()
+}
+
+// WrapCommand wraps a docker command with the detected escalation method
+func (ed *EscalationDetector) WrapCommand(ctx context.Context, args ...string) *exec.Cmd {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	return ed.command(ctx, ed.method, false, args...)
+}
+
+// command builds the docker command run through method
+func (ed *EscalationDetector) command(ctx context.Context, method shell.Escalation, nonInteractive bool, args ...string) *exec.Cmd {
+	argv := append(method.Args(nonInteractive), "docker")
+	argv = append(argv, ed.dockerArgs(args...)...)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
+
+// dockerArgs prefixes args with the daemon address when one is set
+func (ed *EscalationDetector) dockerArgs(args ...string) []string {
+	if ed.host == "" {
+		return args
+	}
+	return append([]string{"-H", ed.host}, args...)
+}
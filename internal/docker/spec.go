@@ -0,0 +1,182 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ContainerSpec is the configuration a container was created with, as far as it can be
+// recreated on another host. Settings equal to the image's defaults are left out.
+type ContainerSpec struct {
+	Name        string
+	Image       string   // Image reference the container was created from
+	Entrypoint  []string // Empty when the image's entrypoint is used
+	Command     []string // Empty when the image's command is used
+	Env         []string // KEY=value, without the variables the image sets
+	User        string
+	WorkingDir  string
+	Ports       []string // Published ports as [ip:][host:]container[/udp], sorted
+	Mounts      []MountInfo
+	Restart     string // Restart policy such as always or on-failure:3, empty for no
+	NetworkMode string // host or none, empty for a bridge or user-defined network
+	Labels      map[string]string
+	Project     string // Compose project of the container (empty if not managed by compose)
+	Service     string // Compose service of the container
+}
+
+// containerInspect is the part of docker container inspect output a ContainerSpec is built from
+type containerInspect struct {
+	Name   string `json:"Name"`
+	Config struct {
+		Image      string            `json:"Image"`
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+		Env        []string          `json:"Env"`
+		User       string            `json:"User"`
+		WorkingDir string            `json:"WorkingDir"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		NetworkMode   string `json:"NetworkMode"`
+		RestartPolicy struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount int    `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+		PortBindings map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"PortBindings"`
+	} `json:"HostConfig"`
+	Mounts []struct {
+		Type        string `json:"Type"`
+		Name        string `json:"Name"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+}
+
+// imageInspect is the part of docker image inspect output holding the image's defaults
+type imageInspect struct {
+	Config struct {
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+		Env        []string          `json:"Env"`
+		User       string            `json:"User"`
+		WorkingDir string            `json:"WorkingDir"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// ParseContainerSpec builds a ContainerSpec from docker container inspect output, leaving out
+// what the image inspect output shows the image sets anyway. imageOutput may be empty when the
+// image cannot be inspected, in which case everything is kept.
+func ParseContainerSpec(containerOutput, imageOutput string) (*ContainerSpec, error) {
+	var containers []containerInspect
+	if err := json.Unmarshal([]byte(containerOutput), &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse container inspect output: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil, ErrContainerNotFound
+	}
+	c := containers[0]
+
+	var image imageInspect
+	if imageOutput != "" {
+		var images []imageInspect
+		if err := json.Unmarshal([]byte(imageOutput), &images); err != nil {
+			return nil, fmt.Errorf("failed to parse image inspect output: %w", err)
+		}
+		if len(images) > 0 {
+			image = images[0]
+		}
+	}
+
+	spec := &ContainerSpec{
+		Name:    strings.TrimPrefix(c.Name, "/"),
+		Image:   c.Config.Image,
+		Project: c.Config.Labels[ComposeProjectLabel],
+		Service: c.Config.Labels[ComposeServiceLabel],
+	}
+	if !slices.Equal(c.Config.Entrypoint, image.Config.Entrypoint) {
+		spec.Entrypoint = c.Config.Entrypoint
+	}
+	// A new entrypoint resets the image's command, so the command is kept along with it
+	if spec.Entrypoint != nil || !slices.Equal(c.Config.Cmd, image.Config.Cmd) {
+		spec.Command = c.Config.Cmd
+	}
+	for _, env := range c.Config.Env {
+		if !slices.Contains(image.Config.Env, env) {
+			spec.Env = append(spec.Env, env)
+		}
+	}
+	if c.Config.User != image.Config.User {
+		spec.User = c.Config.User
+	}
+	if c.Config.WorkingDir != image.Config.WorkingDir {
+		spec.WorkingDir = c.Config.WorkingDir
+	}
+	for key, value := range c.Config.Labels {
+		if strings.HasPrefix(key, "com.docker.compose.") {
+			continue
+		}
+		if imageValue, ok := image.Config.Labels[key]; ok && imageValue == value {
+			continue
+		}
+		if spec.Labels == nil {
+			spec.Labels = make(map[string]string)
+		}
+		spec.Labels[key] = value
+	}
+
+	switch policy := c.HostConfig.RestartPolicy; policy.Name {
+	case "", "no":
+	case "on-failure":
+		spec.Restart = policy.Name
+		if policy.MaximumRetryCount > 0 {
+			spec.Restart = fmt.Sprintf("on-failure:%d", policy.MaximumRetryCount)
+		}
+	default:
+		spec.Restart = policy.Name
+	}
+	if mode := c.HostConfig.NetworkMode; mode == "host" || mode == "none" {
+		spec.NetworkMode = mode
+	}
+
+	for containerPort, bindings := range c.HostConfig.PortBindings {
+		port := strings.TrimSuffix(containerPort, "/tcp")
+		for _, binding := range bindings {
+			published := port
+			if binding.HostPort != "" {
+				published = binding.HostPort + ":" + port
+			}
+			if binding.HostIP != "" && binding.HostIP != "0.0.0.0" && binding.HostIP != "::" {
+				if binding.HostPort == "" {
+					published = ":" + port // ip::container publishes on a random port of ip
+				}
+				if strings.Contains(binding.HostIP, ":") {
+					published = "[" + binding.HostIP + "]:" + published
+				} else {
+					published = binding.HostIP + ":" + published
+				}
+			}
+			spec.Ports = append(spec.Ports, published)
+		}
+	}
+	sort.Strings(spec.Ports)
+	spec.Ports = slices.Compact(spec.Ports) // IPv4 and IPv6 wildcard bindings of the same port
+
+	for _, m := range c.Mounts {
+		spec.Mounts = append(spec.Mounts, MountInfo{
+			Type:        m.Type,
+			Name:        m.Name,
+			Source:      m.Source,
+			Destination: m.Destination,
+			ReadOnly:    !m.RW,
+		})
+	}
+	return spec, nil
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+const specContainerInspect = `[{
+	"Name": "/web",
+	"Config": {
+		"Image": "nginx:1.25",
+		"Entrypoint": ["/docker-entrypoint.sh"],
+		"Cmd": ["nginx", "-g", "daemon off;"],
+		"Env": ["PATH=/usr/local/sbin:/usr/bin", "NGINX_VERSION=1.25.3", "APP_ENV=production"],
+		"User": "",
+		"WorkingDir": "",
+		"Labels": {
+			"maintainer": "NGINX Docker Maintainers",
+			"com.docker.compose.project": "shop",
+			"com.docker.compose.service": "frontend",
+			"tier": "edge"
+		}
+	},
+	"HostConfig": {
+		"NetworkMode": "shop_default",
+		"RestartPolicy": {"Name": "on-failure", "MaximumRetryCount": 3},
+		"PortBindings": {
+			"80/tcp": [{"HostIp": "", "HostPort": "8080"}, {"HostIp": "::", "HostPort": "8080"}],
+			"53/udp": [{"HostIp": "127.0.0.1", "HostPort": "5353"}],
+			"9090/tcp": [{"HostIp": "10.0.0.5", "HostPort": ""}]
+		}
+	},
+	"Mounts": [
+		{"Type": "volume", "Name": "shop_static", "Destination": "/usr/share/nginx/html", "RW": false},
+		{"Type": "bind", "Source": "/srv/nginx.conf", "Destination": "/etc/nginx/nginx.conf", "RW": true}
+	]
+}]`
+
+const specImageInspect = `[{
+	"Config": {
+		"Entrypoint": ["/docker-entrypoint.sh"],
+		"Cmd": ["nginx", "-g", "daemon off;"],
+		"Env": ["PATH=/usr/local/sbin:/usr/bin", "NGINX_VERSION=1.25.3"],
+		"Labels": {"maintainer": "NGINX Docker Maintainers"}
+	}
+}]`
+
+func TestParseContainerSpec(t *testing.T) {
+	spec, err := ParseContainerSpec(specContainerInspect, specImageInspect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Name != "web" || spec.Image != "nginx:1.25" {
+		t.Errorf("unexpected name or image: %+v", spec)
+	}
+	if spec.Project != "shop" || spec.Service != "frontend" {
+		t.Errorf("unexpected compose labels: project %q, service %q", spec.Project, spec.Service)
+	}
+	if spec.Entrypoint != nil || spec.Command != nil {
+		t.Errorf("image defaults should be left out, got entrypoint %v, command %v", spec.Entrypoint, spec.Command)
+	}
+	if !slices.Equal(spec.Env, []string{"APP_ENV=production"}) {
+		t.Errorf("Env = %v, want [APP_ENV=production]", spec.Env)
+	}
+	if len(spec.Labels) != 1 || spec.Labels["tier"] != "edge" {
+		t.Errorf("Labels = %v, want only tier=edge", spec.Labels)
+	}
+	if want := []string{"10.0.0.5::9090", "127.0.0.1:5353:53/udp", "8080:80"}; !slices.Equal(spec.Ports, want) {
+		t.Errorf("Ports = %v, want %v", spec.Ports, want)
+	}
+	if spec.Restart != "on-failure:3" {
+		t.Errorf("Restart = %q, want on-failure:3", spec.Restart)
+	}
+	if spec.NetworkMode != "" {
+		t.Errorf("NetworkMode = %q, want empty for a user-defined network", spec.NetworkMode)
+	}
+	if len(spec.Mounts) != 2 || !spec.Mounts[0].ReadOnly || spec.Mounts[1].ReadOnly {
+		t.Errorf("unexpected mounts: %+v", spec.Mounts)
+	}
+}
+
+func TestParseContainerSpec_WithoutImage(t *testing.T) {
+	spec, err := ParseContainerSpec(specContainerInspect, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Env) != 3 || len(spec.Entrypoint) != 1 || len(spec.Command) != 3 {
+		t.Errorf("expected every setting without image defaults, got %+v", spec)
+	}
+}
+
+func TestParseContainerSpec_Invalid(t *testing.T) {
+	if _, err := ParseContainerSpec("[]", ""); err != ErrContainerNotFound {
+		t.Errorf("expected ErrContainerNotFound, got: %v", err)
+	}
+	if _, err := ParseContainerSpec("not json", ""); err == nil {
+		t.Error("expected a parse error")
+	}
+}
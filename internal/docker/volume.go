@@ -3,20 +3,65 @@ package docker
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+
+	migerrors "volume-migrator/internal/errors"
 )
 
 // sizeRegex is compiled once at package initialization for performance
 var sizeRegex = regexp.MustCompile(`^([\d.]+)([KMGT]?B?)$`)
 
+// Labels set by Docker Compose on the containers it manages
+const (
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+)
+
+// anonymousVolumeRegex matches the 64-hex names Docker generates for anonymous volumes
+var anonymousVolumeRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 // VolumeInfo holds detailed information about a Docker volume
 type VolumeInfo struct {
-	Name       string
-	Container  string
-	MountPath  string
-	Size       string
-	SizeBytes  int64
-	Selected   bool
+	Name       string `json:"name" yaml:"name"`
+	Container  string `json:"container" yaml:"container"`
+	MountPath  string `json:"mount_path" yaml:"mount_path"`
+	Size       string `json:"size" yaml:"size"`
+	SizeBytes  int64  `json:"size_bytes" yaml:"size_bytes"`
+	Selected   bool   `json:"-" yaml:"-"`
+	Anonymous  bool   `json:"anonymous" yaml:"anonymous"`                         // Volume has a Docker-generated name
+	TargetName string `json:"target_name,omitempty" yaml:"target_name,omitempty"` // Name to create on the remote host (empty means same as Name)
+	Project    string `json:"project,omitempty" yaml:"project,omitempty"`         // Compose project of the container (empty if not managed by compose)
+	Service    string `json:"service,omitempty" yaml:"service,omitempty"`         // Compose service of the container
+
+	// Driver, DriverOpts and Labels are the source volume's creation settings, reused on the remote host
+	Driver     string            `json:"driver,omitempty" yaml:"driver,omitempty"`
+	DriverOpts map[string]string `json:"driver_opts,omitempty" yaml:"driver_opts,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// WithDetails returns v with the driver, options and labels read from the source volume
+// The default local driver is left empty, since it needs no flags on volume create
+func (v VolumeInfo) WithDetails(details *VolumeDetails) VolumeInfo {
+	if details.Driver != "local" {
+		v.Driver = details.Driver
+	}
+	v.DriverOpts = details.Options
+	v.Labels = details.Labels
+	return v
+}
+
+// RemoteName returns the name the volume gets on the remote host
+func (v VolumeInfo) RemoteName() string {
+	if v.TargetName != "" {
+		return v.TargetName
+	}
+	return v.Name
+}
+
+// IsAnonymousVolumeName reports whether name looks like a Docker-generated anonymous volume name
+func IsAnonymousVolumeName(name string) bool {
+	return anonymousVolumeRegex.MatchString(name)
 }
 
 // GetVolumeSize retrieves the size of a Docker volume
@@ -70,7 +115,7 @@ func (c *Client) GetVolumeMountPoints(containerName, volumeName string) (string,
 		}
 	}
 
-	return "", fmt.Errorf("volume %s not found in container %s", volumeName, containerName)
+	return "", migerrors.NewVolumeNotFoundError(volumeName, fmt.Errorf("not mounted by container %s", containerName))
 }
 
 // GetAllVolumesInfo retrieves detailed information about all volumes from specified containers
@@ -78,19 +123,25 @@ func (c *Client) GetAllVolumesInfo(containerNames []string) ([]VolumeInfo, error
 	volumeMap := make(map[string]*VolumeInfo) // deduplicate volumes
 
 	for _, containerName := range containerNames {
-		volumes, err := c.ListVolumes(containerName)
+		info, err := c.InspectContainer(containerName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list volumes for container %s: %w", containerName, err)
 		}
 
-		for _, volumeName := range volumes {
+		for _, mount := range info.Mounts {
+			// Only include named volumes, skip bind mounts
+			if mount.Type != "volume" || mount.Name == "" {
+				continue
+			}
+			volumeName := mount.Name
+
 			// Skip if already processed
 			if _, exists := volumeMap[volumeName]; exists {
 				continue
 			}
 
-			mountPath, err := c.GetVolumeMountPoints(containerName, volumeName)
-			if err != nil {
+			mountPath := mount.Destination
+			if mountPath == "" {
 				mountPath = "N/A"
 			}
 
@@ -100,14 +151,23 @@ func (c *Client) GetAllVolumesInfo(containerNames []string) ([]VolumeInfo, error
 				sizeBytes = 0
 			}
 
-			volumeMap[volumeName] = &VolumeInfo{
+			volume := VolumeInfo{
 				Name:       volumeName,
 				Container:  containerName,
 				MountPath:  mountPath,
 				Size:       size,
 				SizeBytes:  sizeBytes,
 				Selected:   true, // Default to selected
+				Anonymous:  IsAnonymousVolumeName(volumeName),
+				Project:    info.Labels[ComposeProjectLabel],
+				Service:    info.Labels[ComposeServiceLabel],
+			}
+			details, err := c.InspectVolume(volumeName)
+			if err != nil {
+				return nil, err
 			}
+			volume = volume.WithDetails(details)
+			volumeMap[volumeName] = &volume
 		}
 	}
 
@@ -120,6 +180,86 @@ func (c *Client) GetAllVolumesInfo(containerNames []string) ([]VolumeInfo, error
 	return result, nil
 }
 
+// UnmigratedMount is a container mount that is not a Docker volume, such as a bind or tmpfs
+// mount, so a migration leaves its data behind
+type UnmigratedMount struct {
+	Container   string `json:"container"`
+	Type        string `json:"type"`
+	Source      string `json:"source,omitempty"` // Host path of a bind mount
+	Destination string `json:"destination"`
+}
+
+// GetUnmigratedMounts lists the mounts of the containers that are not named volumes,
+// sorted by container and mount path
+func (c *Client) GetUnmigratedMounts(containerNames []string) ([]UnmigratedMount, error) {
+	var result []UnmigratedMount
+	for _, containerName := range containerNames {
+		info, err := c.InspectContainer(containerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list mounts for container %s: %w", containerName, err)
+		}
+		for _, mount := range info.Mounts {
+			if mount.Type == "volume" && mount.Name != "" {
+				continue
+			}
+			result = append(result, UnmigratedMount{
+				Container:   containerName,
+				Type:        mount.Type,
+				Source:      mount.Source,
+				Destination: mount.Destination,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Container != result[j].Container {
+			return result[i].Container < result[j].Container
+		}
+		return result[i].Destination < result[j].Destination
+	})
+	return result, nil
+}
+
+// GetVolumesInfoByName retrieves information about volumes given directly by name
+// No container is inspected, so Container and MountPath are reported as "N/A"
+func (c *Client) GetVolumesInfoByName(volumeNames []string) ([]VolumeInfo, error) {
+	seen := make(map[string]bool) // deduplicate volumes
+	var result []VolumeInfo
+
+	for _, volumeName := range volumeNames {
+		if seen[volumeName] {
+			continue
+		}
+		seen[volumeName] = true
+
+		if err := c.ValidateVolume(volumeName); err != nil {
+			return nil, err
+		}
+
+		size, sizeBytes, err := c.GetVolumeSize(volumeName)
+		if err != nil {
+			size = "Unknown"
+			sizeBytes = 0
+		}
+
+		details, err := c.InspectVolume(volumeName)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, VolumeInfo{
+			Name:      volumeName,
+			Container: "N/A",
+			MountPath: "N/A",
+			Size:      size,
+			SizeBytes: sizeBytes,
+			Selected:  true, // Default to selected
+			Anonymous: IsAnonymousVolumeName(volumeName),
+		}.WithDetails(details))
+	}
+
+	return result, nil
+}
+
 // parseSizeToBytes converts size string (e.g., "1.2GB", "500MB") to bytes
 func parseSizeToBytes(sizeStr string) int64 {
 	// Remove any whitespace
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -164,3 +165,74 @@ func BenchmarkParseSizeToBytes(b *testing.B) {
 		})
 	}
 }
+
+func TestIsAnonymousVolumeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		volume   string
+		expected bool
+	}{
+		{"anonymous volume", "3f4e1a0c9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f", true},
+		{"named volume", "postgres_data", false},
+		{"uppercase hex", "3F4E1A0C9B8D7E6F5A4B3C2D1E0F9A8B7C6D5E4F3A2B1C0D9E8F7A6B5C4D3E2F", false},
+		{"too short", "3f4e1a0c9b8d", false},
+		{"non-hex characters", "zz4e1a0c9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsAnonymousVolumeName(tt.volume); result != tt.expected {
+				t.Errorf("IsAnonymousVolumeName(%q) = %v, want %v", tt.volume, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVolumeInfo_RemoteName(t *testing.T) {
+	if got := (VolumeInfo{Name: "data"}).RemoteName(); got != "data" {
+		t.Errorf("RemoteName() = %q, want %q", got, "data")
+	}
+	if got := (VolumeInfo{Name: "data", TargetName: "web-data"}).RemoteName(); got != "web-data" {
+		t.Errorf("RemoteName() = %q, want %q", got, "web-data")
+	}
+}
+
+func TestVolumeInfoJSON(t *testing.T) {
+	v := VolumeInfo{Name: "data", Container: "web", MountPath: "/var/lib/data", Size: "1.0GB", SizeBytes: 1 << 30, Selected: true}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	expected := `{"name":"data","container":"web","mount_path":"/var/lib/data","size":"1.0GB","size_bytes":1073741824,"anonymous":false}`
+	if string(out) != expected {
+		t.Errorf("json.Marshal() = %s, want %s", out, expected)
+	}
+}
+
+func TestVolumeInfo_WithDetails(t *testing.T) {
+	nfs := map[string]string{"type": "nfs", "o": "addr=10.0.0.5,rw", "device": ":/exports/data"}
+
+	tests := []struct {
+		name       string
+		details    VolumeDetails
+		wantDriver string
+		wantOpts   int
+	}{
+		{"default local volume", VolumeDetails{Driver: "local"}, "", 0},
+		{"local nfs volume", VolumeDetails{Driver: "local", Options: nfs}, "", 3},
+		{"plugin driver", VolumeDetails{Driver: "rexray/ebs", Options: map[string]string{"size": "10"}}, "rexray/ebs", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := VolumeInfo{Name: "data"}.WithDetails(&tt.details)
+			if v.Driver != tt.wantDriver {
+				t.Errorf("Driver = %q, want %q", v.Driver, tt.wantDriver)
+			}
+			if len(v.DriverOpts) != tt.wantOpts {
+				t.Errorf("DriverOpts = %v, want %d options", v.DriverOpts, tt.wantOpts)
+			}
+		})
+	}
+}
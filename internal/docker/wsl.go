@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"os"
+)
+
+// defaultDockerSocket is the socket the docker CLI uses when DOCKER_HOST is not set
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// wslDesktopSockets are the daemon sockets Docker Desktop's WSL integration shares with distributions
+var wslDesktopSockets = []string{
+	"/mnt/wsl/docker-desktop/shared-sockets/guest-services/docker.sock",
+	"/mnt/wsl/docker-desktop/shared-sockets/guest-services/docker.proxy.sock",
+}
+
+// wslDockerHost returns the daemon address to use inside WSL2, or "" to keep the CLI default.
+// Docker Desktop links /var/run/docker.sock into integrated distributions; when the link is
+// missing (integration toggled off and on, or a distribution started before Docker Desktop),
+// the shared socket is used directly. An explicit DOCKER_HOST is always respected.
+func wslDockerHost(exists func(path string) bool) string {
+	if os.Getenv("DOCKER_HOST") != "" || exists(defaultDockerSocket) {
+		return ""
+	}
+	for _, socket := range wslDesktopSockets {
+		if exists(socket) {
+			return "unix://" + socket
+		}
+	}
+	return ""
+}
+
+// socketExists reports whether path exists
+func socketExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
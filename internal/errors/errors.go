@@ -1,9 +1,30 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
+// Sentinels matching the typed errors of this package with errors.Is, for callers branching on the
+// failure category without needing the details
+var (
+	ErrVolumeNotFound   = errors.New("volume not found")
+	ErrSSHConnection    = errors.New("SSH connection failed")
+	ErrDiskSpace        = errors.New("insufficient disk space")
+	ErrPermission       = errors.New("permission denied")
+	ErrPartialMigration = errors.New("some volumes failed to migrate")
+	ErrConfig           = errors.New("invalid configuration")
+	ErrDocker           = errors.New("docker failed")
+	ErrVerification     = errors.New("verification failed")
+)
+
+// ErrCancelled is wrapped by the errors of prompts and selections the user backed out of
+var ErrCancelled = errors.New("cancelled by user")
+
+// ErrSSHAuth is wrapped by connection errors the remote host rejected the credentials of
+var ErrSSHAuth = errors.New("SSH authentication failed")
+
 // VolumeNotFoundError indicates a Docker volume could not be found
 type VolumeNotFoundError struct {
 	VolumeName string
@@ -21,6 +42,11 @@ func (e *VolumeNotFoundError) Unwrap() error {
 	return e.Err
 }
 
+// Is matches ErrVolumeNotFound
+func (e *VolumeNotFoundError) Is(target error) bool {
+	return target == ErrVolumeNotFound
+}
+
 // NewVolumeNotFoundError creates a new VolumeNotFoundError.
 // Use this when a Docker volume inspect or lookup fails because the volume doesn't exist.
 // The err parameter can be nil if no underlying error is available.
@@ -48,6 +74,11 @@ func (e *SSHConnectionError) Unwrap() error {
 	return e.Err
 }
 
+// Is matches ErrSSHConnection
+func (e *SSHConnectionError) Is(target error) bool {
+	return target == ErrSSHConnection
+}
+
 // NewSSHConnectionError creates a new SSHConnectionError.
 // Use this when SSH connection establishment fails (authentication, network, or host key issues).
 // The host parameter should include the user@host format if available.
@@ -69,17 +100,36 @@ type DiskSpaceError struct {
 
 func (e *DiskSpaceError) Error() string {
 	if e.Err != nil {
-		return fmt.Sprintf("insufficient disk space on %s: required %d bytes, available %d bytes: %v",
-			e.Location, e.Required, e.Available, e.Err)
+		return fmt.Sprintf("insufficient disk space on %s: required %d bytes (%s), available %d bytes (%s): %v",
+			e.Location, e.Required, formatBytes(e.Required), e.Available, formatBytes(e.Available), e.Err)
 	}
-	return fmt.Sprintf("insufficient disk space on %s: required %d bytes, available %d bytes",
-		e.Location, e.Required, e.Available)
+	return fmt.Sprintf("insufficient disk space on %s: required %d bytes (%s), available %d bytes (%s)",
+		e.Location, e.Required, formatBytes(e.Required), e.Available, formatBytes(e.Available))
+}
+
+// formatBytes formats bytes like utils.FormatBytes, which imports this package
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
 func (e *DiskSpaceError) Unwrap() error {
 	return e.Err
 }
 
+// Is matches ErrDiskSpace
+func (e *DiskSpaceError) Is(target error) bool {
+	return target == ErrDiskSpace
+}
+
 // NewDiskSpaceError creates a new DiskSpaceError.
 // Use this when a volume migration would fail due to insufficient disk space.
 // The location parameter should be "local" or "remote" to indicate which system
@@ -112,6 +162,11 @@ func (e *PermissionError) Unwrap() error {
 	return e.Err
 }
 
+// Is matches ErrPermission
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermission
+}
+
 // NewPermissionError creates a new PermissionError.
 // Use this when file or directory operations fail due to insufficient permissions.
 // The operation parameter should describe the action (e.g., "read", "write", "execute").
@@ -124,3 +179,109 @@ func NewPermissionError(operation, path string, err error) *PermissionError {
 		Err:       err,
 	}
 }
+
+// PartialMigrationError indicates that some volumes failed while the others were migrated
+type PartialMigrationError struct {
+	Succeeded []string
+	Failed    []string
+}
+
+func (e *PartialMigrationError) Error() string {
+	return fmt.Sprintf("%d of %d volumes failed to migrate: %s",
+		len(e.Failed), len(e.Failed)+len(e.Succeeded), strings.Join(e.Failed, ", "))
+}
+
+// Is matches ErrPartialMigration
+func (e *PartialMigrationError) Is(target error) bool {
+	return target == ErrPartialMigration
+}
+
+// NewPartialMigrationError creates a new PartialMigrationError.
+// Use this when --continue-on-error let a run finish despite failed volumes.
+// The succeeded and failed parameters list volume names in migration order.
+func NewPartialMigrationError(succeeded, failed []string) *PartialMigrationError {
+	return &PartialMigrationError{
+		Succeeded: succeeded,
+		Failed:    failed,
+	}
+}
+
+// ConfigError indicates invalid flags, arguments, profile or plan.
+// Its message is the underlying error's; the type only classifies it.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrConfig
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrConfig
+}
+
+// NewConfigError creates a new ConfigError.
+// Use this when the run cannot start because of what the user asked for, before anything is touched.
+func NewConfigError(err error) *ConfigError {
+	return &ConfigError{Err: err}
+}
+
+// DockerError indicates a local or remote Docker engine that is unreachable, unusable or too old.
+// Its message is the underlying error's; the type only classifies it.
+type DockerError struct {
+	Location string // "local" or "remote"
+	Err      error
+}
+
+func (e *DockerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DockerError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrDocker
+func (e *DockerError) Is(target error) bool {
+	return target == ErrDocker
+}
+
+// NewDockerError creates a new DockerError.
+// The location parameter should be "local" or "remote" to indicate which engine failed.
+func NewDockerError(location string, err error) *DockerError {
+	return &DockerError{
+		Location: location,
+		Err:      err,
+	}
+}
+
+// VerificationError indicates that migrated data does not match its source.
+// Its message is the underlying error's; the type only classifies it.
+type VerificationError struct {
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrVerification
+func (e *VerificationError) Is(target error) bool {
+	return target == ErrVerification
+}
+
+// NewVerificationError creates a new VerificationError.
+// Use this when a comparison of the remote data with the source found differences,
+// not when the comparison itself could not run.
+func NewVerificationError(err error) *VerificationError {
+	return &VerificationError{Err: err}
+}
@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes of volume-migrator. They are part of its interface: wrapper scripts branch on them,
+// so existing codes never change meaning.
+const (
+	ExitOK           = 0
+	ExitFailure      = 1   // Any failure not covered below
+	ExitPartial      = 2   // Some volumes were migrated and others failed (--continue-on-error)
+	ExitConfig       = 3   // Invalid flags, arguments, profile or plan; nothing was touched
+	ExitSSH          = 4   // Connecting or authenticating to the remote host failed
+	ExitDocker       = 5   // The local or remote Docker engine is unreachable, unusable or too old
+	ExitDiskSpace    = 6   // Not enough disk space on either host
+	ExitVerification = 7   // Migrated data does not match its source
+	ExitCancelled    = 130 // Interrupted with Ctrl+C or SIGTERM, or declined at a prompt
+)
+
+// ExitCode returns the exit code for err, ExitOK when it is nil
+func ExitCode(err error) int {
+	var (
+		partial      *PartialMigrationError
+		config       *ConfigError
+		diskSpace    *DiskSpaceError
+		verification *VerificationError
+		sshErr       *SSHConnectionError
+		docker       *DockerError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrCancelled), errors.Is(err, context.Canceled):
+		return ExitCancelled
+	case errors.As(err, &partial):
+		return ExitPartial
+	case errors.As(err, &config):
+		return ExitConfig
+	case errors.As(err, &diskSpace):
+		return ExitDiskSpace
+	case errors.As(err, &verification):
+		return ExitVerification
+	case errors.As(err, &sshErr):
+		return ExitSSH
+	case errors.As(err, &docker):
+		return ExitDocker
+	}
+	return ExitFailure
+}
@@ -0,0 +1,82 @@
+package errors
+
+import "errors"
+
+// Error codes reported by --output json. Like the exit codes they are stable, so calling
+// systems can match on them.
+const (
+	CodeCancelled           = "CANCELLED"
+	CodePartialMigration    = "PARTIAL_MIGRATION"
+	CodeConfigInvalid       = "CONFIG_INVALID"
+	CodeVolumeNotFound      = "VOLUME_NOT_FOUND"
+	CodeDiskSpace           = "DISK_SPACE"
+	CodePermissionDenied    = "PERMISSION_DENIED"
+	CodeVerificationFailed  = "VERIFICATION_FAILED"
+	CodeSSHAuthFailed       = "SSH_AUTH_FAILED"
+	CodeSSHConnectionFailed = "SSH_CONNECTION_FAILED"
+	CodeDockerError         = "DOCKER_ERROR"
+	CodeFailed              = "FAILED" // Any failure not covered above
+)
+
+// Report is an error as --output json prints it. The fields after ExitCode are set when the
+// error carries them.
+type Report struct {
+	Code           string   `json:"code"`
+	Message        string   `json:"message"`
+	ExitCode       int      `json:"exit_code"`
+	Volume         string   `json:"volume,omitempty"`
+	Host           string   `json:"host,omitempty"`
+	Location       string   `json:"location,omitempty"` // "local" or "remote"
+	Path           string   `json:"path,omitempty"`
+	RequiredBytes  int64    `json:"required_bytes,omitempty"`
+	AvailableBytes int64    `json:"available_bytes,omitempty"`
+	Succeeded      []string `json:"succeeded,omitempty"`
+	Failed         []string `json:"failed,omitempty"`
+}
+
+// NewReport describes err for --output json, classifying it by the first of the typed errors
+// of this package it wraps
+func NewReport(err error) Report {
+	r := Report{Code: CodeFailed, Message: err.Error(), ExitCode: ExitCode(err)}
+
+	var (
+		partial      *PartialMigrationError
+		config       *ConfigError
+		volume       *VolumeNotFoundError
+		diskSpace    *DiskSpaceError
+		permission   *PermissionError
+		verification *VerificationError
+		sshErr       *SSHConnectionError
+		docker       *DockerError
+	)
+	switch {
+	case r.ExitCode == ExitCancelled:
+		r.Code = CodeCancelled
+	case errors.As(err, &partial):
+		r.Code = CodePartialMigration
+		r.Succeeded, r.Failed = partial.Succeeded, partial.Failed
+	case errors.As(err, &config):
+		r.Code = CodeConfigInvalid
+	case errors.As(err, &volume):
+		r.Code = CodeVolumeNotFound
+		r.Volume = volume.VolumeName
+	case errors.As(err, &diskSpace):
+		r.Code = CodeDiskSpace
+		r.Location, r.RequiredBytes, r.AvailableBytes = diskSpace.Location, diskSpace.Required, diskSpace.Available
+	case errors.As(err, &permission):
+		r.Code = CodePermissionDenied
+		r.Path = permission.Path
+	case errors.As(err, &verification):
+		r.Code = CodeVerificationFailed
+	case errors.As(err, &sshErr):
+		r.Code = CodeSSHConnectionFailed
+		if errors.Is(err, ErrSSHAuth) {
+			r.Code = CodeSSHAuthFailed
+		}
+		r.Host = sshErr.Host
+	case errors.As(err, &docker):
+		r.Code = CodeDockerError
+		r.Location = docker.Location
+	}
+	return r
+}
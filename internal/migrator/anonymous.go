@@ -0,0 +1,92 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"volume-migrator/internal/docker"
+)
+
+// Supported anonymous volume handling modes
+const (
+	AnonymousInclude = "include" // Migrate under the generated name
+	AnonymousSkip    = "skip"    // Leave anonymous volumes out
+	AnonymousRename  = "rename"  // Migrate as <container>-<mount-path-slug>
+)
+
+// ValidateAnonymousMode checks that mode is empty or one of the supported anonymous volume modes
+func ValidateAnonymousMode(mode string) error {
+	switch mode {
+	case "", AnonymousInclude, AnonymousSkip, AnonymousRename:
+		return nil
+	default:
+		return fmt.Errorf("invalid anonymous volume mode '%s': must be one of include, skip, rename", mode)
+	}
+}
+
+// anonymousVolumeName derives a deterministic, readable name for an anonymous volume
+// from its container and mount path (e.g. web + /var/lib/data -> web-var-lib-data)
+func anonymousVolumeName(container, mountPath string) string {
+	var b strings.Builder
+	for _, r := range container + "-" + strings.Trim(mountPath, "/") {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+
+	// Collapse separators so the name stays valid (no "..", no leading/trailing punctuation)
+	name := b.String()
+	for strings.Contains(name, "--") || strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "--", "-")
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	return strings.Trim(name, "-.")
+}
+
+// ApplyAnonymousMode skips or renames anonymous volumes according to mode
+// Returns the volumes to migrate and the names of the skipped ones
+func ApplyAnonymousMode(volumes []docker.VolumeInfo, mode string) ([]docker.VolumeInfo, []string) {
+	if mode == "" || mode == AnonymousInclude {
+		return volumes, nil
+	}
+
+	taken := make(map[string]bool)
+	for _, v := range volumes {
+		if !v.Anonymous {
+			taken[v.Name] = true
+		}
+	}
+
+	var result []docker.VolumeInfo
+	var skipped []string
+	for _, v := range volumes {
+		if !v.Anonymous {
+			result = append(result, v)
+			continue
+		}
+
+		if mode == AnonymousSkip {
+			skipped = append(skipped, v.Name)
+			continue
+		}
+
+		// Without a container there is nothing to derive a name from (e.g. --by-volume)
+		if v.Container == "N/A" || v.MountPath == "N/A" {
+			result = append(result, v)
+			continue
+		}
+
+		// Fall back to a short id suffix if the derived name is already in use
+		target := anonymousVolumeName(v.Container, v.MountPath)
+		if target == "" || taken[target] {
+			target = strings.TrimPrefix(target+"-"+v.Name[:12], "-")
+		}
+		taken[target] = true
+
+		v.TargetName = target
+		result = append(result, v)
+	}
+	return result, skipped
+}
@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+)
+
+const testAnonymousID = "3f4e1a0c9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+
+func TestValidateAnonymousMode(t *testing.T) {
+	for _, mode := range []string{"", AnonymousInclude, AnonymousSkip, AnonymousRename} {
+		if err := ValidateAnonymousMode(mode); err != nil {
+			t.Errorf("ValidateAnonymousMode(%q) unexpected error: %v", mode, err)
+		}
+	}
+	if err := ValidateAnonymousMode("keep"); err == nil {
+		t.Error("ValidateAnonymousMode(\"keep\") expected error, got nil")
+	}
+}
+
+func TestAnonymousVolumeName(t *testing.T) {
+	tests := []struct {
+		container string
+		mountPath string
+		expected  string
+	}{
+		{"web", "/var/lib/data", "web-var-lib-data"},
+		{"web", "/data/", "web-data"},
+		{"web", "/", "web"},
+		{"my app", "/srv/my files", "my-app-srv-my-files"},
+		{"db", "/var/lib/postgresql/data.d", "db-var-lib-postgresql-data.d"},
+		{"web", "/cache/../tmp", "web-cache-.-tmp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := anonymousVolumeName(tt.container, tt.mountPath)
+			if result != tt.expected {
+				t.Errorf("anonymousVolumeName(%q, %q) = %q, want %q", tt.container, tt.mountPath, result, tt.expected)
+			}
+			if !shell.ValidateVolumeName(result) {
+				t.Errorf("anonymousVolumeName(%q, %q) = %q is not a valid volume name", tt.container, tt.mountPath, result)
+			}
+		})
+	}
+}
+
+func TestApplyAnonymousMode(t *testing.T) {
+	volumes := []docker.VolumeInfo{
+		{Name: "app_data", Container: "web", MountPath: "/data"},
+		{Name: testAnonymousID, Container: "web", MountPath: "/var/cache", Anonymous: true},
+	}
+
+	t.Run("include keeps generated names", func(t *testing.T) {
+		result, skipped := ApplyAnonymousMode(volumes, AnonymousInclude)
+		if !reflect.DeepEqual(result, volumes) || skipped != nil {
+			t.Errorf("ApplyAnonymousMode(include) = %v, %v", result, skipped)
+		}
+	})
+
+	t.Run("skip drops anonymous volumes", func(t *testing.T) {
+		result, skipped := ApplyAnonymousMode(volumes, AnonymousSkip)
+		if len(result) != 1 || result[0].Name != "app_data" {
+			t.Errorf("ApplyAnonymousMode(skip) kept %v", result)
+		}
+		if !reflect.DeepEqual(skipped, []string{testAnonymousID}) {
+			t.Errorf("ApplyAnonymousMode(skip) skipped %v", skipped)
+		}
+	})
+
+	t.Run("rename sets target name", func(t *testing.T) {
+		result, skipped := ApplyAnonymousMode(volumes, AnonymousRename)
+		if len(result) != 2 || skipped != nil {
+			t.Fatalf("ApplyAnonymousMode(rename) = %v, %v", result, skipped)
+		}
+		if result[0].RemoteName() != "app_data" {
+			t.Errorf("named volume remote name = %q, want %q", result[0].RemoteName(), "app_data")
+		}
+		if result[1].RemoteName() != "web-var-cache" {
+			t.Errorf("anonymous volume remote name = %q, want %q", result[1].RemoteName(), "web-var-cache")
+		}
+	})
+
+	t.Run("rename avoids collisions with named volumes", func(t *testing.T) {
+		colliding := []docker.VolumeInfo{
+			{Name: "web-var-cache", Container: "other", MountPath: "/cache"},
+			{Name: testAnonymousID, Container: "web", MountPath: "/var/cache", Anonymous: true},
+		}
+		result, _ := ApplyAnonymousMode(colliding, AnonymousRename)
+		if want := "web-var-cache-3f4e1a0c9b8d"; result[1].RemoteName() != want {
+			t.Errorf("anonymous volume remote name = %q, want %q", result[1].RemoteName(), want)
+		}
+	})
+
+	t.Run("rename keeps volumes without a container", func(t *testing.T) {
+		orphan := []docker.VolumeInfo{{Name: testAnonymousID, Container: "N/A", MountPath: "N/A", Anonymous: true}}
+		result, skipped := ApplyAnonymousMode(orphan, AnonymousRename)
+		if len(result) != 1 || result[0].RemoteName() != testAnonymousID || skipped != nil {
+			t.Errorf("ApplyAnonymousMode(rename) = %v, %v", result, skipped)
+		}
+	})
+}
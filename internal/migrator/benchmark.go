@@ -0,0 +1,153 @@
+package migrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/ssh"
+)
+
+const (
+	// benchmarkSampleSize is how much of a volume's tar stream --adaptive-compression compresses at each level
+	benchmarkSampleSize = 8 * 1024 * 1024
+	// benchmarkUploadSize is how much random data is uploaded to measure the link, once per run
+	benchmarkUploadSize = 4 * 1024 * 1024
+	// benchmarkFile names the upload of the link benchmark in both temp directories
+	benchmarkFile = "link-benchmark"
+)
+
+// benchmarkLevels are the gzip levels compared, gzip.NoCompression storing the data as it is
+var benchmarkLevels = []int{gzip.NoCompression, gzip.BestSpeed, 3, 6, gzip.BestCompression}
+
+// compressionResult is how one gzip level did on a benchmark sample
+type compressionResult struct {
+	Level int
+	Ratio float64 // Compressed size over sample size
+	Rate  float64 // Sample bytes compressed per second
+}
+
+// secondsPerByte estimates the time one byte of volume data takes to compress and then upload
+// at linkRate bytes/second. Export and upload run one after the other, so the times add up.
+func (r compressionResult) secondsPerByte(linkRate float64) float64 {
+	return 1/r.Rate + r.Ratio/linkRate
+}
+
+// benchmarkCompression compresses sample at each of benchmarkLevels, timing it
+func benchmarkCompression(sample []byte) []compressionResult {
+	results := make([]compressionResult, 0, len(benchmarkLevels))
+	for _, level := range benchmarkLevels {
+		counter := &countingWriter{}
+		start := time.Now()
+		gz, err := gzip.NewWriterLevel(counter, level)
+		if err != nil {
+			continue
+		}
+		gz.Write(sample)
+		gz.Close()
+		elapsed := max(time.Since(start), time.Microsecond)
+
+		results = append(results, compressionResult{
+			Level: level,
+			Ratio: float64(counter.n) / float64(len(sample)),
+			Rate:  float64(len(sample)) / elapsed.Seconds(),
+		})
+	}
+	return results
+}
+
+// fastestCompression returns the result with the lowest estimated export and upload time
+func fastestCompression(results []compressionResult, linkRate float64) compressionResult {
+	best := results[0]
+	for _, r := range results[1:] {
+		if r.secondsPerByte(linkRate) < best.secondsPerByte(linkRate) {
+			best = r
+		}
+	}
+	return best
+}
+
+// chooseCompression benchmarks compressing a sample of the volume against the measured link and
+// sets the gzip level of opts to the fastest one. The configured level is kept when the
+// benchmark fails or the volume is too small for the sample to tell.
+func (m *Migrator) chooseCompression(v docker.VolumeInfo, opts *ExportOptions) {
+	fields := log.WithField("volume", v.Name)
+	if m.linkRate == 0 {
+		rate, err := m.measureLinkRate()
+		if err != nil {
+			fields.WithError(err).Warn("Failed to measure the link, keeping the configured compression level")
+			return
+		}
+		m.linkRate = rate
+		log.WithField("rate", ssh.FormatRate(rate)).Debug("Measured upload rate")
+	}
+
+	sample, err := m.sampleVolume(v)
+	if err != nil {
+		fields.WithError(err).Warn("Failed to sample volume, keeping the configured compression level")
+		return
+	}
+	if len(sample) < autoCompressMinSample {
+		fields.Debug("Volume too small to benchmark compression, keeping the configured level")
+		return
+	}
+
+	results := benchmarkCompression(sample)
+	for _, r := range results {
+		fields.WithFields(logrus.Fields{
+			"level": r.Level,
+			"ratio": fmt.Sprintf("%.2f", r.Ratio),
+			"rate":  ssh.FormatRate(r.Rate),
+		}).Debug("Compression benchmark")
+	}
+
+	best := fastestCompression(results, m.linkRate)
+	fields.WithFields(logrus.Fields{
+		"level": best.Level,
+		"ratio": fmt.Sprintf("%.2f", best.Ratio),
+		"link":  ssh.FormatRate(m.linkRate),
+	}).Debug("Chose compression level")
+	if best.Level == gzip.NoCompression {
+		opts.Stored = true
+		return
+	}
+	opts.CompressionLevel = best.Level
+}
+
+// sampleVolume reads up to benchmarkSampleSize bytes of the volume's tar stream
+func (m *Migrator) sampleVolume(v docker.VolumeInfo) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	script := fmt.Sprintf("tar -cf - -C /data . | head -c %d", benchmarkSampleSize)
+	if err := m.dockerClient.ExecCommandWithOutput(&stdout, &stderr, "run", "--rm", "-v", v.Name+":/data:ro", m.helperImage, "sh", "-c", script); err != nil {
+		return nil, fmt.Errorf("failed to read volume %s: %w, stderr: %s", v.Name, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// measureLinkRate uploads benchmarkUploadSize random bytes, which no layer can compress, to the
+// remote temp directory and returns the upload rate in bytes/second
+func (m *Migrator) measureLinkRate() (float64, error) {
+	data := make([]byte, benchmarkUploadSize)
+	rand.Read(data)
+	localPath := filepath.Join(m.config.TempDir, benchmarkFile)
+	if err := os.WriteFile(localPath, data, 0600); err != nil {
+		return 0, fmt.Errorf("failed to write benchmark file: %w", err)
+	}
+	defer os.Remove(localPath)
+
+	remotePath := path.Join(m.config.RemoteTempDir, benchmarkFile)
+	start := time.Now()
+	if err := m.sshClient.TransferFile(localPath, remotePath, false); err != nil {
+		return 0, err
+	}
+	elapsed := max(time.Since(start), time.Microsecond)
+	m.sshClient.RemoveFile(remotePath)
+	return float64(len(data)) / elapsed.Seconds(), nil
+}
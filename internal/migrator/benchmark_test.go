@@ -0,0 +1,108 @@
+package migrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestFastestCompression(t *testing.T) {
+	const mb = 1024 * 1024
+	compressible := []compressionResult{
+		{Level: gzip.NoCompression, Ratio: 1.0, Rate: 2000 * mb},
+		{Level: gzip.BestSpeed, Ratio: 0.4, Rate: 200 * mb},
+		{Level: 6, Ratio: 0.3, Rate: 40 * mb},
+		{Level: gzip.BestCompression, Ratio: 0.29, Rate: 10 * mb},
+	}
+	incompressible := []compressionResult{
+		{Level: gzip.NoCompression, Ratio: 1.0, Rate: 2000 * mb},
+		{Level: gzip.BestSpeed, Ratio: 0.99, Rate: 60 * mb},
+		{Level: gzip.BestCompression, Ratio: 0.98, Rate: 15 * mb},
+	}
+
+	tests := []struct {
+		name     string
+		results  []compressionResult
+		linkRate float64
+		want     int
+	}{
+		{"slow link", compressible, 1 * mb, 6},
+		{"fast link", compressible, 100 * mb, gzip.BestSpeed},
+		{"local network", compressible, 5000 * mb, gzip.NoCompression},
+		{"incompressible data", incompressible, 1 * mb, gzip.NoCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fastestCompression(tt.results, tt.linkRate); got.Level != tt.want {
+				t.Errorf("fastestCompression() level = %d, want %d", got.Level, tt.want)
+			}
+		})
+	}
+}
+
+func TestBenchmarkCompression(t *testing.T) {
+	sample := bytes.Repeat([]byte("volume data compresses well "), 10000)
+
+	results := benchmarkCompression(sample)
+	if len(results) != len(benchmarkLevels) {
+		t.Fatalf("benchmarkCompression() returned %d results, want %d", len(results), len(benchmarkLevels))
+	}
+	for _, r := range results {
+		if r.Rate <= 0 {
+			t.Errorf("level %d: rate = %f, want > 0", r.Level, r.Rate)
+		}
+		if r.Level == gzip.NoCompression && r.Ratio < 1 {
+			t.Errorf("stored ratio = %.2f, want at least 1", r.Ratio)
+		}
+		if r.Level == gzip.BestSpeed && r.Ratio > 0.1 {
+			t.Errorf("level 1 ratio = %.2f on repetitive data, want under 0.1", r.Ratio)
+		}
+	}
+}
+
+func TestChooseCompression(t *testing.T) {
+	sample := string(bytes.Repeat([]byte("volume data compresses well "), 10000))
+
+	tests := []struct {
+		name     string
+		sample   string
+		linkRate float64
+		want     string // stored, compressed, unchanged, or chosen for either of the first two
+	}{
+		// The fake upload is instant, so the choice depends on timing alone
+		{"measured link", sample, 0, "chosen"},
+		{"slow link", sample, 64 * 1024, "compressed"},
+		{"tiny volume", "small", 64 * 1024, "unchanged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &fakeDocker{responses: map[string]fakeResponse{"run": {output: tt.sample}}}
+			m := &Migrator{
+				config:       &Config{TempDir: t.TempDir(), RemoteTempDir: "/tmp/vm"},
+				dockerClient: local,
+				sshClient:    &fakeRemote{},
+				helperImage:  "tools",
+				linkRate:     tt.linkRate,
+			}
+
+			var opts ExportOptions
+			m.chooseCompression(docker.VolumeInfo{Name: "data"}, &opts)
+			if m.linkRate <= 0 {
+				t.Errorf("link rate = %f after the benchmark, want it measured", m.linkRate)
+			}
+			got := "unchanged"
+			if opts.Stored {
+				got = "stored"
+			} else if opts.CompressionLevel != 0 {
+				got = "compressed"
+			}
+			if got != tt.want && !(tt.want == "chosen" && got != "unchanged") {
+				t.Errorf("chooseCompression() left the archive %s (level %d), want %s", got, opts.CompressionLevel, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/ssh"
+	"volume-migrator/internal/utils"
+)
+
+// CheckResult is the outcome of one preflight check
+type CheckResult struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Names of the preflight checks, in the order they run
+const (
+	checkSSH       = "SSH connection and host key"
+	checkDocker    = "Remote Docker access"
+	checkVersion   = "Remote Docker version"
+	checkEnv       = "Remote Docker environment"
+	checkHelper    = "Helper image"
+	checkTempDir   = "Remote temp directory writable"
+	checkDiskSpace = "Remote disk space"
+)
+
+// remoteChecks are the checks that need a working SSH connection with Docker access
+var remoteChecks = []string{checkVersion, checkEnv, checkHelper, checkTempDir, checkDiskSpace}
+
+// RunChecks verifies that the remote host is ready for a migration without migrating anything.
+// Only RemoteHost, the SSH settings, HelperImage and RemoteTempDir are used from the config.
+// Checks that cannot run because an earlier one failed are reported as failed.
+func RunChecks(ctx context.Context, config *Config) []CheckResult {
+	var results []CheckResult
+
+	sshConfig, err := sshClientConfig(config)
+	if err != nil {
+		results = append(results, CheckResult{Name: checkSSH, Detail: err.Error()})
+		return append(results, skippedChecks(append([]string{checkDocker}, remoteChecks...), "requires an SSH connection")...)
+	}
+
+	sshClient, err := ssh.NewClient(ctx, sshConfig)
+	if errors.Is(err, ssh.ErrDockerNotAccessible) {
+		results = append(results,
+			CheckResult{Name: checkSSH, Passed: true, Detail: sshCheckDetail(config)},
+			CheckResult{Name: checkDocker, Detail: "docker ps failed with every allowed escalation method: " + err.Error()})
+		return append(results, skippedChecks(remoteChecks, "requires remote Docker access")...)
+	}
+	if err != nil {
+		results = append(results, CheckResult{Name: checkSSH, Detail: err.Error()})
+		return append(results, skippedChecks(append([]string{checkDocker}, remoteChecks...), "requires an SSH connection")...)
+	}
+	defer sshClient.Close()
+
+	results = append(results, CheckResult{Name: checkSSH, Passed: true, Detail: sshCheckDetail(config)})
+
+	access := "direct"
+	if sshClient.RequiresEscalation() {
+		access = "via " + sshClient.Escalation().Name
+	}
+	results = append(results, CheckResult{Name: checkDocker, Passed: true, Detail: access})
+
+	results = append(results, checkDockerVersion(sshClient, config.MinRemoteVersion))
+	results = append(results, checkEnvironment(sshClient))
+	results = append(results, checkHelperImage(sshClient, resolveHelperImage(config.HelperImage, config.HelperRegistry, false)))
+
+	tempDir := config.RemoteTempDir
+	if tempDir == "" {
+		tempDir = chooseRemoteTempBase(sshClient)
+	}
+	results = append(results, checkWritableDir(sshClient, tempDir))
+	results = append(results, checkRemoteDiskSpace(sshClient, tempDir))
+
+	return results
+}
+
+// FailedChecks returns the number of checks that did not pass
+func FailedChecks(results []CheckResult) int {
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// skippedChecks reports checks that could not run
+func skippedChecks(names []string, reason string) []CheckResult {
+	results := make([]CheckResult, len(names))
+	for i, name := range names {
+		results[i] = CheckResult{Name: name, Detail: "skipped: " + reason}
+	}
+	return results
+}
+
+// sshCheckDetail describes how the host key was checked
+func sshCheckDetail(config *Config) string {
+	if !config.StrictHostKeyChecking {
+		return fmt.Sprintf("connected to %s (host key NOT verified)", config.RemoteHost)
+	}
+	return fmt.Sprintf("connected to %s, host key verified", config.RemoteHost)
+}
+
+// checkDockerVersion reports the remote Docker server version, failing below minimum
+func checkDockerVersion(sshClient RemoteExecutor, minimum string) CheckResult {
+	version, err := remoteDockerVersion(sshClient)
+	if err != nil {
+		return CheckResult{Name: checkVersion, Detail: err.Error()}
+	}
+	if err := checkMinimumVersion("remote", version, minimum); err != nil {
+		return CheckResult{Name: checkVersion, Detail: err.Error()}
+	}
+	return CheckResult{Name: checkVersion, Passed: true, Detail: version}
+}
+
+// checkEnvironment reports the remote storage driver, kernel and architecture, with warnings about the storage
+// Hosts running Windows containers fail, since the Linux helper images cannot run there
+func checkEnvironment(sshClient RemoteExecutor) CheckResult {
+	env, err := RemoteEnvironment(sshClient)
+	if err != nil {
+		return CheckResult{Name: checkEnv, Detail: err.Error()}
+	}
+	for _, issue := range CompareEnvironments(env, env, &Config{}, nil) {
+		if issue.Fatal {
+			return CheckResult{Name: checkEnv, Detail: issue.Message}
+		}
+	}
+	detail := env.String()
+	if root, err := remoteDockerRootDir(sshClient); err == nil {
+		if storage, err := RemoteStorage(sshClient, env.StorageDriver, root); err == nil {
+			for _, issue := range StorageIssues(storage) {
+				detail += "; warning: " + issue.Message
+			}
+		}
+	}
+	return CheckResult{Name: checkEnv, Passed: true, Detail: detail}
+}
+
+// checkHelperImage verifies the helper image provides tar on the remote host
+// A missing image passes, since a migration pulls it (or loads it with --helper-image-tar)
+func checkHelperImage(sshClient RemoteExecutor, image string) CheckResult {
+	if _, err := sshClient.RunDockerCommand("image inspect " + shell.ShellEscape(image)); err != nil {
+		return CheckResult{Name: checkHelper, Passed: true, Detail: fmt.Sprintf("%s not present yet, it will be pulled or loaded during migration", image)}
+	}
+	if err := CheckRemoteHelperImage(sshClient, image, false); err != nil {
+		return CheckResult{Name: checkHelper, Detail: err.Error()}
+	}
+	return CheckResult{Name: checkHelper, Passed: true, Detail: fmt.Sprintf("%s present and provides tar", image)}
+}
+
+// checkWritableDir verifies dir, or the closest existing ancestor it would be created in, is writable
+func checkWritableDir(sshClient RemoteExecutor, dir string) CheckResult {
+	cmd := fmt.Sprintf(`p=%s; while [ ! -e "$p" ]; do p=$(dirname "$p"); done; [ -d "$p" ] && [ -w "$p" ]`, shell.ShellEscape(dir))
+	if _, err := sshClient.RunCommand(cmd); err != nil {
+		return CheckResult{Name: checkTempDir, Detail: fmt.Sprintf("%s is not writable by the SSH user", dir)}
+	}
+	return CheckResult{Name: checkTempDir, Passed: true, Detail: dir}
+}
+
+// checkRemoteDiskSpace reports free space for the temp directory and the Docker data root, and
+// the free inodes of the latter
+func checkRemoteDiskSpace(sshClient RemoteExecutor, tempDir string) CheckResult {
+	tempSpace, err := utils.GetRemoteDiskSpace(sshClient, tempDir)
+	if err != nil {
+		return CheckResult{Name: checkDiskSpace, Detail: err.Error()}
+	}
+	detail := fmt.Sprintf("%s free for %s", utils.FormatBytes(int64(tempSpace.Available)), tempDir)
+
+	if root, err := remoteDockerRootDir(sshClient); err == nil {
+		if rootSpace, err := utils.GetRemoteDiskSpace(sshClient, root); err == nil {
+			detail += fmt.Sprintf(", %s free for %s", utils.FormatBytes(int64(rootSpace.Available)), root)
+		}
+		if inodes, err := utils.GetRemoteInodes(sshClient, root); err == nil && inodes.Total > 0 {
+			detail += fmt.Sprintf(" (%d free inodes)", inodes.Free)
+		}
+	}
+	return CheckResult{Name: checkDiskSpace, Passed: true, Detail: detail}
+}
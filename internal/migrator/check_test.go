@@ -0,0 +1,35 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFailedChecks(t *testing.T) {
+	results := []CheckResult{
+		{Name: checkSSH, Passed: true},
+		{Name: checkDocker, Passed: false},
+	}
+	results = append(results, skippedChecks(remoteChecks, "requires remote Docker access")...)
+
+	if failed := FailedChecks(results); failed != 1+len(remoteChecks) {
+		t.Errorf("FailedChecks() = %d, want %d", failed, 1+len(remoteChecks))
+	}
+	for _, r := range results[2:] {
+		if !strings.HasPrefix(r.Detail, "skipped: ") {
+			t.Errorf("expected skipped detail for %s, got %q", r.Name, r.Detail)
+		}
+	}
+}
+
+func TestSSHCheckDetail(t *testing.T) {
+	config := &Config{RemoteHost: "user@host", StrictHostKeyChecking: true}
+	if detail := sshCheckDetail(config); !strings.Contains(detail, "host key verified") {
+		t.Errorf("unexpected detail with strict checking: %q", detail)
+	}
+
+	config.StrictHostKeyChecking = false
+	if detail := sshCheckDetail(config); !strings.Contains(detail, "NOT verified") {
+		t.Errorf("expected warning without strict checking, got %q", detail)
+	}
+}
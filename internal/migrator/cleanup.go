@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/sirupsen/logrus"
-	"volume-migrator/internal/ssh"
 )
 
 // CleanupLocal removes local temporary files and directories
@@ -20,7 +19,7 @@ func CleanupLocal(tempDir string) error {
 }
 
 // CleanupRemote removes remote temporary files and directories
-func CleanupRemote(sshClient *ssh.Client, remoteTempDir string) error {
+func CleanupRemote(sshClient RemoteExecutor, remoteTempDir string) error {
 	log.WithField("remote_temp_dir", remoteTempDir).Debug("Cleaning up remote temporary directory")
 
 	if err := sshClient.RemoveDirectory(remoteTempDir); err != nil {
@@ -47,7 +46,7 @@ func CleanupArchives(archivePaths map[string]string) error {
 }
 
 // CleanupRemoteArchives removes specific archive files on remote
-func CleanupRemoteArchives(sshClient *ssh.Client, archivePaths map[string]string, remoteTempDir string) error {
+func CleanupRemoteArchives(sshClient RemoteExecutor, archivePaths map[string]string, remoteTempDir string) error {
 	for volumeName, path := range archivePaths {
 		remotePath := fmt.Sprintf("%s/%s", remoteTempDir, path)
 
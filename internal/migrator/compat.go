@@ -0,0 +1,271 @@
+package migrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+)
+
+// environmentFormat is the docker info template gathering the fields of an EnvironmentInfo
+const environmentFormat = "{{.ServerVersion}}|{{.Driver}}|{{.KernelVersion}}|{{.Architecture}}|{{.OSType}}"
+
+// tarVersionCommand prints the first line of the helper image's tar version
+const tarVersionCommand = "tar --version 2>&1 | head -n 1"
+
+// EnvironmentInfo describes the Docker engine and host on one end of a migration
+type EnvironmentInfo struct {
+	DockerVersion string `json:"docker_version"`
+	StorageDriver string `json:"storage_driver"`
+	Kernel        string `json:"kernel"`
+	Architecture  string `json:"architecture"`  // As reported by docker info, normalized to uname names (x86_64, aarch64)
+	OSType        string `json:"os_type"`       // linux or windows
+	Tar           string `json:"tar,omitempty"` // First line of the helper image's tar --version, empty until probed
+}
+
+// String summarizes the environment for reports
+func (e EnvironmentInfo) String() string {
+	return fmt.Sprintf("docker %s, %s storage driver, kernel %s, %s/%s", e.DockerVersion, e.StorageDriver, e.Kernel, e.OSType, e.Architecture)
+}
+
+// CompatIssue is a known-incompatible or risky combination of source and destination
+type CompatIssue struct {
+	Fatal   bool // The migration cannot work; overridden only by --force
+	Message string
+}
+
+// architectureAliases maps Go/OCI architecture names to the uname names docker info reports
+var architectureAliases = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+}
+
+// parseEnvironmentInfo parses the output of docker info with environmentFormat
+func parseEnvironmentInfo(output string) (EnvironmentInfo, error) {
+	fields := strings.Split(strings.TrimSpace(output), "|")
+	if len(fields) != 5 {
+		return EnvironmentInfo{}, fmt.Errorf("unexpected docker info output: %q", output)
+	}
+	arch := fields[3]
+	if alias, ok := architectureAliases[arch]; ok {
+		arch = alias
+	}
+	return EnvironmentInfo{
+		DockerVersion: fields[0],
+		StorageDriver: fields[1],
+		Kernel:        fields[2],
+		Architecture:  arch,
+		OSType:        fields[4],
+	}, nil
+}
+
+// LocalEnvironment gathers the local Docker engine and host details
+func LocalEnvironment(dockerClient DockerRunner) (EnvironmentInfo, error) {
+	output, err := dockerClient.ExecCommand("info", "--format", environmentFormat)
+	if err != nil {
+		return EnvironmentInfo{}, fmt.Errorf("failed to read local docker info: %w", err)
+	}
+	return parseEnvironmentInfo(output)
+}
+
+// RemoteEnvironment gathers the remote Docker engine and host details
+func RemoteEnvironment(sshClient RemoteExecutor) (EnvironmentInfo, error) {
+	output, err := sshClient.RunDockerCommand("info --format " + shell.ShellEscape(environmentFormat))
+	if err != nil {
+		return EnvironmentInfo{}, fmt.Errorf("failed to read remote docker info: %w", err)
+	}
+	return parseEnvironmentInfo(output)
+}
+
+// compareDockerVersions compares two engine versions such as "24.0.7" or "20.10.17+dfsg1" by their
+// numeric components, returning -1, 0 or 1. Missing components count as zero.
+func compareDockerVersions(a, b string) int {
+	pa, pb := versionComponents(a), versionComponents(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionComponents returns the leading dot-separated numbers of a version string
+func versionComponents(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	var components []int
+	for _, part := range strings.Split(version, ".") {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, _ := strconv.Atoi(part[:end])
+		components = append(components, n)
+		if end < len(part) {
+			break
+		}
+	}
+	return components
+}
+
+// isGNUTar reports whether a tar --version line comes from GNU tar
+func isGNUTar(version string) bool {
+	return strings.Contains(version, "GNU tar")
+}
+
+// CompareEnvironments reports the known-incompatible combinations of a source and destination
+// for a migration with config of the given volumes
+func CompareEnvironments(local, remote EnvironmentInfo, config *Config, volumes []docker.VolumeInfo) []CompatIssue {
+	var issues []CompatIssue
+
+	if remote.OSType != "" && remote.OSType != "linux" {
+		issues = append(issues, CompatIssue{Fatal: true, Message: fmt.Sprintf(
+			"remote Docker runs %s containers: the Linux helper images cannot run there", remote.OSType)})
+	}
+
+	if local.Architecture != remote.Architecture {
+		if config.HelperImageTar != "" {
+			issues = append(issues, CompatIssue{Fatal: true, Message: fmt.Sprintf(
+				"--helper-image-tar holds an image for one architecture but the hosts differ (local %s, remote %s)",
+				local.Architecture, remote.Architecture)})
+		}
+		if config.DBMode == "" {
+			for _, v := range volumes {
+				if IsDatabaseVolume(DBModePostgres, v.MountPath) || IsDatabaseVolume(DBModeMySQL, v.MountPath) {
+					issues = append(issues, CompatIssue{Message: fmt.Sprintf(
+						"volume %s holds raw database files, which may not be portable from %s to %s; consider --db-mode",
+						v.Name, local.Architecture, remote.Architecture)})
+				}
+			}
+		}
+	}
+
+	if local.DockerVersion != "" && remote.DockerVersion != "" && compareDockerVersions(remote.DockerVersion, local.DockerVersion) < 0 {
+		issues = append(issues, CompatIssue{Message: fmt.Sprintf(
+			"remote Docker %s is older than local Docker %s", remote.DockerVersion, local.DockerVersion)})
+	}
+
+	return issues
+}
+
+// compareHelperTar reports helper images whose tar implementations differ between the hosts,
+// as happens when a tag resolves to different images
+func compareHelperTar(local, remote EnvironmentInfo) []CompatIssue {
+	if local.Tar == "" || remote.Tar == "" || isGNUTar(local.Tar) == isGNUTar(remote.Tar) {
+		return nil
+	}
+	return []CompatIssue{{Fatal: true, Message: fmt.Sprintf(
+		"the helper image provides different tar implementations (local %q, remote %q): archive formats may not match",
+		local.Tar, remote.Tar)}}
+}
+
+// helperTarVersion returns the first line of tar --version in the helper image on each host
+func (m *Migrator) helperTarVersion(image string) (local, remote string, err error) {
+	local, err = m.dockerClient.ExecCommand("run", "--rm", "--entrypoint", "sh", image, "-c", tarVersionCommand)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read local helper tar version: %w", err)
+	}
+	remote, err = m.sshClient.RunDockerCommand(fmt.Sprintf("run --rm --entrypoint sh %s -c %s",
+		shell.ShellEscape(image), shell.ShellEscape(tarVersionCommand)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read remote helper tar version: %w", err)
+	}
+	return strings.TrimSpace(local), strings.TrimSpace(remote), nil
+}
+
+// checkCompatibility gathers both environments, logs them and reports known-incompatible combinations.
+// Fatal issues fail the migration unless --force is set; the others are warnings.
+func (m *Migrator) checkCompatibility(volumes []docker.VolumeInfo) error {
+	local, err := LocalEnvironment(m.dockerClient)
+	if err != nil {
+		return err
+	}
+	remote, err := RemoteEnvironment(m.sshClient)
+	if err != nil {
+		return err
+	}
+	m.localEnv, m.remoteEnv = local, remote
+
+	for _, side := range []struct {
+		host string
+		env  EnvironmentInfo
+	}{{"local", local}, {"remote", remote}} {
+		env := side.env
+		log.WithFields(logrus.Fields{
+			"host":           side.host,
+			"docker_version": env.DockerVersion,
+			"storage_driver": env.StorageDriver,
+			"kernel":         env.Kernel,
+			"architecture":   env.Architecture,
+			"os_type":        env.OSType,
+		}).Info("Docker environment")
+	}
+
+	issues := CompareEnvironments(local, remote, m.config, volumes)
+	issues = append(issues, m.remoteStorageIssues(remote.StorageDriver)...)
+	return m.reportIssues(issues)
+}
+
+// remoteStorageIssues inspects the remote storage driver and the filesystem of the Docker root.
+// A remote that cannot be inspected has no issues.
+func (m *Migrator) remoteStorageIssues(driver string) []CompatIssue {
+	root, err := remoteDockerRootDir(m.sshClient)
+	if err != nil {
+		log.WithError(err).Debug("Could not determine remote Docker root directory, skipping storage checks")
+		return nil
+	}
+	storage, err := RemoteStorage(m.sshClient, driver, root)
+	if err != nil {
+		log.WithError(err).Debug("Could not inspect remote storage")
+		return nil
+	}
+	log.WithFields(logrus.Fields{
+		"docker_root":        root,
+		"filesystem":         storage.Filesystem,
+		"backing_filesystem": storage.BackingFilesystem,
+		"d_type":             storage.DType,
+		"name_max":           storage.NameMax,
+	}).Debug("Remote storage")
+	return StorageIssues(storage)
+}
+
+// checkHelperTarCompatibility compares the tar implementations of the helper image on both hosts
+func (m *Migrator) checkHelperTarCompatibility(image string) error {
+	localTar, remoteTar, err := m.helperTarVersion(image)
+	if err != nil {
+		return err
+	}
+	m.localEnv.Tar, m.remoteEnv.Tar = localTar, remoteTar
+	log.WithFields(logrus.Fields{
+		"local_tar":  localTar,
+		"remote_tar": remoteTar,
+	}).Debug("Helper image tar versions")
+
+	return m.reportIssues(compareHelperTar(m.localEnv, m.remoteEnv))
+}
+
+// reportIssues logs compatibility issues and returns an error for the first fatal one unless --force is set
+func (m *Migrator) reportIssues(issues []CompatIssue) error {
+	for _, issue := range issues {
+		if issue.Fatal && !m.config.Force {
+			return fmt.Errorf("incompatible environments: %s (use --force to override)", issue.Message)
+		}
+		log.Warn("Compatibility: " + issue.Message)
+	}
+	return nil
+}
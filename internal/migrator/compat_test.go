@@ -0,0 +1,132 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestParseEnvironmentInfo(t *testing.T) {
+	env, err := parseEnvironmentInfo("24.0.7|overlay2|6.1.0-13-amd64|x86_64|linux\n")
+	if err != nil {
+		t.Fatalf("parseEnvironmentInfo() error = %v", err)
+	}
+	want := EnvironmentInfo{DockerVersion: "24.0.7", StorageDriver: "overlay2", Kernel: "6.1.0-13-amd64", Architecture: "x86_64", OSType: "linux"}
+	if env != want {
+		t.Errorf("parseEnvironmentInfo() = %+v, want %+v", env, want)
+	}
+
+	env, err = parseEnvironmentInfo("20.10.24|btrfs|5.15.0|arm64|linux")
+	if err != nil || env.Architecture != "aarch64" {
+		t.Errorf("parseEnvironmentInfo() architecture = %q (err %v), want aarch64", env.Architecture, err)
+	}
+
+	if _, err := parseEnvironmentInfo("Cannot connect to the Docker daemon"); err == nil {
+		t.Error("parseEnvironmentInfo() expected error for unexpected output")
+	}
+}
+
+func TestCompareDockerVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"24.0.7", "24.0.7", 0},
+		{"20.10.17+dfsg1", "20.10.17", 0},
+		{"1.13.1", "20.10.0", -1},
+		{"25.0", "24.0.9", 1},
+		{"v23.0.1", "23.0", 1},
+		{"24.0.0-rc.1", "24.0.0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := compareDockerVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareDockerVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareEnvironments(t *testing.T) {
+	linux := EnvironmentInfo{DockerVersion: "24.0.7", Architecture: "x86_64", OSType: "linux"}
+	arm := EnvironmentInfo{DockerVersion: "24.0.7", Architecture: "aarch64", OSType: "linux"}
+	postgres := []docker.VolumeInfo{{Name: "pgdata", MountPath: "/var/lib/postgresql/data"}}
+
+	tests := []struct {
+		name      string
+		local     EnvironmentInfo
+		remote    EnvironmentInfo
+		config    *Config
+		volumes   []docker.VolumeInfo
+		wantFatal bool
+		want      []string
+	}{
+		{name: "identical", local: linux, remote: linux, config: &Config{}},
+		{
+			name:   "older remote docker",
+			local:  linux,
+			remote: EnvironmentInfo{DockerVersion: "20.10.5", Architecture: "x86_64", OSType: "linux"},
+			config: &Config{},
+			want:   []string{"older than local"},
+		},
+		{
+			name:      "windows containers",
+			local:     linux,
+			remote:    EnvironmentInfo{DockerVersion: "24.0.7", Architecture: "x86_64", OSType: "windows"},
+			config:    &Config{},
+			wantFatal: true,
+			want:      []string{"windows containers"},
+		},
+		{
+			name:      "helper bundle across architectures",
+			local:     linux,
+			remote:    arm,
+			config:    &Config{HelperImageTar: "/tmp/alpine.tar"},
+			wantFatal: true,
+			want:      []string{"--helper-image-tar"},
+		},
+		{
+			name:    "raw database across architectures",
+			local:   linux,
+			remote:  arm,
+			config:  &Config{},
+			volumes: postgres,
+			want:    []string{"pgdata holds raw database files"},
+		},
+		{name: "database dump across architectures", local: linux, remote: arm, config: &Config{DBMode: DBModePostgres}, volumes: postgres},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CompareEnvironments(tt.local, tt.remote, tt.config, tt.volumes)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("CompareEnvironments() = %+v, want %d issues", issues, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(issues[i].Message, want) {
+					t.Errorf("issue %d = %q, want it to contain %q", i, issues[i].Message, want)
+				}
+				if issues[i].Fatal != tt.wantFatal {
+					t.Errorf("issue %d fatal = %v, want %v", i, issues[i].Fatal, tt.wantFatal)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareHelperTar(t *testing.T) {
+	gnu := EnvironmentInfo{Tar: "tar (GNU tar) 1.34"}
+	busybox := EnvironmentInfo{Tar: "tar: unrecognized option '--version'"}
+
+	if issues := compareHelperTar(gnu, gnu); len(issues) != 0 {
+		t.Errorf("compareHelperTar() with matching tar = %+v, want none", issues)
+	}
+	if issues := compareHelperTar(gnu, EnvironmentInfo{}); len(issues) != 0 {
+		t.Errorf("compareHelperTar() without a probe = %+v, want none", issues)
+	}
+	if issues := compareHelperTar(gnu, busybox); len(issues) != 1 || !issues[0].Fatal {
+		t.Errorf("compareHelperTar() with different tar = %+v, want one fatal issue", issues)
+	}
+}
@@ -0,0 +1,199 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"volume-migrator/internal/docker"
+)
+
+// composeHeader starts the generated compose file, explaining where it came from
+const composeHeader = "# Generated by volume-migrator from the source containers.\n" +
+	"# The volumes were migrated to the remote host and are used as external volumes.\n"
+
+// composeFile is the layout of the generated docker-compose file
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]composeVolume  `yaml:"volumes,omitempty"`
+}
+
+// composeService recreates one source container
+type composeService struct {
+	ContainerName string            `yaml:"container_name"`
+	Image         string            `yaml:"image"`
+	Entrypoint    []string          `yaml:"entrypoint,omitempty"`
+	Command       []string          `yaml:"command,omitempty"`
+	Environment   []string          `yaml:"environment,omitempty"`
+	User          string            `yaml:"user,omitempty"`
+	WorkingDir    string            `yaml:"working_dir,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	Volumes       []string          `yaml:"volumes,omitempty"`
+	Restart       string            `yaml:"restart,omitempty"`
+	NetworkMode   string            `yaml:"network_mode,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+}
+
+// composeVolume refers to a volume that already exists on the remote host
+type composeVolume struct {
+	External bool   `yaml:"external"`
+	Name     string `yaml:"name"`
+}
+
+// inspectContainerSpecs reads the configuration of each named local container
+func inspectContainerSpecs(dockerClient DockerRunner, containers []string) ([]*docker.ContainerSpec, error) {
+	var specs []*docker.ContainerSpec
+	for _, name := range containers {
+		output, err := dockerClient.ExecCommand("container", "inspect", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", name, err)
+		}
+		spec, err := docker.ParseContainerSpec(output, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configuration of container %s: %w", name, err)
+		}
+		// Parse again against the image, so its defaults are left out; a missing image keeps them all
+		if imageOutput, err := dockerClient.ExecCommand("image", "inspect", spec.Image); err == nil {
+			if spec, err = docker.ParseContainerSpec(output, imageOutput); err != nil {
+				return nil, fmt.Errorf("failed to read configuration of container %s: %w", name, err)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// BuildComposeFile returns a compose file with a service for each container, mounting the
+// migrated volumes (remoteNames maps source volume names to their names on the remote host).
+// The warnings name what the file cannot reproduce faithfully.
+func BuildComposeFile(specs []*docker.ContainerSpec, remoteNames map[string]string) ([]byte, []string, error) {
+	file := composeFile{Services: make(map[string]composeService)}
+	var warnings []string
+
+	for _, spec := range specs {
+		service := composeService{
+			ContainerName: spec.Name,
+			Image:         spec.Image,
+			Entrypoint:    spec.Entrypoint,
+			Command:       spec.Command,
+			Environment:   spec.Env,
+			User:          spec.User,
+			WorkingDir:    spec.WorkingDir,
+			Restart:       spec.Restart,
+			NetworkMode:   spec.NetworkMode,
+			Labels:        spec.Labels,
+		}
+		// Host networking publishes every port anyway
+		if spec.NetworkMode != "host" {
+			service.Ports = spec.Ports
+		}
+
+		for _, mount := range spec.Mounts {
+			entry, volume, warning := remoteMount(spec.Name, mount, remoteNames)
+			if entry != "" {
+				service.Volumes = append(service.Volumes, entry)
+			}
+			if volume != "" {
+				if file.Volumes == nil {
+					file.Volumes = make(map[string]composeVolume)
+				}
+				file.Volumes[volume] = composeVolume{External: true, Name: volume}
+			}
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+
+		file.Services[composeServiceName(spec, file.Services)] = service
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	return append([]byte(composeHeader), data...), warnings, nil
+}
+
+// remoteMount returns a mount of a source container as a source:destination[:ro] volume entry,
+// along with the remote volume it uses, if any. Mounts that cannot be recreated as they were
+// come with a warning; those that cannot be recreated at all have no entry.
+func remoteMount(container string, mount docker.MountInfo, remoteNames map[string]string) (entry, volume, warning string) {
+	suffix := ""
+	if mount.ReadOnly {
+		suffix = ":ro"
+	}
+
+	switch mount.Type {
+	case "volume":
+		remoteName, migrated := remoteNames[mount.Name]
+		if !migrated && docker.IsAnonymousVolumeName(mount.Name) {
+			return mount.Destination + suffix, "", fmt.Sprintf("%s: anonymous volume at %s was not migrated and starts empty", container, mount.Destination)
+		}
+		if !migrated {
+			remoteName = mount.Name
+			warning = fmt.Sprintf("%s: volume %s was not migrated and must already exist on the remote host", container, mount.Name)
+		}
+		return remoteName + ":" + mount.Destination + suffix, remoteName, warning
+	case "bind":
+		return mount.Source + ":" + mount.Destination + suffix, "", fmt.Sprintf("%s: bind mount %s was not migrated and must exist on the remote host", container, mount.Source)
+	default:
+		return "", "", fmt.Sprintf("%s: %s mount at %s is not included", container, mount.Type, mount.Destination)
+	}
+}
+
+// composeServiceName names a container's service after its compose service, falling back to
+// the container name when there is none or another project already uses the name
+func composeServiceName(spec *docker.ContainerSpec, services map[string]composeService) string {
+	if spec.Service != "" {
+		if _, taken := services[spec.Service]; !taken {
+			return spec.Service
+		}
+	}
+	return spec.Name
+}
+
+// migratedVolumeNames maps the source names of the succeeded volumes to their remote names
+func migratedVolumeNames(volumes []docker.VolumeInfo, succeeded []string) map[string]string {
+	remoteNames := make(map[string]string)
+	for _, v := range volumes {
+		if slices.Contains(succeeded, v.Name) {
+			remoteNames[v.Name] = v.RemoteName()
+		}
+	}
+	return remoteNames
+}
+
+// sourceContainerSpecs reads the configuration of the migration's source containers, sorted by name
+func (m *Migrator) sourceContainerSpecs() ([]*docker.ContainerSpec, error) {
+	containers := append([]string(nil), m.config.Containers...)
+	sort.Strings(containers)
+	return inspectContainerSpecs(m.dockerClient, containers)
+}
+
+// writeComposeFile writes a compose file recreating the source containers on top of the volumes
+// that were migrated; volumes lists every volume of the run and succeeded the migrated ones
+func (m *Migrator) writeComposeFile(volumes []docker.VolumeInfo, succeeded []string) error {
+	specs, err := m.sourceContainerSpecs()
+	if err != nil {
+		return err
+	}
+	data, warnings, err := BuildComposeFile(specs, migratedVolumeNames(volumes, succeeded))
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+
+	if err := os.WriteFile(m.config.ComposeFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write compose file: %w", err)
+	}
+	log.WithFields(logrus.Fields{
+		"path":     m.config.ComposeFile,
+		"services": len(specs),
+	}).Info("Wrote compose file; copy it to the remote host and run docker compose up -d there")
+	return nil
+}
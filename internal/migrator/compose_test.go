@@ -0,0 +1,126 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"volume-migrator/internal/docker"
+)
+
+func TestBuildComposeFile(t *testing.T) {
+	specs := []*docker.ContainerSpec{
+		{
+			Name:    "shop-web-1",
+			Image:   "nginx:1.25",
+			Env:     []string{"APP_ENV=production"},
+			Ports:   []string{"8080:80"},
+			Restart: "always",
+			Service: "web",
+			Mounts: []docker.MountInfo{
+				{Type: "volume", Name: "shop_static", Destination: "/usr/share/nginx/html", ReadOnly: true},
+				{Type: "bind", Source: "/srv/nginx.conf", Destination: "/etc/nginx/nginx.conf"},
+			},
+		},
+		{
+			Name:        "worker",
+			Image:       "busybox",
+			Command:     []string{"sleep", "infinity"},
+			Ports:       []string{"9000:9000"},
+			NetworkMode: "host",
+			Mounts: []docker.MountInfo{
+				{Type: "volume", Name: "cache", Destination: "/cache"},
+				{Type: "volume", Name: strings.Repeat("a", 64), Destination: "/tmp/scratch"},
+				{Type: "tmpfs", Destination: "/run"},
+			},
+		},
+	}
+
+	data, warnings, err := BuildComposeFile(specs, map[string]string{"shop_static": "shop_static", "cache": "cache-renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# Generated by volume-migrator") {
+		t.Errorf("expected the header comment, got:\n%s", data)
+	}
+
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("generated file is not valid YAML: %v\n%s", err, data)
+	}
+
+	web, ok := file.Services["web"]
+	if !ok {
+		t.Fatalf("expected a service named after the compose service, got: %v", file.Services)
+	}
+	if web.ContainerName != "shop-web-1" || web.Restart != "always" || !slices.Equal(web.Ports, []string{"8080:80"}) {
+		t.Errorf("unexpected web service: %+v", web)
+	}
+	if want := []string{"shop_static:/usr/share/nginx/html:ro", "/srv/nginx.conf:/etc/nginx/nginx.conf"}; !slices.Equal(web.Volumes, want) {
+		t.Errorf("web volumes = %v, want %v", web.Volumes, want)
+	}
+
+	worker := file.Services["worker"]
+	if worker.Ports != nil {
+		t.Errorf("host networking should publish no ports, got: %v", worker.Ports)
+	}
+	if want := []string{"cache-renamed:/cache", "/tmp/scratch"}; !slices.Equal(worker.Volumes, want) {
+		t.Errorf("worker volumes = %v, want %v", worker.Volumes, want)
+	}
+
+	if got := file.Volumes["cache-renamed"]; !got.External || got.Name != "cache-renamed" {
+		t.Errorf("expected the renamed volume to be external, got: %+v", file.Volumes)
+	}
+	if len(warnings) != 3 {
+		t.Errorf("expected warnings for the bind mount, anonymous volume and tmpfs, got: %v", warnings)
+	}
+}
+
+func TestBuildComposeFile_NotMigrated(t *testing.T) {
+	specs := []*docker.ContainerSpec{{
+		Name:   "db",
+		Image:  "postgres:16",
+		Mounts: []docker.MountInfo{{Type: "volume", Name: "pgdata", Destination: "/var/lib/postgresql/data"}},
+	}}
+
+	data, warnings, err := BuildComposeFile(specs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- pgdata:/var/lib/postgresql/data") {
+		t.Errorf("expected the volume under its own name, got:\n%s", data)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "was not migrated") {
+		t.Errorf("expected a warning for the volume that was not migrated, got: %v", warnings)
+	}
+}
+
+func TestWriteComposeFile(t *testing.T) {
+	dir := t.TempDir()
+	local := &fakeDocker{responses: map[string]fakeResponse{
+		"container inspect web": {output: `[{"Name": "/web", "Config": {"Image": "nginx:1.25", "Env": ["A=1"]},
+			"Mounts": [{"Type": "volume", "Name": "static", "Destination": "/data", "RW": true}]}]`},
+		"image inspect": {output: `[{"Config": {"Env": ["A=1"]}}]`},
+	}}
+	config := &Config{Containers: []string{"web"}, ComposeFile: filepath.Join(dir, "docker-compose.yml")}
+	m := &Migrator{config: config, dockerClient: local}
+
+	volumes := []docker.VolumeInfo{{Name: "static", TargetName: "static-v2"}}
+	if err := m.writeComposeFile(volumes, []string{"static"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(config.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- static-v2:/data") {
+		t.Errorf("expected the remote volume name in the compose file, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "environment") {
+		t.Errorf("expected the image's environment to be left out, got:\n%s", data)
+	}
+}
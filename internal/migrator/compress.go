@@ -0,0 +1,112 @@
+package migrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+const (
+	// autoCompressSampleSize is how much of the tar stream is test-compressed before a level is chosen
+	autoCompressSampleSize = 16 * 1024 * 1024
+	// autoCompressMinSample is the smallest sample worth judging; tiny streams are always compressed
+	autoCompressMinSample = 64 * 1024
+	// autoCompressMaxRatio is the compressed/original size above which data counts as incompressible
+	autoCompressMaxRatio = 0.9
+)
+
+// autoCompressWriter buffers the start of a stream, test-compresses it, and then writes a gzip
+// stream at the configured level or, for incompressible data, with stored (uncompressed) blocks.
+// Either way the output stays a valid gzip stream, so imports need no change.
+type autoCompressWriter struct {
+	out    io.Writer
+	level  int
+	sample bytes.Buffer
+	gz     *gzip.Writer
+	stored bool
+}
+
+// newAutoCompressWriter creates an auto-compressing writer; level 0 selects DefaultCompressionLevel
+func newAutoCompressWriter(out io.Writer, level int) *autoCompressWriter {
+	if level == 0 {
+		level = DefaultCompressionLevel
+	}
+	return &autoCompressWriter{out: out, level: level}
+}
+
+// Write implements io.Writer
+func (w *autoCompressWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+
+	w.sample.Write(p)
+	if w.sample.Len() >= autoCompressSampleSize {
+		if err := w.choose(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close decides on the level if the stream was shorter than a sample, then flushes the gzip stream
+func (w *autoCompressWriter) Close() error {
+	if w.gz == nil {
+		if err := w.choose(); err != nil {
+			return err
+		}
+	}
+	return w.gz.Close()
+}
+
+// Stored reports whether the data was found incompressible and written uncompressed
+func (w *autoCompressWriter) Stored() bool {
+	return w.stored
+}
+
+// choose compresses the buffered sample to pick a level, then writes the sample to the real stream
+func (w *autoCompressWriter) choose() error {
+	level := w.level
+	if isIncompressible(w.sample.Bytes(), level) {
+		level = gzip.NoCompression
+		w.stored = true
+	}
+
+	gz, err := gzip.NewWriterLevel(w.out, level)
+	if err != nil {
+		return err
+	}
+	w.gz = gz
+
+	_, err = w.gz.Write(w.sample.Bytes())
+	w.sample = bytes.Buffer{}
+	return err
+}
+
+// isIncompressible reports whether data shrinks by less than autoCompressMaxRatio at level
+func isIncompressible(data []byte, level int) bool {
+	if len(data) < autoCompressMinSample {
+		return false
+	}
+
+	counter := &countingWriter{}
+	gz, err := gzip.NewWriterLevel(counter, level)
+	if err != nil {
+		return false
+	}
+	gz.Write(data)
+	gz.Close()
+
+	return float64(counter.n) > float64(len(data))*autoCompressMaxRatio
+}
+
+// countingWriter discards data, counting the bytes written
+type countingWriter struct {
+	n int64
+}
+
+// Write implements io.Writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,60 @@
+package migrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestAutoCompressWriter(t *testing.T) {
+	random := make([]byte, autoCompressSampleSize+4096)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantStored bool
+	}{
+		{"compressible", bytes.Repeat([]byte("volume data "), autoCompressSampleSize/8), false},
+		{"incompressible", random, true},
+		{"shorter than sample", []byte("small volume"), false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newAutoCompressWriter(&out, 0)
+			for data := tt.data; len(data) > 0; {
+				n := min(len(data), 32*1024)
+				if _, err := w.Write(data[:n]); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+				data = data[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if w.Stored() != tt.wantStored {
+				t.Errorf("Stored() = %v, want %v", w.Stored(), tt.wantStored)
+			}
+
+			gr, err := gzip.NewReader(&out)
+			if err != nil {
+				t.Fatalf("output is not gzip: %v", err)
+			}
+			got, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatalf("failed to decompress output: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("round trip mismatch: got %d bytes, want %d", len(got), len(tt.data))
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package migrator
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateConfig_EmptyContainers(t *testing.T) {
@@ -103,6 +105,11 @@ func TestValidateConfig_InvalidRemoteHostFormat(t *testing.T) {
 			remoteHost: "user@   ",
 			errorPart:  "host cannot be empty",
 		},
+		{
+			name:       "unterminated IPv6",
+			remoteHost: "user@[2001:db8::1",
+			errorPart:  "missing ']'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -144,6 +151,14 @@ func TestValidateConfig_ValidRemoteHost(t *testing.T) {
 			name:       "user@host with port in SSHPort",
 			remoteHost: "user@host",
 		},
+		{
+			name:       "bare IPv6",
+			remoteHost: "user@2001:db8::1",
+		},
+		{
+			name:       "bracketed IPv6 with port",
+			remoteHost: "user@[2001:db8::1]:2222",
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,7 +366,7 @@ func TestValidateConfig_NonExistentSSHKeyPath(t *testing.T) {
 	config := &Config{
 		Containers: []string{"container1"},
 		RemoteHost: "user@host",
-		SSHKeyPath: "/nonexistent/path/to/key",
+		SSHKeyPaths: []string{"/nonexistent/path/to/key"},
 	}
 
 	err := ValidateConfig(config)
@@ -374,7 +389,7 @@ func TestValidateConfig_ExistingSSHKeyPath(t *testing.T) {
 	config := &Config{
 		Containers: []string{"container1"},
 		RemoteHost: "user@host",
-		SSHKeyPath: keyPath,
+		SSHKeyPaths: []string{keyPath},
 	}
 
 	err := ValidateConfig(config)
@@ -458,7 +473,7 @@ func TestValidateConfig_CompleteValidConfig(t *testing.T) {
 		SSHPort:               "2222",
 		TempDir:               "/tmp/local",
 		RemoteTempDir:         "/tmp/remote",
-		SSHKeyPath:            keyPath,
+		SSHKeyPaths:           []string{keyPath},
 		StrictHostKeyChecking: true,
 		KnownHostsFile:        knownHostsPath,
 		Verbose:               true,
@@ -471,3 +486,903 @@ func TestValidateConfig_CompleteValidConfig(t *testing.T) {
 		t.Errorf("Expected no error for complete valid config, got: %v", err)
 	}
 }
+
+func TestValidateConfig_DBMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbMode  string
+		wantErr bool
+	}{
+		{"disabled", "", false},
+		{"postgres", "postgres", false},
+		{"mysql", "mysql", false},
+		{"unsupported", "mongodb", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers: []string{"container1"},
+				RemoteHost: "user@host",
+				DBMode:     tt.dbMode,
+			}
+
+			err := ValidateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() with db mode %q error = %v, wantErr %v", tt.dbMode, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_HelperImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		helperImage string
+		wantErr     bool
+	}{
+		{"default", "", false},
+		{"tagged image", "alpine:3.19", false},
+		{"registry mirror", "registry.internal:5000/library/alpine:3.19", false},
+		{"injection attempt", "alpine; rm -rf /", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:  []string{"container1"},
+				RemoteHost:  "user@host",
+				HelperImage: tt.helperImage,
+			}
+
+			err := ValidateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() with helper image %q error = %v, wantErr %v", tt.helperImage, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_HelperImageTar(t *testing.T) {
+	tempDir := t.TempDir()
+	bundlePath := filepath.Join(tempDir, "alpine.tar")
+	if err := os.WriteFile(bundlePath, []byte("image bundle"), 0644); err != nil {
+		t.Fatalf("Failed to create test bundle: %v", err)
+	}
+
+	config := &Config{
+		Containers:     []string{"container1"},
+		RemoteHost:     "user@host",
+		HelperImageTar: bundlePath,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error for existing helper image tar, got: %v", err)
+	}
+
+	config.HelperImageTar = filepath.Join(tempDir, "missing.tar")
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("Expected error for missing helper image tar, got nil")
+	}
+	if !strings.Contains(err.Error(), "helper image tar does not exist") {
+		t.Errorf("Expected 'helper image tar does not exist' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_IDMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		uidMap  []string
+		gidMap  []string
+		wantErr string
+	}{
+		{name: "valid mappings", uidMap: []string{"1000:2000"}, gidMap: []string{"1000:2000"}},
+		{name: "invalid uid mapping", uidMap: []string{"1000"}, wantErr: "invalid uid mapping"},
+		{name: "invalid gid mapping", gidMap: []string{"staff:50"}, wantErr: "invalid gid mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers: []string{"container1"},
+				RemoteHost: "user@host",
+				UIDMap:     tt.uidMap,
+				GIDMap:     tt.gidMap,
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_AllContainers(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []string
+		all        bool
+		filters    []string
+		wantErr    string
+	}{
+		{name: "all without containers", all: true},
+		{name: "all with filters", all: true, filters: []string{"label=com.example.stack=prod", "status=running"}},
+		{name: "all with containers", containers: []string{"web"}, all: true, wantErr: "cannot be combined with --all"},
+		{name: "filter without all", containers: []string{"web"}, filters: []string{"status=running"}, wantErr: "--filter requires --all"},
+		{name: "malformed filter", all: true, filters: []string{"running"}, wantErr: "must be in format key=value"},
+		{name: "unsupported filter key", all: true, filters: []string{"before=web"}, wantErr: "unsupported key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:       tt.containers,
+				RemoteHost:       "user@host",
+				AllContainers:    tt.all,
+				ContainerFilters: tt.filters,
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_ByVolume(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []string
+		volumes    []string
+		byVolume   bool
+		all        bool
+		wantErr    string
+	}{
+		{name: "volume names", volumes: []string{"shared_data", "cache.v1"}, byVolume: true},
+		{name: "no volumes", byVolume: true, wantErr: "no volumes specified"},
+		{name: "invalid volume name", volumes: []string{"data;rm"}, byVolume: true, wantErr: "invalid volume name 'data;rm' at index 0"},
+		{name: "with all", byVolume: true, all: true, volumes: []string{"data"}, wantErr: "--by-volume and --all"},
+		{name: "with containers", byVolume: true, containers: []string{"web"}, volumes: []string{"data"}, wantErr: "cannot be combined with --by-volume"},
+		{name: "volumes without by-volume", containers: []string{"web"}, volumes: []string{"data"}, wantErr: "require --by-volume"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:    tt.containers,
+				Volumes:       tt.volumes,
+				RemoteHost:    "user@host",
+				ByVolume:      tt.byVolume,
+				AllContainers: tt.all,
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_AnonymousVolumes(t *testing.T) {
+	config := &Config{
+		Containers:       []string{"container1"},
+		RemoteHost:       "user@host",
+		AnonymousVolumes: AnonymousRename,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.AnonymousVolumes = "drop"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid anonymous volume mode") {
+		t.Errorf("Expected 'invalid anonymous volume mode' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ChunkSize(t *testing.T) {
+	config := &Config{
+		Containers: []string{"container1"},
+		RemoteHost: "user@host",
+		ChunkSize:  "2GB",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.ChunkSize = "two gigs"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid chunk size") {
+		t.Errorf("Expected 'invalid chunk size' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ParallelStreams(t *testing.T) {
+	config := &Config{
+		Containers:      []string{"container1"},
+		RemoteHost:      "user@host",
+		ParallelStreams: 4,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.TransferBackend = "shell"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "conflicting flags") {
+		t.Errorf("Expected 'conflicting flags' error, got: %v", err)
+	}
+
+	config.TransferBackend = ""
+	config.ParallelStreams = 100
+	err = ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid parallel streams") {
+		t.Errorf("Expected 'invalid parallel streams' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_BufferSize(t *testing.T) {
+	config := &Config{
+		Containers: []string{"container1"},
+		RemoteHost: "user@host",
+		BufferSize: "256KB",
+	}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "invalid buffer size") {
+		t.Errorf("Expected 'invalid buffer size' error, got: %v", err)
+	}
+
+	config.BufferSize = "128KB"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.BufferSize = "big"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid buffer size") {
+		t.Errorf("Expected 'invalid buffer size' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MinFreeSpace(t *testing.T) {
+	config := &Config{
+		Containers:   []string{"container1"},
+		RemoteHost:   "user@host",
+		MinFreeSpace: "0",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.MinFreeSpace = "lots"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid min free space") {
+		t.Errorf("Expected 'invalid min free space' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ImportMethod(t *testing.T) {
+	config := &Config{
+		Containers:   []string{"container1"},
+		RemoteHost:   "user@host",
+		ImportMethod: ImportMethodStream,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.ChunkSize = "2GB"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "--chunk-size") {
+		t.Errorf("Expected chunk size conflict, got: %v", err)
+	}
+
+	config.ImportMethod = ImportMethodCopy
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--chunk-size") {
+		t.Errorf("Expected chunk size conflict for cp, got: %v", err)
+	}
+
+	config.ChunkSize = ""
+	config.Dedup = true
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--import-method cp") {
+		t.Errorf("Expected dedup conflict for cp, got: %v", err)
+	}
+
+	config.Dedup = false
+	config.ImportMethod = "ftp"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "invalid import method") {
+		t.Errorf("Expected 'invalid import method' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_AdaptiveCompression(t *testing.T) {
+	config := &Config{
+		Containers:          []string{"container1"},
+		RemoteHost:          "user@host",
+		AdaptiveCompression: true,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.AutoCompress = true
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--auto-compress") {
+		t.Errorf("Expected auto compress conflict, got: %v", err)
+	}
+
+	config.AutoCompress = false
+	config.ArchiveFormat = FormatTar
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "requires --format tar.gz") {
+		t.Errorf("Expected format conflict, got: %v", err)
+	}
+}
+
+func TestValidateConfig_TransferBackend(t *testing.T) {
+	config := &Config{
+		Containers:      []string{"container1"},
+		RemoteHost:      "user@host",
+		TransferBackend: "shell",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.TransferBackend = "rsync"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "invalid transfer backend") {
+		t.Errorf("Expected 'invalid transfer backend' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_SignKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		signKey      string
+		importMethod string
+		wantErr      string
+	}{
+		{"existing key", keyPath, "", ""},
+		{"missing key", keyPath + ".missing", "", "does not exist"},
+		{"streamed import", keyPath, ImportMethodStream, "--sign-manifest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:   []string{"container1"},
+				RemoteHost:   "user@host",
+				SignKey:      tt.signKey,
+				ImportMethod: tt.importMethod,
+			}
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Timeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"no timeouts", Config{}, ""},
+		{"all timeouts", Config{SSHTimeout: 10 * time.Second, CommandTimeout: time.Hour, TransferStallTimeout: 5 * time.Minute}, ""},
+		{"negative ssh timeout", Config{SSHTimeout: -time.Second}, "--ssh-timeout"},
+		{"negative stall timeout", Config{TransferStallTimeout: -time.Second}, "--transfer-stall-timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Containers = []string{"container1"}
+			config.RemoteHost = "user@host"
+			err := ValidateConfig(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_KeepRemoteArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"keep archives", Config{KeepRemoteArchives: 3}, ""},
+		{"custom archive dir", Config{KeepRemoteArchives: 3, RemoteArchiveDir: "/srv/archives"}, ""},
+		{"negative", Config{KeepRemoteArchives: -1}, "must not be negative"},
+		{"relative archive dir", Config{KeepRemoteArchives: 1, RemoteArchiveDir: "archives"}, "absolute path"},
+		{"streamed import", Config{KeepRemoteArchives: 1, ImportMethod: ImportMethodStream}, "conflicting flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Containers = []string{"container1"}
+			config.RemoteHost = "user@host"
+			err := ValidateConfig(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_ArchiveFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"uncompressed tar", Config{ArchiveFormat: FormatTar}, ""},
+		{"tar with cp import", Config{ArchiveFormat: FormatTar, ImportMethod: ImportMethodCopy}, ""},
+		{"zstd with helper image", Config{ArchiveFormat: FormatTarZst, HelperImage: "tools:1", ImportMethod: ImportMethodStream}, ""},
+		{"squashfs with helper image", Config{ArchiveFormat: FormatSquashfs, HelperImage: "tools:1"}, ""},
+		{"unknown format", Config{ArchiveFormat: "zip"}, "invalid archive format"},
+		{"zstd without helper image", Config{ArchiveFormat: FormatTarZst}, "requires --helper-image"},
+		{"auto-compress with tar", Config{ArchiveFormat: FormatTar, AutoCompress: true}, "conflicting flags"},
+		{"db mode with tar", Config{ArchiveFormat: FormatTar, DBMode: "postgres"}, "conflicting flags"},
+		{"dedup with zstd", Config{ArchiveFormat: FormatTarZst, HelperImage: "tools:1", Dedup: true}, "conflicting flags"},
+		{"incremental with squashfs", Config{ArchiveFormat: FormatSquashfs, HelperImage: "tools:1", Incremental: true}, "conflicting flags"},
+		{"cp import with zstd", Config{ArchiveFormat: FormatTarZst, HelperImage: "tools:1", ImportMethod: ImportMethodCopy}, "conflicting flags"},
+		{"streamed squashfs", Config{ArchiveFormat: FormatSquashfs, HelperImage: "tools:1", ImportMethod: ImportMethodStream}, "conflicting flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Containers = []string{"container1"}
+			config.RemoteHost = "user@host"
+			err := ValidateConfig(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_MaxArchiveSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"split archives", Config{MaxArchiveSize: "4000MB"}, ""},
+		{"split with chunked upload", Config{MaxArchiveSize: "4000MB", ChunkSize: "1GB"}, ""},
+		{"invalid size", Config{MaxArchiveSize: "big"}, "invalid max archive size"},
+		{"zero size", Config{MaxArchiveSize: "0"}, "greater than zero"},
+		{"streamed import", Config{MaxArchiveSize: "1G", ImportMethod: ImportMethodStream}, "conflicting flags"},
+		{"db mode", Config{MaxArchiveSize: "1G", DBMode: "postgres"}, "conflicting flags"},
+		{"squashfs", Config{MaxArchiveSize: "1G", ArchiveFormat: FormatSquashfs, HelperImage: "tools"}, "conflicting flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Containers = []string{"container1"}
+			config.RemoteHost = "user@host"
+			err := ValidateConfig(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_ComposeFile(t *testing.T) {
+	config := &Config{ByVolume: true, Volumes: []string{"data"}, RemoteHost: "user@host", ComposeFile: "docker-compose.yml"}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--by-volume") {
+		t.Errorf("Expected --compose-file to be rejected with --by-volume, got: %v", err)
+	}
+
+	config = &Config{Containers: []string{"web"}, RemoteHost: "user@host", ComposeFile: "docker-compose.yml"}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_PrintRunCommands(t *testing.T) {
+	config := &Config{ByVolume: true, Volumes: []string{"data"}, RemoteHost: "user@host", PrintRunCommands: true}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--by-volume") {
+		t.Errorf("Expected --print-run-commands to be rejected with --by-volume, got: %v", err)
+	}
+
+	config = &Config{Containers: []string{"web"}, RemoteHost: "user@host", PrintRunCommands: true}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_StartRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"start remote", &Config{Containers: []string{"web"}, RemoteHost: "user@host", StartRemote: true, HealthTimeout: time.Minute}, ""},
+		{"by volume", &Config{ByVolume: true, Volumes: []string{"data"}, RemoteHost: "user@host", StartRemote: true}, "--by-volume"},
+		{"timeout without start", &Config{Containers: []string{"web"}, RemoteHost: "user@host", HealthTimeout: time.Minute}, "requires --start-remote"},
+		{"negative timeout", &Config{Containers: []string{"web"}, RemoteHost: "user@host", StartRemote: true, HealthTimeout: -time.Second}, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Watch(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"watch", &Config{Containers: []string{"web"}, RemoteHost: "user@host", Watch: true, Incremental: true, WatchInterval: time.Minute}, ""},
+		{"without incremental", &Config{Containers: []string{"web"}, RemoteHost: "user@host", Watch: true}, "requires --incremental"},
+		{"with start remote", &Config{Containers: []string{"web"}, RemoteHost: "user@host", Watch: true, Incremental: true, StartRemote: true}, "--start-remote"},
+		{"with kept archives", &Config{Containers: []string{"web"}, RemoteHost: "user@host", Watch: true, Incremental: true, KeepRemoteArchives: 2}, "--keep-remote-archives"},
+		{"interval without watch", &Config{Containers: []string{"web"}, RemoteHost: "user@host", WatchInterval: time.Minute}, "requires --watch"},
+		{"negative interval", &Config{Containers: []string{"web"}, RemoteHost: "user@host", Watch: true, Incremental: true, WatchInterval: -time.Second}, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_StandbyInterval(t *testing.T) {
+	standby := func(modify func(*Config)) *Config {
+		config := &Config{Containers: []string{"web"}, RemoteHost: "user@host", Incremental: true, AssumeYes: true, StandbyInterval: 15 * time.Minute}
+		modify(config)
+		return config
+	}
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"standby", standby(func(c *Config) {}), ""},
+		{"without incremental", standby(func(c *Config) { c.Incremental = false }), "requires --incremental"},
+		{"without yes", standby(func(c *Config) { c.AssumeYes = false }), "requires --yes"},
+		{"dry run", standby(func(c *Config) { c.DryRun = true }), "--dry-run"},
+		{"watch", standby(func(c *Config) { c.Watch = true }), "conflicting flags"},
+		{"sudo password", standby(func(c *Config) { c.RemoteSudoPassword = true }), "--remote-sudo-password"},
+		{"negative", standby(func(c *Config) { c.StandbyInterval = -time.Minute }), "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSSHClientConfig_Timeouts(t *testing.T) {
+	config := &Config{
+		RemoteHost:           "user@host",
+		SSHOptions:           []string{"ConnectTimeout=5"},
+		SSHTimeout:           10 * time.Second,
+		CommandTimeout:       time.Hour,
+		TransferStallTimeout: time.Minute,
+	}
+	sshConfig, err := sshClientConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sshConfig.Options.ConnectTimeout != 10*time.Second {
+		t.Errorf("ConnectTimeout = %s, want --ssh-timeout to override the option", sshConfig.Options.ConnectTimeout)
+	}
+	if sshConfig.CommandTimeout != time.Hour || sshConfig.TransferStallTimeout != time.Minute {
+		t.Errorf("timeouts not passed on: %+v", sshConfig)
+	}
+}
+
+func TestNewMigrator_ContainerSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "containers", config: Config{Containers: []string{"web"}, RemoteHost: "user@host"}},
+		{name: "all containers", config: Config{AllContainers: true, RemoteHost: "user@host"}},
+		{name: "by volume", config: Config{ByVolume: true, Volumes: []string{"data"}, RemoteHost: "user@host"}},
+		{name: "nothing to migrate", config: Config{RemoteHost: "user@host"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			_, err := NewMigrator(context.Background(), &config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMigrator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Dedup(t *testing.T) {
+	tests := []struct {
+		name        string
+		incremental bool
+		sparse      bool
+		errContains string
+	}{
+		{"dedup alone", false, false, ""},
+		{"with incremental", true, false, "--dedup and --incremental"},
+		{"with sparse", false, true, "--dedup and --sparse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:  []string{"container1"},
+				RemoteHost:  "user@host",
+				Dedup:       true,
+				Incremental: tt.incremental,
+				Sparse:      tt.sparse,
+			}
+			err := ValidateConfig(config)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_CompressionLevel(t *testing.T) {
+	tests := []struct {
+		format  string
+		level   int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"", 1, false},
+		{"", 9, false},
+		{"", -1, true},
+		{"", 10, true},
+		{"", 19, true},
+		{FormatSquashfs, 10, true},
+		{FormatTarZst, 0, false},
+		{FormatTarZst, 19, false},
+		{FormatTarZst, 20, true},
+	}
+
+	for _, tt := range tests {
+		config := &Config{
+			Containers:       []string{"container1"},
+			RemoteHost:       "user@host",
+			ArchiveFormat:    tt.format,
+			HelperImage:      "zstd-helper:latest",
+			CompressionLevel: tt.level,
+		}
+		err := ValidateConfig(config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateConfig(ArchiveFormat=%q, CompressionLevel=%d) error = %v, wantErr %v", tt.format, tt.level, err, tt.wantErr)
+		}
+		if tt.wantErr && err != nil && !strings.Contains(err.Error(), "between 0 (default) and") {
+			t.Errorf("ValidateConfig(CompressionLevel=%d) error = %v, want the accepted range", tt.level, err)
+		}
+	}
+}
+
+func TestValidateConfig_SSHOptions(t *testing.T) {
+	config := &Config{
+		Containers: []string{"container1"},
+		RemoteHost: "user@host",
+		SSHOptions: []string{"Ciphers=aes256-gcm@openssh.com", "ServerAliveInterval=30"},
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.SSHOptions = []string{"ProxyJump=bastion"}
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "unsupported SSH option") {
+		t.Errorf("Expected 'unsupported SSH option' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Escalation(t *testing.T) {
+	tests := []struct {
+		name         string
+		local        string
+		remote       string
+		sudoPassword bool
+		wantErr      string
+	}{
+		{"defaults", "", "", false, ""},
+		{"explicit methods", "doas", "SUDO", true, ""},
+		{"auto with password", "auto", "auto", true, ""},
+		{"su is not supported", "su", "", false, "--local-escalation"},
+		{"unknown remote method", "", "pkexec", false, "--remote-escalation"},
+		{"password with doas", "", "doas", true, "--remote-sudo-password"},
+		{"password with none", "", "none", true, "--remote-sudo-password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Containers:         []string{"container1"},
+				RemoteHost:         "user@host",
+				LocalEscalation:    tt.local,
+				RemoteEscalation:   tt.remote,
+				RemoteSudoPassword: tt.sudoPassword,
+			}
+			err := ValidateConfig(config)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_MinDockerVersion(t *testing.T) {
+	config := &Config{
+		Containers:       []string{"container1"},
+		RemoteHost:       "user@host",
+		MinLocalVersion:  DefaultMinDockerVersion,
+		MinRemoteVersion: "20.10",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.MinLocalVersion = "stable"
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "--min-local-docker-version") {
+		t.Errorf("Expected '--min-local-docker-version' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HelperBinary(t *testing.T) {
+	config := &Config{
+		Containers:   []string{"container1"},
+		RemoteHost:   "user@host",
+		HelperBinary: EmbeddedHelperBinary,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.HelperImage = "alpine:3.19"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "--helper-binary") {
+		t.Errorf("Expected conflict with --helper-image, got: %v", err)
+	}
+
+	config.HelperImage = ""
+	config.PreserveXattrs = true
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "busybox tar") {
+		t.Errorf("Expected busybox tar error, got: %v", err)
+	}
+
+	config.PreserveXattrs = false
+	config.HelperBinary = "/nonexistent/busybox"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected missing binary error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MaxSessions(t *testing.T) {
+	config := &Config{
+		Containers:  []string{"container1"},
+		RemoteHost:  "user@host",
+		MaxSessions: 4,
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.MaxSessions = -1
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid max sessions") {
+		t.Errorf("Expected 'invalid max sessions' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_TransferStallRestarts(t *testing.T) {
+	config := &Config{
+		Containers:            []string{"container1"},
+		RemoteHost:            "user@host",
+		TransferStallRestarts: -1,
+	}
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "invalid transfer stall restarts") {
+		t.Errorf("Expected 'invalid transfer stall restarts' error, got: %v", err)
+	}
+}
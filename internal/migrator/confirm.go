@@ -0,0 +1,78 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/ssh"
+	"volume-migrator/internal/ui"
+	"volume-migrator/internal/utils"
+)
+
+// assumedThroughput is the transfer rate behind the estimated duration, in bytes/second
+// It is roughly what a gigabit link sustains; compression usually makes runs faster
+const assumedThroughput = 100 * 1024 * 1024
+
+// migrationSummary describes what a run is about to do
+type migrationSummary struct {
+	Volumes     int
+	TotalBytes  int64
+	Destination string
+	Estimate    time.Duration
+}
+
+// summarizeMigration describes the migration of volumes to the remote host in config
+func summarizeMigration(volumes []docker.VolumeInfo, config *Config) migrationSummary {
+	summary := migrationSummary{Volumes: len(volumes), Destination: config.RemoteHost}
+	for _, v := range volumes {
+		summary.TotalBytes += v.SizeBytes
+	}
+	summary.Estimate = time.Duration(float64(summary.TotalBytes) / assumedThroughput * float64(time.Second)).Round(time.Second)
+	return summary
+}
+
+// String renders the summary shown before a migration starts
+func (s migrationSummary) String() string {
+	estimate := "under a second"
+	if s.Estimate > 0 {
+		estimate = "about " + s.Estimate.String()
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "Migration summary:")
+	fmt.Fprintf(&b, "  Volumes:            %d\n", s.Volumes)
+	fmt.Fprintf(&b, "  Total size:         %s\n", utils.FormatBytes(s.TotalBytes))
+	fmt.Fprintf(&b, "  Destination:        %s\n", s.Destination)
+	fmt.Fprintf(&b, "  Estimated duration: %s (at %s)\n", estimate, ssh.FormatRate(assumedThroughput))
+	return b.String()
+}
+
+// ErrNotConfirmed is returned when the user declines to start the migration
+var ErrNotConfirmed = fmt.Errorf("migration %w", migerrors.ErrCancelled)
+
+// confirmMigration prints the summary and, unless --yes was given or the volumes were just
+// picked in the selector, asks the user to start the migration
+func (m *Migrator) confirmMigration(volumes []docker.VolumeInfo) error {
+	fmt.Println(summarizeMigration(volumes, m.config))
+	if m.config.AssumeYes || m.config.Interactive {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return migerrors.NewConfigError(errors.New("cannot ask for confirmation, stdin is not a terminal: pass --yes to migrate without confirmation"))
+	}
+
+	confirmed, err := ui.Confirm("Start the migration")
+	if err != nil {
+		return fmt.Errorf("confirmation failed: %w", err)
+	}
+	if !confirmed {
+		return ErrNotConfirmed
+	}
+	return nil
+}
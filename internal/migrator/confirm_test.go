@@ -0,0 +1,57 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestSummarizeMigration(t *testing.T) {
+	volumes := []docker.VolumeInfo{
+		{Name: "db", SizeBytes: 300 * 1024 * 1024},
+		{Name: "cache", SizeBytes: 200 * 1024 * 1024},
+	}
+
+	summary := summarizeMigration(volumes, &Config{RemoteHost: "user@host"})
+	if summary.Volumes != 2 || summary.TotalBytes != 500*1024*1024 {
+		t.Errorf("unexpected totals: %+v", summary)
+	}
+	if summary.Estimate != 5*time.Second {
+		t.Errorf("Estimate = %s, want 5s", summary.Estimate)
+	}
+
+	text := summary.String()
+	for _, want := range []string{"Volumes:            2", "Destination:        user@host", "about 5s"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in summary:\n%s", want, text)
+		}
+	}
+}
+
+func TestSummarizeMigration_Empty(t *testing.T) {
+	text := summarizeMigration([]docker.VolumeInfo{{Name: "empty"}}, &Config{}).String()
+	if !strings.Contains(text, "under a second") {
+		t.Errorf("expected an estimate of under a second:\n%s", text)
+	}
+}
+
+func TestConfirmMigration_NoPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"--yes", Config{AssumeYes: true}},
+		{"interactive selection", Config{Interactive: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: &tt.config}
+			if err := m.confirmMigration([]docker.VolumeInfo{{Name: "db"}}); err != nil {
+				t.Errorf("expected no confirmation to be needed, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package migrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/utils"
+)
+
+// Supported database dump modes
+const (
+	DBModePostgres = "postgres"
+	DBModeMySQL    = "mysql"
+)
+
+// dbDataDirs maps each dump mode to the container paths where the database keeps its data files
+var dbDataDirs = map[string][]string{
+	DBModePostgres: {"/var/lib/postgresql/data", "/var/lib/postgresql"},
+	DBModeMySQL:    {"/var/lib/mysql"},
+}
+
+// ValidateDBMode checks that mode is empty or one of the supported database dump modes
+func ValidateDBMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	if _, ok := dbDataDirs[mode]; !ok {
+		return fmt.Errorf("invalid db mode '%s': must be one of postgres, mysql", mode)
+	}
+	return nil
+}
+
+// IsDatabaseVolume reports whether a volume mounted at mountPath holds the data files of the given database
+func IsDatabaseVolume(mode, mountPath string) bool {
+	mountPath = strings.TrimSuffix(mountPath, "/")
+	for _, dir := range dbDataDirs[mode] {
+		if mountPath == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpCommand returns the shell command executed inside the database container to produce a logical dump
+// Credentials are taken from the environment variables used by the official images
+func dumpCommand(mode string) (string, error) {
+	switch mode {
+	case DBModePostgres:
+		return `exec pg_dumpall -U "${POSTGRES_USER:-postgres}"`, nil
+	case DBModeMySQL:
+		return `exec mysqldump --all-databases --single-transaction --routines --events --triggers -uroot ${MYSQL_ROOT_PASSWORD:+-p"$MYSQL_ROOT_PASSWORD"}`, nil
+	default:
+		return "", fmt.Errorf("unsupported db mode: %s", mode)
+	}
+}
+
+// dumpFileName returns the name of the SQL file stored inside a dump archive
+func dumpFileName(mode string) string {
+	if mode == DBModeMySQL {
+		return "mysqldump.sql"
+	}
+	return "pg_dumpall.sql"
+}
+
+// ExportDatabaseDump runs a logical dump (pg_dumpall/mysqldump) inside the container that owns
+// the volume and packages the resulting SQL file as a tar.gz archive at outputPath, compressed at level.
+// Unlike ExportVolume this produces a restore-safe archive even while the database is running.
+func ExportDatabaseDump(dockerClient DockerRunner, mode string, volume docker.VolumeInfo, outputPath string, level int) error {
+	if !shell.ValidateVolumeName(volume.Name) {
+		return fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volume.Name)
+	}
+
+	dumpCmd, err := dumpCommand(mode)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"volume":    volume.Name,
+		"container": volume.Container,
+		"db_mode":   mode,
+	}).Debug("Exporting database volume as logical dump")
+
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Write the raw dump next to the archive, then package it
+	dumpPath := filepath.Join(outputDir, fmt.Sprintf("%s.sql", volume.Name))
+	dumpFile, err := os.Create(dumpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create dump file: %w", err)
+	}
+	defer os.Remove(dumpPath)
+
+	var stderr bytes.Buffer
+	args := []string{"exec", volume.Container, "sh", "-c", dumpCmd}
+	if err := dockerClient.ExecCommandStream(dumpFile, &stderr, args...); err != nil {
+		dumpFile.Close()
+		return fmt.Errorf("failed to dump database in container %s: %w, stderr: %s", volume.Container, err, stderr.String())
+	}
+	if err := dumpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write dump file: %w", err)
+	}
+
+	if err := writeDumpArchive(dumpPath, dumpFileName(mode), outputPath, level); err != nil {
+		return fmt.Errorf("failed to package dump for volume %s: %w", volume.Name, err)
+	}
+
+	stat, _ := os.Stat(outputPath)
+	log.WithFields(logrus.Fields{
+		"volume": volume.Name,
+		"size":   utils.FormatBytes(stat.Size()),
+	}).Debug("Successfully exported database dump")
+
+	return nil
+}
+
+// writeDumpArchive writes a tar.gz archive at archivePath containing the single file srcPath stored as name
+func writeDumpArchive(srcPath, name, archivePath string, level int) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz, err := newGzipWriter(out, level)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, src); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,107 @@
+package migrator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsDatabaseVolume(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		mountPath string
+		want      bool
+	}{
+		{"postgres data dir", DBModePostgres, "/var/lib/postgresql/data", true},
+		{"postgres parent dir", DBModePostgres, "/var/lib/postgresql", true},
+		{"postgres trailing slash", DBModePostgres, "/var/lib/postgresql/data/", true},
+		{"mysql data dir", DBModeMySQL, "/var/lib/mysql", true},
+		{"mysql path in postgres mode", DBModePostgres, "/var/lib/mysql", false},
+		{"unrelated path", DBModeMySQL, "/app/uploads", false},
+		{"no mode", "", "/var/lib/mysql", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDatabaseVolume(tt.mode, tt.mountPath); got != tt.want {
+				t.Errorf("IsDatabaseVolume(%q, %q) = %v, want %v", tt.mode, tt.mountPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpCommand(t *testing.T) {
+	tests := []struct {
+		mode     string
+		contains string
+		wantErr  bool
+	}{
+		{DBModePostgres, "pg_dumpall", false},
+		{DBModeMySQL, "mysqldump", false},
+		{"oracle", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cmd, err := dumpCommand(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dumpCommand(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if !strings.Contains(cmd, tt.contains) {
+				t.Errorf("dumpCommand(%q) = %q, expected to contain %q", tt.mode, cmd, tt.contains)
+			}
+		})
+	}
+}
+
+func TestWriteDumpArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	dumpPath := filepath.Join(tempDir, "db.sql")
+	archivePath := filepath.Join(tempDir, "db.tar.gz")
+	content := "CREATE TABLE test (id int);\n"
+
+	if err := os.WriteFile(dumpPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create dump file: %v", err)
+	}
+
+	if err := writeDumpArchive(dumpPath, "pg_dumpall.sql", archivePath, DefaultCompressionLevel); err != nil {
+		t.Fatalf("writeDumpArchive() failed: %v", err)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Archive is not gzip compressed: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatalf("Failed to read tar header: %v", err)
+	}
+	if header.Name != "pg_dumpall.sql" {
+		t.Errorf("archive entry name = %q, want %q", header.Name, "pg_dumpall.sql")
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("Failed to read tar entry: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("archive entry content = %q, want %q", string(data), content)
+	}
+
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("expected archive to contain a single entry, got err = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package migrator
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"volume-migrator/internal/shell"
+)
+
+// dedupMinSize is the smallest file considered for deduplication; smaller files are cheaper to resend
+const dedupMinSize = 64 * 1024
+
+// dedupSource identifies where the first copy of a file's content is stored on the remote host
+type dedupSource struct {
+	Volume string // Remote volume name
+	Path   string // Path inside the volume
+}
+
+// dedupEntry is a file sent as an empty placeholder whose content is copied from Source on the remote
+type dedupEntry struct {
+	Source  dedupSource
+	Path    string
+	ModTime int64 // Unix seconds, restored after the copy
+}
+
+// DedupIndex tracks file contents already sent during a run so identical files in later
+// volumes (or later in the same volume) are transferred once
+type DedupIndex struct {
+	sources map[string]dedupSource  // sha256 -> first copy
+	entries map[string][]dedupEntry // remote volume -> placeholders to restore
+}
+
+// NewDedupIndex creates an empty deduplication index
+func NewDedupIndex() *DedupIndex {
+	return &DedupIndex{
+		sources: make(map[string]dedupSource),
+		entries: make(map[string][]dedupEntry),
+	}
+}
+
+// Entries returns the placeholders written for a volume
+func (d *DedupIndex) Entries(volume string) []dedupEntry {
+	return d.entries[volume]
+}
+
+// Forget drops everything recorded for a volume that failed to migrate, so later volumes
+// never reference content that did not reach the remote host
+func (d *DedupIndex) Forget(volume string) {
+	for sum, source := range d.sources {
+		if source.Volume == volume {
+			delete(d.sources, sum)
+		}
+	}
+	delete(d.entries, volume)
+}
+
+// dedupTarStream copies the tar stream r to w, replacing regular files whose content was
+// already seen with empty placeholders (recorded in the index under volume).
+// File content is spooled to spoolDir so it can be hashed before its header is written.
+func dedupTarStream(r io.Reader, w io.Writer, volume string, index *DedupIndex, spoolDir string) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar stream: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg || header.Size < dedupMinSize {
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := dedupFile(tr, tw, header, volume, index, spoolDir); err != nil {
+			return fmt.Errorf("failed to deduplicate %s: %w", header.Name, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// dedupFile writes one regular file, either in full or as a placeholder for known content
+func dedupFile(tr io.Reader, tw *tar.Writer, header *tar.Header, volume string, index *DedupIndex, spoolDir string) error {
+	spool, err := os.CreateTemp(spoolDir, "dedup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(spool, hash), tr); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	name := path.Clean(header.Name)
+
+	if source, ok := index.sources[sum]; ok {
+		placeholder := *header
+		placeholder.Size = 0
+		if err := tw.WriteHeader(&placeholder); err != nil {
+			return err
+		}
+		index.entries[volume] = append(index.entries[volume], dedupEntry{
+			Source:  source,
+			Path:    name,
+			ModTime: header.ModTime.Unix(),
+		})
+		return nil
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, spool); err != nil {
+		return err
+	}
+	index.sources[sum] = dedupSource{Volume: volume, Path: name}
+	return nil
+}
+
+// dedupSourceVolumes returns the other volumes a volume's placeholders are restored from, sorted
+// The i-th volume is mounted read-only at /src<i> during import
+func dedupSourceVolumes(volume string, entries []dedupEntry) []string {
+	seen := make(map[string]bool)
+	var volumes []string
+	for _, e := range entries {
+		if e.Source.Volume != volume && !seen[e.Source.Volume] {
+			seen[e.Source.Volume] = true
+			volumes = append(volumes, e.Source.Volume)
+		}
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+// buildDedupScript generates the shell script that fills placeholders from their source copies
+// It runs in the import helper container with the volume at /data and sources at /src<i>
+func buildDedupScript(volume string, entries []dedupEntry) string {
+	mounts := make(map[string]string)
+	for i, v := range dedupSourceVolumes(volume, entries) {
+		mounts[v] = fmt.Sprintf("/src%d", i)
+	}
+	mounts[volume] = "/data"
+
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	for _, e := range entries {
+		src := shell.ShellEscape(path.Join(mounts[e.Source.Volume], e.Source.Path))
+		dst := shell.ShellEscape(path.Join("/data", e.Path))
+		fmt.Fprintf(&b, "cat %s > %s\ntouch -d @%d %s\n", src, dst, e.ModTime, dst)
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildTar creates an in-memory tar stream with the given regular files
+func buildTar(t *testing.T, files map[string][]byte, order []string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		data := files[name]
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+			ModTime:  time.Unix(1700000000, 0),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// readTarSizes returns the size of every entry in a tar stream
+func readTarSizes(t *testing.T, r io.Reader) map[string]int64 {
+	t.Helper()
+	sizes := make(map[string]int64)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return sizes
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[header.Name] = header.Size
+	}
+}
+
+func TestDedupTarStream(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), dedupMinSize)
+	other := bytes.Repeat([]byte("b"), dedupMinSize)
+	small := []byte("small")
+	index := NewDedupIndex()
+	spool := t.TempDir()
+
+	var first bytes.Buffer
+	in := buildTar(t, map[string][]byte{"./big.bin": large, "./copy.bin": large, "./small.txt": small},
+		[]string{"./big.bin", "./copy.bin", "./small.txt"})
+	if err := dedupTarStream(in, &first, "vol1", index, spool); err != nil {
+		t.Fatalf("dedupTarStream failed: %v", err)
+	}
+	sizes := readTarSizes(t, &first)
+	if sizes["./big.bin"] != int64(len(large)) || sizes["./copy.bin"] != 0 || sizes["./small.txt"] != int64(len(small)) {
+		t.Errorf("unexpected entry sizes in first volume: %v", sizes)
+	}
+
+	var second bytes.Buffer
+	in = buildTar(t, map[string][]byte{"./data/dup.bin": large, "./other.bin": other, "./small.txt": small},
+		[]string{"./data/dup.bin", "./other.bin", "./small.txt"})
+	if err := dedupTarStream(in, &second, "vol2", index, spool); err != nil {
+		t.Fatalf("dedupTarStream failed: %v", err)
+	}
+	sizes = readTarSizes(t, &second)
+	if sizes["./data/dup.bin"] != 0 || sizes["./other.bin"] != int64(len(other)) || sizes["./small.txt"] != int64(len(small)) {
+		t.Errorf("unexpected entry sizes in second volume: %v", sizes)
+	}
+
+	want := dedupEntry{Source: dedupSource{Volume: "vol1", Path: "big.bin"}, Path: "data/dup.bin", ModTime: 1700000000}
+	entries := index.Entries("vol2")
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("Entries(vol2) = %+v, want [%+v]", entries, want)
+	}
+	if entries := index.Entries("vol1"); len(entries) != 1 || entries[0].Source.Volume != "vol1" {
+		t.Errorf("expected one same-volume entry for vol1, got %+v", entries)
+	}
+}
+
+func TestDedupSourceVolumes(t *testing.T) {
+	entries := []dedupEntry{
+		{Source: dedupSource{Volume: "zeta"}},
+		{Source: dedupSource{Volume: "self"}},
+		{Source: dedupSource{Volume: "alpha"}},
+		{Source: dedupSource{Volume: "zeta"}},
+	}
+	got := dedupSourceVolumes("self", entries)
+	if strings.Join(got, ",") != "alpha,zeta" {
+		t.Errorf("dedupSourceVolumes() = %v, want [alpha zeta]", got)
+	}
+}
+
+func TestBuildDedupScript(t *testing.T) {
+	entries := []dedupEntry{
+		{Source: dedupSource{Volume: "other", Path: "lib/app.jar"}, Path: "app.jar", ModTime: 42},
+		{Source: dedupSource{Volume: "self", Path: "a.bin"}, Path: "it's.bin", ModTime: 7},
+	}
+	script := buildDedupScript("self", entries)
+
+	for _, want := range []string{
+		"set -e\n",
+		"cat /src0/lib/app.jar > /data/app.jar\ntouch -d @42 /data/app.jar\n",
+		`cat /data/a.bin > '/data/it'\''s.bin'` + "\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+func TestDedupIndexForget(t *testing.T) {
+	index := NewDedupIndex()
+	index.sources["aa"] = dedupSource{Volume: "failed", Path: "a.bin"}
+	index.sources["bb"] = dedupSource{Volume: "ok", Path: "b.bin"}
+	index.entries["failed"] = []dedupEntry{{Source: dedupSource{Volume: "ok", Path: "b.bin"}, Path: "copy.bin"}}
+
+	index.Forget("failed")
+
+	if _, ok := index.sources["aa"]; ok {
+		t.Error("expected sources of the failed volume to be dropped")
+	}
+	if _, ok := index.sources["bb"]; !ok {
+		t.Error("expected sources of other volumes to be kept")
+	}
+	if len(index.Entries("failed")) != 0 {
+		t.Error("expected entries of the failed volume to be dropped")
+	}
+}
@@ -0,0 +1,51 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/utils"
+)
+
+// measureDesktopVolumes fills in the sizes Docker Desktop did not report.
+// `docker system df -v` sizes come from the VM and are often missing (0B) for volumes that no
+// running container uses, which would skip the disk space check, so du is run in a helper container.
+func (m *Migrator) measureDesktopVolumes(volumes []docker.VolumeInfo) {
+	image := resolveHelperImage(m.config.HelperImage, m.config.HelperRegistry, false)
+	for i := range volumes {
+		if volumes[i].SizeBytes > 0 {
+			continue
+		}
+		size, err := localVolumeUsage(m.dockerClient, volumes[i].Name, image)
+		if err != nil {
+			log.WithError(err).WithField("volume", volumes[i].Name).Warn("Failed to measure volume size in Docker Desktop")
+			continue
+		}
+		volumes[i].SizeBytes = size
+		volumes[i].Size = utils.FormatBytes(size)
+		log.WithFields(logrus.Fields{
+			"volume": volumes[i].Name,
+			"size":   volumes[i].Size,
+		}).Debug("Measured volume size in helper container")
+	}
+}
+
+// localVolumeUsage returns the number of bytes stored in a local volume, measured with du
+func localVolumeUsage(dockerClient DockerRunner, volumeName, image string) (int64, error) {
+	output, err := dockerClient.ExecCommand("run", "--rm", "-v", volumeName+":/data:ro", image, "du", "-sk", "/data")
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure volume %s: %w", volumeName, err)
+	}
+	return parseDUKilobytes(output)
+}
+
+// TranslateWSLPaths rewrites local directories given as Windows paths (C:\Temp) to their WSL
+// mounts (/mnt/c/Temp) when running inside WSL2, so paths copied from Windows tools work
+func TranslateWSLPaths(config *Config) {
+	if !utils.IsWSL2() {
+		return
+	}
+	config.TempDir = utils.WSLPath(config.TempDir)
+	config.StateDir = utils.WSLPath(config.StateDir)
+}
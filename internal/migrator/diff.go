@@ -0,0 +1,35 @@
+package migrator
+
+import (
+	"context"
+	"sort"
+)
+
+// DiffVolumes compares each local volume in config.Volumes with its remote copy like VerifyVolumes,
+// also counting files whose modification time differs as changed, so edits that keep a file's
+// size are found without reading all data. checksums adds a sha256 of every file on both hosts.
+// In the results, Missing lists the files added locally, Extra those removed locally and
+// Different those changed since the remote copy was made.
+func DiffVolumes(ctx context.Context, config *Config, checksums bool) ([]VerifyResult, error) {
+	return compareVolumes(ctx, config, checksums, true)
+}
+
+// DiffLines returns the differing files of a result sorted by path, each prefixed with
+// + when it was added locally, - when it was removed locally or ~ when it changed
+func DiffLines(result VerifyResult) []string {
+	var lines []string
+	for _, group := range []struct {
+		marker string
+		paths  []string
+	}{
+		{"+ ", result.Missing},
+		{"- ", result.Extra},
+		{"~ ", result.Different},
+	} {
+		for _, path := range group.paths {
+			lines = append(lines, group.marker+path)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	return lines
+}
@@ -0,0 +1,22 @@
+package migrator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	result := VerifyResult{
+		Missing:   []string{"./b/new", "./d"},
+		Extra:     []string{"./a/old"},
+		Different: []string{"./c"},
+	}
+	want := []string{"- ./a/old", "+ ./b/new", "~ ./c", "+ ./d"}
+	if got := DiffLines(result); !slices.Equal(got, want) {
+		t.Errorf("DiffLines() = %v, want %v", got, want)
+	}
+
+	if got := DiffLines(VerifyResult{}); len(got) != 0 {
+		t.Errorf("expected no lines for identical volumes, got: %v", got)
+	}
+}
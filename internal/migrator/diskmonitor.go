@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/utils"
+)
+
+// DefaultMinFreeSpace is how much free space --min-free-space keeps in both temp directories
+const DefaultMinFreeSpace = "512MB"
+
+// diskMonitorInterval is how often free space is measured while volumes migrate
+const diskMonitorInterval = 5 * time.Second
+
+// minFreeSpace returns the --min-free-space floor in bytes, 0 when monitoring is off
+func (m *Migrator) minFreeSpace() int64 {
+	size := m.config.MinFreeSpace
+	if size == "" {
+		size = DefaultMinFreeSpace
+	}
+	floor, _ := utils.ParseSize(size)
+	return floor
+}
+
+// belowFloor returns a DiskSpaceError when info has less than floor bytes free
+func belowFloor(location string, info *utils.DiskSpaceInfo, floor int64) error {
+	if info.Available >= uint64(floor) {
+		return nil
+	}
+	err := migerrors.NewDiskSpaceError(location, floor, int64(info.Available), nil)
+	return fmt.Errorf("free space in the %s temp directory fell below --min-free-space: %w", location, err)
+}
+
+// checkFreeSpace measures both temp directories against floor. A directory that cannot be
+// measured is skipped, since the up-front validation already reported it.
+func (m *Migrator) checkFreeSpace(floor int64) error {
+	if local, err := utils.GetLocalDiskSpace(utils.NearestExistingDir(m.config.TempDir)); err != nil {
+		log.WithError(err).Debug("Could not monitor local disk space")
+	} else if err := belowFloor("local", local, floor); err != nil {
+		return err
+	}
+	if remote, err := utils.GetRemoteDiskSpace(m.sshClient, m.config.RemoteTempDir); err != nil {
+		log.WithError(err).Debug("Could not monitor remote disk space")
+	} else if err := belowFloor("remote", remote, floor); err != nil {
+		return err
+	}
+	return nil
+}
+
+// monitorDiskSpace measures free space every diskMonitorInterval until the returned function is
+// called, aborting the run when either temp directory falls below --min-free-space. Archives can
+// end up larger than estimated and other processes write to the same filesystems, so the
+// up-front validation alone does not keep them from filling up.
+func (m *Migrator) monitorDiskSpace() func() {
+	floor := m.minFreeSpace()
+	if floor == 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(diskMonitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := m.checkFreeSpace(floor); err != nil {
+				log.Warn("Free space fell below --min-free-space, aborting the migration before the disk fills up")
+				m.abort(err)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// abortCause returns why the disk space monitor aborted the run, nil unless it did
+func (m *Migrator) abortCause() error {
+	if cause := context.Cause(m.ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return nil
+}
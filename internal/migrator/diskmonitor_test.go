@@ -0,0 +1,86 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/utils"
+)
+
+func TestMinFreeSpace(t *testing.T) {
+	tests := []struct {
+		setting string
+		want    int64
+	}{
+		{"", 512 * 1024 * 1024},
+		{"2GB", 2 * 1024 * 1024 * 1024},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		m := &Migrator{config: &Config{MinFreeSpace: tt.setting}}
+		if got := m.minFreeSpace(); got != tt.want {
+			t.Errorf("minFreeSpace() with %q = %d, want %d", tt.setting, got, tt.want)
+		}
+	}
+}
+
+func TestBelowFloor(t *testing.T) {
+	if err := belowFloor("local", &utils.DiskSpaceInfo{Available: 1000}, 1000); err != nil {
+		t.Errorf("belowFloor() at the floor = %v, want nil", err)
+	}
+	err := belowFloor("remote", &utils.DiskSpaceInfo{Available: 999}, 1000)
+	if !errors.Is(err, migerrors.ErrDiskSpace) {
+		t.Fatalf("belowFloor() = %v, want a disk space error", err)
+	}
+	var diskErr *migerrors.DiskSpaceError
+	if !errors.As(err, &diskErr) || diskErr.Location != "remote" || diskErr.Available != 999 {
+		t.Errorf("belowFloor() = %#v, want remote with 999 bytes available", diskErr)
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	df := "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+		"/dev/sda1        10000000   9999000      1000     100% /\n"
+	remote := &fakeRemote{shell: map[string]fakeResponse{"p=": {output: df}}}
+	m := &Migrator{config: &Config{TempDir: t.TempDir(), RemoteTempDir: "/tmp/volume-migration-1"}, sshClient: remote}
+
+	// 1000 KiB are free on the remote
+	if err := m.checkFreeSpace(1000 * 1024); err != nil {
+		t.Errorf("checkFreeSpace() = %v, want nil", err)
+	}
+	err := m.checkFreeSpace(1001 * 1024)
+	var diskErr *migerrors.DiskSpaceError
+	if !errors.As(err, &diskErr) || diskErr.Location != "remote" {
+		t.Errorf("checkFreeSpace() = %v, want a remote disk space error", err)
+	}
+
+	// The remote cannot be measured: nothing to abort for
+	remote.shell["p="] = fakeResponse{err: errors.New("connection lost")}
+	if err := m.checkFreeSpace(1001 * 1024); err != nil {
+		t.Errorf("checkFreeSpace() with an unmeasurable remote = %v, want nil", err)
+	}
+}
+
+func TestAbortCause(t *testing.T) {
+	parent, interrupt := context.WithCancel(context.Background())
+	ctx, abort := context.WithCancelCause(parent)
+	m := &Migrator{ctx: ctx, abort: abort}
+
+	if err := m.abortCause(); err != nil {
+		t.Errorf("abortCause() of a running migration = %v, want nil", err)
+	}
+	interrupt()
+	if err := m.abortCause(); err != nil {
+		t.Errorf("abortCause() after Ctrl+C = %v, want nil", err)
+	}
+
+	ctx, abort = context.WithCancelCause(context.Background())
+	m = &Migrator{ctx: ctx, abort: abort}
+	full := migerrors.NewDiskSpaceError("local", 1, 0, nil)
+	m.abort(full)
+	if err := m.abortCause(); err != full {
+		t.Errorf("abortCause() = %v, want %v", err, full)
+	}
+}
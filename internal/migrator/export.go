@@ -2,19 +2,85 @@ package migrator
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/schollz/progressbar/v3"
 	"github.com/sirupsen/logrus"
-	"volume-migrator/internal/docker"
 	"volume-migrator/internal/shell"
 	"volume-migrator/internal/utils"
 )
 
-// ExportVolume exports a Docker volume to a tar.gz archive
-// Uses a temporary Alpine container to access and compress the volume data
-func ExportVolume(dockerClient *docker.Client, volumeName, outputPath string) error {
+// DefaultCompressionLevel favours speed: archives usually cross a network that is slower than
+// gzip at level 1, so higher levels mostly add CPU time
+const DefaultCompressionLevel = gzip.BestSpeed
+
+// ExportOptions controls how volume archives are produced
+type ExportOptions struct {
+	// HelperImage is the image used to run tar against the volume (default: alpine)
+	HelperImage string
+	// PreserveXattrs stores extended attributes in the archive (requires GNU tar)
+	PreserveXattrs bool
+	// PreserveACLs stores POSIX ACLs in the archive (requires GNU tar)
+	PreserveACLs bool
+	// Sparse stores sparse files efficiently (requires GNU tar)
+	Sparse bool
+	// SnapshotPath enables an incremental archive (GNU tar --listed-incremental) using this
+	// snapshot file on the host; an existing snapshot limits the archive to changes since it was written
+	SnapshotPath string
+	// Dedup replaces files whose content was already exported during this run with placeholders,
+	// recorded in the index under DedupVolume (the remote volume name)
+	Dedup       *DedupIndex
+	DedupVolume string
+	// Format is the archive format (see FormatTarGz and friends), empty meaning tar.gz
+	Format string
+	// CompressionLevel is the gzip, zstd or squashfs level from 1 (fastest), 0 means DefaultCompressionLevel
+	CompressionLevel int
+	// MaxArchiveSize splits the archive into numbered parts of at most this many bytes, 0 for one file
+	MaxArchiveSize int64
+	// AutoCompress stores the archive without compression when a sample of the data barely compresses
+	AutoCompress bool
+	// Stored writes the gzip archive without compressing it, ignoring CompressionLevel
+	Stored bool
+	// ShowProgress displays a progress bar while the volume is archived
+	ShowProgress bool
+	// ExpectedSize is the volume size in bytes the progress bar is measured against (0 if unknown)
+	ExpectedSize int64
+	// Scan, when set, receives the archive's manifest entry, computed while the archive is written
+	// so it need not be read back; squashfs images are written by the helper container and leave it empty
+	Scan *ArchiveEntry
+}
+
+// RequiresGNUTar reports whether the options use features only GNU tar provides
+func (o ExportOptions) RequiresGNUTar() bool {
+	return o.PreserveXattrs || o.PreserveACLs || o.Sparse || o.SnapshotPath != ""
+}
+
+// tarPreserveFlags returns the GNU tar flags for the requested metadata preservation features
+// Hard links are preserved by every tar implementation and need no flag
+func tarPreserveFlags(xattrs, acls, sparse bool) []string {
+	var flags []string
+	if xattrs {
+		flags = append(flags, "--xattrs", "--xattrs-include=*")
+	}
+	if acls {
+		flags = append(flags, "--acls")
+	}
+	if sparse {
+		flags = append(flags, "--sparse")
+	}
+	return flags
+}
+
+// ExportVolume exports a Docker volume to an archive in opts.Format
+// Uses a temporary helper container to read the volume data; tar.gz is compressed on the host
+func ExportVolume(dockerClient DockerRunner, volumeName, outputPath string, opts ExportOptions) error {
 	// Validate volume name to prevent command injection and path traversal
 	if !shell.ValidateVolumeName(volumeName) {
 		return fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volumeName)
@@ -31,50 +97,219 @@ func ExportVolume(dockerClient *docker.Client, volumeName, outputPath string) er
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Construct docker command to export volume
-	// Mount volume as read-only to avoid conflicts with running containers
-	args := []string{
-		"run", "--rm",
-		"-v", fmt.Sprintf("%s:/data:ro", volumeName),
-		"-v", fmt.Sprintf("%s:/backup", outputDir),
-		"alpine",
-		"tar", "czf", fmt.Sprintf("/backup/%s", filepath.Base(outputPath)),
-		"-C", "/data", ".",
+	write := writeVolumeArchive
+	if opts.Format == FormatSquashfs {
+		write = writeSquashfsImage
 	}
-
-	var stdout, stderr bytes.Buffer
-	if err := dockerClient.ExecCommandWithOutput(&stdout, &stderr, args...); err != nil {
-		return fmt.Errorf("failed to export volume %s: %w, stderr: %s", volumeName, err, stderr.String())
-	}
-
-	// Verify archive was created
-	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-		return fmt.Errorf("archive %s was not created", outputPath)
+	if err := write(dockerClient, volumeName, outputPath, opts); err != nil {
+		removeArchiveFiles(outputPath)
+		return err
 	}
 
 	// Get archive size
-	stat, _ := os.Stat(outputPath)
+	files := ArchiveFiles(outputPath)
+	size, _ := archiveSize(files)
 	log.WithFields(logrus.Fields{
 		"volume": volumeName,
-		"size":   utils.FormatBytes(stat.Size()),
+		"size":   utils.FormatBytes(size),
+		"parts":  len(files),
 	}).Debug("Successfully exported volume")
 
 	return nil
 }
 
-// ExportVolumes exports multiple volumes to a directory
-func ExportVolumes(dockerClient *docker.Client, volumes []string, outputDir string) (map[string]string, error) {
-	archivePaths := make(map[string]string)
+// newGzipWriter creates a gzip writer at the given level, 0 selecting DefaultCompressionLevel
+func newGzipWriter(w io.Writer, level int) (*gzip.Writer, error) {
+	if level == 0 {
+		level = DefaultCompressionLevel
+	}
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compression level %d: %w", level, err)
+	}
+	return gz, nil
+}
+
+// writeVolumeArchive streams the tar output of the helper container into an archive at outputPath,
+// gzipped on the host for tar.gz. Counting the uncompressed stream lets the progress bar track the
+// volume size; tar.zst arrives compressed, so its bar only counts bytes.
+// With MaxArchiveSize the archive is written to numbered parts next to outputPath instead.
+// With Scan the bytes going into the archive, and the tar stream before compression, are scanned on the way.
+func writeVolumeArchive(dockerClient DockerRunner, volumeName, outputPath string, opts ExportOptions) error {
+	var out io.WriteCloser
+	if opts.MaxArchiveSize > 0 {
+		out = newSplitWriter(outputPath, opts.MaxArchiveSize)
+	} else {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		out = file
+	}
+	defer out.Close()
+
+	var archive io.Writer = out
+	var scan *archiveScanner
+	if opts.Scan != nil {
+		scan = newArchiveScanner(opts.Format)
+		defer scan.close()
+		archive = io.MultiWriter(out, scan)
+	}
+
+	var err error
+	var gz io.WriteCloser
+	var auto *autoCompressWriter
+	if opts.Format == FormatTar || opts.Format == FormatTarZst {
+		gz = nopWriteCloser{archive}
+	} else if opts.AutoCompress {
+		auto = newAutoCompressWriter(archive, opts.CompressionLevel)
+		gz = auto
+	} else if opts.Stored {
+		gz, _ = gzip.NewWriterLevel(archive, gzip.NoCompression)
+	} else if gz, err = newGzipWriter(archive, opts.CompressionLevel); err != nil {
+		return err
+	}
+	var tarStream io.Writer = gz
+	if scan != nil {
+		tarStream = io.MultiWriter(gz, scan.tarWriter())
+	}
+
+	var progress io.Writer = io.Discard
+	if opts.ShowProgress {
+		expected := opts.ExpectedSize
+		if opts.Format == FormatTarZst {
+			expected = 0
+		}
+		bar := newExportProgressBar(expected, volumeName)
+		progress = bar
+		defer bar.Finish()
+	}
+
+	var stderr bytes.Buffer
+	args := buildExportArgs(volumeName, opts)
+	if opts.Dedup == nil {
+		err = dockerClient.ExecCommandStream(io.MultiWriter(tarStream, progress), &stderr, args...)
+	} else {
+		err = exportDeduplicated(dockerClient, args, tarStream, progress, &stderr, filepath.Dir(outputPath), opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export volume %s: %w, stderr: %s", volumeName, err, stderr.String())
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if auto != nil && auto.Stored() {
+		log.WithField("volume", volumeName).Info("Volume data looks incompressible, archive stored without compression")
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if scan != nil {
+		*opts.Scan = newArchiveEntry(volumeName, outputPath)
+		return scan.finish(opts.Scan)
+	}
+	return nil
+}
+
+// nopWriteCloser writes an archive that needs no compression, leaving the file to be closed by its owner
+type nopWriteCloser struct {
+	io.Writer
+}
 
-	for _, volumeName := range volumes {
-		archivePath := filepath.Join(outputDir, fmt.Sprintf("%s.tar.gz", volumeName))
+func (nopWriteCloser) Close() error { return nil }
+
+// writeSquashfsImage builds a squashfs image of the volume with mksquashfs in the helper container,
+// which writes it straight into the archive's directory on the host
+func writeSquashfsImage(dockerClient DockerRunner, volumeName, outputPath string, opts ExportOptions) error {
+	if opts.ShowProgress {
+		log.WithField("volume", volumeName).Info("Building squashfs image")
+	}
 
-		if err := ExportVolume(dockerClient, volumeName, archivePath); err != nil {
-			return nil, fmt.Errorf("failed to export volume %s: %w", volumeName, err)
+	var stdout, stderr bytes.Buffer
+	if err := dockerClient.ExecCommandWithOutput(&stdout, &stderr, buildSquashfsArgs(volumeName, outputPath, opts)...); err != nil {
+		return fmt.Errorf("failed to export volume %s: %w, stderr: %s", volumeName, err, stderr.String())
+	}
+	return nil
+}
+
+// buildSquashfsArgs constructs the docker command building a squashfs image of a volume
+// The image is gzip-compressed, which every unsquashfs can read
+func buildSquashfsArgs(volumeName, outputPath string, opts ExportOptions) []string {
+	args := []string{
+		"run", "--rm",
+		"-v", fmt.Sprintf("%s:/data:ro", volumeName),
+		"-v", fmt.Sprintf("%s:/out", filepath.Dir(outputPath)),
+		opts.HelperImage,
+		"mksquashfs", "/data", "/out/" + filepath.Base(outputPath),
+		"-noappend", "-no-progress", "-comp", "gzip",
+		"-Xcompression-level", strconv.Itoa(compressionLevel(opts.CompressionLevel)),
+	}
+	return args
+}
+
+// exportDeduplicated runs the export command and rewrites its tar stream on the fly,
+// replacing content already exported during the run with placeholders
+func exportDeduplicated(dockerClient DockerRunner, args []string, out, progress io.Writer, stderr *bytes.Buffer, spoolDir string, opts ExportOptions) error {
+	pr, pw := io.Pipe()
+	dedupDone := make(chan error, 1)
+	go func() {
+		err := dedupTarStream(pr, out, opts.DedupVolume, opts.Dedup, spoolDir)
+		if err == nil {
+			_, err = io.Copy(io.Discard, pr) // trailing tar padding
 		}
+		pr.CloseWithError(err)
+		dedupDone <- err
+	}()
+
+	execErr := dockerClient.ExecCommandStream(io.MultiWriter(pw, progress), stderr, args...)
+	pw.CloseWithError(execErr)
+	dedupErr := <-dedupDone
+
+	// A failed command also fails the reader; report whichever side failed first
+	if dedupErr != nil && (execErr == nil || !errors.Is(dedupErr, execErr)) {
+		return fmt.Errorf("deduplication failed: %w", dedupErr)
+	}
+	return execErr
+}
+
+// newExportProgressBar creates the progress bar shown while a volume is archived
+// Falls back to a spinner with a byte counter when the volume size is unknown
+func newExportProgressBar(expectedSize int64, volumeName string) *progressbar.ProgressBar {
+	if expectedSize <= 0 {
+		expectedSize = -1
+	}
+	return utils.NewProgressBar(expectedSize, fmt.Sprintf("Exporting %s", volumeName))
+}
 
-		archivePaths[volumeName] = archivePath
+// buildExportArgs constructs the docker command used to export a volume
+// The volume is mounted read-only to avoid conflicts with running containers,
+// and tar writes an uncompressed stream to stdout that is compressed on the host.
+// For tar.zst the stream is piped through zstd in the helper container instead.
+func buildExportArgs(volumeName string, opts ExportOptions) []string {
+	args := []string{
+		"run", "--rm",
+		"-v", fmt.Sprintf("%s:/data:ro", volumeName),
+	}
+	if opts.SnapshotPath != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:/state", filepath.Dir(opts.SnapshotPath)))
 	}
+	args = append(args, resolveHelperImage(opts.HelperImage, "", opts.RequiresGNUTar()))
 
-	return archivePaths, nil
+	tarArgs := []string{"tar"}
+	tarArgs = append(tarArgs, tarPreserveFlags(opts.PreserveXattrs, opts.PreserveACLs, opts.Sparse)...)
+	if opts.SnapshotPath != "" {
+		tarArgs = append(tarArgs, "--listed-incremental=/state/"+filepath.Base(opts.SnapshotPath))
+	}
+	tarArgs = append(tarArgs, "-cf", "-", "-C", "/data", ".")
+	if opts.Format != FormatTarZst {
+		return append(args, tarArgs...)
+	}
+
+	escaped := make([]string, len(tarArgs))
+	for i, arg := range tarArgs {
+		escaped[i] = shell.ShellEscape(arg)
+	}
+	pipeline := fmt.Sprintf("%s | zstd -q -c -T0 -%d", strings.Join(escaped, " "), compressionLevel(opts.CompressionLevel))
+	return append(args, "sh", "-c", pipefailScript(pipeline))
 }
@@ -1,6 +1,9 @@
 package migrator
 
 import (
+	"io"
+	"slices"
+	"strings"
 	"testing"
 
 	"volume-migrator/internal/utils"
@@ -115,6 +118,148 @@ func TestFormatBytes_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestBuildExportArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ExportOptions
+		wantImage string
+	}{
+		{"default helper image", ExportOptions{}, DefaultHelperImage},
+		{"custom helper image", ExportOptions{HelperImage: "registry.local/tools/alpine:3.19"}, "registry.local/tools/alpine:3.19"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildExportArgs("myvolume", tt.opts)
+			joined := strings.Join(args, " ")
+
+			if !strings.Contains(joined, "myvolume:/data:ro") {
+				t.Errorf("expected volume to be mounted read-only, got: %s", joined)
+			}
+			if strings.Contains(joined, "/backup") {
+				t.Errorf("expected no output directory mount, got: %s", joined)
+			}
+			if !strings.Contains(joined, " "+tt.wantImage+" tar ") {
+				t.Errorf("expected helper image %s, got: %s", tt.wantImage, joined)
+			}
+			if !strings.HasSuffix(joined, "tar -cf - -C /data .") {
+				t.Errorf("expected tar to stream to stdout, got: %s", joined)
+			}
+		})
+	}
+}
+
+func TestBuildExportArgs_Zstd(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     int
+		wantLevel string
+	}{
+		{"default level", 0, "-1"},
+		{"configured level", 6, "-6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildExportArgs("myvolume", ExportOptions{HelperImage: "tools:1", Format: FormatTarZst, CompressionLevel: tt.level})
+			want := []string{"tools:1", "sh", "-c", "set -o pipefail; tar -cf - -C /data . | zstd -q -c -T0 " + tt.wantLevel}
+			if got := args[len(args)-4:]; !slices.Equal(got, want) {
+				t.Errorf("buildExportArgs() ends with %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestBuildSquashfsArgs(t *testing.T) {
+	args := buildSquashfsArgs("myvolume", "/tmp/export/myvolume.squashfs", ExportOptions{HelperImage: "tools:1", CompressionLevel: 6})
+	joined := strings.Join(args, " ")
+
+	// An unset level compresses at DefaultCompressionLevel, as with gzip
+	if defaults := strings.Join(buildSquashfsArgs("myvolume", "/tmp/export/myvolume.squashfs", ExportOptions{HelperImage: "tools:1"}), " "); !strings.Contains(defaults, "-Xcompression-level 1") {
+		t.Errorf("expected the default level in squashfs command, got: %s", defaults)
+	}
+
+	for _, want := range []string{
+		"-v myvolume:/data:ro -v /tmp/export:/out tools:1",
+		"mksquashfs /data /out/myvolume.squashfs -noappend",
+		"-comp gzip -Xcompression-level 6",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in squashfs command, got: %s", want, joined)
+		}
+	}
+}
+
+func TestBuildExportArgs_PreserveMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ExportOptions
+		wantFlags []string
+		wantImage string
+	}{
+		{
+			name:      "busybox compatible",
+			opts:      ExportOptions{},
+			wantFlags: nil,
+			wantImage: DefaultHelperImage,
+		},
+		{
+			name:      "all features",
+			opts:      ExportOptions{PreserveXattrs: true, PreserveACLs: true, Sparse: true},
+			wantFlags: []string{"--xattrs", "--xattrs-include=*", "--acls", "--sparse"},
+			wantImage: DefaultGNUHelperImage,
+		},
+		{
+			name:      "sparse only with custom image",
+			opts:      ExportOptions{Sparse: true, HelperImage: "my/gnu-tar:1"},
+			wantFlags: []string{"--sparse"},
+			wantImage: "my/gnu-tar:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildExportArgs("vol", tt.opts)
+
+			imageIdx := -1
+			for i, arg := range args {
+				if arg == "tar" {
+					imageIdx = i - 1
+					break
+				}
+			}
+			if imageIdx < 0 || args[imageIdx] != tt.wantImage {
+				t.Fatalf("expected helper image %s before tar, got args: %v", tt.wantImage, args)
+			}
+
+			gotFlags := args[imageIdx+2 : imageIdx+2+len(tt.wantFlags)]
+			for i, flag := range tt.wantFlags {
+				if gotFlags[i] != flag {
+					t.Errorf("flag %d = %s, want %s (args: %v)", i, gotFlags[i], flag, args)
+				}
+			}
+			if next := args[imageIdx+2+len(tt.wantFlags)]; next != "-cf" {
+				t.Errorf("expected -cf after preservation flags, got %s", next)
+			}
+		})
+	}
+}
+
+func TestBuildExportArgs_Incremental(t *testing.T) {
+	args := buildExportArgs("vol", ExportOptions{SnapshotPath: "/tmp/work/snapshots/vol.snar"})
+	joined := strings.Join(args, " ")
+
+	if !strings.Contains(joined, "-v /tmp/work/snapshots:/state ") {
+		t.Errorf("expected snapshot directory to be mounted at /state, got: %s", joined)
+	}
+	if !strings.Contains(joined, " "+DefaultGNUHelperImage+" tar ") {
+		t.Errorf("expected GNU helper image for incremental export, got: %s", joined)
+	}
+	if !strings.Contains(joined, " --listed-incremental=/state/vol.snar -cf - ") {
+		t.Errorf("expected --listed-incremental before -cf, got: %s", joined)
+	}
+}
+
 func containsUnit(s, unit string) bool {
 	return len(s) >= len(unit) && s[len(s)-len(unit):] == unit
 }
@@ -135,3 +280,25 @@ func BenchmarkFormatBytes(b *testing.B) {
 		})
 	}
 }
+
+func TestNewGzipWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   int
+		wantErr bool
+	}{
+		{"default", 0, false},
+		{"fastest", 1, false},
+		{"smallest", 9, false},
+		{"out of range", 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newGzipWriter(io.Discard, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newGzipWriter(%d) error = %v, wantErr %v", tt.level, err, tt.wantErr)
+			}
+		})
+	}
+}
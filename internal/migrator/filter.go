@@ -0,0 +1,164 @@
+package migrator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/utils"
+)
+
+// containerFilterKeys lists the `docker ps` filter keys accepted with --all
+var containerFilterKeys = map[string]bool{
+	"ancestor": true,
+	"health":   true,
+	"id":       true,
+	"label":    true,
+	"name":     true,
+	"network":  true,
+	"status":   true,
+	"volume":   true,
+}
+
+// ValidateContainerFilters checks that every filter has the form key=value with a supported key
+func ValidateContainerFilters(filters []string) error {
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok || value == "" {
+			return fmt.Errorf("invalid container filter '%s': must be in format key=value", filter)
+		}
+		if !containerFilterKeys[key] {
+			return fmt.Errorf("invalid container filter '%s': unsupported key '%s'", filter, key)
+		}
+	}
+	return nil
+}
+
+// ValidateExcludePatterns checks that every exclusion is a valid volume name or glob pattern
+func ValidateExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("invalid exclude pattern: must not be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesAnyPattern reports whether name equals or matches one of the glob patterns
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterExcludedVolumes removes volumes whose name matches any of the exclusion patterns
+// Returns the remaining volumes and the names of the excluded ones
+func FilterExcludedVolumes(volumes []docker.VolumeInfo, patterns []string) ([]docker.VolumeInfo, []string) {
+	if len(patterns) == 0 {
+		return volumes, nil
+	}
+
+	var kept []docker.VolumeInfo
+	var excluded []string
+	for _, v := range volumes {
+		if matchesAnyPattern(v.Name, patterns) {
+			excluded = append(excluded, v.Name)
+			continue
+		}
+		kept = append(kept, v)
+	}
+	return kept, excluded
+}
+
+// ValidateSizeFilters checks that the --min-size and --max-size bounds are valid sizes, the lower
+// one no larger than the upper one; empty means no bound
+func ValidateSizeFilters(minSize, maxSize string) error {
+	minBytes, maxBytes, err := parseSizeFilters(minSize, maxSize)
+	if err != nil {
+		return err
+	}
+	if maxSize != "" && minBytes > maxBytes {
+		return fmt.Errorf("conflicting flags: --min-size %s is larger than --max-size %s", minSize, maxSize)
+	}
+	return nil
+}
+
+// parseSizeFilters returns the size bounds in bytes, 0 for no bound
+func parseSizeFilters(minSize, maxSize string) (int64, int64, error) {
+	var minBytes, maxBytes int64
+	var err error
+	if minSize != "" {
+		if minBytes, err = utils.ParseSize(minSize); err != nil {
+			return 0, 0, fmt.Errorf("invalid --min-size: %w", err)
+		}
+	}
+	if maxSize != "" {
+		if maxBytes, err = utils.ParseSize(maxSize); err != nil {
+			return 0, 0, fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+	return minBytes, maxBytes, nil
+}
+
+// FilterVolumesBySize removes volumes smaller than minSize or larger than maxSize bytes, 0 for no
+// bound. Volumes whose size could not be measured are kept, since nothing is known against them.
+// Returns the remaining volumes and the names of the skipped ones.
+func FilterVolumesBySize(volumes []docker.VolumeInfo, minSize, maxSize int64) ([]docker.VolumeInfo, []string) {
+	if minSize == 0 && maxSize == 0 {
+		return volumes, nil
+	}
+
+	var kept []docker.VolumeInfo
+	var skipped []string
+	for _, v := range volumes {
+		unknown := v.SizeBytes == 0 && v.Size == "Unknown"
+		if !unknown && (v.SizeBytes < minSize || maxSize > 0 && v.SizeBytes > maxSize) {
+			skipped = append(skipped, v.Name)
+			continue
+		}
+		kept = append(kept, v)
+	}
+	return kept, skipped
+}
+
+// findUnmigratedMounts records the bind and tmpfs mounts of the containers, whose data stays
+// behind. Failing to list them only costs the warning, so the run goes on.
+func (m *Migrator) findUnmigratedMounts() {
+	mounts, err := m.dockerClient.GetUnmigratedMounts(m.config.Containers)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list bind and tmpfs mounts")
+		return
+	}
+	m.unmigrated = mounts
+	for _, mount := range mounts {
+		log.WithFields(unmigratedFields(mount)).Warn("Not migrating non-volume mount")
+	}
+}
+
+// reportUnmigratedMounts repeats at the end of a run which mounts were not migrated
+func (m *Migrator) reportUnmigratedMounts() {
+	for _, mount := range m.unmigrated {
+		log.WithFields(unmigratedFields(mount)).Warn("Not migrated (bind/tmpfs): copy this data separately if the application needs it")
+	}
+}
+
+// unmigratedFields are the log fields describing a mount left behind
+func unmigratedFields(mount docker.UnmigratedMount) logrus.Fields {
+	fields := logrus.Fields{
+		"container":  mount.Container,
+		"type":       mount.Type,
+		"mount_path": mount.Destination,
+	}
+	if mount.Source != "" {
+		fields["source"] = mount.Source
+	}
+	return fields
+}
@@ -0,0 +1,200 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestValidateExcludePatterns(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  []string
+		expectErr bool
+	}{
+		{"no patterns", nil, false},
+		{"exact name", []string{"media_cache"}, false},
+		{"glob patterns", []string{"*_cache", "app-?", "db[0-9]"}, false},
+		{"empty pattern", []string{""}, true},
+		{"malformed pattern", []string{"db[0-9"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExcludePatterns(tt.patterns)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateExcludePatterns(%v) error = %v, expectErr %v", tt.patterns, err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestFilterExcludedVolumes(t *testing.T) {
+	volumes := []docker.VolumeInfo{
+		{Name: "app_data"},
+		{Name: "media_cache"},
+		{Name: "thumb_cache"},
+		{Name: "db1"},
+	}
+
+	tests := []struct {
+		name         string
+		patterns     []string
+		wantKept     []string
+		wantExcluded []string
+	}{
+		{
+			name:     "no patterns keeps everything",
+			patterns: nil,
+			wantKept: []string{"app_data", "media_cache", "thumb_cache", "db1"},
+		},
+		{
+			name:         "exact name",
+			patterns:     []string{"media_cache"},
+			wantKept:     []string{"app_data", "thumb_cache", "db1"},
+			wantExcluded: []string{"media_cache"},
+		},
+		{
+			name:         "glob",
+			patterns:     []string{"*_cache"},
+			wantKept:     []string{"app_data", "db1"},
+			wantExcluded: []string{"media_cache", "thumb_cache"},
+		},
+		{
+			name:         "multiple patterns",
+			patterns:     []string{"db?", "app_*"},
+			wantKept:     []string{"media_cache", "thumb_cache"},
+			wantExcluded: []string{"app_data", "db1"},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"missing"},
+			wantKept: []string{"app_data", "media_cache", "thumb_cache", "db1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, excluded := FilterExcludedVolumes(volumes, tt.patterns)
+
+			var keptNames []string
+			for _, v := range kept {
+				keptNames = append(keptNames, v.Name)
+			}
+			if !reflect.DeepEqual(keptNames, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", keptNames, tt.wantKept)
+			}
+			if !reflect.DeepEqual(excluded, tt.wantExcluded) {
+				t.Errorf("excluded = %v, want %v", excluded, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestValidateSizeFilters(t *testing.T) {
+	tests := []struct {
+		minSize string
+		maxSize string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"1MB", "", false},
+		{"", "50GB", false},
+		{"1MB", "50GB", false},
+		{"1GB", "1GB", false},
+		{"2GB", "1GB", true},
+		{"lots", "", true},
+		{"", "-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.minSize+"-"+tt.maxSize, func(t *testing.T) {
+			if err := ValidateSizeFilters(tt.minSize, tt.maxSize); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSizeFilters(%q, %q) error = %v, wantErr %v", tt.minSize, tt.maxSize, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterVolumesBySize(t *testing.T) {
+	volumes := []docker.VolumeInfo{
+		{Name: "empty", Size: "0B"},
+		{Name: "small", SizeBytes: 512 << 10},
+		{Name: "medium", SizeBytes: 1 << 30},
+		{Name: "large", SizeBytes: 100 << 30},
+		{Name: "unmeasured", Size: "Unknown"},
+	}
+
+	tests := []struct {
+		name        string
+		minSize     int64
+		maxSize     int64
+		wantKept    []string
+		wantSkipped []string
+	}{
+		{
+			name:     "no bounds keeps everything",
+			wantKept: []string{"empty", "small", "medium", "large", "unmeasured"},
+		},
+		{
+			name:        "minimum",
+			minSize:     1 << 20,
+			wantKept:    []string{"medium", "large", "unmeasured"},
+			wantSkipped: []string{"empty", "small"},
+		},
+		{
+			name:        "maximum",
+			maxSize:     50 << 30,
+			wantKept:    []string{"empty", "small", "medium", "unmeasured"},
+			wantSkipped: []string{"large"},
+		},
+		{
+			name:        "inclusive range",
+			minSize:     512 << 10,
+			maxSize:     1 << 30,
+			wantKept:    []string{"small", "medium", "unmeasured"},
+			wantSkipped: []string{"empty", "large"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := FilterVolumesBySize(volumes, tt.minSize, tt.maxSize)
+
+			var keptNames []string
+			for _, v := range kept {
+				keptNames = append(keptNames, v.Name)
+			}
+			if !reflect.DeepEqual(keptNames, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", keptNames, tt.wantKept)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestDiscoverVolumes_UnmigratedMounts(t *testing.T) {
+	mounts := []docker.UnmigratedMount{
+		{Container: "web", Type: "bind", Source: "/srv/web/config", Destination: "/etc/nginx"},
+		{Container: "web", Type: "tmpfs", Destination: "/run"},
+	}
+
+	for _, byVolume := range []bool{false, true} {
+		dockerClient := &fakeDocker{mounts: mounts}
+		m := &Migrator{config: &Config{Containers: []string{"web"}, ByVolume: byVolume}, dockerClient: dockerClient}
+		if _, err := m.discoverVolumes(); err != nil {
+			t.Fatalf("discoverVolumes() error = %v", err)
+		}
+
+		want := mounts
+		if byVolume {
+			want = nil // No container is inspected with --by-volume
+		}
+		if !reflect.DeepEqual(m.unmigrated, want) {
+			t.Errorf("byVolume=%v: unmigrated = %v, want %v", byVolume, m.unmigrated, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package migrator
+
+import (
+	"fmt"
+
+	"volume-migrator/internal/shell"
+)
+
+// Archive formats selected with --format
+const (
+	FormatTar      = "tar"      // Uncompressed tar, for links faster than the CPU can compress
+	FormatTarGz    = "tar.gz"   // Tar compressed with gzip on the host (default)
+	FormatTarZst   = "tar.zst"  // Tar compressed with zstd in the helper container
+	FormatSquashfs = "squashfs" // Squashfs image built with mksquashfs, extracted with unsquashfs
+)
+
+// MaxZstdLevel is the highest compression level accepted with tar.zst; zstd needs --ultra above it
+const MaxZstdLevel = 19
+
+// ValidateArchiveFormat checks that format is empty (tar.gz) or a supported archive format
+func ValidateArchiveFormat(format string) error {
+	switch format {
+	case "", FormatTar, FormatTarGz, FormatTarZst, FormatSquashfs:
+		return nil
+	}
+	return fmt.Errorf("invalid archive format '%s': must be one of tar, tar.gz, tar.zst, squashfs", format)
+}
+
+// archiveFormat returns format, or the default tar.gz when none is set
+func archiveFormat(format string) string {
+	if format == "" {
+		return FormatTarGz
+	}
+	return format
+}
+
+// archiveFileName returns the name of the archive of a volume in the given format
+func archiveFileName(volumeName, format string) string {
+	return volumeName + "." + archiveFormat(format)
+}
+
+// isTarFormat reports whether the archive is a tar stream the host writes and rewrites itself
+func isTarFormat(format string) bool {
+	format = archiveFormat(format)
+	return format == FormatTar || format == FormatTarGz
+}
+
+// formatTools returns the commands beyond tar the helper image needs locally and remotely for format
+func formatTools(format string) (local, remote string) {
+	switch format {
+	case FormatTarZst:
+		return "zstd", "zstd"
+	case FormatSquashfs:
+		return "mksquashfs", "unsquashfs"
+	}
+	return "", ""
+}
+
+// buildToolProbe returns the shell snippet checking that the helper image provides tool
+func buildToolProbe(tool string) string {
+	return "command -v " + shell.ShellEscape(tool)
+}
+
+// compressionLevel returns the level passed to zstd and mksquashfs, 0 selecting DefaultCompressionLevel
+// as it does for gzip, so a config file without a level compresses like the --compression-level default
+func compressionLevel(level int) int {
+	if level == 0 {
+		return DefaultCompressionLevel
+	}
+	return level
+}
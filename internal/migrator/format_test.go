@@ -0,0 +1,34 @@
+package migrator
+
+import "testing"
+
+func TestValidateArchiveFormat(t *testing.T) {
+	for _, format := range []string{"", FormatTar, FormatTarGz, FormatTarZst, FormatSquashfs} {
+		if err := ValidateArchiveFormat(format); err != nil {
+			t.Errorf("ValidateArchiveFormat(%q) unexpected error: %v", format, err)
+		}
+	}
+	for _, format := range []string{"zip", "tgz", "TAR"} {
+		if err := ValidateArchiveFormat(format); err == nil {
+			t.Errorf("ValidateArchiveFormat(%q) expected an error", format)
+		}
+	}
+}
+
+func TestArchiveFileName(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "data.tar.gz"},
+		{FormatTar, "data.tar"},
+		{FormatTarZst, "data.tar.zst"},
+		{FormatSquashfs, "data.squashfs"},
+	}
+
+	for _, tt := range tests {
+		if got := archiveFileName("data", tt.format); got != tt.want {
+			t.Errorf("archiveFileName(%q) = %s, want %s", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package migrator
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/shell"
+)
+
+// DefaultHelperImage is the image used to access volume data when no helper image is configured.
+// It is pinned by digest (alpine 3.19.0, multi-arch index) so every run uses the same image.
+const DefaultHelperImage = "alpine@sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b"
+
+// DefaultGNUHelperImage is used instead of DefaultHelperImage when GNU tar features are requested
+// (busybox tar in alpine cannot preserve xattrs, ACLs or sparse files). It is pinned by digest
+// too (ubuntu 24.04, multi-arch index).
+const DefaultGNUHelperImage = "ubuntu@sha256:3f85b7caad41a95462cf5b787d8a04604c8262cdcdf9a472b8c52ef83375fe15"
+
+// dockerHubNamespace is the repository prefix of official images when they are pulled from a mirror
+const dockerHubNamespace = "library/"
+
+// helperImageIdentityFormat is the image inspect template printing the image ID and its repo digests
+const helperImageIdentityFormat = `{{.Id}}|{{join .RepoDigests ","}}`
+
+// tarProbeCommand checks that the helper image ships a tar binary
+const tarProbeCommand = "command -v tar"
+
+// gnuTarProbeCommand checks that the helper image ships GNU tar
+const gnuTarProbeCommand = "tar --version 2>/dev/null | grep -q 'GNU tar'"
+
+// ValidateHelperImageReference checks that a configured helper image reference is safe to pass to docker
+func ValidateHelperImageReference(image string) error {
+	if image == "" {
+		return nil
+	}
+	if !shell.ValidateImageReference(image) {
+		return fmt.Errorf("invalid helper image '%s': must be a valid image reference (registry/name:tag or name@digest)", image)
+	}
+	return nil
+}
+
+// ValidateHelperRegistry checks a registry mirror given as host[:port][/path]
+func ValidateHelperRegistry(registry string) error {
+	if registry == "" {
+		return nil
+	}
+	if !shell.ValidateImageReference(registry) || strings.Contains(registry, "@") {
+		return fmt.Errorf("invalid helper registry '%s': must be host[:port][/path]", registry)
+	}
+	return nil
+}
+
+// resolveHelperImage returns the configured image, or the appropriate default when none is set.
+// Defaults are pulled from registry (a Docker Hub mirror) when one is given; configured images are used as is.
+func resolveHelperImage(image, registry string, gnuTar bool) string {
+	if image != "" {
+		return image
+	}
+	image = DefaultHelperImage
+	if gnuTar {
+		image = DefaultGNUHelperImage
+	}
+	if registry != "" {
+		image = strings.TrimSuffix(registry, "/") + "/" + dockerHubNamespace + image
+	}
+	return image
+}
+
+// HelperImageIdentity identifies the helper image a run used, for the migration manifest
+type HelperImageIdentity struct {
+	Reference   string   `json:"reference"`
+	LocalID     string   `json:"local_id"`
+	RemoteID    string   `json:"remote_id"`
+	RepoDigests []string `json:"repo_digests,omitempty"` // Registry digests of the local image
+}
+
+// parseImageIdentity parses the output of image inspect with helperImageIdentityFormat
+func parseImageIdentity(output string) (id string, digests []string) {
+	id, list, _ := strings.Cut(strings.TrimSpace(output), "|")
+	if list != "" {
+		digests = strings.Split(list, ",")
+	}
+	return id, digests
+}
+
+// helperImageIdentity inspects the helper image on both hosts
+func (m *Migrator) helperImageIdentity(image string) (HelperImageIdentity, error) {
+	identity := HelperImageIdentity{Reference: image}
+	output, err := m.dockerClient.ExecCommand("image", "inspect", "--format", helperImageIdentityFormat, image)
+	if err != nil {
+		return identity, fmt.Errorf("failed to inspect helper image %s locally: %w", image, err)
+	}
+	identity.LocalID, identity.RepoDigests = parseImageIdentity(output)
+
+	output, err = m.sshClient.RunDockerCommand(fmt.Sprintf("image inspect --format %s %s",
+		shell.ShellEscape(helperImageIdentityFormat), shell.ShellEscape(image)))
+	if err != nil {
+		return identity, fmt.Errorf("failed to inspect helper image %s on remote host: %w", image, err)
+	}
+	identity.RemoteID, _ = parseImageIdentity(output)
+	return identity, nil
+}
+
+// tarProbe returns the shell snippet used to check the helper image's tar implementation
+func tarProbe(gnuTar bool) (probe, requirement string) {
+	if gnuTar {
+		return gnuTarProbeCommand, "GNU tar"
+	}
+	return tarProbeCommand, "tar"
+}
+
+// CheckLocalHelperImage verifies that the helper image can be run locally and provides tar
+// When gnuTar is set the image must provide GNU tar rather than busybox tar
+func CheckLocalHelperImage(dockerClient DockerRunner, image string, gnuTar bool) error {
+	probe, requirement := tarProbe(gnuTar)
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "--rm", "--entrypoint", "sh", image, "-c", probe}
+	if err := dockerClient.ExecCommandWithOutput(&stdout, &stderr, args...); err != nil {
+		return fmt.Errorf("helper image %s does not provide %s locally: %w, stderr: %s", image, requirement, err, stderr.String())
+	}
+	return nil
+}
+
+// CheckRemoteHelperImage verifies that the helper image can be run on the remote host and provides tar
+// When gnuTar is set the image must provide GNU tar rather than busybox tar
+func CheckRemoteHelperImage(sshClient RemoteExecutor, image string, gnuTar bool) error {
+	probe, requirement := tarProbe(gnuTar)
+	cmd := fmt.Sprintf("run --rm --entrypoint sh %s -c %s", shell.ShellEscape(image), shell.ShellEscape(probe))
+	if _, err := sshClient.RunDockerCommand(cmd); err != nil {
+		return fmt.Errorf("helper image %s does not provide %s on remote host: %w", image, requirement, err)
+	}
+	return nil
+}
+
+// CheckHelperFormatTools verifies that the helper image provides the tools the archive format needs
+// beyond tar: the packing one locally and the unpacking one on the remote host
+func CheckHelperFormatTools(dockerClient DockerRunner, sshClient RemoteExecutor, image, format string) error {
+	local, remote := formatTools(format)
+	if local == "" {
+		return nil
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "--rm", "--entrypoint", "sh", image, "-c", buildToolProbe(local)}
+	if err := dockerClient.ExecCommandWithOutput(&stdout, &stderr, args...); err != nil {
+		return fmt.Errorf("helper image %s does not provide %s locally, which --format %s needs: %w", image, local, format, err)
+	}
+	cmd := fmt.Sprintf("run --rm --entrypoint sh %s -c %s", shell.ShellEscape(image), shell.ShellEscape(buildToolProbe(remote)))
+	if _, err := sshClient.RunDockerCommand(cmd); err != nil {
+		return fmt.Errorf("helper image %s does not provide %s on remote host, which --format %s needs: %w", image, remote, format, err)
+	}
+	return nil
+}
+
+// EnsureLocalHelperImage makes sure the helper image is present in the local image store.
+// Missing images are loaded from bundlePath when given (offline hosts), otherwise pulled.
+func EnsureLocalHelperImage(dockerClient DockerRunner, image, bundlePath string) error {
+	if _, err := dockerClient.ExecCommand("image", "inspect", image); err == nil {
+		log.WithField("helper_image", image).Debug("Helper image present locally")
+		return nil
+	}
+
+	if bundlePath != "" {
+		log.WithFields(logrus.Fields{
+			"helper_image": image,
+			"bundle":       bundlePath,
+		}).Info("Loading helper image bundle locally")
+		if _, err := dockerClient.ExecCommand("load", "-i", bundlePath); err != nil {
+			return fmt.Errorf("failed to load helper image bundle %s locally: %w", bundlePath, err)
+		}
+		if _, err := dockerClient.ExecCommand("image", "inspect", image); err != nil {
+			return fmt.Errorf("helper image bundle %s does not contain image %s", bundlePath, image)
+		}
+		return nil
+	}
+
+	log.WithField("helper_image", image).Info("Pulling helper image locally")
+	if _, err := dockerClient.ExecCommand("pull", image); err != nil {
+		return fmt.Errorf("failed to pull helper image %s locally (use --helper-image-tar on offline hosts): %w", image, err)
+	}
+	return nil
+}
+
+// EnsureRemoteHelperImage makes sure the helper image is present on the remote host.
+// Missing images are loaded from bundlePath (uploaded to remoteTempDir first) when given, otherwise pulled.
+func EnsureRemoteHelperImage(sshClient RemoteExecutor, image, bundlePath, remoteTempDir string, showProgress bool) error {
+	escapedImage := shell.ShellEscape(image)
+	if _, err := sshClient.RunDockerCommand("image inspect " + escapedImage); err == nil {
+		log.WithField("helper_image", image).Debug("Helper image present on remote host")
+		return nil
+	}
+
+	if bundlePath != "" {
+		remoteBundle := path.Join(remoteTempDir, filepath.Base(bundlePath))
+		log.WithFields(logrus.Fields{
+			"helper_image": image,
+			"bundle":       remoteBundle,
+		}).Info("Uploading helper image bundle to remote host")
+
+		if err := sshClient.CreateDirectory(remoteTempDir); err != nil {
+			return fmt.Errorf("failed to create remote temp directory: %w", err)
+		}
+		if err := sshClient.TransferFile(bundlePath, remoteBundle, showProgress); err != nil {
+			return fmt.Errorf("failed to upload helper image bundle: %w", err)
+		}
+		defer func() {
+			if err := sshClient.RemoveFile(remoteBundle); err != nil {
+				log.WithError(err).Warn("Failed to remove helper image bundle from remote host")
+			}
+		}()
+
+		if _, err := sshClient.RunDockerCommand("load -i " + shell.ShellEscape(remoteBundle)); err != nil {
+			return fmt.Errorf("failed to load helper image bundle on remote host: %w", err)
+		}
+		if _, err := sshClient.RunDockerCommand("image inspect " + escapedImage); err != nil {
+			return fmt.Errorf("helper image bundle %s does not contain image %s", bundlePath, image)
+		}
+		return nil
+	}
+
+	log.WithField("helper_image", image).Info("Pulling helper image on remote host")
+	if _, err := sshClient.RunDockerCommand("pull " + escapedImage); err != nil {
+		return fmt.Errorf("failed to pull helper image %s on remote host (use --helper-image-tar on offline hosts): %w", image, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveHelperImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		registry string
+		gnuTar   bool
+		want     string
+	}{
+		{name: "pinned default", want: DefaultHelperImage},
+		{name: "GNU default", gnuTar: true, want: DefaultGNUHelperImage},
+		{name: "mirror", registry: "mirror.gcr.io", want: "mirror.gcr.io/library/" + DefaultHelperImage},
+		{name: "mirror with path", registry: "registry.internal:5000/dockerhub/", gnuTar: true, want: "registry.internal:5000/dockerhub/library/" + DefaultGNUHelperImage},
+		{name: "configured image ignores mirror", image: "my/tar:1", registry: "mirror.gcr.io", want: "my/tar:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveHelperImage(tt.image, tt.registry, tt.gnuTar); got != tt.want {
+				t.Errorf("resolveHelperImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultHelperImagePinned(t *testing.T) {
+	for name, image := range map[string]string{"DefaultHelperImage": DefaultHelperImage, "DefaultGNUHelperImage": DefaultGNUHelperImage} {
+		if !strings.Contains(image, "@sha256:") {
+			t.Errorf("%s = %q, want a digest reference", name, image)
+		}
+		if err := ValidateHelperImageReference(image); err != nil {
+			t.Errorf("%s is not a valid reference: %v", name, err)
+		}
+	}
+}
+
+func TestValidateHelperRegistry(t *testing.T) {
+	for _, registry := range []string{"", "mirror.gcr.io", "registry.internal:5000/dockerhub"} {
+		if err := ValidateHelperRegistry(registry); err != nil {
+			t.Errorf("ValidateHelperRegistry(%q) error = %v", registry, err)
+		}
+	}
+	for _, registry := range []string{"-mirror", "mirror;rm -rf /", "mirror@sha256"} {
+		if err := ValidateHelperRegistry(registry); err == nil {
+			t.Errorf("ValidateHelperRegistry(%q) expected error", registry)
+		}
+	}
+}
+
+func TestParseImageIdentity(t *testing.T) {
+	id, digests := parseImageIdentity("sha256:abc|alpine@sha256:111,mirror.gcr.io/library/alpine@sha256:111\n")
+	if id != "sha256:abc" || len(digests) != 2 || digests[1] != "mirror.gcr.io/library/alpine@sha256:111" {
+		t.Errorf("parseImageIdentity() = %q, %v", id, digests)
+	}
+
+	// Images loaded with docker load have no repo digests
+	id, digests = parseImageIdentity("sha256:def|")
+	if id != "sha256:def" || digests != nil {
+		t.Errorf("parseImageIdentity() without digests = %q, %v", id, digests)
+	}
+}
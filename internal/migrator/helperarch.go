@@ -0,0 +1,174 @@
+package migrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/shell"
+)
+
+// helperImagePlatformFormat is the image inspect template printing the platform an image is built for
+const helperImagePlatformFormat = "{{.Os}}/{{.Architecture}}"
+
+// platformArchitectures maps the uname names docker info reports to the architectures of image platforms
+var platformArchitectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm/v7",
+	"armv6l":  "arm/v6",
+	"i686":    "386",
+	"i386":    "386",
+}
+
+// hostPlatform returns the image platform matching a host architecture as EnvironmentInfo holds it
+func hostPlatform(arch string) string {
+	if platform, ok := platformArchitectures[arch]; ok {
+		return "linux/" + platform
+	}
+	return "linux/" + arch
+}
+
+// imageMatchesHost reports whether an image of platform, as printed by helperImagePlatformFormat,
+// runs on a host of arch. ARM variants are not printed by the template, so any ARM image matches
+// an ARM host.
+func imageMatchesHost(platform, arch string) bool {
+	_, imageArch, _ := strings.Cut(platform, "/")
+	want, _, _ := strings.Cut(strings.TrimPrefix(hostPlatform(arch), "linux/"), "/")
+	return imageArch == want
+}
+
+// helperHost is one end of a migration as ensureHelperArchitecture sees it
+type helperHost struct {
+	side     string
+	arch     string
+	platform func() (string, error)      // Platform of the helper image on the host
+	pull     func(platform string) error // Pulls the helper image for platform
+}
+
+// ensureHelperArchitecture makes sure the helper image on each host is built for that host when
+// their architectures differ. An image pulled or loaded for the other host would only fail with
+// exec format errors once an import runs, so a mismatched one is pulled again for the host's
+// platform. Images loaded from --helper-image-tar cannot be.
+func (m *Migrator) ensureHelperArchitecture(image string) error {
+	if m.localEnv.Architecture == m.remoteEnv.Architecture {
+		return nil
+	}
+	escapedImage := shell.ShellEscape(image)
+	hosts := []helperHost{
+		{
+			side: "local",
+			arch: m.localEnv.Architecture,
+			platform: func() (string, error) {
+				return m.dockerClient.ExecCommand("image", "inspect", "--format", helperImagePlatformFormat, image)
+			},
+			pull: func(platform string) error {
+				_, err := m.dockerClient.ExecCommand("pull", "--platform", platform, image)
+				return err
+			},
+		},
+		{
+			side: "remote",
+			arch: m.remoteEnv.Architecture,
+			platform: func() (string, error) {
+				return m.sshClient.RunDockerCommand(fmt.Sprintf("image inspect --format %s %s", shell.ShellEscape(helperImagePlatformFormat), escapedImage))
+			},
+			pull: func(platform string) error {
+				_, err := m.sshClient.RunDockerCommand(fmt.Sprintf("pull --platform %s %s", shell.ShellEscape(platform), escapedImage))
+				return err
+			},
+		},
+	}
+
+	for _, host := range hosts {
+		output, err := host.platform()
+		if err != nil {
+			log.WithError(err).WithField("host", host.side).Debug("Could not read the platform of the helper image")
+			continue
+		}
+		// Output that is not a platform tells nothing about the image
+		platform := strings.TrimSpace(output)
+		if !strings.Contains(platform, "/") || imageMatchesHost(platform, host.arch) {
+			continue
+		}
+		if m.config.HelperImageTar != "" {
+			return fmt.Errorf("helper image %s loaded from --helper-image-tar is built for %s, but the %s host is %s", image, platform, host.side, host.arch)
+		}
+
+		want := hostPlatform(host.arch)
+		log.WithFields(logrus.Fields{
+			"helper_image": image,
+			"host":         host.side,
+			"found":        platform,
+			"platform":     want,
+		}).Info("Helper image is built for another architecture, pulling it for the host's platform")
+		if err := host.pull(want); err != nil {
+			return fmt.Errorf("helper image %s is built for %s, but the %s host is %s, and no %s variant could be pulled (use a multi-arch image): %w",
+				image, platform, host.side, host.arch, want, err)
+		}
+		if output, err := host.platform(); err == nil && !imageMatchesHost(strings.TrimSpace(output), host.arch) {
+			return fmt.Errorf("helper image %s has no %s variant for the %s host (use a multi-arch image)", image, want, host.side)
+		}
+	}
+	return nil
+}
+
+// imageIndex is the part of docker manifest inspect output listing the platforms of a multi-arch image
+type imageIndex struct {
+	Manifests []struct {
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// missingPlatforms returns the architectures of arches that a docker manifest inspect output has
+// no variant for. A single-platform manifest has none.
+func missingPlatforms(output string, arches ...string) ([]string, error) {
+	var index imageIndex
+	if err := json.Unmarshal([]byte(output), &index); err != nil {
+		return nil, fmt.Errorf("failed to parse image manifest: %w", err)
+	}
+	var missing []string
+	for _, arch := range arches {
+		found := false
+		for _, manifest := range index.Manifests {
+			platform := manifest.Platform.OS + "/" + manifest.Platform.Architecture
+			if manifest.Platform.OS == "linux" && imageMatchesHost(platform, arch) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, arch)
+		}
+	}
+	return missing, nil
+}
+
+// warnSingleArchHelper warns when a custom helper image has no variant for one of the hosts, whose
+// architectures differ. The registry is asked for the image's manifest; a registry that cannot be
+// reached is not reported.
+func (m *Migrator) warnSingleArchHelper(image string) {
+	if m.config.HelperImage == "" || m.localEnv.Architecture == m.remoteEnv.Architecture {
+		return
+	}
+	output, err := m.dockerClient.ExecCommand("manifest", "inspect", image)
+	if err != nil {
+		log.WithError(err).WithField("helper_image", image).Debug("Could not inspect the helper image manifest")
+		return
+	}
+	missing, err := missingPlatforms(output, m.localEnv.Architecture, m.remoteEnv.Architecture)
+	if err != nil {
+		log.WithError(err).WithField("helper_image", image).Debug("Could not inspect the helper image manifest")
+		return
+	}
+	if len(missing) > 0 {
+		log.WithFields(logrus.Fields{
+			"helper_image": image,
+			"missing":      strings.Join(missing, ", "),
+		}).Warn("Custom helper image is not multi-arch: hosts of the missing architectures cannot run it")
+	}
+}
@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestImageMatchesHost(t *testing.T) {
+	tests := []struct {
+		platform string
+		arch     string
+		want     bool
+	}{
+		{"linux/amd64", "x86_64", true},
+		{"linux/arm64", "aarch64", true},
+		{"linux/amd64", "aarch64", false},
+		{"linux/arm64", "x86_64", false},
+		{"linux/arm", "armv7l", true},
+		{"linux/386", "i686", true},
+		{"linux/s390x", "s390x", true},
+	}
+	for _, tt := range tests {
+		if got := imageMatchesHost(tt.platform, tt.arch); got != tt.want {
+			t.Errorf("imageMatchesHost(%q, %q) = %v, want %v", tt.platform, tt.arch, got, tt.want)
+		}
+	}
+	if got := hostPlatform("aarch64"); got != "linux/arm64" {
+		t.Errorf("hostPlatform(aarch64) = %q, want linux/arm64", got)
+	}
+}
+
+func TestMissingPlatforms(t *testing.T) {
+	index := `{"manifests": [
+		{"platform": {"architecture": "amd64", "os": "linux"}},
+		{"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+		{"platform": {"architecture": "unknown", "os": "unknown"}}
+	]}`
+	single := `{"schemaVersion": 2, "config": {"mediaType": "application/vnd.docker.container.image.v1+json"}}`
+
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"multi-arch", index, nil},
+		{"single platform", single, []string{"x86_64", "aarch64"}},
+	}
+	for _, tt := range tests {
+		got, err := missingPlatforms(tt.output, "x86_64", "aarch64")
+		if err != nil {
+			t.Fatalf("%s: missingPlatforms() error = %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: missingPlatforms() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := missingPlatforms("not json", "x86_64"); err == nil {
+		t.Error("missingPlatforms() of invalid output succeeded, want error")
+	}
+}
+
+func TestEnsureHelperArchitecture(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteArch string
+		remote     string // Platform of the remote image
+		pullErr    error
+		bundle     string
+		wantErr    string
+		wantPull   bool
+	}{
+		{"same architecture", "x86_64", "linux/amd64", nil, "", "", false},
+		{"matching variants", "aarch64", "linux/arm64", nil, "", "", false},
+		{"pull fails", "aarch64", "linux/amd64", errors.New("no matching manifest"), "", "no linux/arm64 variant could be pulled", true},
+		{"pulled variant still mismatched", "aarch64", "linux/amd64", nil, "", "has no linux/arm64 variant", true},
+		{"bundle for the other host", "aarch64", "linux/amd64", nil, "helper.tar", "--helper-image-tar", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &fakeDocker{responses: map[string]fakeResponse{"image inspect": {output: "linux/amd64\n"}}}
+			remote := &fakeRemote{responses: map[string]fakeResponse{
+				"image inspect": {output: tt.remote + "\n"},
+				"pull":          {err: tt.pullErr},
+			}}
+			m := &Migrator{config: &Config{HelperImageTar: tt.bundle}, dockerClient: local, sshClient: remote}
+			m.localEnv.Architecture, m.remoteEnv.Architecture = "x86_64", tt.remoteArch
+
+			err := m.ensureHelperArchitecture("alpine:3.19")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("ensureHelperArchitecture() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ensureHelperArchitecture() = %v, want error containing %q", err, tt.wantErr)
+			}
+			pulled := slices.ContainsFunc(remote.commands, func(cmd string) bool { return strings.HasPrefix(cmd, "pull --platform linux/arm64 ") })
+			if pulled != tt.wantPull {
+				t.Errorf("pulled for linux/arm64 = %v, want %v (commands %q)", pulled, tt.wantPull, remote.commands)
+			}
+		})
+	}
+}
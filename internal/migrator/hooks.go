@@ -0,0 +1,79 @@
+package migrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+)
+
+// VolumeSettings is the entry of one volume in the volumes section of the config file
+type VolumeSettings struct {
+	// PreExport is a shell command run inside the container owning the volume right before it is
+	// exported, such as redis-cli SAVE, so the application leaves its data consistent on disk
+	PreExport string `json:"pre_export"`
+}
+
+// readConfigFile parses the config file at path; a missing file reads as empty
+func readConfigFile(path string) (configFile, error) {
+	var config configFile
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// LoadPreExportHooks returns the pre-export commands of the volumes section of the config file
+// at path, keyed by source volume name. A missing config file has none.
+func LoadPreExportHooks(path string) (map[string]string, error) {
+	config, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hooks map[string]string
+	for name, settings := range config.Volumes {
+		if strings.TrimSpace(settings.PreExport) == "" {
+			continue
+		}
+		if hooks == nil {
+			hooks = make(map[string]string)
+		}
+		hooks[name] = settings.PreExport
+	}
+	return hooks, nil
+}
+
+// runPreExportHook runs the configured pre-export command of a volume, if any, with sh -c inside
+// the container that owns it. A failing command fails the volume, as its archive could be inconsistent.
+func (m *Migrator) runPreExportHook(v docker.VolumeInfo) error {
+	command, ok := m.config.PreExportHooks[v.Name]
+	if !ok {
+		return nil
+	}
+	if v.Container == "" {
+		return fmt.Errorf("pre-export hook of volume %s needs a container mounting it", v.Name)
+	}
+
+	fields := log.WithFields(logrus.Fields{"volume": v.Name, "container": v.Container})
+	fields.WithField("command", command).Info("Running pre-export hook")
+	var stdout, stderr bytes.Buffer
+	if err := m.dockerClient.ExecCommandWithOutput(&stdout, &stderr, "exec", v.Container, "sh", "-c", command); err != nil {
+		return fmt.Errorf("pre-export hook failed in container %s: %w, stderr: %s", v.Container, err, stderr.String())
+	}
+	if output := strings.TrimSpace(stdout.String()); output != "" {
+		fields.WithField("output", output).Debug("Pre-export hook output")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestLoadPreExportHooks(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "config.json")
+	content := `{"volumes": {"redis_data": {"pre_export": "redis-cli SAVE"}, "app_data": {"pre_export": " "}, "web_data": {}}}`
+	if err := os.WriteFile(valid, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"volumes": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"missing config file", filepath.Join(dir, "missing.json"), nil, false},
+		{"volumes with and without hooks", valid, map[string]string{"redis_data": "redis-cli SAVE"}, false},
+		{"invalid volumes section", invalid, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadPreExportHooks(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPreExportHooks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LoadPreExportHooks() = %v, want %v", got, tt.want)
+			}
+			for name, command := range tt.want {
+				if got[name] != command {
+					t.Errorf("LoadPreExportHooks()[%s] = %q, want %q", name, got[name], command)
+				}
+			}
+		})
+	}
+}
+
+func TestRunPreExportHook(t *testing.T) {
+	hooks := map[string]string{"redis_data": "redis-cli SAVE"}
+
+	tests := []struct {
+		name     string
+		volume   docker.VolumeInfo
+		err      error
+		wantCmds []string
+		wantErr  string
+	}{
+		{"volume without hook", docker.VolumeInfo{Name: "app_data", Container: "app"}, nil, nil, ""},
+		{"hook runs in owning container", docker.VolumeInfo{Name: "redis_data", Container: "cache"}, nil, []string{"exec cache sh -c redis-cli SAVE"}, ""},
+		{"failing hook", docker.VolumeInfo{Name: "redis_data", Container: "cache"}, errors.New("exit status 1"), []string{"exec cache sh -c redis-cli SAVE"}, "pre-export hook failed in container cache"},
+		{"volume without container", docker.VolumeInfo{Name: "redis_data"}, nil, nil, "needs a container"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dockerClient := &fakeDocker{responses: map[string]fakeResponse{"exec": {err: tt.err}}}
+			m := &Migrator{config: &Config{PreExportHooks: hooks}, dockerClient: dockerClient}
+
+			err := m.runPreExportHook(tt.volume)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runPreExportHook() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runPreExportHook() error = %v, want %q", err, tt.wantErr)
+			}
+			if strings.Join(dockerClient.commands, "\n") != strings.Join(tt.wantCmds, "\n") {
+				t.Errorf("commands = %q, want %q", dockerClient.commands, tt.wantCmds)
+			}
+		})
+	}
+}
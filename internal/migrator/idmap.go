@@ -0,0 +1,77 @@
+package migrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IDMapping remaps a numeric owner (UID or GID) found in a volume to another ID on the target host
+type IDMapping struct {
+	From int
+	To   int
+}
+
+// ParseIDMappings parses mappings in "from:to" form (e.g. "1000:2000")
+// kind is used in error messages ("uid" or "gid")
+func ParseIDMappings(kind string, specs []string) ([]IDMapping, error) {
+	var mappings []IDMapping
+	seen := make(map[int]bool)
+
+	for _, spec := range specs {
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s mapping '%s': must be in format 'from:to'", kind, spec)
+		}
+
+		from, err := parseID(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s mapping '%s': %w", kind, spec, err)
+		}
+		to, err := parseID(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s mapping '%s': %w", kind, spec, err)
+		}
+
+		if seen[from] {
+			return nil, fmt.Errorf("duplicate %s mapping for %d", kind, from)
+		}
+		seen[from] = true
+
+		mappings = append(mappings, IDMapping{From: from, To: to})
+	}
+
+	return mappings, nil
+}
+
+// parseID parses a non-negative numeric user or group ID
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a numeric id", s)
+	}
+	if id < 0 {
+		return 0, fmt.Errorf("id %d must not be negative", id)
+	}
+	return id, nil
+}
+
+// buildRemapScript returns shell commands that apply UID/GID mappings to everything under root.
+// All matching files are collected before any ownership is changed, so swapped mappings
+// (1000:1001 together with 1001:1000) are applied correctly.
+func buildRemapScript(root string, uidMap, gidMap []IDMapping) string {
+	var collect, apply []string
+
+	for i, m := range uidMap {
+		list := fmt.Sprintf("/tmp/uidmap-%d", i)
+		collect = append(collect, fmt.Sprintf("find %s -xdev -user %d -print0 > %s", root, m.From, list))
+		apply = append(apply, fmt.Sprintf("xargs -0 -r chown -h %d < %s", m.To, list))
+	}
+	for i, m := range gidMap {
+		list := fmt.Sprintf("/tmp/gidmap-%d", i)
+		collect = append(collect, fmt.Sprintf("find %s -xdev -group %d -print0 > %s", root, m.From, list))
+		apply = append(apply, fmt.Sprintf("xargs -0 -r chgrp -h %d < %s", m.To, list))
+	}
+
+	return strings.Join(append(collect, apply...), " && ")
+}
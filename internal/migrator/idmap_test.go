@@ -0,0 +1,95 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIDMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []IDMapping
+		wantErr bool
+	}{
+		{
+			name:  "no mappings",
+			specs: nil,
+			want:  nil,
+		},
+		{
+			name:  "single mapping",
+			specs: []string{"1000:2000"},
+			want:  []IDMapping{{From: 1000, To: 2000}},
+		},
+		{
+			name:  "multiple mappings with whitespace",
+			specs: []string{" 1000:2000", "33 : 82"},
+			want:  []IDMapping{{From: 1000, To: 2000}, {From: 33, To: 82}},
+		},
+		{
+			name:    "missing separator",
+			specs:   []string{"1000"},
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric id",
+			specs:   []string{"www-data:1000"},
+			wantErr: true,
+		},
+		{
+			name:    "negative id",
+			specs:   []string{"-1:1000"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate source id",
+			specs:   []string{"1000:2000", "1000:3000"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIDMappings("uid", tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIDMappings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseIDMappings() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("mapping %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildRemapScript_CollectsBeforeApplying(t *testing.T) {
+	// Swapped mappings must not chain (1000 -> 1001 -> 1000)
+	uidMap := []IDMapping{{From: 1000, To: 1001}, {From: 1001, To: 1000}}
+	gidMap := []IDMapping{{From: 50, To: 60}}
+
+	script := buildRemapScript("/data", uidMap, gidMap)
+
+	lastFind := strings.LastIndex(script, "find ")
+	firstChange := strings.Index(script, "xargs ")
+	if lastFind < 0 || firstChange < 0 || lastFind > firstChange {
+		t.Errorf("expected all find commands before any ownership change, got: %s", script)
+	}
+
+	for _, want := range []string{
+		"find /data -xdev -user 1000 -print0 > /tmp/uidmap-0",
+		"find /data -xdev -user 1001 -print0 > /tmp/uidmap-1",
+		"find /data -xdev -group 50 -print0 > /tmp/gidmap-0",
+		"xargs -0 -r chown -h 1001 < /tmp/uidmap-0",
+		"xargs -0 -r chown -h 1000 < /tmp/uidmap-1",
+		"xargs -0 -r chgrp -h 60 < /tmp/gidmap-0",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %q, got: %s", want, script)
+		}
+	}
+}
@@ -2,15 +2,81 @@ package migrator
 
 import (
 	"fmt"
-	"path/filepath"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"volume-migrator/internal/shell"
-	"volume-migrator/internal/ssh"
+	"volume-migrator/internal/utils"
 )
 
+// ImportOptions controls how archives are extracted on the remote machine
+type ImportOptions struct {
+	// HelperImage is the image used to run tar on the remote host (default: alpine)
+	HelperImage string
+	// Format is the archive format (see FormatTarGz and friends), empty meaning tar.gz
+	Format string
+	// ArchiveParts is the number of numbered parts the archive was split into, 0 for a single file
+	ArchiveParts int
+	// PreserveXattrs restores extended attributes from the archive (requires GNU tar)
+	PreserveXattrs bool
+	// PreserveACLs restores POSIX ACLs from the archive (requires GNU tar)
+	PreserveACLs bool
+	// NumericOwner restores numeric UIDs/GIDs instead of mapping owners by name
+	NumericOwner bool
+	// UIDMap and GIDMap remap file ownership after extraction
+	UIDMap []IDMapping
+	GIDMap []IDMapping
+	// Incremental extracts an incremental archive, deleting files that no longer exist at the source
+	// so the volume mirrors the exported generation (requires GNU tar)
+	Incremental bool
+	// DedupScript is the file name (next to the archive) of the script restoring deduplicated files,
+	// and DedupSources the volumes it copies from, mounted read-only at /src0, /src1, ...
+	DedupScript  string
+	DedupSources []string
+	// ShowProgress displays a progress bar while the archive is extracted
+	ShowProgress bool
+	// ExpectedSize is the volume size in bytes the progress bar is measured against (0 if unknown)
+	ExpectedSize int64
+	// Driver and DriverOpts recreate the source volume's driver and --opt settings (empty Driver means local)
+	Driver     string
+	DriverOpts map[string]string
+	// Labels are copied to the remote volume, so compose still recognizes it as its own
+	Labels map[string]string
+}
+
+// Import methods selected with --import-method
+const (
+	ImportMethodArchive = "archive" // Upload the archive to the remote temp dir, then extract it
+	ImportMethodStream  = "stream"  // Pipe the archive into tar over the SSH session, never storing it remotely
+	ImportMethodCopy    = "cp"      // Pipe the archive into docker cp on a paused container, running no tar
+)
+
+// ValidateImportMethod checks that method is empty (archive) or a supported import method
+func ValidateImportMethod(method string) error {
+	switch method {
+	case "", ImportMethodArchive, ImportMethodStream, ImportMethodCopy:
+		return nil
+	}
+	return fmt.Errorf("invalid import method '%s': must be one of archive, stream, cp", method)
+}
+
+// copyContainerPrefix names the paused containers the cp import method copies through
+const copyContainerPrefix = "volume-migrator-cp-"
+
+// importProgressInterval is how often the extracted size is polled on the remote host
+const importProgressInterval = 5 * time.Second
+
+// RequiresGNUTar reports whether the options use features only GNU tar provides
+func (o ImportOptions) RequiresGNUTar() bool {
+	return o.PreserveXattrs || o.PreserveACLs || o.Incremental
+}
+
 // ImportVolume imports a volume archive on the remote machine
 // Creates a Docker volume and populates it with data from the archive
-func ImportVolume(sshClient *ssh.Client, volumeName, archivePath string) error {
+func ImportVolume(sshClient RemoteExecutor, volumeName, archivePath string, opts ImportOptions) error {
 	// Validate volume name to prevent command injection
 	if !shell.ValidateVolumeName(volumeName) {
 		return fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volumeName)
@@ -18,30 +84,88 @@ func ImportVolume(sshClient *ssh.Client, volumeName, archivePath string) error {
 
 	log.WithField("volume", volumeName).Debug("Importing volume on remote host")
 
-	// Step 1: Create the volume on remote
-	createCmd := fmt.Sprintf("volume create %s", volumeName)
-	if _, err := sshClient.RunDockerCommand(createCmd); err != nil {
+	importCmd := buildImportCommand(volumeName, archivePath, opts)
+	return importInto(sshClient, volumeName, opts, func() error {
+		return runImport(sshClient, volumeName, importCmd, opts)
+	})
+}
+
+// StreamImportVolume imports a volume by piping the local archive into tar in a helper container over
+// the SSH session, so the archive never lands on the remote disk. remoteTempDir holds the dedup script, if any.
+func StreamImportVolume(sshClient RemoteExecutor, volumeName, localArchive, remoteTempDir string, opts ImportOptions) error {
+	if !shell.ValidateVolumeName(volumeName) {
+		return fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volumeName)
+	}
+
+	log.WithField("volume", volumeName).Debug("Streaming volume into remote host")
+
+	importCmd := buildStreamImportCommand(volumeName, remoteTempDir, opts)
+	return importInto(sshClient, volumeName, opts, func() error {
+		return sshClient.StreamFileToDocker(localArchive, opts.ShowProgress, importCmd)
+	})
+}
+
+// CopyImportVolume imports a volume without extracting in a container: the volume is attached to a
+// paused helper container and the local archive is piped into docker cp, which unpacks it in the daemon
+func CopyImportVolume(sshClient RemoteExecutor, volumeName, localArchive string, opts ImportOptions) error {
+	if !shell.ValidateVolumeName(volumeName) {
+		return fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volumeName)
+	}
+
+	log.WithField("volume", volumeName).Debug("Copying volume into remote host")
+
+	container := copyContainerPrefix + volumeName
+	return importInto(sshClient, volumeName, opts, func() error {
+		if _, err := sshClient.RunDockerCommand(buildCopyContainerCommand(container, volumeName, opts)); err != nil {
+			return fmt.Errorf("failed to start copy container: %w", err)
+		}
+		defer removeCopyContainer(sshClient, container)
+
+		if _, err := sshClient.RunDockerCommand("pause " + container); err != nil {
+			return fmt.Errorf("failed to pause copy container: %w", err)
+		}
+		return sshClient.StreamFileToDocker(localArchive, opts.ShowProgress, buildCopyCommand(container))
+	})
+}
+
+// buildCopyContainerCommand constructs the docker arguments starting the idle container holding the volume
+func buildCopyContainerCommand(container, volumeName string, opts ImportOptions) string {
+	return fmt.Sprintf("run -d --name %s -v %s:/data %s tail -f /dev/null",
+		container, volumeName, shell.ShellEscape(resolveHelperImage(opts.HelperImage, "", false)))
+}
+
+// buildCopyCommand constructs the docker arguments unpacking a tar or tar.gz archive from stdin into the
+// container's /data; --archive keeps the owners recorded in the archive instead of using root
+func buildCopyCommand(container string) string {
+	return fmt.Sprintf("cp --archive - %s:/data", container)
+}
+
+// removeCopyContainer unpauses and removes a copy container, leaving the volume in place
+func removeCopyContainer(sshClient RemoteExecutor, container string) {
+	sshClient.RunDockerCommand("unpause " + container)
+	if _, err := sshClient.RunDockerCommand("rm -f " + container); err != nil {
+		log.WithField("container", container).WithError(err).Warn("Failed to remove copy container")
+	}
+}
+
+// importInto creates the remote volume and runs extract to populate it.
+// A volume created here is removed again when extraction fails.
+func importInto(sshClient RemoteExecutor, volumeName string, opts ImportOptions, extract func() error) error {
+	// Step 1: Create the volume on remote (a no-op if it already exists)
+	existed, _ := VerifyVolumeExists(sshClient, volumeName)
+	if _, err := sshClient.RunDockerCommand(buildVolumeCreateCommand(volumeName, opts)); err != nil {
 		return fmt.Errorf("failed to create volume %s on remote: %w", volumeName, err)
 	}
 
 	log.WithField("volume", volumeName).Debug("Created volume on remote")
 
 	// Step 2: Extract archive data into the volume
-	// Get the directory and filename from archive path
-	archiveDir := filepath.Dir(archivePath)
-	archiveFile := filepath.Base(archivePath)
-
-	// Build docker command to import
-	// Note: On remote, we need to escape the command properly
-	importCmd := fmt.Sprintf(
-		`run --rm -v %s:/data -v %s:/backup alpine tar xzf /backup/%s -C /data`,
-		volumeName, archiveDir, archiveFile,
-	)
-
-	if _, err := sshClient.RunDockerCommand(importCmd); err != nil {
-		// Cleanup: remove the volume we just created
-		if _, cleanupErr := sshClient.RunDockerCommand(fmt.Sprintf("volume rm %s", volumeName)); cleanupErr != nil {
-			log.WithField("volume", volumeName).WithError(cleanupErr).Warn("Failed to cleanup volume after import failure")
+	if err := extract(); err != nil {
+		// Cleanup: remove the volume we just created (never one that held data before)
+		if !existed {
+			if _, cleanupErr := sshClient.RunDockerCommand(fmt.Sprintf("volume rm %s", volumeName)); cleanupErr != nil {
+				log.WithField("volume", volumeName).WithError(cleanupErr).Warn("Failed to cleanup volume after import failure")
+			}
 		}
 		return fmt.Errorf("failed to import data into volume %s: %w", volumeName, err)
 	}
@@ -51,22 +175,226 @@ func ImportVolume(sshClient *ssh.Client, volumeName, archivePath string) error {
 	return nil
 }
 
-// ImportVolumes imports multiple volumes from archives on the remote machine
-func ImportVolumes(sshClient *ssh.Client, archivePaths map[string]string, remoteTempDir string) error {
-	for volumeName, archivePath := range archivePaths {
-		// Construct remote archive path
-		remoteArchivePath := filepath.Join(remoteTempDir, filepath.Base(archivePath))
+// runImport runs the remote extraction command
+// With progress enabled, the extracted size of the volume is polled while tar runs
+func runImport(sshClient RemoteExecutor, volumeName, importCmd string, opts ImportOptions) error {
+	if !opts.ShowProgress {
+		_, err := sshClient.RunDockerCommand(importCmd)
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sshClient.RunDockerCommand(importCmd)
+		done <- err
+	}()
 
-		if err := ImportVolume(sshClient, volumeName, remoteArchivePath); err != nil {
-			return fmt.Errorf("failed to import volume %s: %w", volumeName, err)
+	expected := opts.ExpectedSize
+	if expected <= 0 {
+		expected = -1
+	}
+	bar := utils.NewProgressBar(expected, fmt.Sprintf("Importing %s", volumeName))
+
+	ticker := time.NewTicker(importProgressInterval)
+	defer ticker.Stop()
+
+	image := resolveHelperImage(opts.HelperImage, "", opts.RequiresGNUTar())
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				bar.Finish()
+			}
+			return err
+		case <-ticker.C:
+			if used, err := remoteVolumeUsage(sshClient, volumeName, image); err == nil {
+				bar.Set64(used)
+			}
 		}
 	}
+}
 
-	return nil
+// remoteVolumeUsage returns the number of bytes currently stored in a remote volume
+func remoteVolumeUsage(sshClient RemoteExecutor, volumeName, image string) (int64, error) {
+	output, err := sshClient.RunDockerCommand(fmt.Sprintf(
+		"run --rm -v %s:/data:ro %s du -sk /data",
+		volumeName, shell.ShellEscape(image),
+	))
+	if err != nil {
+		return 0, err
+	}
+	return parseDUKilobytes(output)
+}
+
+// parseDUKilobytes parses the output of `du -sk <path>` into bytes
+func parseDUKilobytes(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected du output: %q", output)
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse du output %q: %w", output, err)
+	}
+	return kb * 1024, nil
+}
+
+// buildVolumeCreateCommand constructs the remote docker arguments creating the volume with the
+// source volume's driver, options and labels, sorted so the command is stable
+func buildVolumeCreateCommand(volumeName string, opts ImportOptions) string {
+	cmd := "volume create"
+	if opts.Driver != "" {
+		cmd += " --driver " + shell.ShellEscape(opts.Driver)
+	}
+	cmd += keyValueFlags("--opt", opts.DriverOpts)
+	cmd += keyValueFlags("--label", opts.Labels)
+
+	return cmd + " " + volumeName
+}
+
+// keyValueFlags formats values as repeated flag key=value arguments, sorted by key
+func keyValueFlags(flag string, values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args string
+	for _, key := range keys {
+		args += " " + flag + " " + shell.ShellEscape(key+"="+values[key])
+	}
+	return args
+}
+
+// buildImportCommand constructs the remote docker arguments used to extract an archive into a volume
+// A split archive is extracted from its parts concatenated in order
+func buildImportCommand(volumeName, archivePath string, opts ImportOptions) string {
+	source := "/backup/" + path.Base(archivePath)
+	if opts.ArchiveParts == 0 {
+		return buildExtractCommand(volumeName, "--rm", path.Dir(archivePath), source, "", opts)
+	}
+
+	parts := make([]string, opts.ArchiveParts)
+	for i := range parts {
+		parts[i] = shell.ShellEscape(archivePartName(source, i))
+	}
+	return buildExtractCommand(volumeName, "--rm", path.Dir(archivePath), "-", "cat "+strings.Join(parts, " "), opts)
+}
+
+// buildStreamImportCommand constructs the remote docker arguments extracting an archive read from stdin
+// remoteTempDir is only mounted (at /backup) when a dedup script has to run
+func buildStreamImportCommand(volumeName, remoteTempDir string, opts ImportOptions) string {
+	backupDir := ""
+	if opts.DedupScript != "" {
+		backupDir = remoteTempDir
+	}
+	return buildExtractCommand(volumeName, "-i --rm", backupDir, "-", "", opts)
+}
+
+// buildExtractCommand constructs the docker run arguments extracting source (a path under /backup,
+// or - for stdin) into the volume. backupDir is mounted at /backup unless empty.
+// A non-empty input is a command whose output is piped into the extraction as its stdin.
+func buildExtractCommand(volumeName, runFlags, backupDir, source, input string, opts ImportOptions) string {
+	extract, pipeline := buildExtractStep(source, opts)
+	if input != "" {
+		extract, pipeline = input+" | "+extract, true
+	}
+
+	// Deduplicated files are filled in, then ownership remapped, in the same helper container
+	steps := []string{extract}
+	if opts.DedupScript != "" {
+		steps = append(steps, "sh "+shell.ShellEscape("/backup/"+opts.DedupScript))
+	}
+	if len(opts.UIDMap) > 0 || len(opts.GIDMap) > 0 {
+		steps = append(steps, buildRemapScript("/data", opts.UIDMap, opts.GIDMap))
+	}
+	if len(steps) > 1 || pipeline {
+		script := strings.Join(steps, " && ")
+		if pipeline {
+			script = pipefailScript(script)
+		}
+		extract = "sh -c " + shell.ShellEscape(script)
+	}
+
+	var sourceMounts string
+	for i, source := range opts.DedupSources {
+		sourceMounts += fmt.Sprintf(" -v %s:/src%d:ro", source, i)
+	}
+
+	if backupDir != "" {
+		sourceMounts += fmt.Sprintf(" -v %s:/backup", shell.ShellEscape(backupDir))
+	}
+
+	// Note: On remote, we need to escape the command properly
+	return fmt.Sprintf(
+		`run %s -v %s:/data%s %s %s`,
+		runFlags, volumeName, sourceMounts, shell.ShellEscape(resolveHelperImage(opts.HelperImage, "", opts.RequiresGNUTar())), extract,
+	)
+}
+
+// pipefailScript makes a shell script fail when any command of its pipelines does, not only the
+// last: tar would otherwise extract the prefix a failing cat or zstd gave it and exit 0, and a
+// zstd compressing the output of a failing tar would report success
+func pipefailScript(script string) string {
+	return "set -o pipefail; " + script
+}
+
+// buildExtractStep returns the command unpacking source into /data for the archive format, and
+// whether it is a pipeline that has to run in a shell
+func buildExtractStep(source string, opts ImportOptions) (string, bool) {
+	if opts.Format == FormatSquashfs {
+		// -f writes into the existing mount point, restoring owners and modes as root
+		return strings.Join([]string{"unsquashfs", "-f", "-no-progress", "-d", "/data", shell.ShellEscape(source)}, " "), false
+	}
+
+	// Sparse files are restored automatically, so only xattrs/ACLs need flags on extraction
+	tarArgs := []string{"tar"}
+	tarArgs = append(tarArgs, tarPreserveFlags(opts.PreserveXattrs, opts.PreserveACLs, false)...)
+	if opts.NumericOwner {
+		tarArgs = append(tarArgs, "--numeric-owner")
+	}
+	if opts.Incremental {
+		tarArgs = append(tarArgs, "--listed-incremental=/dev/null")
+	}
+	switch archiveFormat(opts.Format) {
+	case FormatTarGz:
+		tarArgs = append(tarArgs, "-xzf", source)
+	case FormatTar:
+		tarArgs = append(tarArgs, "-xf", source)
+	case FormatTarZst:
+		tarArgs = append(tarArgs, "-xf", "-")
+	}
+	tarArgs = append(tarArgs, "-C", "/data")
+
+	escapedTar := make([]string, len(tarArgs))
+	for i, arg := range tarArgs {
+		escapedTar[i] = shell.ShellEscape(arg)
+	}
+	extract := strings.Join(escapedTar, " ")
+	if opts.Format != FormatTarZst {
+		return extract, false
+	}
+
+	// zstd decompresses outside tar, so no tar implementation needs zstd support
+	decompress := "zstd -dc"
+	if source != "-" {
+		decompress += " " + shell.ShellEscape(source)
+	}
+	return decompress + " | " + extract, true
+}
+
+// RemoteVolumeUsers returns the names of the running remote containers mounting a volume
+func RemoteVolumeUsers(sshClient RemoteExecutor, volumeName string) ([]string, error) {
+	output, err := sshClient.RunDockerCommand(fmt.Sprintf("ps --filter volume=%s --format '{{.Names}}'", volumeName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers using volume %s: %w", volumeName, err)
+	}
+	return strings.Fields(output), nil
 }
 
 // VerifyVolumeExists checks if a volume exists on the remote host
-func VerifyVolumeExists(sshClient *ssh.Client, volumeName string) (bool, error) {
+func VerifyVolumeExists(sshClient RemoteExecutor, volumeName string) (bool, error) {
 	output, err := sshClient.RunDockerCommand(fmt.Sprintf("volume inspect %s", volumeName))
 	if err != nil {
 		return false, nil
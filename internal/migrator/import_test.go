@@ -0,0 +1,252 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/shell"
+)
+
+func TestBuildImportCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ImportOptions
+		wantImage string
+	}{
+		{"default helper image", ImportOptions{}, DefaultHelperImage},
+		{"custom helper image", ImportOptions{HelperImage: "mirror.example.com/alpine:3.19"}, "mirror.example.com/alpine:3.19"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar.gz", tt.opts)
+
+			if !strings.HasPrefix(cmd, "run --rm -v myvolume:/data -v /tmp/remote:/backup ") {
+				t.Errorf("unexpected mounts in import command: %s", cmd)
+			}
+			if !strings.Contains(cmd, " "+shell.ShellEscape(tt.wantImage)+" tar -xzf /backup/myvolume.tar.gz -C /data") {
+				t.Errorf("expected helper image %s in import command, got: %s", tt.wantImage, cmd)
+			}
+		})
+	}
+}
+
+func TestBuildImportCommand_Formats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatTar, " tools tar -xf /backup/myvolume.tar -C /data"},
+		{FormatTarGz, " tools tar -xzf /backup/myvolume.tar -C /data"},
+		{FormatTarZst, " tools sh -c 'set -o pipefail; zstd -dc /backup/myvolume.tar | tar -xf - -C /data'"},
+		{FormatSquashfs, " tools unsquashfs -f -no-progress -d /data /backup/myvolume.tar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar", ImportOptions{HelperImage: "tools", Format: tt.format})
+			if !strings.HasSuffix(cmd, tt.want) {
+				t.Errorf("buildImportCommand() = %s, want suffix %s", cmd, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildImportCommand_Split(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatTarGz, " tools sh -c 'set -o pipefail; cat /backup/data.tar.gz.part000 /backup/data.tar.gz.part001 | tar -xzf - -C /data'"},
+		{FormatTarZst, " tools sh -c 'set -o pipefail; cat /backup/data.tar.gz.part000 /backup/data.tar.gz.part001 | zstd -dc | tar -xf - -C /data'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cmd := buildImportCommand("data", "/tmp/remote/data.tar.gz", ImportOptions{HelperImage: "tools", Format: tt.format, ArchiveParts: 2})
+			if !strings.HasSuffix(cmd, tt.want) {
+				t.Errorf("buildImportCommand() = %s, want suffix %s", cmd, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildImportCommand_PreserveMetadata(t *testing.T) {
+	opts := ImportOptions{PreserveXattrs: true, PreserveACLs: true}
+	cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar.gz", opts)
+
+	if !strings.Contains(cmd, " "+shell.ShellEscape(DefaultGNUHelperImage)+" ") {
+		t.Errorf("expected GNU helper image when preserving metadata, got: %s", cmd)
+	}
+	for _, flag := range []string{"--xattrs", "'--xattrs-include=*'", "--acls"} {
+		if !strings.Contains(cmd, " "+flag+" ") {
+			t.Errorf("expected flag %s in import command, got: %s", flag, cmd)
+		}
+	}
+	if strings.Contains(cmd, "--sparse") {
+		t.Errorf("extraction should not pass --sparse, got: %s", cmd)
+	}
+}
+
+func TestBuildImportCommand_OwnershipRemap(t *testing.T) {
+	opts := ImportOptions{
+		NumericOwner: true,
+		UIDMap:       []IDMapping{{From: 1000, To: 2000}},
+	}
+	cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar.gz", opts)
+
+	if !strings.Contains(cmd, " "+shell.ShellEscape(DefaultHelperImage)+" sh -c '") {
+		t.Fatalf("expected remap to run through sh -c in the helper image, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, "tar --numeric-owner -xzf /backup/myvolume.tar.gz -C /data && find /data") {
+		t.Errorf("expected extraction followed by remap, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, "chown -h 2000") {
+		t.Errorf("expected uid remap in command, got: %s", cmd)
+	}
+}
+
+func TestParseDUKilobytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected int64
+		wantErr  bool
+	}{
+		{"busybox output", "2048\t/data\n", 2048 * 1024, false},
+		{"empty volume", "0\t/data\n", 0, false},
+		{"empty output", "", 0, true},
+		{"non-numeric", "du: /data: Permission denied\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseDUKilobytes(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDUKilobytes(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseDUKilobytes(%q) = %d, want %d", tt.output, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildImportCommand_Incremental(t *testing.T) {
+	cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar.gz", ImportOptions{Incremental: true})
+
+	if !strings.Contains(cmd, " "+shell.ShellEscape(DefaultGNUHelperImage)+" ") {
+		t.Errorf("expected GNU helper image for incremental import, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, "tar '--listed-incremental=/dev/null' -xzf /backup/myvolume.tar.gz -C /data") {
+		t.Errorf("expected incremental extraction, got: %s", cmd)
+	}
+}
+
+func TestBuildImportCommand_Dedup(t *testing.T) {
+	opts := ImportOptions{DedupScript: "myvolume.dedup.sh", DedupSources: []string{"shared", "cache"}}
+	cmd := buildImportCommand("myvolume", "/tmp/remote/myvolume.tar.gz", opts)
+
+	if !strings.Contains(cmd, "-v myvolume:/data -v shared:/src0:ro -v cache:/src1:ro -v ") {
+		t.Errorf("expected source volumes mounted read-only, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, "tar -xzf /backup/myvolume.tar.gz -C /data && sh ") || !strings.Contains(cmd, "/backup/myvolume.dedup.sh") {
+		t.Errorf("expected dedup script to run after extraction, got: %s", cmd)
+	}
+}
+
+func TestBuildStreamImportCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ImportOptions
+		wantPrefix string
+		wantTar    string
+	}{
+		{"plain", ImportOptions{}, "run -i --rm -v myvolume:/data " + shell.ShellEscape(DefaultHelperImage) + " ", "tar -xzf - -C /data"},
+		{
+			"dedup mounts the temp dir",
+			ImportOptions{DedupScript: "myvolume.dedup.sh", DedupSources: []string{"shared"}},
+			"run -i --rm -v myvolume:/data -v shared:/src0:ro -v /tmp/remote:/backup ",
+			"tar -xzf - -C /data && sh ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := buildStreamImportCommand("myvolume", "/tmp/remote", tt.opts)
+
+			if !strings.HasPrefix(cmd, tt.wantPrefix) {
+				t.Errorf("expected prefix %q, got: %s", tt.wantPrefix, cmd)
+			}
+			if !strings.Contains(cmd, tt.wantTar) {
+				t.Errorf("expected %q in stream import command, got: %s", tt.wantTar, cmd)
+			}
+		})
+	}
+}
+
+func TestBuildStreamImportCommand_Zstd(t *testing.T) {
+	cmd := buildStreamImportCommand("myvolume", "/tmp/remote", ImportOptions{HelperImage: "tools", Format: FormatTarZst})
+	if want := "run -i --rm -v myvolume:/data tools sh -c 'set -o pipefail; zstd -dc | tar -xf - -C /data'"; cmd != want {
+		t.Errorf("buildStreamImportCommand() = %s, want %s", cmd, want)
+	}
+}
+
+func TestValidateImportMethod(t *testing.T) {
+	for _, method := range []string{"", ImportMethodArchive, ImportMethodStream} {
+		if err := ValidateImportMethod(method); err != nil {
+			t.Errorf("ValidateImportMethod(%q) unexpected error: %v", method, err)
+		}
+	}
+	if err := ValidateImportMethod("rsync"); err == nil {
+		t.Error("expected error for unknown import method")
+	}
+}
+
+func TestBuildCopyCommands(t *testing.T) {
+	container := copyContainerPrefix + "myvolume"
+
+	run := buildCopyContainerCommand(container, "myvolume", ImportOptions{})
+	want := "run -d --name volume-migrator-cp-myvolume -v myvolume:/data " + shell.ShellEscape(DefaultHelperImage) + " tail -f /dev/null"
+	if run != want {
+		t.Errorf("buildCopyContainerCommand() = %q, want %q", run, want)
+	}
+
+	if cp := buildCopyCommand(container); cp != "cp --archive - volume-migrator-cp-myvolume:/data" {
+		t.Errorf("buildCopyCommand() = %q", cp)
+	}
+}
+
+func TestBuildVolumeCreateCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ImportOptions
+		want string
+	}{
+		{"default driver", ImportOptions{}, "volume create myvolume"},
+		{
+			"local nfs options",
+			ImportOptions{DriverOpts: map[string]string{"type": "nfs", "o": "addr=10.0.0.5,rw", "device": ":/exports/data"}},
+			"volume create --opt 'device=:/exports/data' --opt 'o=addr=10.0.0.5,rw' --opt 'type=nfs' myvolume",
+		},
+		{"plugin driver", ImportOptions{Driver: "rexray/ebs"}, "volume create --driver rexray/ebs myvolume"},
+		{
+			"compose labels",
+			ImportOptions{Labels: map[string]string{"com.docker.compose.project": "web", "com.docker.compose.volume": "data", "com.docker.compose.version": ""}},
+			"volume create --label 'com.docker.compose.project=web' --label 'com.docker.compose.version=' --label 'com.docker.compose.volume=data' myvolume",
+		},
+		{
+			"options and labels",
+			ImportOptions{DriverOpts: map[string]string{"type": "tmpfs"}, Labels: map[string]string{"owner": "team a"}},
+			"volume create --opt 'type=tmpfs' --label 'owner=team a' myvolume",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildVolumeCreateCommand("myvolume", tt.opts); got != tt.want {
+				t.Errorf("buildVolumeCreateCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotExt is the file extension of GNU tar incremental snapshot files
+const snapshotExt = ".snar"
+
+// DefaultStateDir returns the default directory where incremental snapshots are kept
+func DefaultStateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "volume-migrator-state")
+	}
+	return filepath.Join(home, ".volume-migrator", "state")
+}
+
+// hostStateKey turns a remote host string into a safe directory name (user@host:22 -> user_host_22)
+func hostStateKey(remoteHost string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, remoteHost)
+}
+
+// snapshotPath returns where the snapshot of the last generation imported into remoteVolume on remoteHost is kept
+func snapshotPath(stateDir, remoteHost, remoteVolume string) string {
+	return filepath.Join(stateDir, hostStateKey(remoteHost), remoteVolume+snapshotExt)
+}
+
+// prepareSnapshot creates the working snapshot used by an incremental export in workDir.
+// When the remote still has the previous generation (remoteExists) the stored snapshot is copied,
+// so tar only archives changes since then; otherwise the work copy is empty and a full archive is made.
+// Returns the work copy path and whether the export is a delta.
+func prepareSnapshot(statePath, workDir string, remoteExists bool) (string, bool, error) {
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	workPath := filepath.Join(workDir, filepath.Base(statePath))
+	if err := os.Remove(workPath); err != nil && !os.IsNotExist(err) {
+		return "", false, fmt.Errorf("failed to reset snapshot: %w", err)
+	}
+
+	if !remoteExists {
+		return workPath, false, nil
+	}
+	if _, err := os.Stat(statePath); os.IsNotExist(err) {
+		return workPath, false, nil
+	}
+
+	if err := copyFile(statePath, workPath); err != nil {
+		return "", false, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	return workPath, true, nil
+}
+
+// commitSnapshot stores the snapshot written by a successful export as the new base generation
+func commitSnapshot(workPath, statePath string) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmpPath := statePath + ".tmp"
+	if err := copyFile(workPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// copyFile copies src to dst, replacing dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
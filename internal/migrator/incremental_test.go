@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostStateKey(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"user@host", "user_host"},
+		{"deploy@10.0.0.5:2222", "deploy_10.0.0.5_2222"},
+		{"root@my-host.example.com", "root_my-host.example.com"},
+		{"../../etc", ".._.._etc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if result := hostStateKey(tt.host); result != tt.expected {
+				t.Errorf("hostStateKey(%q) = %q, want %q", tt.host, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	got := snapshotPath("/state", "user@host:22", "app_data")
+	if want := filepath.Join("/state", "user_host_22", "app_data.snar"); got != want {
+		t.Errorf("snapshotPath() = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareSnapshot(t *testing.T) {
+	base := t.TempDir()
+	statePath := filepath.Join(base, "state", "vol.snar")
+	workDir := filepath.Join(base, "work")
+
+	// No stored snapshot: full export
+	workPath, delta, err := prepareSnapshot(statePath, workDir, true)
+	if err != nil {
+		t.Fatalf("prepareSnapshot() unexpected error: %v", err)
+	}
+	if delta {
+		t.Error("expected full export without a stored snapshot")
+	}
+	if _, err := os.Stat(workPath); !os.IsNotExist(err) {
+		t.Errorf("expected no work snapshot for a full export, stat err: %v", err)
+	}
+
+	// Stored snapshot and remote volume present: delta export from a copy
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		t.Fatalf("failed to create state dir: %v", err)
+	}
+	if err := os.WriteFile(statePath, []byte("snapshot-1"), 0600); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	workPath, delta, err = prepareSnapshot(statePath, workDir, true)
+	if err != nil {
+		t.Fatalf("prepareSnapshot() unexpected error: %v", err)
+	}
+	if !delta {
+		t.Error("expected delta export with a stored snapshot")
+	}
+	if data, _ := os.ReadFile(workPath); string(data) != "snapshot-1" {
+		t.Errorf("work snapshot = %q, want copy of stored snapshot", data)
+	}
+
+	// Remote volume gone: the stored snapshot must not be used
+	_, delta, err = prepareSnapshot(statePath, workDir, false)
+	if err != nil {
+		t.Fatalf("prepareSnapshot() unexpected error: %v", err)
+	}
+	if delta {
+		t.Error("expected full export when the remote volume is missing")
+	}
+	if _, err := os.Stat(workPath); !os.IsNotExist(err) {
+		t.Errorf("expected stale work snapshot to be removed, stat err: %v", err)
+	}
+}
+
+func TestCommitSnapshot(t *testing.T) {
+	base := t.TempDir()
+	workPath := filepath.Join(base, "work", "vol.snar")
+	statePath := filepath.Join(base, "state", "host", "vol.snar")
+
+	if err := os.MkdirAll(filepath.Dir(workPath), 0755); err != nil {
+		t.Fatalf("failed to create work dir: %v", err)
+	}
+	if err := os.WriteFile(workPath, []byte("snapshot-2"), 0644); err != nil {
+		t.Fatalf("failed to write work snapshot: %v", err)
+	}
+
+	if err := commitSnapshot(workPath, statePath); err != nil {
+		t.Fatalf("commitSnapshot() unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(statePath); string(data) != "snapshot-2" {
+		t.Errorf("stored snapshot = %q, want %q", data, "snapshot-2")
+	}
+	if _, err := os.Stat(statePath + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected temporary snapshot file to be renamed")
+	}
+}
@@ -0,0 +1,79 @@
+package migrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/utils"
+)
+
+// validateInodes checks that the remote Docker root has a free inode for every file of the volumes.
+// Volumes of millions of small files can exhaust the inodes of a filesystem with plenty of bytes
+// free. Filesystems allocating inodes on demand report none and are not checked, and neither are
+// database dumps, which are restored by the database instead of extracted.
+func (m *Migrator) validateInodes(volumes []docker.VolumeInfo) error {
+	dockerRoot, err := remoteDockerRootDir(m.sshClient)
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not determine remote Docker root directory")
+		}
+		return nil
+	}
+	inodes, err := utils.GetRemoteInodes(m.sshClient, dockerRoot)
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not check remote free inodes")
+		}
+		return nil
+	}
+	if inodes.Total == 0 {
+		log.WithField("docker_root", dockerRoot).Debug("Remote filesystem allocates inodes on demand, skipping inode check")
+		return nil
+	}
+
+	image := resolveHelperImage(m.config.HelperImage, m.config.HelperRegistry, false)
+	var files uint64
+	for _, v := range volumes {
+		if m.isDumpVolume(v) {
+			continue
+		}
+		count, err := localVolumeFileCount(m.dockerClient, v.Name, image)
+		if err != nil {
+			if m.config.Verbose {
+				log.WithError(err).WithField("volume", v.Name).Warn("Could not count the files of the volume, skipping inode check")
+			}
+			return nil
+		}
+		files += count
+	}
+
+	log.WithFields(logrus.Fields{
+		"docker_root": dockerRoot,
+		"free_inodes": inodes.Free,
+		"files":       files,
+	}).Debug("Remote inode check")
+
+	if files > inodes.Free {
+		return fmt.Errorf("%w on remote docker root (%s): %d free inodes, but the volumes hold %d files (use --force to override)",
+			migerrors.ErrDiskSpace, dockerRoot, inodes.Free, files)
+	}
+	return nil
+}
+
+// localVolumeFileCount returns the number of files, directories and links in a local volume,
+// each of which takes an inode once extracted
+func localVolumeFileCount(dockerClient DockerRunner, volumeName, image string) (uint64, error) {
+	output, err := dockerClient.ExecCommand("run", "--rm", "-v", volumeName+":/data:ro", image, "sh", "-c", "find /data | wc -l")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count files of volume %s: %w", volumeName, err)
+	}
+	count, err := strconv.ParseUint(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse file count %q: %w", output, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,52 @@
+package migrator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
+)
+
+func TestValidateInodes(t *testing.T) {
+	dfInodes := func(total, free string) string {
+		return "Filesystem      Inodes  IUsed   IFree IUse% Mounted on\n" +
+			"/dev/sda1      " + total + " 0 " + free + "    0% /\n"
+	}
+	volumes := []docker.VolumeInfo{{Name: "data"}, {Name: "logs"}}
+
+	tests := []struct {
+		name      string
+		df        string
+		count     fakeResponse
+		wantErr   bool
+		wantCount bool // Whether the files were counted
+	}{
+		{"enough inodes", dfInodes("10000", "2000"), fakeResponse{output: "1000\n"}, false, true},
+		{"too few inodes", dfInodes("10000", "1999"), fakeResponse{output: "1000\n"}, true, true},
+		{"inodes on demand", dfInodes("0", "0"), fakeResponse{output: "1000\n"}, false, false},
+		{"files not countable", dfInodes("10000", "10"), fakeResponse{err: errors.New("no such image")}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{
+				responses: map[string]fakeResponse{"info": {output: "/var/lib/docker\n"}},
+				shell:     map[string]fakeResponse{"d=": {output: tt.df}},
+			}
+			local := &fakeDocker{responses: map[string]fakeResponse{"run": tt.count}}
+			m := &Migrator{config: &Config{}, sshClient: remote, dockerClient: local}
+
+			err := m.validateInodes(volumes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateInodes() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && (!errors.Is(err, migerrors.ErrDiskSpace) || !strings.Contains(err.Error(), "1999 free inodes")) {
+				t.Errorf("validateInodes() = %v, want a disk space error naming the free inodes", err)
+			}
+			if counted := len(local.commands) > 0; counted != tt.wantCount {
+				t.Errorf("files counted = %v, want %v", counted, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
+)
+
+// remoteLockPath is the marker that stops two runs from importing into the same remote host at once
+const remoteLockPath = "/tmp/volume-migrator.lock"
+
+// lockAcquired is printed by the remote lock command when the marker was created
+const lockAcquired = "acquired"
+
+// localLockPath returns the lock file guarding migrations from this machine to remoteHost
+// It lives in the system temp root because every run has its own temp directory
+func localLockPath(remoteHost string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("volume-migrator-%s.lock", hostStateKey(remoteHost)))
+}
+
+// lockOwner describes the current process for lock files, so a conflicting run can be identified
+func lockOwner(remoteHost string) string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("pid %d on %s migrating to %s since %s", os.Getpid(), hostname, remoteHost, time.Now().Format(time.RFC3339))
+}
+
+// acquireLocalLock creates the lock file at path, failing if another run holds it unless force is set
+func acquireLocalLock(path, owner string, force bool) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+	if force {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		holder, _ := os.ReadFile(path)
+		return fmt.Errorf("another migration is running (%s); if it is stale, remove %s or pass --force-lock",
+			strings.TrimSpace(string(holder)), path)
+	}
+	if os.IsPermission(err) {
+		return migerrors.NewPermissionError("write", path, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(owner + "\n"); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// buildRemoteLockCommand returns the shell command creating the remote marker
+// noclobber (set -C) makes creation atomic; when the marker exists its content is printed instead
+func buildRemoteLockCommand(owner string, force bool) string {
+	write := fmt.Sprintf("printf '%%s\\n' %s > %s", shell.ShellEscape(owner), remoteLockPath)
+	if force {
+		return fmt.Sprintf("%s && echo %s", write, lockAcquired)
+	}
+	return fmt.Sprintf("if (set -C; %s) 2>/dev/null; then echo %s; else cat %s; fi", write, lockAcquired, remoteLockPath)
+}
+
+// acquireRemoteLock creates the remote marker, failing if another run holds it unless force is set
+func acquireRemoteLock(sshClient RemoteExecutor, owner string, force bool) error {
+	output, err := sshClient.RunCommand(buildRemoteLockCommand(owner, force))
+	if err != nil {
+		return fmt.Errorf("failed to create remote lock: %w", err)
+	}
+
+	output = strings.TrimSpace(output)
+	if output != lockAcquired {
+		return fmt.Errorf("another migration to this host is running (%s); if it is stale, remove %s on the remote host or pass --force-lock",
+			output, remoteLockPath)
+	}
+	return nil
+}
+
+// acquireLocks takes the local and remote locks for this run and returns a function releasing both
+func (m *Migrator) acquireLocks() (func(), error) {
+	owner := lockOwner(m.config.RemoteHost)
+	localPath := localLockPath(m.config.RemoteHost)
+
+	if err := acquireLocalLock(localPath, owner, m.config.ForceLock); err != nil {
+		return nil, err
+	}
+	if err := acquireRemoteLock(m.sshClient, owner, m.config.ForceLock); err != nil {
+		os.Remove(localPath)
+		return nil, err
+	}
+
+	return func() {
+		if _, err := m.cleanupClient().RunCommand("rm -f " + remoteLockPath); err != nil {
+			log.WithError(err).Warn("Failed to remove remote lock")
+		}
+		if err := os.Remove(localPath); err != nil {
+			log.WithError(err).Warn("Failed to remove local lock")
+		}
+	}, nil
+}
@@ -0,0 +1,51 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquireLocalLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "volume-migrator-host.lock")
+
+	if err := acquireLocalLock(path, "pid 1 on a", false); err != nil {
+		t.Fatalf("first lock failed: %v", err)
+	}
+
+	err := acquireLocalLock(path, "pid 2 on b", false)
+	if err == nil || !strings.Contains(err.Error(), "pid 1 on a") || !strings.Contains(err.Error(), "--force-lock") {
+		t.Errorf("expected conflict naming the holder, got: %v", err)
+	}
+
+	if err := acquireLocalLock(path, "pid 2 on b", true); err != nil {
+		t.Fatalf("forced lock failed: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(content)) != "pid 2 on b" {
+		t.Errorf("expected forced lock to take over, got %q", content)
+	}
+}
+
+func TestBuildRemoteLockCommand(t *testing.T) {
+	cmd := buildRemoteLockCommand("pid 1 on it's host", false)
+	if !strings.Contains(cmd, "set -C;") || !strings.Contains(cmd, "cat "+remoteLockPath) {
+		t.Errorf("expected noclobber create falling back to printing the holder, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, `'pid 1 on it'\''s host'`) {
+		t.Errorf("expected escaped owner, got: %s", cmd)
+	}
+
+	forced := buildRemoteLockCommand("pid 1", true)
+	if strings.Contains(forced, "set -C") || !strings.HasSuffix(forced, "echo "+lockAcquired) {
+		t.Errorf("expected forced lock to overwrite the marker, got: %s", forced)
+	}
+}
+
+func TestLocalLockPath(t *testing.T) {
+	path := localLockPath("user@host:2222")
+	if filepath.Base(path) != "volume-migrator-user_host_2222.lock" {
+		t.Errorf("unexpected lock path: %s", path)
+	}
+}
@@ -0,0 +1,327 @@
+package migrator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
+)
+
+// ArchiveManifestFile is the name of the archive manifest kept next to the archives on both hosts
+const ArchiveManifestFile = "manifest.json"
+
+// Signed manifests get an SSHSIG signature next to them, in this namespace
+const (
+	manifestSignatureFile      = ArchiveManifestFile + ".sig"
+	manifestSignatureNamespace = "volume-migrator"
+)
+
+// ArchiveEntry describes one exported volume archive
+type ArchiveEntry struct {
+	Volume            string `json:"volume"`
+	Archive           string `json:"archive"` // File name of the archive
+	SHA256            string `json:"sha256"`  // Checksum of the compressed archive
+	ArchiveBytes      int64  `json:"archive_bytes"`
+	UncompressedBytes int64  `json:"uncompressed_bytes"`       // Total size of the regular files in the archive
+	Files             int    `json:"files"`                    // Number of regular files in the archive
+	Parts             int    `json:"parts,omitempty"`          // Number of parts a split archive was written to
+	FileChecksums     string `json:"file_checksums,omitempty"` // Per-file sha256 list written with --deep-verify
+}
+
+// ArchiveManifest lists the archives exported during a run
+type ArchiveManifest struct {
+	Archives []ArchiveEntry `json:"archives"`
+}
+
+// MigrationManifest records what a migration run did and with which images, for auditing
+type MigrationManifest struct {
+	RemoteHost  string                   `json:"remote_host"`
+	StartedAt   time.Time                `json:"started_at"`
+	FinishedAt  time.Time                `json:"finished_at"`
+	HelperImage HelperImageIdentity      `json:"helper_image"`
+	Local       EnvironmentInfo          `json:"local"`
+	Remote      EnvironmentInfo          `json:"remote"`
+	Archives    []ArchiveEntry           `json:"archives"`
+	Succeeded   []string                 `json:"succeeded"`
+	Failed      []string                 `json:"failed"`
+	NotMigrated []docker.UnmigratedMount `json:"not_migrated,omitempty"` // Bind and tmpfs mounts of the containers
+	Error       string                   `json:"error,omitempty"`
+}
+
+// WriteManifest writes the manifest as indented JSON to path
+func WriteManifest(path string, manifest *MigrationManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode migration manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write migration manifest: %w", err)
+	}
+	return nil
+}
+
+// scanArchive reads an archive back, hashing it and, for tar and tar.gz, counting its regular files
+// Other formats are only hashed, leaving Files and UncompressedBytes at zero.
+// The parts of a split archive are read in order, as if they were one file.
+func scanArchive(volume, archivePath, format string) (ArchiveEntry, error) {
+	entry := newArchiveEntry(volume, archivePath)
+
+	files := ArchiveFiles(archivePath)
+	readers := make([]io.Reader, len(files))
+	for i, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return entry, fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer f.Close()
+		readers[i] = f
+	}
+
+	scan := newArchiveScanner(format)
+	defer scan.close()
+	var r io.Reader = io.TeeReader(io.MultiReader(readers...), scan)
+	if archiveFormat(format) == FormatTarGz {
+		var err error
+		if r, err = gzip.NewReader(r); err != nil {
+			return entry, fmt.Errorf("failed to read archive %s: %w", entry.Archive, err)
+		}
+	}
+	if _, err := io.Copy(scan.tarWriter(), r); err != nil {
+		return entry, fmt.Errorf("failed to read archive %s: %w", entry.Archive, err)
+	}
+	return entry, scan.finish(&entry)
+}
+
+// newArchiveEntry returns the entry of an archive, without the fields its contents determine
+func newArchiveEntry(volume, archivePath string) ArchiveEntry {
+	entry := ArchiveEntry{Volume: volume, Archive: filepath.Base(archivePath)}
+	if files := ArchiveFiles(archivePath); files[0] != archivePath {
+		entry.Parts = len(files)
+	}
+	return entry
+}
+
+// archiveScanner computes the checksum and file counts of an archive from the bytes written to it
+// and, for tar and tar.gz, from the uncompressed tar stream written to tarWriter, so an archive
+// can be scanned while it is exported instead of being read back
+type archiveScanner struct {
+	hash    hash.Hash
+	archive countingWriter
+	tar     *io.PipeWriter // nil when the format is not a tar stream
+	done    chan error
+	files   int
+	bytes   int64
+}
+
+// newArchiveScanner creates a scanner for an archive in format
+func newArchiveScanner(format string) *archiveScanner {
+	s := &archiveScanner{hash: sha256.New()}
+	if !isTarFormat(format) {
+		return s
+	}
+
+	pr, pw := io.Pipe()
+	s.tar = pw
+	s.done = make(chan error, 1)
+	go func() {
+		err := s.countFiles(pr)
+		// Keep reading, so the writer never blocks on a stream the scanner gave up on
+		io.Copy(io.Discard, pr)
+		s.done <- err
+	}()
+	return s
+}
+
+// Write hashes and counts archive bytes
+func (s *archiveScanner) Write(p []byte) (int, error) {
+	s.hash.Write(p)
+	return s.archive.Write(p)
+}
+
+// tarWriter returns where the uncompressed tar stream goes, io.Discard when nothing is counted
+func (s *archiveScanner) tarWriter() io.Writer {
+	if s.tar == nil {
+		return io.Discard
+	}
+	return s.tar
+}
+
+// countFiles counts the regular files of a tar stream and their size
+func (s *archiveScanner) countFiles(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			s.files++
+			s.bytes += header.Size
+		}
+	}
+}
+
+// finish waits for the tar stream to be counted and fills in the entry's checksum and counts
+func (s *archiveScanner) finish(entry *ArchiveEntry) error {
+	if s.tar != nil {
+		s.tar.Close()
+		if err := <-s.done; err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", entry.Archive, err)
+		}
+	}
+	entry.SHA256 = hex.EncodeToString(s.hash.Sum(nil))
+	entry.ArchiveBytes = s.archive.n
+	entry.Files = s.files
+	entry.UncompressedBytes = s.bytes
+	return nil
+}
+
+// close stops counting after a failed scan; closing a finished scanner does nothing
+func (s *archiveScanner) close() {
+	if s.tar != nil {
+		s.tar.Close()
+	}
+}
+
+// recordArchive adds a freshly exported archive to the archive manifest and ships the
+// updated manifest to the remote temp directory. checksumFile names the per-file list, if any,
+// and scanned is the entry computed during the export, read back from the archive when empty.
+func (m *Migrator) recordArchive(volume, archivePath, checksumFile string, scanned ArchiveEntry) error {
+	// Archives not streamed through the host, such as squashfs images, were not scanned while written
+	entry := scanned
+	if entry.SHA256 == "" {
+		var err error
+		if entry, err = scanArchive(volume, archivePath, m.config.ArchiveFormat); err != nil {
+			return err
+		}
+	}
+	entry.FileChecksums = checksumFile
+	log.WithFields(logrus.Fields{
+		"volume": volume,
+		"sha256": entry.SHA256,
+		"files":  entry.Files,
+	}).Debug("Recorded archive in manifest")
+
+	m.archives.Archives = append(m.archives.Archives, entry)
+
+	data, err := json.MarshalIndent(m.archives, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive manifest: %w", err)
+	}
+	data = append(data, '\n')
+	localPath := filepath.Join(m.config.TempDir, ArchiveManifestFile)
+	if err := os.WriteFile(localPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+	if err := m.sshClient.TransferFile(localPath, path.Join(m.config.RemoteTempDir, ArchiveManifestFile), false); err != nil {
+		return fmt.Errorf("failed to upload archive manifest: %w", err)
+	}
+
+	if m.signingKey == nil {
+		return nil
+	}
+	signature, err := m.signingKey.Sign(manifestSignatureNamespace, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign archive manifest: %w", err)
+	}
+	localSig := filepath.Join(m.config.TempDir, manifestSignatureFile)
+	if err := os.WriteFile(localSig, signature, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest signature: %w", err)
+	}
+	if err := m.sshClient.TransferFile(localSig, path.Join(m.config.RemoteTempDir, manifestSignatureFile), false); err != nil {
+		return fmt.Errorf("failed to upload manifest signature: %w", err)
+	}
+	return nil
+}
+
+// verifyRemoteArchive checks the signature of the manifest in the remote temp directory and
+// that the uploaded archive matches the checksum it records, before the archive is imported
+func (m *Migrator) verifyRemoteArchive(volume, remoteArchive string) error {
+	manifestData, err := m.sshClient.RunCommand("cat " + shell.ShellEscape(path.Join(m.config.RemoteTempDir, ArchiveManifestFile)))
+	if err != nil {
+		return fmt.Errorf("failed to read remote archive manifest: %w", err)
+	}
+	signature, err := m.sshClient.RunCommand("cat " + shell.ShellEscape(path.Join(m.config.RemoteTempDir, manifestSignatureFile)))
+	if err != nil {
+		return fmt.Errorf("failed to read remote manifest signature: %w", err)
+	}
+	if err := m.signingKey.Verify(manifestSignatureNamespace, []byte(manifestData), []byte(signature)); err != nil {
+		return fmt.Errorf("remote archive manifest is not trusted: %w", err)
+	}
+
+	entry, err := findArchiveEntry([]byte(manifestData), volume, path.Base(remoteArchive))
+	if err != nil {
+		return err
+	}
+	checksumCmd := "sha256sum " + shell.ShellEscape(remoteArchive)
+	if entry.Parts > 0 {
+		parts := make([]string, entry.Parts)
+		for i := range parts {
+			parts[i] = shell.ShellEscape(archivePartName(remoteArchive, i))
+		}
+		checksumCmd = "cat " + strings.Join(parts, " ") + " | sha256sum"
+	}
+	output, err := m.sshClient.RunCommand(checksumCmd)
+	if err != nil {
+		return fmt.Errorf("failed to checksum remote archive: %w", err)
+	}
+	if fields := strings.Fields(output); len(fields) == 0 || fields[0] != entry.SHA256 {
+		return migerrors.NewVerificationError(fmt.Errorf("remote archive %s does not match the signed manifest (sha256 %s, want %s)", entry.Archive, strings.Join(fields[:min(len(fields), 1)], ""), entry.SHA256))
+	}
+
+	log.WithField("volume", volume).Debug("Remote archive matches the signed manifest")
+	return nil
+}
+
+// findArchiveEntry returns the entry of an archive manifest describing archive of volume
+func findArchiveEntry(manifestData []byte, volume, archive string) (ArchiveEntry, error) {
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return ArchiveEntry{}, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	// The latest entry wins, should a volume have been exported twice
+	for i := len(manifest.Archives) - 1; i >= 0; i-- {
+		if entry := manifest.Archives[i]; entry.Volume == volume && entry.Archive == archive {
+			return entry, nil
+		}
+	}
+	return ArchiveEntry{}, fmt.Errorf("archive %s of volume %s is not in the signed manifest", archive, volume)
+}
+
+// finishManifest completes the run's manifest and writes it when --manifest is set
+// A manifest that cannot be written is logged, since the migration itself already finished
+func (m *Migrator) finishManifest(succeeded, failed []string, runErr error) {
+	if m.config.ManifestFile == "" || m.manifest == nil {
+		return
+	}
+	m.manifest.FinishedAt = time.Now()
+	m.manifest.Local, m.manifest.Remote = m.localEnv, m.remoteEnv
+	m.manifest.Archives = m.archives.Archives
+	m.manifest.Succeeded, m.manifest.Failed = succeeded, failed
+	m.manifest.NotMigrated = m.unmigrated
+	if runErr != nil {
+		m.manifest.Error = runErr.Error()
+	}
+	if err := WriteManifest(m.config.ManifestFile, m.manifest); err != nil {
+		log.WithError(err).Error("Failed to write migration manifest")
+		return
+	}
+	log.WithField("manifest", m.config.ManifestFile).Info("Migration manifest written")
+}
@@ -0,0 +1,254 @@
+package migrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestWriteManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := &MigrationManifest{
+		RemoteHost:  "user@host",
+		HelperImage: HelperImageIdentity{Reference: DefaultHelperImage, LocalID: "sha256:abc", RemoteID: "sha256:abc"},
+		Remote:      EnvironmentInfo{DockerVersion: "24.0.7", OSType: "linux"},
+		Succeeded:   []string{"app-data"},
+	}
+	if err := WriteManifest(path, manifest); err != nil {
+		t.Fatalf("WriteManifest() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	image := decoded["helper_image"].(map[string]interface{})
+	if image["reference"] != DefaultHelperImage || image["local_id"] != "sha256:abc" {
+		t.Errorf("unexpected helper image in manifest: %v", image)
+	}
+	if decoded["remote"].(map[string]interface{})["docker_version"] != "24.0.7" {
+		t.Errorf("unexpected remote environment in manifest: %v", decoded["remote"])
+	}
+	if _, ok := decoded["error"]; ok {
+		t.Error("manifest of a successful run must not have an error")
+	}
+}
+
+func TestFinishManifest_Disabled(t *testing.T) {
+	m := &Migrator{config: &Config{}, manifest: &MigrationManifest{}}
+	m.finishManifest([]string{"a"}, nil, nil)
+	if !m.manifest.FinishedAt.IsZero() {
+		t.Error("finishManifest() must not touch the manifest without --manifest")
+	}
+}
+
+func TestFinishManifest_NotMigrated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m := &Migrator{
+		config:     &Config{ManifestFile: path},
+		manifest:   &MigrationManifest{},
+		unmigrated: []docker.UnmigratedMount{{Container: "web", Type: "bind", Source: "/srv/web", Destination: "/data"}},
+	}
+	m.finishManifest([]string{"a"}, nil, nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		NotMigrated []map[string]string `json:"not_migrated"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	if len(decoded.NotMigrated) != 1 || decoded.NotMigrated[0]["type"] != "bind" || decoded.NotMigrated[0]["source"] != "/srv/web" {
+		t.Errorf("not_migrated = %v, want the bind mount", decoded.NotMigrated)
+	}
+}
+
+func TestScanArchive(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "data.tar.gz")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755})
+	for name, content := range map[string]string{"./a.txt": "hello", "./sub/b.txt": "world!"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.WriteHeader(&tar.Header{Name: "./link", Typeflag: tar.TypeSymlink, Linkname: "a.txt"})
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	entry, err := scanArchive("data", archivePath, "")
+	if err != nil {
+		t.Fatalf("scanArchive() error = %v", err)
+	}
+	if entry.Files != 2 || entry.UncompressedBytes != 11 {
+		t.Errorf("scanArchive() = %d files, %d bytes, want 2 files, 11 bytes", entry.Files, entry.UncompressedBytes)
+	}
+
+	data, _ := os.ReadFile(archivePath)
+	sum := sha256.Sum256(data)
+	if entry.SHA256 != hex.EncodeToString(sum[:]) || entry.ArchiveBytes != int64(len(data)) {
+		t.Errorf("scanArchive() checksum %s over %d bytes, want %x over %d", entry.SHA256, entry.ArchiveBytes, sum, len(data))
+	}
+	if entry.Volume != "data" || entry.Archive != "data.tar.gz" {
+		t.Errorf("unexpected entry names: %+v", entry)
+	}
+
+	if err := os.WriteFile(archivePath, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanArchive("data", archivePath, ""); err == nil {
+		t.Error("scanArchive() expected error for a corrupt archive")
+	}
+}
+
+func TestScanArchive_Formats(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "./a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+
+	tests := []struct {
+		format    string
+		wantFiles int
+	}{
+		{FormatTar, 1},
+		{FormatSquashfs, 0}, // Only hashed
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			archivePath := filepath.Join(dir, archiveFileName("data", tt.format))
+			if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			entry, err := scanArchive("data", archivePath, tt.format)
+			if err != nil {
+				t.Fatalf("scanArchive() error = %v", err)
+			}
+			if entry.Files != tt.wantFiles || entry.ArchiveBytes != int64(buf.Len()) {
+				t.Errorf("scanArchive() = %d files over %d bytes, want %d files over %d", entry.Files, entry.ArchiveBytes, tt.wantFiles, buf.Len())
+			}
+		})
+	}
+}
+
+func TestScanArchive_Split(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "data.tar")
+	w := newSplitWriter(archivePath, 700)
+	tw := tar.NewWriter(w)
+	tw.WriteHeader(&tar.Header{Name: "./a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+	w.Close()
+
+	entry, err := scanArchive("data", archivePath, FormatTar)
+	if err != nil {
+		t.Fatalf("scanArchive() error = %v", err)
+	}
+	if entry.Files != 1 || entry.Parts != len(w.parts) || entry.Parts < 2 {
+		t.Errorf("scanArchive() = %d files in %d parts, want 1 file in %d parts", entry.Files, entry.Parts, len(w.parts))
+	}
+	if entry.Archive != "data.tar" {
+		t.Errorf("Archive = %s, want data.tar", entry.Archive)
+	}
+}
+
+func TestWriteVolumeArchive_Scan(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "./a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.WriteHeader(&tar.Header{Name: "./sub", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "./sub/b.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 6})
+	tw.Write([]byte(" world"))
+	tw.Close()
+
+	tests := []struct {
+		name string
+		opts ExportOptions
+	}{
+		{"tar.gz", ExportOptions{}},
+		{"tar", ExportOptions{Format: FormatTar}},
+		{"auto compress", ExportOptions{AutoCompress: true}},
+		{"split", ExportOptions{MaxArchiveSize: 100}},
+		{"zstd", ExportOptions{Format: FormatTarZst}}, // Only hashed
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), archiveFileName("data", tt.opts.Format))
+			local := &fakeDocker{responses: map[string]fakeResponse{"run": {output: buf.String()}}}
+			var scanned ArchiveEntry
+			tt.opts.Scan = &scanned
+
+			if err := writeVolumeArchive(local, "data", archivePath, tt.opts); err != nil {
+				t.Fatalf("writeVolumeArchive() error = %v", err)
+			}
+			want, err := scanArchive("data", archivePath, tt.opts.Format)
+			if err != nil {
+				t.Fatalf("scanArchive() error = %v", err)
+			}
+			if scanned != want {
+				t.Errorf("scanned entry = %+v, want %+v as read back", scanned, want)
+			}
+			if tt.opts.Format != FormatTarZst && scanned.Files != 2 {
+				t.Errorf("scanned %d files, want 2", scanned.Files)
+			}
+		})
+	}
+}
+
+func TestFindArchiveEntry(t *testing.T) {
+	data := []byte(`{"archives":[
+		{"volume":"app","archive":"app.tar.gz","sha256":"old"},
+		{"volume":"db","archive":"db.tar.gz","sha256":"db1"},
+		{"volume":"app","archive":"app.tar.gz","sha256":"new"}
+	]}`)
+
+	tests := []struct {
+		name    string
+		volume  string
+		archive string
+		want    string
+		wantErr bool
+	}{
+		{"latest entry wins", "app", "app.tar.gz", "new", false},
+		{"other volume", "db", "db.tar.gz", "db1", false},
+		{"unknown volume", "cache", "cache.tar.gz", "", true},
+		{"archive renamed", "db", "app.tar.gz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := findArchiveEntry(data, tt.volume, tt.archive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findArchiveEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if entry.SHA256 != tt.want {
+				t.Errorf("findArchiveEntry() sha256 = %q, want %q", entry.SHA256, tt.want)
+			}
+		})
+	}
+}
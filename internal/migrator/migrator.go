@@ -1,16 +1,22 @@
 package migrator
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
 	"volume-migrator/internal/ssh"
 	"volume-migrator/internal/ui"
 	"volume-migrator/internal/utils"
@@ -24,28 +30,174 @@ func init() {
 
 // Config holds migration configuration
 type Config struct {
-	Containers            []string
-	RemoteHost            string
-	SSHKeyPath            string
-	SSHPort               string
-	TempDir               string
-	RemoteTempDir         string
-	Interactive           bool
-	Verbose               bool
-	DryRun                bool
-	NoCleanup             bool
-	ShowProgress          bool
-	StrictHostKeyChecking bool
-	AcceptHostKey         bool
-	KnownHostsFile        string
-	Force                 bool
+	Containers            []string            `yaml:"containers,omitempty"`
+	RemoteHost            string              `yaml:"remote_host,omitempty"`
+	SSHKeyPaths           []string            `yaml:"ssh_key_paths,omitempty"`
+	SSHPort               string              `yaml:"ssh_port,omitempty"`
+	TempDir               string              `yaml:"-"`
+	RemoteTempDir         string              `yaml:"-"`
+	Interactive           bool                `yaml:"-"`
+	AssumeYes             bool                `yaml:"-"` // Start without asking for confirmation
+	Verbose               bool                `yaml:"-"`
+	DryRun                bool                `yaml:"-"`
+	NoCleanup             bool                `yaml:"-"`
+	ShowProgress          bool                `yaml:"-"`
+	StrictHostKeyChecking bool                `yaml:"strict_host_key_checking,omitempty"`
+	AcceptHostKey         bool                `yaml:"accept_host_key,omitempty"`
+	KnownHostsFile        string              `yaml:"known_hosts_file,omitempty"`
+	Force                 bool                `yaml:"force,omitempty"` // Skip disk space checks and continue past compatibility failures
+	DBMode                string              `yaml:"db_mode,omitempty"`
+	PreExportHooks        map[string]string   `yaml:"pre_export_hooks,omitempty"` // Command run in the owning container before each volume is exported, by volume name
+	Quiesce               string              `yaml:"quiesce,omitempty"`          // off (default) or auto, running the built-in recipe of known datastores around each export
+	HelperImage           string              `yaml:"helper_image,omitempty"`
+	HelperImageTar        string              `yaml:"helper_image_tar,omitempty"`
+	PreserveXattrs        bool                `yaml:"preserve_xattrs,omitempty"`
+	PreserveACLs          bool                `yaml:"preserve_acls,omitempty"`
+	Sparse                bool                `yaml:"sparse,omitempty"`
+	UIDMap                []string            `yaml:"uid_map,omitempty"`
+	GIDMap                []string            `yaml:"gid_map,omitempty"`
+	NumericOwner          bool                `yaml:"numeric_owner,omitempty"`
+	ExcludeVolumes        []string            `yaml:"exclude_volumes,omitempty"`
+	MinSize               string              `yaml:"min_size,omitempty"` // Skip volumes smaller than this (e.g. 1MB)
+	MaxSize               string              `yaml:"max_size,omitempty"` // Skip volumes larger than this (e.g. 50GB)
+	AllContainers         bool                `yaml:"all_containers,omitempty"`
+	ContainerFilters      []string            `yaml:"container_filters,omitempty"`
+	ByVolume              bool                `yaml:"by_volume,omitempty"`
+	Volumes               []string            `yaml:"volumes,omitempty"`
+	AnonymousVolumes      string              `yaml:"anonymous_volumes,omitempty"`
+	ChunkSize             string              `yaml:"chunk_size,omitempty"`
+	MaxArchiveSize        string              `yaml:"max_archive_size,omitempty"` // Split archives into parts of at most this size, empty for one file
+	Incremental           bool                `yaml:"incremental,omitempty"`
+	StateDir              string              `yaml:"state_dir,omitempty"`
+	Dedup                 bool                `yaml:"dedup,omitempty"`
+	ArchiveFormat         string              `yaml:"archive_format,omitempty"` // tar, tar.gz (default), tar.zst or squashfs
+	CompressionLevel      int                 `yaml:"compression_level,omitempty"`
+	AutoCompress          bool                `yaml:"auto_compress,omitempty"`
+	AdaptiveCompression   bool                `yaml:"adaptive_compression,omitempty"` // Benchmark each volume and the link to pick the gzip level
+	ContinueOnError       bool                `yaml:"continue_on_error,omitempty"`
+	ForceLock             bool                `yaml:"-"`
+	ForceInUse            bool                `yaml:"force_in_use,omitempty"`          // Import into remote volumes that running containers mount
+	StopRemoteConsumers   bool                `yaml:"stop_remote_consumers,omitempty"` // Stop those containers for the migration and start them again afterwards
+	DeepVerify            bool                `yaml:"deep_verify,omitempty"`           // Check a sha256 of every file after import
+	SignKey               string              `yaml:"sign_key,omitempty"`              // SSH private key signing manifest.json, verified before each import
+	SSHOptions            []string            `yaml:"ssh_options,omitempty"`
+	GSSAPI                bool                `yaml:"gssapi,omitempty"`
+	ProxyCommand          string              `yaml:"proxy_command,omitempty"`
+	RemoteSudoPassword    bool                `yaml:"remote_sudo_password,omitempty"`    // Prompt for the remote sudo password when sudo -n is refused
+	LocalEscalation       string              `yaml:"local_escalation,omitempty"`        // auto, none, sudo or doas for local docker commands
+	RemoteEscalation      string              `yaml:"remote_escalation,omitempty"`       // auto, none, sudo or doas for remote docker commands
+	MinLocalVersion       string              `yaml:"min_local_version,omitempty"`       // Oldest local engine accepted, empty for no minimum
+	MinRemoteVersion      string              `yaml:"min_remote_version,omitempty"`      // Oldest remote engine accepted, empty for no minimum
+	HelperRegistry        string              `yaml:"helper_registry,omitempty"`         // Docker Hub mirror the default helper images are pulled from
+	HelperBinary          string              `yaml:"helper_binary,omitempty"`           // Static busybox (path or "embedded") to build the helper image from
+	ManifestFile          string              `yaml:"manifest_file,omitempty"`           // Where to write the JSON manifest of the run, empty for none
+	ComposeFile           string              `yaml:"compose_file,omitempty"`            // Where to write a compose file recreating the source containers, empty for none
+	PrintRunCommands      bool                `yaml:"print_run_commands,omitempty"`      // Print docker run commands recreating the source containers
+	StartRemote           bool                `yaml:"start_remote,omitempty"`            // Recreate the source containers on the remote and wait for them to come up
+	HealthTimeout         time.Duration       `yaml:"health_timeout,omitempty"`          // How long StartRemote waits, 0 for DefaultHealthTimeout
+	Watch                 bool                `yaml:"watch,omitempty"`                   // After the migration, keep pushing changes until interrupted
+	WatchInterval         time.Duration       `yaml:"watch_interval,omitempty"`          // How often Watch checks for changes, 0 for DefaultWatchInterval
+	StandbyInterval       time.Duration       `yaml:"standby_interval,omitempty"`        // Repeat the migration this often until interrupted (see Standby), 0 for once
+	ImportMethod          string              `yaml:"import_method,omitempty"`           // archive (default), stream or cp
+	KeepRemoteArchives    int                 `yaml:"keep_remote_archives,omitempty"`    // Generations of imported archives kept on the remote, 0 deletes them
+	RemoteArchiveDir      string              `yaml:"remote_archive_dir,omitempty"`      // Where kept archives go, empty for DefaultRemoteArchiveDir
+	SSHTimeout            time.Duration       `yaml:"ssh_timeout,omitempty"`             // SSH connect and handshake timeout, 0 for the ConnectTimeout option or 30s
+	CommandTimeout        time.Duration       `yaml:"command_timeout,omitempty"`         // Bounds each remote and local docker command, 0 for none
+	TransferStallTimeout  time.Duration       `yaml:"transfer_stall_timeout,omitempty"`  // Aborts transfers that move no data for this long, 0 for never
+	TransferStallRestarts int                 `yaml:"transfer_stall_restarts,omitempty"` // Times a stalled upload or download is restarted, resuming where it stopped
+	MaxSessions           int                 `yaml:"max_sessions,omitempty"`            // Remote commands run at once over the SSH connection, 0 for ssh.DefaultMaxSessions
+	ParallelStreams       int                 `yaml:"parallel_streams,omitempty"`        // SFTP sessions a single large upload is split over, 0 or 1 for one
+	BufferSize            string              `yaml:"buffer_size,omitempty"`             // Size of each SFTP packet and download copy, empty for ssh.DefaultBufferSize
+	MinFreeSpace          string              `yaml:"min_free_space,omitempty"`          // Abort when either temp directory has less free space, empty for DefaultMinFreeSpace, 0 for never
+	TransferBackend       string              `yaml:"transfer_backend,omitempty"`        // How files are uploaded: auto (default), sftp, shell or a plugin name
+	Trace                 bool                `yaml:"-"`                                 // Log every executed command line with its exit code and duration
+	LogFile               string              `yaml:"-"`                                 // Also append the log to this file, empty for none
+	SavePlan              string              `yaml:"-"`                                 // Write the resolved plan to this file, empty for none
+	PlannedVolumes        []docker.VolumeInfo `yaml:"-"`                                 // Volumes of an applied plan, checked instead of discovered
+	SizeTolerance         float64             `yaml:"-"`                                 // Percent planned volume sizes may differ by
 }
 
 // ValidateConfig validates the migration configuration
 func ValidateConfig(config *Config) error {
-	// Validate containers are non-empty
-	if len(config.Containers) == 0 {
-		return fmt.Errorf("no containers specified")
+	// Validate volume names when they are given directly instead of containers
+	if config.ByVolume {
+		if config.AllContainers {
+			return fmt.Errorf("conflicting flags: --by-volume and --all cannot both be enabled")
+		}
+		if len(config.Containers) > 0 {
+			return fmt.Errorf("conflicting arguments: containers cannot be combined with --by-volume")
+		}
+		if len(config.Volumes) == 0 {
+			return fmt.Errorf("no volumes specified")
+		}
+		for i, volume := range config.Volumes {
+			if !shell.ValidateVolumeName(volume) {
+				return fmt.Errorf("invalid volume name '%s' at index %d: must contain only alphanumeric characters, dashes, underscores, and dots", volume, i)
+			}
+		}
+	} else if len(config.Volumes) > 0 {
+		return fmt.Errorf("volume names require --by-volume")
+	}
+
+	// Validate containers are non-empty (unless they are enumerated with --all or volumes are given)
+	if config.AllContainers {
+		if len(config.Containers) > 0 {
+			return fmt.Errorf("conflicting arguments: container names cannot be combined with --all")
+		}
+	} else if !config.ByVolume && len(config.Containers) == 0 {
+		return fmt.Errorf("no containers specified (pass container names or use --all)")
+	}
+
+	if config.ComposeFile != "" && config.ByVolume {
+		return fmt.Errorf("conflicting flags: --compose-file describes the source containers, which --by-volume does not have")
+	}
+	if config.PrintRunCommands && config.ByVolume {
+		return fmt.Errorf("conflicting flags: --print-run-commands describes the source containers, which --by-volume does not have")
+	}
+	if config.StartRemote && config.ByVolume {
+		return fmt.Errorf("conflicting flags: --start-remote recreates the source containers, which --by-volume does not have")
+	}
+	if config.HealthTimeout != 0 && !config.StartRemote {
+		return fmt.Errorf("--health-timeout requires --start-remote")
+	}
+
+	// Validate watch mode: every sync after the first must be a delta
+	if config.Watch {
+		if !config.Incremental {
+			return fmt.Errorf("--watch requires --incremental, so each sync only sends the changes")
+		}
+		if config.StartRemote {
+			return fmt.Errorf("conflicting flags: --watch keeps importing into the volumes, which --start-remote would have in use")
+		}
+		if config.KeepRemoteArchives > 0 {
+			return fmt.Errorf("conflicting flags: --watch replaces each volume's archive on every sync, so --keep-remote-archives cannot keep them")
+		}
+	}
+	if config.WatchInterval != 0 && !config.Watch {
+		return fmt.Errorf("--watch-interval requires --watch")
+	}
+
+	// Validate standby syncs: unattended runs that only send the changes
+	if config.StandbyInterval > 0 {
+		if !config.Incremental {
+			return fmt.Errorf("--standby-interval requires --incremental, so each run only sends the changes")
+		}
+		if !config.AssumeYes {
+			return fmt.Errorf("--standby-interval runs unattended and requires --yes")
+		}
+		if config.Interactive || config.DryRun || config.Watch || config.StartRemote {
+			return fmt.Errorf("conflicting flags: --standby-interval cannot be combined with --interactive, --dry-run, --watch or --start-remote")
+		}
+		if config.RemoteSudoPassword {
+			return fmt.Errorf("conflicting flags: --remote-sudo-password would prompt on every standby run; allow passwordless sudo or docker access instead")
+		}
+	}
+
+	if len(config.ContainerFilters) > 0 && !config.AllContainers {
+		return fmt.Errorf("--filter requires --all")
+	}
+	if err := ValidateContainerFilters(config.ContainerFilters); err != nil {
+		return err
 	}
 
 	// Validate each container name is non-empty
@@ -55,7 +207,7 @@ func ValidateConfig(config *Config) error {
 		}
 	}
 
-	// Validate remote host format (user@host or user@host:port)
+	// Validate remote host format (user@host or user@host:port, IPv6 as user@[addr]:port)
 	if config.RemoteHost == "" {
 		return fmt.Errorf("remote host not specified")
 	}
@@ -82,6 +234,10 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("host cannot be empty in remote host: %s", config.RemoteHost)
 	}
 
+	if strings.HasPrefix(hostPart, "[") && !strings.Contains(hostPart, "]") {
+		return fmt.Errorf("missing ']' in IPv6 remote host: %s", config.RemoteHost)
+	}
+
 	// Validate SSH port if specified
 	if config.SSHPort != "" {
 		port, err := strconv.Atoi(config.SSHPort)
@@ -98,19 +254,276 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("temp directory must be an absolute path: %s", config.TempDir)
 	}
 
-	if config.RemoteTempDir != "" && !filepath.IsAbs(config.RemoteTempDir) {
+	if config.RemoteTempDir != "" && !path.IsAbs(config.RemoteTempDir) {
 		return fmt.Errorf("remote temp directory must be an absolute path: %s", config.RemoteTempDir)
 	}
 
+	// Validate privilege escalation methods
+	if _, err := shell.ParseEscalation(config.LocalEscalation); err != nil {
+		return fmt.Errorf("--local-escalation: %w", err)
+	}
+	if _, err := shell.ParseEscalation(config.RemoteEscalation); err != nil {
+		return fmt.Errorf("--remote-escalation: %w", err)
+	}
+	if remote := strings.ToLower(config.RemoteEscalation); config.RemoteSudoPassword && (remote == shell.EscalationNone.Name || remote == shell.EscalationDoas.Name) {
+		return fmt.Errorf("conflicting flags: --remote-sudo-password requires --remote-escalation auto or sudo")
+	}
+
+	// Validate minimum Docker versions
+	if err := ValidateMinDockerVersion("--min-local-docker-version", config.MinLocalVersion); err != nil {
+		return err
+	}
+	if err := ValidateMinDockerVersion("--min-remote-docker-version", config.MinRemoteVersion); err != nil {
+		return err
+	}
+
 	// Validate conflicting flags
 	if config.StrictHostKeyChecking && config.AcceptHostKey {
 		return fmt.Errorf("conflicting flags: --strict-host-key-checking and --accept-host-key cannot both be enabled")
 	}
 
-	// Validate SSH key path exists if specified
-	if config.SSHKeyPath != "" {
-		if _, err := os.Stat(config.SSHKeyPath); os.IsNotExist(err) {
-			return fmt.Errorf("SSH key file does not exist: %s", config.SSHKeyPath)
+	// Validate database dump mode
+	if err := ValidateDBMode(config.DBMode); err != nil {
+		return err
+	}
+
+	// Validate helper image reference
+	if err := ValidateHelperImageReference(config.HelperImage); err != nil {
+		return err
+	}
+
+	if err := ValidateHelperRegistry(config.HelperRegistry); err != nil {
+		return err
+	}
+
+	// Validate the static helper binary: it replaces the helper image and only offers busybox tar
+	if config.HelperBinary != "" {
+		if config.HelperImage != "" || config.HelperImageTar != "" || config.HelperRegistry != "" {
+			return fmt.Errorf("conflicting flags: --helper-binary cannot be combined with --helper-image, --helper-image-tar or --helper-registry")
+		}
+		if config.PreserveXattrs || config.PreserveACLs || config.Sparse || config.Incremental {
+			return fmt.Errorf("conflicting flags: --helper-binary provides busybox tar, which cannot preserve xattrs, ACLs or sparse files or run incremental migrations")
+		}
+		if config.HelperBinary != EmbeddedHelperBinary {
+			if _, err := os.Stat(config.HelperBinary); err != nil {
+				return fmt.Errorf("helper binary does not exist: %s", config.HelperBinary)
+			}
+		}
+	}
+
+	// Validate helper image bundle exists if specified
+	if config.HelperImageTar != "" {
+		if _, err := os.Stat(config.HelperImageTar); os.IsNotExist(err) {
+			return fmt.Errorf("helper image tar does not exist: %s", config.HelperImageTar)
+		}
+	}
+
+	// Validate ownership mappings
+	if _, err := ParseIDMappings("uid", config.UIDMap); err != nil {
+		return err
+	}
+	if _, err := ParseIDMappings("gid", config.GIDMap); err != nil {
+		return err
+	}
+
+	// Validate anonymous volume handling
+	if err := ValidateAnonymousMode(config.AnonymousVolumes); err != nil {
+		return err
+	}
+
+	// Validate incremental state directory
+	if config.StateDir != "" && !filepath.IsAbs(config.StateDir) {
+		return fmt.Errorf("state directory must be an absolute path: %s", config.StateDir)
+	}
+
+	// Validate deduplication compatibility
+	if config.Dedup && config.Incremental {
+		return fmt.Errorf("conflicting flags: --dedup and --incremental cannot both be enabled")
+	}
+	if config.Dedup && config.Sparse {
+		return fmt.Errorf("conflicting flags: --dedup and --sparse cannot both be enabled")
+	}
+	if config.AdaptiveCompression && config.AutoCompress {
+		return fmt.Errorf("conflicting flags: --adaptive-compression and --auto-compress cannot both be enabled")
+	}
+
+	// Validate archive format compatibility
+	if err := ValidateArchiveFormat(config.ArchiveFormat); err != nil {
+		return err
+	}
+	format := archiveFormat(config.ArchiveFormat)
+	if format != FormatTarGz {
+		if config.AutoCompress {
+			return fmt.Errorf("conflicting flags: --auto-compress requires --format tar.gz")
+		}
+		if config.AdaptiveCompression {
+			return fmt.Errorf("conflicting flags: --adaptive-compression requires --format tar.gz")
+		}
+		if config.DBMode != "" {
+			return fmt.Errorf("conflicting flags: --db-mode requires --format tar.gz")
+		}
+	}
+	if !isTarFormat(format) {
+		if config.HelperImage == "" {
+			local, remote := formatTools(format)
+			return fmt.Errorf("--format %s requires --helper-image with an image providing %s and %s", format, local, remote)
+		}
+		if config.Dedup {
+			return fmt.Errorf("conflicting flags: --dedup requires --format tar or tar.gz")
+		}
+		if config.Incremental {
+			return fmt.Errorf("conflicting flags: --incremental requires --format tar or tar.gz")
+		}
+		if config.ImportMethod == ImportMethodCopy {
+			return fmt.Errorf("conflicting flags: --import-method cp requires --format tar or tar.gz")
+		}
+	}
+	if format == FormatSquashfs && config.ImportMethod == ImportMethodStream {
+		return fmt.Errorf("conflicting flags: --import-method stream cannot be combined with --format squashfs, which unsquashfs reads from a file")
+	}
+
+	// Validate the compression level (0 selects the default): zstd goes up to MaxZstdLevel, gzip and squashfs to 9
+	maxLevel := gzip.BestCompression
+	if format == FormatTarZst {
+		maxLevel = MaxZstdLevel
+	}
+	if config.CompressionLevel < 0 || config.CompressionLevel > maxLevel {
+		return fmt.Errorf("invalid compression level %d: must be between 0 (default) and %d for --format %s", config.CompressionLevel, maxLevel, format)
+	}
+
+	// Validate SSH option passthrough
+	if _, err := ssh.ParseOptions(config.SSHOptions); err != nil {
+		return err
+	}
+
+	// Validate transfer chunk size
+	if config.ChunkSize != "" {
+		if _, err := utils.ParseSize(config.ChunkSize); err != nil {
+			return fmt.Errorf("invalid chunk size: %w", err)
+		}
+	}
+
+	if err := ssh.ValidateMaxSessions(config.MaxSessions); err != nil {
+		return err
+	}
+
+	// Validate parallel uploads, which write ranges of the remote file over SFTP
+	if err := ssh.ValidateParallelStreams(config.ParallelStreams); err != nil {
+		return err
+	}
+	if config.ParallelStreams > 1 && config.TransferBackend != "" && config.TransferBackend != ssh.TransferBackendAuto && config.TransferBackend != ssh.TransferBackendSFTP {
+		return fmt.Errorf("conflicting flags: --parallel-streams needs the sftp transfer backend, not --transfer-backend %s", config.TransferBackend)
+	}
+
+	// Validate the transfer buffer size
+	if config.BufferSize != "" {
+		size, err := utils.ParseSize(config.BufferSize)
+		if err != nil {
+			return fmt.Errorf("invalid buffer size: %w", err)
+		}
+		if err := ssh.ValidateBufferSize(size); err != nil {
+			return err
+		}
+	}
+
+	// Validate the free space kept while migrating
+	if config.MinFreeSpace != "" {
+		if _, err := utils.ParseSize(config.MinFreeSpace); err != nil {
+			return fmt.Errorf("invalid min free space: %w", err)
+		}
+	}
+
+	// Validate archive splitting: the remote concatenates the uploaded parts while extracting
+	if config.MaxArchiveSize != "" {
+		if size, err := utils.ParseSize(config.MaxArchiveSize); err != nil {
+			return fmt.Errorf("invalid max archive size: %w", err)
+		} else if size <= 0 {
+			return fmt.Errorf("invalid max archive size %s: must be greater than zero", config.MaxArchiveSize)
+		}
+		if config.ImportMethod == ImportMethodStream || config.ImportMethod == ImportMethodCopy {
+			return fmt.Errorf("conflicting flags: --max-archive-size splits uploaded archives, which --import-method %s does not create", config.ImportMethod)
+		}
+		if config.DBMode != "" {
+			return fmt.Errorf("conflicting flags: --max-archive-size cannot be combined with --db-mode")
+		}
+		if archiveFormat(config.ArchiveFormat) == FormatSquashfs {
+			return fmt.Errorf("conflicting flags: --max-archive-size cannot be combined with --format squashfs, which unsquashfs reads from a single file")
+		}
+	}
+
+	// Validate timeouts
+	for _, t := range []struct {
+		flag    string
+		timeout time.Duration
+	}{
+		{"--ssh-timeout", config.SSHTimeout},
+		{"--command-timeout", config.CommandTimeout},
+		{"--transfer-stall-timeout", config.TransferStallTimeout},
+		{"--health-timeout", config.HealthTimeout},
+		{"--watch-interval", config.WatchInterval},
+		{"--standby-interval", config.StandbyInterval},
+	} {
+		if t.timeout < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", t.flag, t.timeout)
+		}
+	}
+
+	if err := ssh.ValidateStallRestarts(config.TransferStallRestarts); err != nil {
+		return err
+	}
+
+	if config.SignKey != "" {
+		if _, err := os.Stat(config.SignKey); err != nil {
+			return fmt.Errorf("manifest signing key does not exist: %s", config.SignKey)
+		}
+		if config.ImportMethod == ImportMethodStream || config.ImportMethod == ImportMethodCopy {
+			return fmt.Errorf("conflicting flags: --sign-manifest verifies uploaded archives, which --import-method %s does not create", config.ImportMethod)
+		}
+	}
+
+	// Validate the import method
+	if err := ValidateImportMethod(config.ImportMethod); err != nil {
+		return err
+	}
+	if err := ValidateQuiesce(config.Quiesce); err != nil {
+		return err
+	}
+	if err := ssh.ValidateTransferBackend(config.TransferBackend); err != nil {
+		return err
+	}
+	if config.ImportMethod == ImportMethodStream || config.ImportMethod == ImportMethodCopy {
+		if config.ChunkSize != "" {
+			return fmt.Errorf("conflicting flags: --chunk-size cannot be combined with --import-method %s, which does not store the archive remotely", config.ImportMethod)
+		}
+	}
+	if config.KeepRemoteArchives < 0 {
+		return fmt.Errorf("--keep-remote-archives must not be negative, got %d", config.KeepRemoteArchives)
+	}
+	if config.KeepRemoteArchives > 0 && (config.ImportMethod == ImportMethodStream || config.ImportMethod == ImportMethodCopy) {
+		return fmt.Errorf("conflicting flags: --keep-remote-archives keeps uploaded archives, which --import-method %s does not create", config.ImportMethod)
+	}
+	if config.RemoteArchiveDir != "" && !path.IsAbs(config.RemoteArchiveDir) {
+		return fmt.Errorf("remote archive directory must be an absolute path: %s", config.RemoteArchiveDir)
+	}
+	if config.ImportMethod == ImportMethodCopy {
+		// Nothing runs inside the volume after docker cp, so no remapping, deletes, dedup or ACLs
+		if len(config.UIDMap) > 0 || len(config.GIDMap) > 0 || config.Incremental || config.Dedup || config.PreserveACLs {
+			return fmt.Errorf("conflicting flags: --import-method cp cannot be combined with --uid-map, --gid-map, --incremental, --dedup or --preserve-acls")
+		}
+	}
+
+	// Validate volume exclusion patterns
+	if err := ValidateExcludePatterns(config.ExcludeVolumes); err != nil {
+		return err
+	}
+	if err := ValidateSizeFilters(config.MinSize, config.MaxSize); err != nil {
+		return err
+	}
+
+	// Validate SSH key paths exist if specified
+	for _, keyPath := range config.SSHKeyPaths {
+		if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+			return fmt.Errorf("SSH key file does not exist: %s", keyPath)
 		}
 	}
 
@@ -126,15 +539,34 @@ func ValidateConfig(config *Config) error {
 
 // Migrator orchestrates the volume migration process
 type Migrator struct {
-	config       *Config
-	dockerClient *docker.Client
-	sshClient    *ssh.Client
-	ctx          context.Context
+	config         *Config
+	dockerClient   DockerRunner
+	sshClient      RemoteExecutor
+	sshCleanup     RemoteExecutor           // sshClient without cancellation, so cleanup still runs after Ctrl+C
+	dockerCleanup  DockerRunner             // dockerClient without cancellation, for resuming quiesced datastores after Ctrl+C
+	unmigrated     []docker.UnmigratedMount // Bind and tmpfs mounts of the containers, left behind
+	ctx            context.Context
+	abort          context.CancelCauseFunc // Cancels ctx when the disk space monitor stops the run
+	helperImage    string                  // Helper image resolved for this run, used by every export and import
+	dedup          *DedupIndex             // Content already sent during this run (nil unless --dedup)
+	localEnv       EnvironmentInfo
+	remoteEnv      EnvironmentInfo
+	manifest       *MigrationManifest // Filled in as the run progresses, written with --manifest
+	archives       ArchiveManifest    // Archives exported so far, shipped as manifest.json
+	signingKey     *ssh.SigningKey    // Loaded from --sign-manifest, nil when manifests are not signed
+	transfers      []volumeTransfer
+	remoteArchives []string                // Imported archives kept with --keep-remote-archives
+	watchBaselines map[string]fileManifest // Files of each volume as last sent, with --watch
+	linkRate       float64                 // Upload rate measured for --adaptive-compression, bytes/second
+	volumeCount    int                     // Volumes selected for the run, for telemetry
+
+	autoTempDir       bool // TempDir was defaulted, so it is moved off a tmpfs or nearly full system temp directory
+	autoRemoteTempDir bool // RemoteTempDir was defaulted, so it is moved to the roomiest remote directory
 }
 
 // NewMigrator creates a new migrator instance
 func NewMigrator(ctx context.Context, config *Config) (*Migrator, error) {
-	if len(config.Containers) == 0 {
+	if len(config.Containers) == 0 && !config.AllContainers && !config.ByVolume {
 		return nil, fmt.Errorf("no containers specified")
 	}
 
@@ -143,17 +575,25 @@ func NewMigrator(ctx context.Context, config *Config) (*Migrator, error) {
 	}
 
 	// Set default temp directories if not specified
-	if config.TempDir == "" {
+	autoTempDir := config.TempDir == ""
+	if autoTempDir {
 		config.TempDir = filepath.Join(os.TempDir(), fmt.Sprintf("volume-migration-%d", time.Now().Unix()))
 	}
 
-	if config.RemoteTempDir == "" {
+	autoRemoteTempDir := config.RemoteTempDir == ""
+	if autoRemoteTempDir {
 		config.RemoteTempDir = fmt.Sprintf("/tmp/volume-migration-%d", time.Now().Unix())
 	}
 
+	if config.RemoteArchiveDir == "" {
+		config.RemoteArchiveDir = DefaultRemoteArchiveDir
+	}
+
 	return &Migrator{
-		config: config,
-		ctx:    ctx,
+		config:            config,
+		ctx:               ctx,
+		autoTempDir:       autoTempDir,
+		autoRemoteTempDir: autoRemoteTempDir,
 	}, nil
 }
 
@@ -161,43 +601,102 @@ func NewMigrator(ctx context.Context, config *Config) (*Migrator, error) {
 func (m *Migrator) Migrate() error {
 	// Set verbose logging
 	utils.SetVerbose(m.config.Verbose)
+	utils.SetTrace(m.config.Trace)
+	if m.config.LogFile != "" {
+		closeLog, err := utils.SetLogFile(m.config.LogFile)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+	}
+	started := time.Now()
+
+	ctx, abort := context.WithCancelCause(m.ctx)
+	defer abort(nil)
+	m.ctx, m.abort = ctx, abort
 
 	// Phase 1: Initialize Docker client
 	log.Info("=== Phase 1: Initialization ===")
+	m.selectLocalTempDir()
 
-	dockerClient, err := docker.NewClient(m.ctx)
+	dockerClient, err := localDockerClient(m.ctx, m.config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Docker client: %w", err)
 	}
 	m.dockerClient = dockerClient
+	m.dockerCleanup = dockerClient.WithoutCancel()
+
+	log.WithField("escalation", dockerClient.Escalation().Name).Debug("Local Docker privilege escalation detection complete")
+
+	localVersion, err := localDockerVersion(dockerClient)
+	if err != nil {
+		return migerrors.NewDockerError("local", err)
+	}
+	if err := checkMinimumVersion("local", localVersion, m.config.MinLocalVersion); err != nil {
+		return migerrors.NewDockerError("local", err)
+	}
+	if utils.IsWSL2() {
+		log.WithField("docker_host", dockerClient.DaemonHost()).Debug("Running inside WSL2")
+	}
+	if dockerClient.IsDockerDesktop() {
+		log.Info("Local daemon is Docker Desktop: volume data is read through helper containers")
+	}
 
-	log.WithField("requires_sudo", dockerClient.RequiresSudo()).Debug("Local Docker sudo detection complete")
+	if m.config.AllContainers && m.config.PlannedVolumes == nil {
+		if err := m.enumerateContainers(); err != nil {
+			return err
+		}
+		if len(m.config.Containers) == 0 {
+			log.Warn("No containers found to migrate")
+			return nil
+		}
+	}
 
 	// Phase 2: Establish SSH connection
 	log.WithField("remote_host", m.config.RemoteHost).Info("Connecting to remote host")
 
-	sshConfig := &ssh.ClientConfig{
-		HostString:            m.config.RemoteHost,
-		CustomKeyPath:         m.config.SSHKeyPath,
-		StrictHostKeyChecking: m.config.StrictHostKeyChecking,
-		AcceptHostKey:         m.config.AcceptHostKey,
-		KnownHostsFile:        m.config.KnownHostsFile,
+	sshConfig, err := sshClientConfig(m.config)
+	if err != nil {
+		return err
 	}
 
 	sshClient, err := ssh.NewClient(m.ctx, sshConfig)
 	if err != nil {
-		return fmt.Errorf("failed to connect to remote host: %w", err)
+		return connectError(m.config.RemoteHost, err)
 	}
 	m.sshClient = sshClient
+	m.sshCleanup = sshClient.WithoutCancel()
 	defer sshClient.Close()
 
-	log.WithField("requires_sudo", sshClient.RequiresSudo()).Debug("Remote Docker sudo detection complete")
+	log.WithField("escalation", sshClient.Escalation().Name).Debug("Remote Docker privilege escalation detection complete")
+
+	backend := sshClient.TransferBackend()
+	log.WithField("transfer_backend", backend).Debug("Selected transfer backend")
+	if backend == ssh.TransferBackendShell && m.config.TransferBackend != ssh.TransferBackendShell {
+		log.Info("Remote host offers no SFTP subsystem, uploading through cat over SSH")
+		if m.config.ParallelStreams > 1 {
+			log.Warn("--parallel-streams needs SFTP, uploading each file over a single stream")
+		}
+	}
+
+	remoteVersion, err := remoteDockerVersion(sshClient)
+	if err != nil {
+		return migerrors.NewDockerError("remote", err)
+	}
+	if err := checkMinimumVersion("remote", remoteVersion, m.config.MinRemoteVersion); err != nil {
+		return migerrors.NewDockerError("remote", err)
+	}
+	m.selectRemoteTempDir()
 
 	// Phase 3: Discover volumes
 	log.Info("=== Phase 2: Volume Discovery ===")
 
-	volumes, err := m.discoverVolumes()
-	if err != nil {
+	var volumes []docker.VolumeInfo
+	if m.config.PlannedVolumes != nil {
+		if volumes, err = m.checkPlannedVolumes(); err != nil {
+			return err
+		}
+	} else if volumes, err = m.discoverVolumes(); err != nil {
 		return fmt.Errorf("failed to discover volumes: %w", err)
 	}
 
@@ -219,163 +718,982 @@ func (m *Migrator) Migrate() error {
 		// Display volumes that will be migrated
 		ui.DisplayVolumeTable(volumes)
 	}
+	ui.DisplayUnmigratedMounts(m.unmigrated)
+
+	m.volumeCount = len(volumes)
+
+	// Phase 4.4: Compare both Docker environments for known-incompatible combinations
+	if err := m.checkCompatibility(volumes); err != nil {
+		return err
+	}
 
 	// Phase 4.5: Disk space validation
 	if !m.config.Force {
-		log.Debug("Validating disk space requirements")
+		if err := m.validateDiskSpace(volumes); err != nil {
+			return err
+		}
+		if err := m.validateInodes(volumes); err != nil {
+			return err
+		}
+	} else {
+		log.Warn("Skipping disk space validation (--force enabled)")
+	}
 
-		// Calculate total required space
-		var totalVolumeSize int64
-		for _, v := range volumes {
-			totalVolumeSize += v.SizeBytes
+	// Phase 4.6: Refuse to extract into volumes live remote containers are using
+	if err := m.checkVolumesInUse(volumes); err != nil {
+		return err
+	}
+
+	if m.config.SavePlan != "" {
+		if err := SavePlan(m.config.SavePlan, NewPlan(m.config, volumes)); err != nil {
+			return err
 		}
+		log.WithField("plan", m.config.SavePlan).Info("Saved migration plan")
+	}
 
-		estimatedArchiveSize := utils.CalculateRequiredSpace(totalVolumeSize)
-		log.WithFields(logrus.Fields{
-			"total_volume_size": utils.FormatBytes(totalVolumeSize),
-			"estimated_archive": utils.FormatBytes(estimatedArchiveSize),
-		}).Debug("Calculated space requirements")
+	if m.config.DryRun {
+		fmt.Println(summarizeMigration(volumes, m.config))
+		log.WithField("volume_count", len(volumes)).Info("Dry run mode: No actual migration will be performed")
+		return nil
+	}
 
-		// Check local disk space
-		localSpace, err := utils.GetLocalDiskSpace(m.config.TempDir)
-		if err != nil {
-			if m.config.Verbose {
-				log.WithError(err).Warn("Could not check local disk space")
-			}
-		} else {
-			log.WithFields(logrus.Fields{
-				"available": utils.FormatBytes(int64(localSpace.Available)),
-				"required":  utils.FormatBytes(estimatedArchiveSize),
-			}).Debug("Local disk space check")
+	// Nothing has been written on either host yet: last chance to back out
+	if err := m.confirmMigration(volumes); err != nil {
+		return err
+	}
+
+	// Refuse to run alongside another migration from this machine or into the remote host
+	release, err := m.acquireLocks()
+	if err != nil {
+		return err
+	}
+	defer release()
 
-			if err := utils.ValidateDiskSpace("local", uint64(estimatedArchiveSize), localSpace.Available); err != nil {
-				return fmt.Errorf("%w (use --force to override)", err)
+	if err := os.MkdirAll(m.config.TempDir, 0755); os.IsPermission(err) {
+		return migerrors.NewPermissionError("write", m.config.TempDir, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if err := m.sshClient.CreateDirectory(m.config.RemoteTempDir); err != nil {
+		return fmt.Errorf("failed to create remote temp directory: %w", err)
+	}
+
+	// Setup cleanup on exit if not disabled, before anything is uploaded, so an interrupted
+	// helper or archive upload does not leave files in the remote temp directory
+	if !m.config.NoCleanup {
+		defer func() {
+			log.Debug("=== Phase 4: Cleanup ===")
+			if err := CleanupLocal(m.config.TempDir); err != nil {
+				log.WithError(err).Error("Failed to cleanup local temporary directory")
 			}
+			if len(m.remoteArchives) > 0 {
+				generation, err := RetainRemoteArchives(m.cleanupClient(), m.remoteArchives, m.config.RemoteTempDir,
+					m.config.RemoteArchiveDir, m.config.KeepRemoteArchives, time.Now())
+				if generation == "" {
+					// Deleting the temp dir now would lose the archives the user asked to keep
+					log.WithError(err).WithField("remote_temp_dir", m.config.RemoteTempDir).Error("Failed to keep remote archives, leaving them in the remote temporary directory")
+					return
+				}
+				if err != nil {
+					log.WithError(err).Warn("Failed to rotate remote archive generations")
+				}
+				log.WithField("generation", generation).Info("Kept remote archives")
+			}
+			if err := CleanupRemote(m.cleanupClient(), m.config.RemoteTempDir); err != nil {
+				log.WithError(err).Error("Failed to cleanup remote temporary directory")
+			}
+		}()
+	}
+
+	// Make sure the helper image is available and provides tar on both hosts before touching any data
+	gnuTar := m.exportOptions().RequiresGNUTar() || m.importOptions().RequiresGNUTar()
+	helperImage := resolveHelperImage(m.config.HelperImage, m.config.HelperRegistry, gnuTar)
+	if m.config.HelperBinary != "" {
+		if helperImage, err = m.prepareStaticHelper(); err != nil {
+			return err
+		}
+	}
+	m.helperImage = helperImage
+	log.WithFields(logrus.Fields{
+		"helper_image": helperImage,
+		"gnu_tar":      gnuTar,
+	}).Debug("Checking helper image")
+	if m.config.HelperBinary == "" {
+		m.warnSingleArchHelper(helperImage)
+		if err := EnsureLocalHelperImage(m.dockerClient, helperImage, m.config.HelperImageTar); err != nil {
+			return err
 		}
+		if err := EnsureRemoteHelperImage(m.sshClient, helperImage, m.config.HelperImageTar, m.config.RemoteTempDir, m.config.ShowProgress); err != nil {
+			return err
+		}
+		if err := m.ensureHelperArchitecture(helperImage); err != nil {
+			return err
+		}
+	}
+	if err := CheckLocalHelperImage(m.dockerClient, helperImage, gnuTar); err != nil {
+		return err
+	}
+	if err := CheckRemoteHelperImage(m.sshClient, helperImage, gnuTar); err != nil {
+		return err
+	}
+	if err := m.checkHelperTarCompatibility(helperImage); err != nil {
+		return err
+	}
+	if err := CheckHelperFormatTools(m.dockerClient, m.sshClient, helperImage, m.config.ArchiveFormat); err != nil {
+		return err
+	}
 
-		// Check remote disk space
-		remoteSpace, err := utils.GetRemoteDiskSpace(m.sshClient, m.config.RemoteTempDir)
+	identity, err := m.helperImageIdentity(helperImage)
+	if err != nil {
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"helper_image": identity.Reference,
+		"local_id":     identity.LocalID,
+		"remote_id":    identity.RemoteID,
+	}).Info("Using helper image")
+	// Images built with docker import get a new ID on every host, as their creation time differs
+	if identity.LocalID != identity.RemoteID && m.config.HelperBinary == "" {
+		log.WithField("helper_image", identity.Reference).Warn("Helper image differs between the hosts; pin it by digest to use the same image on both")
+	}
+	m.manifest = &MigrationManifest{RemoteHost: m.config.RemoteHost, StartedAt: started, HelperImage: identity}
+
+	// Phase 5: Migrate volumes one at a time (export -> transfer -> import)
+	log.Info("=== Phase 3: Migrate Volumes ===")
+
+	if m.config.SignKey != "" {
+		if m.signingKey, err = ssh.LoadSigningKey(m.config.SignKey); err != nil {
+			return err
+		}
+		log.WithField("key", m.signingKey.Fingerprint()).Info("Signing archive manifests")
+	}
+
+	// Stopped consumers are started again however the run ends
+	if m.config.StopRemoteConsumers {
+		restart, err := m.stopRemoteConsumers(volumes)
 		if err != nil {
-			if m.config.Verbose {
-				log.WithError(err).Warn("Could not check remote disk space")
+			return err
+		}
+		defer restart()
+	}
+
+	if m.config.Dedup {
+		m.dedup = NewDedupIndex()
+	}
+
+	if m.config.Watch {
+		m.watchBaselines = make(map[string]fileManifest)
+	}
+
+	stopMonitor := m.monitorDiskSpace()
+	defer stopMonitor()
+
+	var succeeded, failed []string
+	for i, v := range volumes {
+		log.WithFields(logrus.Fields{
+			"volume":   v.Name,
+			"progress": fmt.Sprintf("%d/%d", i+1, len(volumes)),
+		}).Info("Migrating volume")
+
+		if m.config.Watch {
+			m.recordWatchBaseline(v)
+		}
+		if err := m.migrateVolume(v); err != nil {
+			if cause := m.abortCause(); cause != nil {
+				err = fmt.Errorf("failed to migrate volume %s: %w", v.Name, cause)
+				m.finishManifest(succeeded, append(failed, v.Name), err)
+				return err
 			}
-		} else {
-			log.WithFields(logrus.Fields{
-				"available": utils.FormatBytes(int64(remoteSpace.Available)),
-				"required":  utils.FormatBytes(estimatedArchiveSize),
-			}).Debug("Remote disk space check")
+			if !m.config.ContinueOnError {
+				err = fmt.Errorf("failed to migrate volume %s: %w", v.Name, err)
+				m.finishManifest(succeeded, append(failed, v.Name), err)
+				return err
+			}
+			log.WithError(err).WithField("volume", v.Name).Error("Failed to migrate volume, continuing with the remaining volumes")
+			m.discardFailedVolume(v)
+			failed = append(failed, v.Name)
+			continue
+		}
+		succeeded = append(succeeded, v.Name)
+	}
+
+	if m.config.ComposeFile != "" && len(succeeded) > 0 {
+		if err := m.writeComposeFile(volumes, succeeded); err != nil {
+			log.WithError(err).Error("Failed to generate compose file")
+		}
+	}
+	if m.config.PrintRunCommands && len(succeeded) > 0 {
+		if err := m.printRunCommands(volumes, succeeded); err != nil {
+			log.WithError(err).Error("Failed to generate docker run commands")
+		}
+	}
+	// Runs until interrupted, after the compose file and run commands are out
+	if m.config.Watch && len(succeeded) > 0 {
+		m.watchVolumes(volumes, succeeded)
+		if err := m.abortCause(); err != nil {
+			return err
+		}
+	}
+	// Started last, so automation can gate the cutover on the exit status
+	var startErr error
+	if m.config.StartRemote && len(succeeded) > 0 {
+		if startErr = m.startRecreatedContainers(volumes, succeeded); startErr != nil {
+			startErr = fmt.Errorf("migrated containers did not come up on the remote host: %w", startErr)
+		}
+	}
+
+	logTransferSummary(m.transfers)
+	m.reportUnmigratedMounts()
+	if len(failed) > 0 {
+		if startErr != nil {
+			log.WithError(startErr).Error("Remote containers did not come up")
+		}
+		log.WithFields(logrus.Fields{
+			"succeeded":   succeeded,
+			"failed":      failed,
+			"remote_host": m.config.RemoteHost,
+		}).Error("Migration finished with failures")
+		err := migerrors.NewPartialMigrationError(succeeded, failed)
+		m.finishManifest(succeeded, failed, err)
+		return err
+	}
+	m.finishManifest(succeeded, nil, nil)
+	if startErr != nil {
+		return startErr
+	}
+
+	log.WithFields(logrus.Fields{
+		"volumes":     len(volumes),
+		"remote_host": m.config.RemoteHost,
+	}).Info("Migration completed successfully")
+
+	return nil
+}
+
+// checkVolumesInUse fails when a running remote container mounts one of the target volumes,
+// since extracting under a live application can corrupt it. --force-inuse downgrades this to a warning,
+// and with --stop-remote-consumers the containers are stopped during the migration instead.
+func (m *Migrator) checkVolumesInUse(volumes []docker.VolumeInfo) error {
+	var inUse []string
+	for _, v := range volumes {
+		users, err := RemoteVolumeUsers(m.sshClient, v.RemoteName())
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			continue
+		}
+
+		fields := log.WithFields(logrus.Fields{
+			"volume":     v.RemoteName(),
+			"containers": strings.Join(users, ", "),
+		})
+		if m.config.StopRemoteConsumers {
+			fields.Info("Remote volume is in use; its containers will be stopped during the migration")
+			continue
+		}
+		fields.Warn("Remote volume is in use by running containers")
+		inUse = append(inUse, v.RemoteName())
+	}
+
+	if len(inUse) > 0 && !m.config.ForceInUse {
+		return fmt.Errorf("remote volumes in use by running containers: %s (stop them first, or pass --stop-remote-consumers or --force-inuse)", strings.Join(inUse, ", "))
+	}
+	return nil
+}
 
-			if err := utils.ValidateDiskSpace("remote", uint64(estimatedArchiveSize), remoteSpace.Available); err != nil {
-				return fmt.Errorf("%w (use --force to override)", err)
+// stopRemoteConsumers stops the running remote containers mounting any of the target volumes
+// and returns a function starting them again
+func (m *Migrator) stopRemoteConsumers(volumes []docker.VolumeInfo) (func(), error) {
+	var consumers []string
+	for _, v := range volumes {
+		users, err := RemoteVolumeUsers(m.sshClient, v.RemoteName())
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if !slices.Contains(consumers, user) {
+				consumers = append(consumers, user)
 			}
 		}
+	}
+	if len(consumers) == 0 {
+		return func() {}, nil
+	}
+
+	log.WithField("containers", strings.Join(consumers, ", ")).Info("Stopping remote containers using the target volumes")
+	if _, err := m.sshClient.RunDockerCommand("stop " + strings.Join(consumers, " ")); err != nil {
+		// Some may have stopped, so start them all again
+		m.startRemoteContainers(consumers)
+		return nil, fmt.Errorf("failed to stop remote containers: %w", err)
+	}
+
+	return func() { m.startRemoteContainers(consumers) }, nil
+}
+
+// startRemoteContainers starts containers stopped by stopRemoteConsumers
+func (m *Migrator) startRemoteContainers(containers []string) {
+	log.WithField("containers", strings.Join(containers, ", ")).Info("Starting remote containers again")
+	if _, err := m.cleanupClient().RunDockerCommand("start " + strings.Join(containers, " ")); err != nil {
+		log.WithError(err).Error("Failed to start remote containers; start them manually")
+	}
+}
+
+// cleanupClient returns the remote executor for cleanup, which must still work once the run is cancelled
+func (m *Migrator) cleanupClient() RemoteExecutor {
+	if m.sshCleanup != nil {
+		return m.sshCleanup
+	}
+	return m.sshClient
+}
+
+// validateDiskSpace checks that both hosts can hold the temporary archives.
+// Volumes are migrated one at a time and each archive is deleted once it is no longer
+// needed, so the requirement is the peak usage (the largest volume) rather than the total.
+func (m *Migrator) validateDiskSpace(volumes []docker.VolumeInfo) error {
+	log.Debug("Validating disk space requirements")
+
+	localRequirements := make([]int64, len(volumes))
+	remoteRequirements := make([]int64, len(volumes))
+	var largest docker.VolumeInfo
+	for i, v := range volumes {
+		archive := utils.CalculateRequiredSpace(v.SizeBytes)
+		localRequirements[i] = archive
+		// Streamed and copied archives never land in the remote temp dir
+		if m.config.ImportMethod != ImportMethodStream && m.config.ImportMethod != ImportMethodCopy {
+			remoteRequirements[i] = archive
+		}
+		// Dumps are staged as a raw SQL file next to the archive
+		if m.isDumpVolume(v) {
+			localRequirements[i] *= 2
+		}
+		if v.SizeBytes >= largest.SizeBytes {
+			largest = v
+		}
+	}
+
+	// Kept archives stay in the remote temp dir until the run ends
+	remoteConcurrency := 1
+	if m.config.KeepRemoteArchives > 0 {
+		remoteConcurrency = len(volumes)
+	}
+	localRequired := utils.PeakSpaceRequirement(localRequirements, 1)
+	remoteRequired := utils.PeakSpaceRequirement(remoteRequirements, remoteConcurrency)
+	log.WithFields(logrus.Fields{
+		"largest_volume":  largest.Name,
+		"local_required":  utils.FormatBytes(localRequired),
+		"remote_required": utils.FormatBytes(remoteRequired),
+	}).Debug("Calculated peak space requirements")
 
-		log.Debug("Disk space validation passed")
+	// Check local disk space (the temp dir itself may not exist yet)
+	localSpace, err := utils.GetLocalDiskSpace(utils.NearestExistingDir(m.config.TempDir))
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not check local disk space")
+		}
 	} else {
-		log.Warn("Skipping disk space validation (--force enabled)")
+		log.WithFields(logrus.Fields{
+			"available": utils.FormatBytes(int64(localSpace.Available)),
+			"required":  utils.FormatBytes(localRequired),
+		}).Debug("Local disk space check")
+
+		if err := utils.ValidateDiskSpace("local", uint64(localRequired), localSpace.Available); err != nil {
+			return fmt.Errorf("%w while exporting volume %s (use --force to override)", err, largest.Name)
+		}
 	}
 
-	if m.config.DryRun {
-		log.WithField("volume_count", len(volumes)).Info("Dry run mode: No actual migration will be performed")
+	// Check remote disk space
+	remoteSpace, err := utils.GetRemoteDiskSpace(m.sshClient, m.config.RemoteTempDir)
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not check remote disk space")
+		}
+	} else {
+		log.WithFields(logrus.Fields{
+			"available": utils.FormatBytes(int64(remoteSpace.Available)),
+			"required":  utils.FormatBytes(remoteRequired),
+		}).Debug("Remote disk space check")
+
+		if err := utils.ValidateDiskSpace("remote", uint64(remoteRequired), remoteSpace.Available); err != nil {
+			return fmt.Errorf("%w while transferring volume %s (use --force to override)", err, largest.Name)
+		}
+	}
+
+	// Check the remote Docker root, where imported volumes are extracted and stay
+	var totalSize int64
+	for _, v := range volumes {
+		totalSize += v.SizeBytes
+	}
+	dataRequired := utils.CalculateRequiredSpace(totalSize)
+
+	dockerRoot, err := remoteDockerRootDir(m.sshClient)
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not determine remote Docker root directory")
+		}
+		return nil
+	}
+	rootSpace, err := utils.GetRemoteDiskSpace(m.sshClient, dockerRoot)
+	if err != nil {
+		if m.config.Verbose {
+			log.WithError(err).Warn("Could not check remote Docker root disk space")
+		}
 		return nil
 	}
 
-	// Extract volume names
-	volumeNames := make([]string, len(volumes))
-	for i, v := range volumes {
-		volumeNames[i] = v.Name
+	// When the temp dir lives on the same filesystem the largest archive must fit alongside the data
+	if remoteSpace != nil && rootSpace.MountPoint != "" && rootSpace.MountPoint == remoteSpace.MountPoint {
+		dataRequired += remoteRequired
+	}
+
+	log.WithFields(logrus.Fields{
+		"docker_root": dockerRoot,
+		"available":   utils.FormatBytes(int64(rootSpace.Available)),
+		"required":    utils.FormatBytes(dataRequired),
+	}).Debug("Remote Docker root disk space check")
+
+	if err := utils.ValidateDiskSpace("remote docker root ("+dockerRoot+")", uint64(dataRequired), rootSpace.Available); err != nil {
+		return fmt.Errorf("%w for imported volumes (use --force to override)", err)
+	}
+
+	log.Debug("Disk space validation passed")
+	return nil
+}
+
+// sshClientConfig builds the SSH client configuration for the remote host
+func sshClientConfig(config *Config) (*ssh.ClientConfig, error) {
+	options, err := ssh.ParseOptions(config.SSHOptions)
+	if err != nil {
+		return nil, err
+	}
+	escalation, err := shell.ParseEscalation(config.RemoteEscalation)
+	if err != nil {
+		return nil, fmt.Errorf("--remote-escalation: %w", err)
+	}
+	if config.SSHTimeout > 0 {
+		options.ConnectTimeout = config.SSHTimeout
+	}
+	var bufferSize int64
+	if config.BufferSize != "" {
+		if bufferSize, err = utils.ParseSize(config.BufferSize); err != nil {
+			return nil, fmt.Errorf("invalid buffer size: %w", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		HostString:            config.RemoteHost,
+		CustomKeyPaths:        config.SSHKeyPaths,
+		StrictHostKeyChecking: config.StrictHostKeyChecking,
+		AcceptHostKey:         config.AcceptHostKey,
+		KnownHostsFile:        config.KnownHostsFile,
+		Options:               options,
+		GSSAPI:                config.GSSAPI,
+		ProxyCommand:          config.ProxyCommand,
+		SudoPassword:          sudoPasswordPrompt(config),
+		Escalation:            escalation,
+		CommandTimeout:        config.CommandTimeout,
+		TransferStallTimeout:  config.TransferStallTimeout,
+		TransferStallRestarts: config.TransferStallRestarts,
+		OnStallRestart:        logStallRestart,
+		TransferBackend:       config.TransferBackend,
+		BufferSize:            int(bufferSize),
+		ParallelStreams:       config.ParallelStreams,
+		MaxSessions:           config.MaxSessions,
+	}, nil
+}
+
+// localDockerClient creates the local Docker client with the --local-escalation methods
+func localDockerClient(ctx context.Context, config *Config) (*docker.Client, error) {
+	escalation, err := shell.ParseEscalation(config.LocalEscalation)
+	if err != nil {
+		return nil, fmt.Errorf("--local-escalation: %w", err)
+	}
+	client, err := docker.NewClientWithEscalation(ctx, escalation)
+	if err != nil {
+		return nil, migerrors.NewDockerError("local", err)
+	}
+	client.SetCommandTimeout(config.CommandTimeout)
+	return client, nil
+}
+
+// connectError classifies a failed ssh.NewClient: a remote Docker engine none of the escalation
+// methods can use is a Docker failure, anything else an SSH one
+func connectError(host string, err error) error {
+	switch {
+	case errors.Is(err, ssh.ErrDockerNotAccessible):
+		return migerrors.NewDockerError("remote", fmt.Errorf("failed to connect to remote host: %w", err))
+	case errors.Is(err, migerrors.ErrSSHConnection):
+		return err
+	}
+	return migerrors.NewSSHConnectionError(host, err)
+}
+
+// sudoPasswordPrompt returns the remote sudo password prompt, or nil when it is not enabled
+func sudoPasswordPrompt(config *Config) func() (string, error) {
+	if !config.RemoteSudoPassword {
+		return nil
+	}
+	return func() (string, error) {
+		return ui.PromptPassword(fmt.Sprintf("sudo password for %s", config.RemoteHost))
+	}
+}
+
+// logStallRestart reports the restart of a stalled transfer
+func logStallRestart(remotePath string, restart, restarts int) {
+	log.WithFields(logrus.Fields{
+		"file":    remotePath,
+		"restart": fmt.Sprintf("%d/%d", restart, restarts),
+	}).Warn("Transfer stalled, restarting it where it stopped")
+}
+
+// remoteDockerRootDir returns the remote Docker root directory (where volume data is stored)
+func remoteDockerRootDir(sshClient RemoteExecutor) (string, error) {
+	output, err := sshClient.RunDockerCommand("info --format '{{.DockerRootDir}}'")
+	if err != nil {
+		return "", fmt.Errorf("failed to query docker info: %w", err)
+	}
+
+	root := strings.TrimSpace(output)
+	if !strings.HasPrefix(root, "/") {
+		return "", fmt.Errorf("unexpected docker root directory: %q", root)
+	}
+	return root, nil
+}
+
+// migrateVolume exports, transfers and imports a single volume.
+// The local archive is removed right after the transfer and the remote archive right
+// after the import (unless --no-cleanup), so temporary space holds one volume at a time.
+// With --import-method stream or cp the archive is piped into the remote tar or docker cp instead of uploaded.
+func (m *Migrator) migrateVolume(v docker.VolumeInfo) error {
+	exportOpts := m.exportOptions()
+	exportOpts.ExpectedSize = v.SizeBytes
+	importOpts := m.importOptions()
+	importOpts.ExpectedSize = v.SizeBytes
+	importOpts.Incremental = false
+	importOpts.Driver = v.Driver
+	importOpts.DriverOpts = v.DriverOpts
+	importOpts.Labels = v.Labels
+
+	// Incremental mode only sends changes since the generation last imported into the remote volume
+	var statePath string
+	if m.config.Incremental && !m.isDumpVolume(v) {
+		remoteExists, _ := VerifyVolumeExists(m.sshClient, v.RemoteName())
+		statePath = snapshotPath(m.stateDir(), m.config.RemoteHost, v.RemoteName())
+
+		workPath, delta, err := prepareSnapshot(statePath, filepath.Join(m.config.TempDir, "snapshots"), remoteExists)
+		if err != nil {
+			return err
+		}
+		exportOpts.SnapshotPath = workPath
+		importOpts.Incremental = true
+
+		log.WithFields(logrus.Fields{
+			"volume": v.Name,
+			"delta":  delta,
+		}).Info("Incremental export")
+	}
+
+	if err := m.runPreExportHook(v); err != nil {
+		return err
+	}
+
+	if m.config.AdaptiveCompression && !m.isDumpVolume(v) {
+		m.chooseCompression(v, &exportOpts)
+	}
+
+	if m.dedup != nil && !m.isDumpVolume(v) {
+		exportOpts.Dedup = m.dedup
+		exportOpts.DedupVolume = v.RemoteName()
+	}
+
+	// A quiesced datastore resumes as soon as its archive is written, without waiting for the upload
+	resume, err := m.quiesceVolume(v)
+	if err != nil {
+		return err
+	}
+	defer resume()
+
+	// Deep verification hashes every file of the source right before it is archived
+	var sourceFiles fileManifest
+	var checksumFile string
+	if m.config.DeepVerify && !m.isDumpVolume(v) {
+		files, err := localFileManifest(m.dockerClient, v.Name, m.helperImage, buildManifestScript(true))
+		if err != nil {
+			return fmt.Errorf("deep verification failed: %w", err)
+		}
+		sourceFiles = files
+		checksumFile = fmt.Sprintf("%s.sha256", v.Name)
+		if err := writeChecksumList(filepath.Join(m.config.TempDir, checksumFile), sourceFiles); err != nil {
+			return err
+		}
+	}
+
+	var scanned ArchiveEntry
+	exportOpts.Scan = &scanned
+	archivePath, err := m.exportVolume(v, exportOpts)
+	resume()
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if err := m.recordArchive(v.Name, archivePath, checksumFile, scanned); err != nil {
+		return err
 	}
 
-	// Phase 5: Export volumes
-	log.Info("=== Phase 3: Export Volumes ===")
+	if exportOpts.Dedup != nil {
+		if err := m.transferDedupScript(v, &importOpts); err != nil {
+			return err
+		}
+	}
 
-	archivePaths, err := m.exportVolumes(volumeNames)
+	archiveFiles := ArchiveFiles(archivePath)
+	size, err := archiveSize(archiveFiles)
 	if err != nil {
-		return fmt.Errorf("failed to export volumes: %w", err)
+		return err
+	}
+
+	remotePath := path.Join(m.config.RemoteTempDir, filepath.Base(archivePath))
+	remoteFiles := remoteArchiveFiles(archiveFiles, m.config.RemoteTempDir)
+	if m.config.ImportMethod == ImportMethodStream || m.config.ImportMethod == ImportMethodCopy {
+		// Transfer and import are one step: the archive is piped straight into the remote tar or docker cp
+		log.WithField("volume", v.Name).Debug("Streaming volume")
+		start := time.Now()
+		var err error
+		if m.config.ImportMethod == ImportMethodCopy {
+			err = CopyImportVolume(m.sshClient, v.RemoteName(), archivePath, importOpts)
+		} else {
+			err = StreamImportVolume(m.sshClient, v.RemoteName(), archivePath, m.config.RemoteTempDir, importOpts)
+		}
+		if !m.config.NoCleanup {
+			if err := CleanupArchives(map[string]string{v.Name: archivePath}); err != nil {
+				log.WithError(err).Warn("Failed to remove local archive")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+		transfer := volumeTransfer{Volume: v.Name, Bytes: size, Duration: time.Since(start)}
+		m.transfers = append(m.transfers, transfer)
+		log.WithFields(transferFields(transfer)).Debug("Streamed volume archive")
+		remoteFiles = nil
+	} else {
+		log.WithField("volume", v.Name).Debug("Transferring volume")
+		start := time.Now()
+		for i, file := range archiveFiles {
+			if err := m.sshClient.TransferFileChunked(file, remoteFiles[i], m.chunkSize(), m.config.ShowProgress); err != nil {
+				return fmt.Errorf("transfer failed: %w", err)
+			}
+		}
+		transfer := volumeTransfer{Volume: v.Name, Bytes: size, Duration: time.Since(start)}
+		m.transfers = append(m.transfers, transfer)
+		log.WithFields(transferFields(transfer)).Debug("Transferred volume archive")
+
+		if !m.config.NoCleanup {
+			for _, file := range archiveFiles {
+				if err := CleanupArchives(map[string]string{v.Name: file}); err != nil {
+					log.WithError(err).Warn("Failed to remove local archive")
+				}
+			}
+		}
+
+		if m.signingKey != nil {
+			if err := m.verifyRemoteArchive(v.Name, remotePath); err != nil {
+				return err
+			}
+		}
+
+		if archiveFiles[0] != archivePath {
+			importOpts.ArchiveParts = len(archiveFiles)
+		}
+		if err := ImportVolume(m.sshClient, v.RemoteName(), remotePath, importOpts); err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+	}
+
+	if sourceFiles != nil {
+		remoteFiles, err := remoteFileManifest(m.sshClient, v.RemoteName(), m.helperImage, buildManifestScript(true))
+		if err != nil {
+			return fmt.Errorf("deep verification failed: %w", err)
+		}
+		if result := compareManifests(v.RemoteName(), sourceFiles, remoteFiles); !result.OK() {
+			return deepVerifyFailure(result)
+		}
+		log.WithFields(logrus.Fields{
+			"volume": v.Name,
+			"files":  len(sourceFiles),
+		}).Info("Deep verification passed")
+	}
+
+	// Only a successful import advances the base generation for the next incremental run
+	if exportOpts.SnapshotPath != "" {
+		if err := commitSnapshot(exportOpts.SnapshotPath, statePath); err != nil {
+			log.WithError(err).Warn("Failed to save incremental snapshot; the next run will resend changes since the previous one")
+		}
 	}
 
-	// Setup cleanup on exit if not disabled
 	if !m.config.NoCleanup {
-		defer func() {
-			log.Debug("=== Phase 6: Cleanup ===")
-			if err := CleanupLocal(m.config.TempDir); err != nil {
-				log.WithError(err).Error("Failed to cleanup local temporary directory")
+		if m.config.KeepRemoteArchives > 0 {
+			m.remoteArchives = append(m.remoteArchives, remoteFiles...) // Moved to the archive dir during cleanup
+		} else {
+			for _, file := range remoteFiles {
+				if err := m.sshClient.RemoveFile(file); err != nil {
+					log.WithError(err).Warn("Failed to remove remote archive")
+				}
 			}
-			if err := CleanupRemote(m.sshClient, m.config.RemoteTempDir); err != nil {
-				log.WithError(err).Error("Failed to cleanup remote temporary directory")
+		}
+		if importOpts.DedupScript != "" {
+			if err := m.sshClient.RemoveFile(path.Join(m.config.RemoteTempDir, importOpts.DedupScript)); err != nil {
+				log.WithError(err).Warn("Failed to remove remote dedup script")
 			}
-		}()
+		}
 	}
 
-	// Phase 6: Transfer volumes
-	log.Debug("=== Phase 4: Transfer Archives ===")
+	if m.isDumpVolume(v) {
+		log.WithFields(logrus.Fields{
+			"volume": v.Name,
+			"file":   dumpFileName(m.config.DBMode),
+		}).Warn("Remote volume contains a logical database dump; restore it into a fresh database instead of mounting it as a data directory")
+	}
+
+	return nil
+}
 
-	if err := m.transferVolumes(archivePaths); err != nil {
-		return fmt.Errorf("failed to transfer volumes: %w", err)
+// discardFailedVolume removes what a failed volume left behind so the remaining volumes
+// have the temporary space and deduplication sources they expect
+func (m *Migrator) discardFailedVolume(v docker.VolumeInfo) {
+	if m.dedup != nil {
+		m.dedup.Forget(v.RemoteName())
+	}
+	if m.config.NoCleanup {
+		return
 	}
 
-	// Phase 7: Import volumes on remote
-	log.Debug("=== Phase 5: Import Volumes ===")
+	archivePath := filepath.Join(m.config.TempDir, archiveFileName(v.Name, m.config.ArchiveFormat))
+	archiveFiles := ArchiveFiles(archivePath)
+	var names []string
+	for _, file := range archiveFiles {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).Warn("Failed to remove local archive")
+		}
+		names = append(names, filepath.Base(file))
+	}
+	for _, name := range append(names, fmt.Sprintf("%s.dedup.sh", v.Name)) {
+		remotePath := path.Join(m.config.RemoteTempDir, name)
+		if exists, err := m.sshClient.FileExists(remotePath); err == nil && exists {
+			if err := m.sshClient.RemoveFile(remotePath); err != nil {
+				log.WithError(err).Warn("Failed to remove remote file")
+			}
+		}
+	}
+}
 
-	if err := m.importVolumes(archivePaths); err != nil {
-		return fmt.Errorf("failed to import volumes: %w", err)
+// transferDedupScript uploads the script restoring a volume's deduplicated files and
+// points the import options at it; volumes without duplicates need no script
+func (m *Migrator) transferDedupScript(v docker.VolumeInfo, importOpts *ImportOptions) error {
+	volume := v.RemoteName()
+	entries := m.dedup.Entries(volume)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	scriptName := fmt.Sprintf("%s.dedup.sh", v.Name)
+	localPath := filepath.Join(m.config.TempDir, scriptName)
+	if err := os.WriteFile(localPath, []byte(buildDedupScript(volume, entries)), 0644); err != nil {
+		return fmt.Errorf("failed to write dedup script: %w", err)
+	}
+	if err := m.sshClient.TransferFile(localPath, path.Join(m.config.RemoteTempDir, scriptName), false); err != nil {
+		return fmt.Errorf("failed to transfer dedup script: %w", err)
 	}
 
 	log.WithFields(logrus.Fields{
-		"volumes":     len(volumeNames),
-		"remote_host": m.config.RemoteHost,
-	}).Info("Migration completed successfully")
+		"volume":       v.Name,
+		"deduplicated": len(entries),
+	}).Info("Sending duplicate files as references")
+
+	importOpts.DedupScript = scriptName
+	importOpts.DedupSources = dedupSourceVolumes(volume, entries)
+	return nil
+}
+
+// enumerateContainers replaces the configured containers with every local container matching the filters
+func (m *Migrator) enumerateContainers() error {
+	containers, err := m.dockerClient.ListContainers(m.config.ContainerFilters)
+	if err != nil {
+		return err
+	}
 
+	log.WithFields(logrus.Fields{
+		"containers": len(containers),
+		"filters":    m.config.ContainerFilters,
+	}).Info("Enumerated local containers")
+	m.config.Containers = containers
 	return nil
 }
 
-// discoverVolumes discovers all volumes from specified containers
+// ListVolumes discovers the volumes a migration with config would consider, including
+// exclusions and anonymous volume handling, without connecting to the remote host.
+// The bind and tmpfs mounts of the containers, which are not migrated, are returned too.
+func ListVolumes(ctx context.Context, config *Config) ([]docker.VolumeInfo, []docker.UnmigratedMount, error) {
+	dockerClient, err := localDockerClient(ctx, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+	}
+
+	m := &Migrator{config: config, dockerClient: dockerClient, ctx: ctx}
+	if config.AllContainers {
+		if err := m.enumerateContainers(); err != nil {
+			return nil, nil, err
+		}
+		if len(config.Containers) == 0 {
+			return nil, nil, nil
+		}
+	}
+	volumes, err := m.discoverVolumes()
+	return volumes, m.unmigrated, err
+}
+
+// discoverVolumes discovers all volumes from specified containers (or the volumes named with --by-volume)
 func (m *Migrator) discoverVolumes() ([]docker.VolumeInfo, error) {
-	volumes, err := m.dockerClient.GetAllVolumesInfo(m.config.Containers)
+	var volumes []docker.VolumeInfo
+	var err error
+	if m.config.ByVolume {
+		volumes, err = m.dockerClient.GetVolumesInfoByName(m.config.Volumes)
+	} else {
+		volumes, err = m.dockerClient.GetAllVolumesInfo(m.config.Containers)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if !m.config.ByVolume {
+		m.findUnmigratedMounts()
+	}
+
+	volumes, excluded := FilterExcludedVolumes(volumes, m.config.ExcludeVolumes)
+	for _, name := range excluded {
+		log.WithField("volume", name).Info("Excluding volume")
+	}
+
+	volumes, skipped := ApplyAnonymousMode(volumes, m.config.AnonymousVolumes)
+	for _, name := range skipped {
+		log.WithField("volume", name).Info("Skipping anonymous volume")
+	}
+
+	if m.dockerClient.IsDockerDesktop() {
+		m.measureDesktopVolumes(volumes)
+	}
+
+	// Sizes are complete once Docker Desktop volumes are measured; already validated by ValidateConfig
+	minSize, maxSize, _ := parseSizeFilters(m.config.MinSize, m.config.MaxSize)
+	volumes, outOfRange := FilterVolumesBySize(volumes, minSize, maxSize)
+	for _, name := range outOfRange {
+		log.WithField("volume", name).Info("Skipping volume outside --min-size/--max-size")
+	}
+
+	for _, v := range volumes {
+		if (minSize > 0 || maxSize > 0) && v.SizeBytes == 0 && v.Size == "Unknown" {
+			log.WithField("volume", v.Name).Warn("Cannot measure volume size, keeping it despite --min-size/--max-size")
+		}
+		if v.TargetName != "" {
+			log.WithFields(logrus.Fields{
+				"volume": v.Name,
+				"target": v.TargetName,
+			}).Info("Renaming anonymous volume on remote")
+		} else if v.Anonymous {
+			log.WithField("volume", v.Name).Warn("Migrating anonymous volume under its generated name (use --anonymous-volumes rename or skip)")
+		}
+	}
 
 	log.WithFields(logrus.Fields{
 		"volumes":    len(volumes),
+		"excluded":   len(excluded),
+		"size_skips": len(outOfRange),
 		"containers": len(m.config.Containers),
 	}).Debug("Volume discovery complete")
 
 	return volumes, nil
 }
 
-// exportVolumes exports all volumes to local archives
-func (m *Migrator) exportVolumes(volumeNames []string) (map[string]string, error) {
-	// Create temp directory
-	if err := os.MkdirAll(m.config.TempDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+// exportVolume exports a single volume to an archive in the local temp directory
+// Database volumes are dumped logically when a db mode is configured
+func (m *Migrator) exportVolume(v docker.VolumeInfo, opts ExportOptions) (string, error) {
+	archivePath := filepath.Join(m.config.TempDir, archiveFileName(v.Name, m.config.ArchiveFormat))
+
+	if m.isDumpVolume(v) {
+		return archivePath, ExportDatabaseDump(m.dockerClient, m.config.DBMode, v, archivePath, m.config.CompressionLevel)
 	}
+	return archivePath, ExportVolume(m.dockerClient, v.Name, archivePath, opts)
+}
 
-	return ExportVolumes(m.dockerClient, volumeNames, m.config.TempDir)
+// isDumpVolume reports whether a volume should be exported as a logical database dump
+func (m *Migrator) isDumpVolume(v docker.VolumeInfo) bool {
+	return m.config.DBMode != "" && IsDatabaseVolume(m.config.DBMode, v.MountPath)
 }
 
-// transferVolumes transfers archive files to remote host
-func (m *Migrator) transferVolumes(archivePaths map[string]string) error {
-	// Create remote temp directory
-	if err := m.sshClient.CreateDirectory(m.config.RemoteTempDir); err != nil {
-		return fmt.Errorf("failed to create remote temp directory: %w", err)
+// chunkSize returns the configured transfer chunk size in bytes (0 disables chunking)
+func (m *Migrator) chunkSize() int64 {
+	if m.config.ChunkSize == "" {
+		return 0
 	}
+	// Already validated by ValidateConfig
+	size, _ := utils.ParseSize(m.config.ChunkSize)
+	return size
+}
 
-	// Transfer each archive
-	for volumeName, localPath := range archivePaths {
-		remotePath := filepath.Join(m.config.RemoteTempDir, filepath.Base(localPath))
+// maxArchiveSize returns the configured maximum archive part size in bytes (0 disables splitting)
+func (m *Migrator) maxArchiveSize() int64 {
+	if m.config.MaxArchiveSize == "" {
+		return 0
+	}
+	// Already validated by ValidateConfig
+	size, _ := utils.ParseSize(m.config.MaxArchiveSize)
+	return size
+}
 
-		log.WithField("volume", volumeName).Debug("Transferring volume")
+// exportOptions builds the export options from the migration configuration
+func (m *Migrator) exportOptions() ExportOptions {
+	return ExportOptions{
+		HelperImage:      m.helperImageRef(),
+		PreserveXattrs:   m.config.PreserveXattrs,
+		PreserveACLs:     m.config.PreserveACLs,
+		Sparse:           m.config.Sparse,
+		Format:           m.config.ArchiveFormat,
+		MaxArchiveSize:   m.maxArchiveSize(),
+		CompressionLevel: m.config.CompressionLevel,
+		AutoCompress:     m.config.AutoCompress,
+		ShowProgress:     m.config.ShowProgress,
+	}
+}
 
-		if err := m.sshClient.TransferFile(localPath, remotePath, m.config.ShowProgress); err != nil {
-			return fmt.Errorf("failed to transfer volume %s: %w", volumeName, err)
-		}
+// importOptions builds the import options from the migration configuration
+// ID mappings were checked by ValidateConfig, so parse errors cannot occur here
+func (m *Migrator) importOptions() ImportOptions {
+	uidMap, _ := ParseIDMappings("uid", m.config.UIDMap)
+	gidMap, _ := ParseIDMappings("gid", m.config.GIDMap)
+
+	return ImportOptions{
+		HelperImage:    m.helperImageRef(),
+		Format:         m.config.ArchiveFormat,
+		PreserveXattrs: m.config.PreserveXattrs,
+		PreserveACLs:   m.config.PreserveACLs,
+		NumericOwner:   m.config.NumericOwner,
+		UIDMap:         uidMap,
+		GIDMap:         gidMap,
+		Incremental:    m.config.Incremental,
+		ShowProgress:   m.config.ShowProgress,
 	}
+}
 
-	return nil
+// helperImageRef returns the helper image resolved for this run, or the configured one before resolution
+func (m *Migrator) helperImageRef() string {
+	if m.helperImage != "" {
+		return m.helperImage
+	}
+	return m.config.HelperImage
 }
 
-// importVolumes imports volumes on remote host
-func (m *Migrator) importVolumes(archivePaths map[string]string) error {
-	return ImportVolumes(m.sshClient, archivePaths, m.config.RemoteTempDir)
+// stateDir returns the directory holding incremental snapshots
+func (m *Migrator) stateDir() string {
+	if m.config.StateDir != "" {
+		return m.config.StateDir
+	}
+	return DefaultStateDir()
 }
@@ -0,0 +1,140 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/utils"
+)
+
+// PlanVersion is the format version written to plan files
+const PlanVersion = 1
+
+// DefaultSizeTolerance is how much, in percent, a volume may have grown or shrunk since its plan was saved
+const DefaultSizeTolerance = 10
+
+// minSizeSlack is the size change always accepted, so small volumes may still gain or lose a few files
+const minSizeSlack = 1024 * 1024
+
+// Plan is a resolved migration, saved with --save-plan: the configuration and the exact
+// volume set chosen, so the run can be reviewed and replayed later
+type Plan struct {
+	Version   int                 `yaml:"version"`
+	CreatedAt time.Time           `yaml:"created_at"`
+	Source    string              `yaml:"source,omitempty"` // Hostname of the machine the plan was made on
+	Config    *Config             `yaml:"config"`
+	Volumes   []docker.VolumeInfo `yaml:"volumes"`
+}
+
+// NewPlan records config and the volumes about to be migrated
+// Run-specific settings such as temp directories, verbosity and prompts are not part of a plan
+func NewPlan(config *Config, volumes []docker.VolumeInfo) *Plan {
+	source, _ := os.Hostname()
+	return &Plan{
+		Version:   PlanVersion,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Source:    source,
+		Config:    config,
+		Volumes:   volumes,
+	}
+}
+
+// SavePlan writes plan as YAML to path
+func SavePlan(path string, plan *Plan) error {
+	data, err := yaml.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to encode migration plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan written by SavePlan, rejecting unknown settings and newer format versions
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration plan: %w", err)
+	}
+
+	var plan Plan
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("failed to parse migration plan %s: %w", path, err)
+	}
+	if plan.Version < 1 || plan.Version > PlanVersion {
+		return nil, fmt.Errorf("unsupported migration plan version %d (supported: 1 to %d)", plan.Version, PlanVersion)
+	}
+	if plan.Config == nil {
+		return nil, errors.New("migration plan has no config")
+	}
+	if len(plan.Volumes) == 0 {
+		return nil, errors.New("migration plan has no volumes")
+	}
+	return &plan, nil
+}
+
+// checkPlannedVolumes verifies the volumes of an applied plan still exist locally with about
+// the size they had when it was saved, and returns them with their current sizes
+func (m *Migrator) checkPlannedVolumes() ([]docker.VolumeInfo, error) {
+	names := make([]string, len(m.config.PlannedVolumes))
+	for i, v := range m.config.PlannedVolumes {
+		names[i] = v.Name
+	}
+	current, err := m.dockerClient.GetVolumesInfoByName(names)
+	if err != nil {
+		return nil, fmt.Errorf("environment no longer matches the plan: %w", err)
+	}
+	if m.dockerClient.IsDockerDesktop() {
+		m.measureDesktopVolumes(current)
+	}
+	return comparePlannedVolumes(m.config.PlannedVolumes, current, m.config.SizeTolerance)
+}
+
+// comparePlannedVolumes checks the planned volumes against the current ones, allowing sizes
+// to differ by tolerance percent. Volumes whose size cannot be measured are not compared.
+func comparePlannedVolumes(planned, current []docker.VolumeInfo, tolerance float64) ([]docker.VolumeInfo, error) {
+	byName := make(map[string]docker.VolumeInfo, len(current))
+	for _, v := range current {
+		byName[v.Name] = v
+	}
+
+	var volumes []docker.VolumeInfo
+	var problems []string
+	for _, v := range planned {
+		c, ok := byName[v.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s no longer exists", v.Name))
+			continue
+		}
+		if c.SizeBytes == 0 && c.Size == "Unknown" {
+			log.WithField("volume", v.Name).Warn("Cannot measure volume size, skipping the plan size check")
+		} else if !withinTolerance(v.SizeBytes, c.SizeBytes, tolerance) {
+			problems = append(problems, fmt.Sprintf("%s is %s, the plan expected %s", v.Name, utils.FormatBytes(c.SizeBytes), utils.FormatBytes(v.SizeBytes)))
+		}
+		v.Size, v.SizeBytes = c.Size, c.SizeBytes
+		volumes = append(volumes, v)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("environment no longer matches the plan: %s", strings.Join(problems, "; "))
+	}
+	return volumes, nil
+}
+
+// withinTolerance reports whether current differs from planned by at most tolerance percent,
+// or by minSizeSlack for small volumes
+func withinTolerance(planned, current int64, tolerance float64) bool {
+	diff := current - planned
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= max(int64(float64(planned)*tolerance/100), minSizeSlack)
+}
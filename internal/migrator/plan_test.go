@@ -0,0 +1,171 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"volume-migrator/internal/docker"
+)
+
+func TestSavePlan(t *testing.T) {
+	config := &Config{
+		Containers:            []string{"app"},
+		RemoteHost:            "deploy@db.example.com",
+		StrictHostKeyChecking: true,
+		CompressionLevel:      6,
+		CommandTimeout:        2 * time.Hour,
+		TempDir:               "/tmp/volume-migration-1",
+		Interactive:           true,
+		Verbose:               true,
+	}
+	volumes := []docker.VolumeInfo{
+		{Name: "app_data", Container: "app", MountPath: "/data", Size: "1.0 MB", SizeBytes: 1 << 20, Selected: true},
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	if err := SavePlan(path, NewPlan(config, volumes)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"version: 1", "remote_host: deploy@db.example.com", "command_timeout: 2h0m0s", "size_bytes: 1048576"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in plan:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"temp_dir", "interactive", "verbose", "selected"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("expected run-specific %q to be left out of the plan:\n%s", unwanted, content)
+		}
+	}
+
+	var plan Plan
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		t.Fatal(err)
+	}
+	want := *config
+	want.TempDir, want.Interactive, want.Verbose = "", false, false
+	if !reflect.DeepEqual(*plan.Config, want) {
+		t.Errorf("config did not survive the round trip:\n%+v\nwant\n%+v", *plan.Config, want)
+	}
+	volumes[0].Selected = false
+	if !reflect.DeepEqual(plan.Volumes, volumes) {
+		t.Errorf("volumes = %+v, want %+v", plan.Volumes, volumes)
+	}
+}
+
+func TestLoadPlan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.yaml")
+	config := &Config{RemoteHost: "deploy@host", ByVolume: true, Volumes: []string{"data"}}
+	if err := SavePlan(path, NewPlan(config, []docker.VolumeInfo{{Name: "data", SizeBytes: 42}})); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := LoadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Config.RemoteHost != "deploy@host" || len(plan.Volumes) != 1 || plan.Volumes[0].SizeBytes != 42 {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestLoadPlan_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown setting", "version: 1\nconfig:\n  remote_hots: deploy@host\nvolumes:\n  - name: data\n", "remote_hots"},
+		{"newer version", "version: 2\nconfig:\n  remote_host: deploy@host\nvolumes:\n  - name: data\n", "unsupported migration plan version 2"},
+		{"no config", "version: 1\nvolumes:\n  - name: data\n", "no config"},
+		{"no volumes", "version: 1\nconfig:\n  remote_host: deploy@host\n", "no volumes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPlan(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadPlan() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithinTolerance(t *testing.T) {
+	const gb = 1 << 30
+	tests := []struct {
+		name             string
+		planned, current int64
+		want             bool
+	}{
+		{"unchanged", gb, gb, true},
+		{"grown within tolerance", gb, gb + gb/20, true},
+		{"shrunk within tolerance", gb, gb - gb/20, true},
+		{"grown too much", gb, gb + gb/5, false},
+		{"shrunk too much", gb, gb / 2, false},
+		{"small volume gained files", 0, 512 * 1024, true},
+		{"small volume filled up", 0, 10 * 1024 * 1024, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withinTolerance(tt.planned, tt.current, DefaultSizeTolerance); got != tt.want {
+				t.Errorf("withinTolerance(%d, %d) = %v, want %v", tt.planned, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPlannedVolumes(t *testing.T) {
+	planned := []docker.VolumeInfo{
+		{Name: "db", SizeBytes: 100 << 20, TargetName: "db-renamed"},
+		{Name: "cache", SizeBytes: 10 << 20},
+	}
+
+	tests := []struct {
+		name    string
+		current []docker.VolumeInfo
+		wantErr string
+	}{
+		{"matching", []docker.VolumeInfo{{Name: "db", SizeBytes: 105 << 20}, {Name: "cache", SizeBytes: 10 << 20}}, ""},
+		{"unmeasurable size", []docker.VolumeInfo{{Name: "db", Size: "Unknown"}, {Name: "cache", SizeBytes: 10 << 20}}, ""},
+		{"missing", []docker.VolumeInfo{{Name: "db", SizeBytes: 100 << 20}}, "cache no longer exists"},
+		{"grown", []docker.VolumeInfo{{Name: "db", SizeBytes: 300 << 20}, {Name: "cache", SizeBytes: 10 << 20}}, "db is 300.0 MB, the plan expected 100.0 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{
+				config:       &Config{PlannedVolumes: planned, SizeTolerance: DefaultSizeTolerance},
+				dockerClient: &fakeDocker{volumes: tt.current},
+			}
+			volumes, err := m.checkPlannedVolumes()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("checkPlannedVolumes() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if volumes[0].TargetName != "db-renamed" || volumes[0].SizeBytes != tt.current[0].SizeBytes {
+				t.Errorf("expected the planned volume with its current size, got %+v", volumes[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Profile holds the flag values of a named migration target, keyed by flag name without dashes.
+// Values are strings, numbers or booleans; arrays set repeatable flags once per element.
+type Profile map[string]interface{}
+
+// ProfileValue is one flag assignment from a profile
+type ProfileValue struct {
+	Flag  string
+	Value string
+}
+
+// configFile is the layout of the config file
+type configFile struct {
+	Profiles  map[string]Profile        `json:"profiles"`
+	Telemetry TelemetrySettings         `json:"telemetry"`
+	Volumes   map[string]VolumeSettings `json:"volumes"`
+}
+
+// DefaultConfigFile returns the default config file location (~/.volume-migrator/config.json)
+func DefaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".volume-migrator", "config.json")
+	}
+	return filepath.Join(home, ".volume-migrator", "config.json")
+}
+
+// LoadProfile reads the named profile from the config file at path
+func LoadProfile(path, name string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config configFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for n := range config.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile '%s' not found in %s (available: %v)", name, path, names)
+	}
+	return profile, nil
+}
+
+// Values returns the flag assignments of the profile, sorted by flag name
+func (p Profile) Values() ([]ProfileValue, error) {
+	flags := make([]string, 0, len(p))
+	for flag := range p {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+
+	var values []ProfileValue
+	for _, flag := range flags {
+		if flag == "profile" || flag == "config" {
+			return nil, fmt.Errorf("profile cannot set --%s", flag)
+		}
+
+		items, isList := p[flag].([]interface{})
+		if !isList {
+			items = []interface{}{p[flag]}
+		}
+		for _, item := range items {
+			value, err := profileScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --%s in profile: %w", flag, err)
+			}
+			values = append(values, ProfileValue{Flag: flag, Value: value})
+		}
+	}
+	return values, nil
+}
+
+// profileScalar formats a JSON scalar as a flag value
+func profileScalar(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("must be a string, number, boolean or array of those")
+	}
+}
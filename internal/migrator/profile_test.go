@@ -0,0 +1,78 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"profiles": {"staging": {"remote": "deploy@staging"}, "dr-site": {"remote": "ops@dr"}}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := LoadProfile(path, "dr-site")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if profile["remote"] != "ops@dr" {
+		t.Errorf("unexpected profile: %v", profile)
+	}
+
+	_, err = LoadProfile(path, "prod")
+	if err == nil || !strings.Contains(err.Error(), "available: [dr-site staging]") {
+		t.Errorf("expected error listing available profiles, got: %v", err)
+	}
+
+	if _, err := LoadProfile(filepath.Join(t.TempDir(), "missing.json"), "staging"); err == nil {
+		t.Error("expected error for missing config file")
+	}
+}
+
+func TestProfileValues(t *testing.T) {
+	profile := Profile{
+		"remote":            "deploy@host",
+		"compression-level": float64(6),
+		"dedup":             true,
+		"exclude-volume":    []interface{}{"cache*", "tmp"},
+	}
+
+	values, err := profile.Values()
+	if err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	expected := []ProfileValue{
+		{"compression-level", "6"},
+		{"dedup", "true"},
+		{"exclude-volume", "cache*"},
+		{"exclude-volume", "tmp"},
+		{"remote", "deploy@host"},
+	}
+	if len(values) != len(expected) {
+		t.Fatalf("Values() = %v, want %v", values, expected)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("Values()[%d] = %v, want %v", i, values[i], expected[i])
+		}
+	}
+
+	tests := []struct {
+		name    string
+		profile Profile
+	}{
+		{"nested object", Profile{"remote": map[string]interface{}{"host": "x"}}},
+		{"null", Profile{"remote": nil}},
+		{"sets profile", Profile{"profile": "other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.profile.Values(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
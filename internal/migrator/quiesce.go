@@ -0,0 +1,183 @@
+package migrator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+)
+
+// Quiesce modes (--quiesce)
+const (
+	QuiesceOff  = "off"  // Export volumes as they are
+	QuiesceAuto = "auto" // Run the built-in recipe of each container whose image is a known datastore
+)
+
+// quiesceRecipe holds the commands run with sh -c in a datastore's container around an export:
+// Pre before it is read, Post once the archive is written (empty for none)
+type quiesceRecipe struct {
+	Name string
+	Pre  string
+	Post string
+}
+
+// mysqlQuiesceClient defines q, running the mariadb or mysql client as root with the password of the official images
+const mysqlQuiesceClient = `client=$(command -v mariadb || command -v mysql) || exit 1
+q() { "$client" -uroot ${MYSQL_ROOT_PASSWORD:+-p"$MYSQL_ROOT_PASSWORD"} ${MARIADB_ROOT_PASSWORD:+-p"$MARIADB_ROOT_PASSWORD"} "$@"; }
+`
+
+// mysqlQuiesceSession matches the processlist entry of the session holding the read lock
+const mysqlQuiesceSession = `INFO LIKE 'SELECT ''volume-migrator quiesce''%'`
+
+// mongoQuiesceClient defines q, running mongosh or the legacy mongo shell with the root user of the official image
+const mongoQuiesceClient = `client=$(command -v mongosh || command -v mongo) || exit 1
+q() { "$client" --quiet ${MONGO_INITDB_ROOT_USERNAME:+-u "$MONGO_INITDB_ROOT_USERNAME" -p "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin} --eval "$1"; }
+`
+
+// quiesceRecipes are the built-in recipes by name. A MySQL read lock only lasts as long as its
+// session, so one is left sleeping in the background until Post kills it. MongoDB keeps its
+// fsync lock until unlocked. Postgres and Redis are only flushed to disk.
+var quiesceRecipes = map[string]quiesceRecipe{
+	"postgres": {
+		Name: "postgres",
+		Pre:  `psql -U "${POSTGRES_USER:-postgres}" -c CHECKPOINT`,
+	},
+	"mysql": {
+		Name: "mysql",
+		Pre: mysqlQuiesceClient + `q -e "FLUSH TABLES WITH READ LOCK; SELECT 'volume-migrator quiesce', SLEEP(86400)" >/dev/null 2>&1 &
+i=0
+while [ $i -lt 30 ]; do
+	[ "$(q -N -e "SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE ` + mysqlQuiesceSession + `")" -gt 0 ] 2>/dev/null && exit 0
+	sleep 1
+	i=$((i + 1))
+done
+echo "read lock not acquired after 30s" >&2
+exit 1`,
+		Post: mysqlQuiesceClient + `for id in $(q -N -e "SELECT ID FROM information_schema.PROCESSLIST WHERE ` + mysqlQuiesceSession + `"); do
+	q -e "KILL $id"
+done`,
+	},
+	"redis": {
+		Name: "redis",
+		Pre:  `redis-cli ${REDIS_PASSWORD:+-a "$REDIS_PASSWORD" --no-auth-warning} SAVE`,
+	},
+	"mongodb": {
+		Name: "mongodb",
+		Pre:  mongoQuiesceClient + `q 'db.fsyncLock()'`,
+		Post: mongoQuiesceClient + `q 'db.fsyncUnlock()'`,
+	},
+}
+
+// quiesceImages maps image repository names to the recipe of the datastore they run
+var quiesceImages = map[string]string{
+	"postgres":                 "postgres",
+	"postgresql":               "postgres",
+	"postgis":                  "postgres",
+	"timescaledb":              "postgres",
+	"mysql":                    "mysql",
+	"mysql-server":             "mysql",
+	"mariadb":                  "mysql",
+	"percona-server":           "mysql",
+	"redis":                    "redis",
+	"redis-stack":              "redis",
+	"redis-stack-server":       "redis",
+	"mongo":                    "mongodb",
+	"mongodb":                  "mongodb",
+	"mongodb-community-server": "mongodb",
+}
+
+// ValidateQuiesce checks that mode is empty (off) or a supported quiesce mode
+func ValidateQuiesce(mode string) error {
+	switch mode {
+	case "", QuiesceOff, QuiesceAuto:
+		return nil
+	}
+	return fmt.Errorf("invalid quiesce mode '%s': must be one of off, auto", mode)
+}
+
+// imageRepository returns the last path element of an image reference without tag or digest,
+// such as postgres for docker.io/library/postgres:16
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	image, _, _ = strings.Cut(image, ":")
+	return strings.ToLower(image)
+}
+
+// recipeForImage returns the built-in recipe of the datastore an image runs
+func recipeForImage(image string) (quiesceRecipe, bool) {
+	name, ok := quiesceImages[imageRepository(image)]
+	if !ok {
+		return quiesceRecipe{}, false
+	}
+	return quiesceRecipes[name], true
+}
+
+// quiesceVolume runs the recipe of the container owning a volume before it is exported, with
+// --quiesce auto. The returned function runs the recipe's Post command and may be called more
+// than once; it still runs after Ctrl+C, since a locked datastore must not be left behind.
+func (m *Migrator) quiesceVolume(v docker.VolumeInfo) (func(), error) {
+	if m.config.Quiesce != QuiesceAuto || v.Container == "" || m.isDumpVolume(v) {
+		return func() {}, nil
+	}
+	output, err := m.dockerClient.ExecCommand("inspect", "--format", "{{.Config.Image}} {{.State.Running}}", v.Container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", v.Container, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("failed to inspect container %s: unexpected output %q", v.Container, output)
+	}
+	recipe, ok := recipeForImage(fields[0])
+	if !ok || fields[1] != "true" {
+		// A stopped container writes nothing, so its files are consistent already
+		return func() {}, nil
+	}
+
+	entry := log.WithFields(logrus.Fields{"volume": v.Name, "container": v.Container, "recipe": recipe.Name})
+	entry.Info("Quiescing datastore before export")
+	if err := quiesceExec(m.dockerClient, v.Container, recipe.Pre); err != nil {
+		// The lock may have been taken before the command failed
+		m.resumeDatastore(entry, v.Container, recipe)
+		return nil, fmt.Errorf("failed to quiesce %s in container %s (pass --quiesce off to export without it): %w", recipe.Name, v.Container, err)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { m.resumeDatastore(entry, v.Container, recipe) })
+	}, nil
+}
+
+// resumeDatastore runs the Post command of a recipe, logging a failure since the export itself succeeded
+func (m *Migrator) resumeDatastore(entry *logrus.Entry, container string, recipe quiesceRecipe) {
+	if recipe.Post == "" {
+		return
+	}
+	if err := quiesceExec(m.cleanupDocker(), container, recipe.Post); err != nil {
+		entry.WithError(err).Error("Failed to resume datastore after export; its writes may still be blocked")
+		return
+	}
+	entry.Debug("Resumed datastore")
+}
+
+// quiesceExec runs a recipe command with sh -c in a container
+func quiesceExec(dockerClient DockerRunner, container, script string) error {
+	var stdout, stderr bytes.Buffer
+	if err := dockerClient.ExecCommandWithOutput(&stdout, &stderr, "exec", container, "sh", "-c", script); err != nil {
+		return fmt.Errorf("%w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// cleanupDocker returns the local Docker runner for cleanup, which must still work once the run is cancelled
+func (m *Migrator) cleanupDocker() DockerRunner {
+	if m.dockerCleanup != nil {
+		return m.dockerCleanup
+	}
+	return m.dockerClient
+}
@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestRecipeForImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"postgres", "postgres"},
+		{"postgres:16-alpine", "postgres"},
+		{"docker.io/library/mysql:8.4", "mysql"},
+		{"bitnami/postgresql:latest", "postgres"},
+		{"mariadb@sha256:0123abcd", "mysql"},
+		{"registry.example.com:5000/cache/redis:7", "redis"},
+		{"Mongo:7", "mongodb"},
+		{"nginx:latest", ""},
+		{"mysql-exporter", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			recipe, ok := recipeForImage(tt.image)
+			if ok != (tt.want != "") || recipe.Name != tt.want {
+				t.Errorf("recipeForImage(%q) = %q, %v, want %q", tt.image, recipe.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuiesce(t *testing.T) {
+	for _, mode := range []string{"", QuiesceOff, QuiesceAuto} {
+		if err := ValidateQuiesce(mode); err != nil {
+			t.Errorf("ValidateQuiesce(%q) error = %v", mode, err)
+		}
+	}
+	if err := ValidateQuiesce("postgres"); err == nil {
+		t.Error("ValidateQuiesce(postgres) succeeded, want error")
+	}
+}
+
+func TestQuiesceVolume(t *testing.T) {
+	volume := docker.VolumeInfo{Name: "db_data", Container: "db"}
+
+	tests := []struct {
+		name     string
+		mode     string
+		inspect  string
+		execErr  error
+		wantCmds []string // exec commands, by recipe step
+		wantErr  bool
+	}{
+		{"off", QuiesceOff, "mongo:7 true", nil, nil, false},
+		{"unknown image", QuiesceAuto, "nginx true", nil, nil, false},
+		{"stopped container", QuiesceAuto, "mongo:7 false", nil, nil, false},
+		{"recipe without resume step", QuiesceAuto, "postgres:16 true", nil, []string{"pre"}, false},
+		{"lock and unlock", QuiesceAuto, "mongo:7 true", nil, []string{"pre", "post"}, false},
+		{"failed lock is released", QuiesceAuto, "mongo:7 true", errors.New("exit status 1"), []string{"pre", "post"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dockerClient := &fakeDocker{responses: map[string]fakeResponse{
+				"inspect": {output: tt.inspect + "\n"},
+				"exec":    {err: tt.execErr},
+			}}
+			m := &Migrator{config: &Config{Quiesce: tt.mode}, dockerClient: dockerClient}
+
+			resume, err := m.quiesceVolume(volume)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("quiesceVolume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resume != nil {
+				resume()
+				resume()
+			}
+
+			var steps []string
+			for _, cmd := range dockerClient.commands {
+				switch {
+				case !strings.HasPrefix(cmd, "exec db sh -c "):
+				case strings.Contains(cmd, "fsyncUnlock"):
+					steps = append(steps, "post")
+				default:
+					steps = append(steps, "pre")
+				}
+			}
+			if strings.Join(steps, ",") != strings.Join(tt.wantCmds, ",") {
+				t.Errorf("recipe steps = %v, want %v (commands %q)", steps, tt.wantCmds, dockerClient.commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package migrator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/shell"
+)
+
+// DefaultRemoteArchiveDir is where --keep-remote-archives keeps archives on the remote host;
+// unlike /tmp, /var/tmp survives reboots
+const DefaultRemoteArchiveDir = "/var/tmp/volume-migrator-archives"
+
+// generationTimeFormat names generation directories so they sort oldest first
+const generationTimeFormat = "20060102-150405"
+
+// RetainRemoteArchives moves the imported archives, with the archive manifest and its signature,
+// from the remote temp dir into a new generation directory under archiveDir, then removes all
+// but the newest keep generations. It returns the generation directory.
+func RetainRemoteArchives(sshClient RemoteExecutor, archives []string, remoteTempDir, archiveDir string, keep int, now time.Time) (string, error) {
+	generation := path.Join(archiveDir, now.UTC().Format(generationTimeFormat))
+	if err := sshClient.CreateDirectory(generation); err != nil {
+		return "", err
+	}
+
+	files := append([]string(nil), archives...)
+	for _, name := range []string{ArchiveManifestFile, manifestSignatureFile} {
+		manifest := path.Join(remoteTempDir, name)
+		if exists, err := sshClient.FileExists(manifest); err == nil && exists {
+			files = append(files, manifest)
+		}
+	}
+	escaped := make([]string, len(files))
+	for i, f := range files {
+		escaped[i] = shell.ShellEscape(f)
+	}
+	if _, err := sshClient.RunCommand(fmt.Sprintf("mv %s %s/", strings.Join(escaped, " "), shell.ShellEscape(generation))); err != nil {
+		return "", fmt.Errorf("failed to move archives to %s: %w", generation, err)
+	}
+
+	if err := rotateRemoteArchives(sshClient, archiveDir, keep); err != nil {
+		return generation, err
+	}
+	return generation, nil
+}
+
+// rotateRemoteArchives removes the oldest generation directories under archiveDir, keeping keep
+// Entries not named like a generation are left alone
+func rotateRemoteArchives(sshClient RemoteExecutor, archiveDir string, keep int) error {
+	output, err := sshClient.RunCommand("ls -1 " + shell.ShellEscape(archiveDir))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", archiveDir, err)
+	}
+
+	var generations []string
+	for _, name := range strings.Fields(output) {
+		if _, err := time.Parse(generationTimeFormat, name); err == nil {
+			generations = append(generations, name)
+		}
+	}
+	sort.Strings(generations)
+
+	for len(generations) > keep {
+		old := path.Join(archiveDir, generations[0])
+		log.WithField("generation", old).Info("Removing old remote archive generation")
+		if err := sshClient.RemoveDirectory(old); err != nil {
+			return fmt.Errorf("failed to remove old archive generation: %w", err)
+		}
+		generations = generations[1:]
+	}
+	log.WithFields(logrus.Fields{
+		"archive_dir": archiveDir,
+		"generations": len(generations),
+	}).Debug("Rotated remote archive generations")
+	return nil
+}
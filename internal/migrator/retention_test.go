@@ -0,0 +1,43 @@
+package migrator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetainRemoteArchives(t *testing.T) {
+	remote := &fakeRemote{shell: map[string]fakeResponse{
+		"ls -1 ": {output: "20260101-000000\n20260102-000000\nnotes\n20261014-120000\n"},
+	}}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	generation, err := RetainRemoteArchives(remote, []string{"/tmp/run/db.tar.gz", "/tmp/run/cache.tar.gz"}, "/tmp/run", "/var/tmp/archives", 2, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generation != "/var/tmp/archives/20261014-120000" {
+		t.Errorf("generation = %s", generation)
+	}
+	if !slices.Contains(remote.ran, "mv /tmp/run/db.tar.gz /tmp/run/cache.tar.gz /var/tmp/archives/20261014-120000/") {
+		t.Errorf("expected the archives to be moved, commands: %v", remote.ran)
+	}
+	if !slices.Equal(remote.removed, []string{"/var/tmp/archives/20260101-000000"}) {
+		t.Errorf("removed = %v, want only the oldest generation", remote.removed)
+	}
+}
+
+func TestRetainRemoteArchives_MoveFails(t *testing.T) {
+	remote := &fakeRemote{shell: map[string]fakeResponse{
+		"mv ": {err: errors.New("mv: cannot create directory")},
+	}}
+
+	generation, err := RetainRemoteArchives(remote, []string{"/tmp/run/db.tar.gz"}, "/tmp/run", "/var/tmp/archives", 1, time.Now())
+	if err == nil || generation != "" {
+		t.Errorf("expected no generation and an error, got %q, %v", generation, err)
+	}
+	if len(remote.removed) > 0 {
+		t.Errorf("expected nothing to be removed, removed: %v", remote.removed)
+	}
+}
@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+)
+
+// BuildRunCommand returns a docker run command recreating a source container on the remote host,
+// mounting the migrated volumes (remoteNames maps source volume names to their remote names).
+// Each option goes on its own continued line so the command can be pasted into a shell.
+// The warnings name what the command cannot reproduce faithfully.
+func BuildRunCommand(spec *docker.ContainerSpec, remoteNames map[string]string) (string, []string) {
+	args, warnings := buildRunArgs(spec, remoteNames)
+	return "docker " + strings.Join(args, " \\\n  "), warnings
+}
+
+// buildRunArgs returns the shell-escaped docker arguments of BuildRunCommand, one option per element
+func buildRunArgs(spec *docker.ContainerSpec, remoteNames map[string]string) ([]string, []string) {
+	var warnings []string
+	lines := []string{"run -d", "--name " + shell.ShellEscape(spec.Name)}
+	option := func(flag, value string) {
+		lines = append(lines, flag+" "+shell.ShellEscape(value))
+	}
+
+	if spec.Restart != "" {
+		option("--restart", spec.Restart)
+	}
+	if spec.NetworkMode != "" {
+		option("--network", spec.NetworkMode)
+	}
+	if spec.NetworkMode != "host" {
+		for _, port := range spec.Ports {
+			option("-p", port)
+		}
+	}
+	if spec.User != "" {
+		option("-u", spec.User)
+	}
+	if spec.WorkingDir != "" {
+		option("-w", spec.WorkingDir)
+	}
+	for _, env := range spec.Env {
+		option("-e", env)
+	}
+
+	keys := make([]string, 0, len(spec.Labels))
+	for key := range spec.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		option("-l", key+"="+spec.Labels[key])
+	}
+
+	for _, mount := range spec.Mounts {
+		entry, _, warning := remoteMount(spec.Name, mount, remoteNames)
+		if entry != "" {
+			option("-v", entry)
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	// --entrypoint takes a single executable; its remaining arguments precede the command
+	args := spec.Command
+	if len(spec.Entrypoint) > 0 {
+		option("--entrypoint", spec.Entrypoint[0])
+		args = append(append([]string(nil), spec.Entrypoint[1:]...), spec.Command...)
+	}
+
+	image := shell.ShellEscape(spec.Image)
+	for _, arg := range args {
+		image += " " + shell.ShellEscape(arg)
+	}
+	lines = append(lines, image)
+
+	return lines, warnings
+}
+
+// printRunCommands prints a docker run command for each source container, mounting the volumes
+// that were migrated; volumes lists every volume of the run and succeeded the migrated ones
+func (m *Migrator) printRunCommands(volumes []docker.VolumeInfo, succeeded []string) error {
+	specs, err := m.sourceContainerSpecs()
+	if err != nil {
+		return err
+	}
+	remoteNames := migratedVolumeNames(volumes, succeeded)
+
+	fmt.Printf("\nRun these commands on %s to recreate the source containers:\n", m.config.RemoteHost)
+	for _, spec := range specs {
+		cmd, warnings := BuildRunCommand(spec, remoteNames)
+		for _, warning := range warnings {
+			log.Warn(warning)
+		}
+		fmt.Printf("\n%s\n", cmd)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestBuildRunCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         *docker.ContainerSpec
+		remoteNames  map[string]string
+		expected     string
+		warningCount int
+	}{
+		{
+			name: "published ports, environment and volumes",
+			spec: &docker.ContainerSpec{
+				Name:    "web",
+				Image:   "nginx",
+				Env:     []string{"APP_ENV=production"},
+				Ports:   []string{"8080:80"},
+				Restart: "always",
+				Labels:  map[string]string{"tier": "front", "app": "shop"},
+				Mounts: []docker.MountInfo{
+					{Type: "volume", Name: "static", Destination: "/usr/share/nginx/html", ReadOnly: true},
+					{Type: "bind", Source: "/srv/nginx.conf", Destination: "/etc/nginx/nginx.conf"},
+				},
+			},
+			remoteNames: map[string]string{"static": "static-renamed"},
+			expected: "docker run -d \\\n  --name web \\\n  --restart always \\\n  -p '8080:80' \\\n" +
+				"  -e 'APP_ENV=production' \\\n  -l 'app=shop' \\\n  -l 'tier=front' \\\n" +
+				"  -v 'static-renamed:/usr/share/nginx/html:ro' \\\n  -v '/srv/nginx.conf:/etc/nginx/nginx.conf' \\\n  nginx",
+			warningCount: 1,
+		},
+		{
+			name: "entrypoint with arguments and host networking",
+			spec: &docker.ContainerSpec{
+				Name:        "worker",
+				Image:       "busybox",
+				Entrypoint:  []string{"sh", "-c"},
+				Command:     []string{"sleep infinity"},
+				User:        "1000",
+				WorkingDir:  "/work",
+				Ports:       []string{"9000:9000"},
+				NetworkMode: "host",
+				Mounts:      []docker.MountInfo{{Type: "tmpfs", Destination: "/run"}},
+			},
+			expected: "docker run -d \\\n  --name worker \\\n  --network host \\\n  -u 1000 \\\n  -w /work \\\n" +
+				"  --entrypoint sh \\\n  busybox -c 'sleep infinity'",
+			warningCount: 1,
+		},
+		{
+			name: "image defaults only",
+			spec: &docker.ContainerSpec{
+				Name:    "cache",
+				Image:   "redis",
+				Command: []string{"redis-server", "--appendonly", "yes"},
+				Mounts:  []docker.MountInfo{{Type: "volume", Name: "cache", Destination: "/data"}},
+			},
+			remoteNames: map[string]string{"cache": "cache"},
+			expected:    "docker run -d \\\n  --name cache \\\n  -v 'cache:/data' \\\n  redis redis-server --appendonly yes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, warnings := BuildRunCommand(tt.spec, tt.remoteNames)
+			if cmd != tt.expected {
+				t.Errorf("BuildRunCommand() =\n%s\nwant:\n%s", cmd, tt.expected)
+			}
+			if len(warnings) != tt.warningCount {
+				t.Errorf("expected %d warnings, got: %v", tt.warningCount, warnings)
+			}
+		})
+	}
+}
+
+func TestBuildRunCommand_DoesNotModifySpec(t *testing.T) {
+	spec := &docker.ContainerSpec{
+		Name:       "worker",
+		Image:      "busybox",
+		Entrypoint: []string{"sh", "-c"},
+		Command:    make([]string, 1, 4),
+	}
+	spec.Command[0] = "true"
+	BuildRunCommand(spec, nil)
+	if strings.Join(spec.Entrypoint, " ") != "sh -c" || strings.Join(spec.Command, " ") != "true" {
+		t.Errorf("spec was modified: %+v", spec)
+	}
+}
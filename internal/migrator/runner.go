@@ -0,0 +1,47 @@
+package migrator
+
+import (
+	"bytes"
+	"io"
+
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/ssh"
+)
+
+// DockerRunner runs docker commands against the local daemon
+// *docker.Client is the real implementation; tests substitute fakes
+type DockerRunner interface {
+	ExecCommand(args ...string) (string, error)
+	ExecCommandWithOutput(stdout, stderr *bytes.Buffer, args ...string) error
+	ExecCommandStream(stdout io.Writer, stderr *bytes.Buffer, args ...string) error
+	ListContainers(filters []string) ([]string, error)
+	GetAllVolumesInfo(containerNames []string) ([]docker.VolumeInfo, error)
+	GetVolumesInfoByName(volumeNames []string) ([]docker.VolumeInfo, error)
+	GetUnmigratedMounts(containerNames []string) ([]docker.UnmigratedMount, error)
+	IsDockerDesktop() bool
+	DaemonHost() string
+	Escalation() shell.Escalation
+}
+
+// RemoteExecutor runs commands and transfers files on the remote host
+// *ssh.Client is the real implementation; tests substitute fakes
+type RemoteExecutor interface {
+	RunCommand(cmd string) (string, error)
+	RunDockerCommand(args ...string) (string, error)
+	RunDockerCommandWithInput(input io.Reader, args ...string) (string, error)
+	CreateDirectory(path string) error
+	RemoveFile(path string) error
+	RemoveDirectory(path string) error
+	FileExists(remotePath string) (bool, error)
+	TransferFile(localPath, remotePath string, showProgress bool) error
+	TransferFileChunked(localPath, remotePath string, chunkSize int64, showProgress bool) error
+	StreamFileToDocker(localPath string, showProgress bool, args ...string) error
+	RequiresEscalation() bool
+	Escalation() shell.Escalation
+}
+
+var (
+	_ DockerRunner   = (*docker.Client)(nil)
+	_ RemoteExecutor = (*ssh.Client)(nil)
+)
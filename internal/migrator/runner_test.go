@@ -0,0 +1,246 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+)
+
+// fakeResponse is the canned result of a command
+type fakeResponse struct {
+	output string
+	err    error
+}
+
+// respondTo records cmd and returns the response with the longest matching prefix,
+// or empty output when none matches
+func respondTo(commands *[]string, responses map[string]fakeResponse, cmd string) (string, error) {
+	*commands = append(*commands, cmd)
+	best, found := "", false
+	for prefix := range responses {
+		if strings.HasPrefix(cmd, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	if !found {
+		return "", nil
+	}
+	return responses[best].output, responses[best].err
+}
+
+// fakeRemote is a RemoteExecutor answering docker commands from canned responses
+type fakeRemote struct {
+	responses map[string]fakeResponse // Keyed by docker command prefix, such as "volume inspect"
+	commands  []string                // Docker commands in the order they ran
+	removed   []string                // Files and directories removed
+	shell     map[string]fakeResponse // Keyed by shell command prefix
+	ran       []string                // Shell commands in the order they ran
+}
+
+func (f *fakeRemote) RunCommand(cmd string) (string, error) {
+	return respondTo(&f.ran, f.shell, cmd)
+}
+func (f *fakeRemote) RunDockerCommand(args ...string) (string, error) {
+	return respondTo(&f.commands, f.responses, strings.Join(args, " "))
+}
+func (f *fakeRemote) RunDockerCommandWithInput(input io.Reader, args ...string) (string, error) {
+	return f.RunDockerCommand(args...)
+}
+func (f *fakeRemote) CreateDirectory(path string) error { return nil }
+func (f *fakeRemote) RemoveFile(path string) error {
+	f.removed = append(f.removed, path)
+	return nil
+}
+func (f *fakeRemote) RemoveDirectory(path string) error {
+	f.removed = append(f.removed, path)
+	return nil
+}
+func (f *fakeRemote) FileExists(remotePath string) (bool, error)                         { return false, nil }
+func (f *fakeRemote) TransferFile(localPath, remotePath string, showProgress bool) error { return nil }
+func (f *fakeRemote) TransferFileChunked(localPath, remotePath string, chunkSize int64, showProgress bool) error {
+	return nil
+}
+func (f *fakeRemote) StreamFileToDocker(localPath string, showProgress bool, args ...string) error {
+	_, err := f.RunDockerCommand(args...)
+	return err
+}
+func (f *fakeRemote) RequiresEscalation() bool     { return false }
+func (f *fakeRemote) Escalation() shell.Escalation { return shell.Escalation{Name: "none"} }
+
+// fakeDocker is a DockerRunner answering docker commands from canned responses
+type fakeDocker struct {
+	responses  map[string]fakeResponse
+	commands   []string
+	containers []string
+	volumes    []docker.VolumeInfo      // Returned by GetVolumesInfoByName
+	mounts     []docker.UnmigratedMount // Returned by GetUnmigratedMounts
+}
+
+func (f *fakeDocker) ExecCommand(args ...string) (string, error) {
+	return respondTo(&f.commands, f.responses, strings.Join(args, " "))
+}
+func (f *fakeDocker) ExecCommandWithOutput(stdout, stderr *bytes.Buffer, args ...string) error {
+	output, err := f.ExecCommand(args...)
+	stdout.WriteString(output)
+	return err
+}
+func (f *fakeDocker) ExecCommandStream(stdout io.Writer, stderr *bytes.Buffer, args ...string) error {
+	output, err := f.ExecCommand(args...)
+	io.WriteString(stdout, output)
+	return err
+}
+func (f *fakeDocker) ListContainers(filters []string) ([]string, error) { return f.containers, nil }
+func (f *fakeDocker) GetAllVolumesInfo(containerNames []string) ([]docker.VolumeInfo, error) {
+	return nil, nil
+}
+func (f *fakeDocker) GetVolumesInfoByName(volumeNames []string) ([]docker.VolumeInfo, error) {
+	return f.volumes, nil
+}
+func (f *fakeDocker) GetUnmigratedMounts(containerNames []string) ([]docker.UnmigratedMount, error) {
+	return f.mounts, nil
+}
+func (f *fakeDocker) IsDockerDesktop() bool        { return false }
+func (f *fakeDocker) DaemonHost() string           { return "" }
+func (f *fakeDocker) Escalation() shell.Escalation { return shell.Escalation{Name: "none"} }
+
+func TestImportVolume_CleanupOnFailure(t *testing.T) {
+	errExtract := errors.New("tar: short read")
+	tests := []struct {
+		name       string
+		existed    bool
+		wantRemove bool
+	}{
+		{"created volume is removed", false, true},
+		{"existing volume is kept", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{responses: map[string]fakeResponse{
+				"volume inspect": {err: errors.New("no such volume")},
+				"run --rm":       {err: errExtract},
+			}}
+			if tt.existed {
+				remote.responses["volume inspect"] = fakeResponse{output: `[{"Name": "data"}]`}
+			}
+
+			err := ImportVolume(remote, "data", "/tmp/remote/data.tar.gz", ImportOptions{})
+			if !errors.Is(err, errExtract) {
+				t.Fatalf("expected the extraction error, got: %v", err)
+			}
+			if removed := slices.Contains(remote.commands, "volume rm data"); removed != tt.wantRemove {
+				t.Errorf("volume removed = %v, want %v (commands: %v)", removed, tt.wantRemove, remote.commands)
+			}
+		})
+	}
+}
+
+func TestCopyImportVolume_RemovesContainer(t *testing.T) {
+	remote := &fakeRemote{responses: map[string]fakeResponse{
+		"cp --archive": {err: errors.New("copy failed")},
+	}}
+
+	if err := CopyImportVolume(remote, "data", "/tmp/data.tar.gz", ImportOptions{}); err == nil {
+		t.Fatal("expected the copy error")
+	}
+
+	container := copyContainerPrefix + "data"
+	for _, want := range []string{"pause " + container, "unpause " + container, "rm -f " + container} {
+		if !slices.Contains(remote.commands, want) {
+			t.Errorf("expected %q to run, commands: %v", want, remote.commands)
+		}
+	}
+}
+
+func TestCheckVolumesInUse(t *testing.T) {
+	volumes := []docker.VolumeInfo{{Name: "db"}, {Name: "cache"}}
+	tests := []struct {
+		name    string
+		users   string
+		config  Config
+		wantErr bool
+	}{
+		{"unused volumes", "", Config{}, false},
+		{"volume in use", "app\n", Config{}, true},
+		{"forced", "app\n", Config{ForceInUse: true}, false},
+		{"consumers stopped", "app\n", Config{StopRemoteConsumers: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{responses: map[string]fakeResponse{
+				"ps --filter volume=db": {output: tt.users},
+			}}
+			m := &Migrator{config: &tt.config, sshClient: remote}
+
+			err := m.checkVolumesInUse(volumes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkVolumesInUse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStopRemoteConsumers(t *testing.T) {
+	remote := &fakeRemote{responses: map[string]fakeResponse{
+		"ps --filter volume=db":    {output: "app\nworker\n"},
+		"ps --filter volume=cache": {output: "app\n"},
+	}}
+	m := &Migrator{config: &Config{}, sshClient: remote}
+
+	restart, err := m.stopRemoteConsumers([]docker.VolumeInfo{{Name: "db"}, {Name: "cache"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(remote.commands, "stop app worker") {
+		t.Errorf("expected each consumer to be stopped once, commands: %v", remote.commands)
+	}
+
+	restart()
+	if last := remote.commands[len(remote.commands)-1]; last != "start app worker" {
+		t.Errorf("expected consumers to be started again, got: %s", last)
+	}
+}
+
+func TestEnumerateContainers(t *testing.T) {
+	config := &Config{ContainerFilters: []string{"label=backup"}}
+	m := &Migrator{config: config, dockerClient: &fakeDocker{containers: []string{"web", "db"}}}
+
+	if err := m.enumerateContainers(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(config.Containers, []string{"web", "db"}) {
+		t.Errorf("Containers = %v, want [web db]", config.Containers)
+	}
+}
+
+func TestLocalEnvironment(t *testing.T) {
+	local := &fakeDocker{responses: map[string]fakeResponse{
+		"info": {output: "24.0.7|overlay2|6.1.0|x86_64|linux\n"},
+	}}
+
+	env, err := LocalEnvironment(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.DockerVersion != "24.0.7" || env.StorageDriver != "overlay2" {
+		t.Errorf("unexpected environment: %+v", env)
+	}
+}
+
+func TestCleanupRemoteArchives(t *testing.T) {
+	remote := &fakeRemote{}
+
+	if err := CleanupRemoteArchives(remote, map[string]string{"data": "data.tar.gz"}, "/tmp/remote"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(remote.removed, []string{"/tmp/remote/data.tar.gz"}) {
+		t.Errorf("removed = %v, want [/tmp/remote/data.tar.gz]", remote.removed)
+	}
+}
@@ -0,0 +1,133 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// archivePartName returns the file name of part n of an archive split with --max-archive-size
+func archivePartName(archive string, n int) string {
+	return fmt.Sprintf("%s.part%03d", archive, n)
+}
+
+// splitWriter writes a stream to numbered part files of at most size bytes each,
+// so no single file exceeds the file-size limit of the disks it passes through
+type splitWriter struct {
+	base    string
+	size    int64
+	file    *os.File
+	written int64 // Bytes in the current part
+	parts   []string
+}
+
+// newSplitWriter creates a writer splitting into base.part000, base.part001, ...
+func newSplitWriter(base string, size int64) *splitWriter {
+	return &splitWriter{base: base, size: size}
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		if w.file == nil || w.written == w.size {
+			if err := w.nextPart(); err != nil {
+				return total, err
+			}
+		}
+		chunk := p
+		if room := w.size - w.written; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		n, err := w.file.Write(chunk)
+		total += n
+		w.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// nextPart closes the current part and opens the next one
+func (w *splitWriter) nextPart() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to write archive part: %w", err)
+		}
+	}
+	name := archivePartName(w.base, len(w.parts))
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create archive part: %w", err)
+	}
+	w.file, w.written = file, 0
+	w.parts = append(w.parts, name)
+	return nil
+}
+
+// Close closes the last part; an empty stream still gets one (empty) part
+// Closing more than once is a no-op
+func (w *splitWriter) Close() error {
+	if w.file == nil && len(w.parts) == 0 {
+		if err := w.nextPart(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to write archive part: %w", err)
+	}
+	return nil
+}
+
+// ArchiveFiles returns the files an archive was written to: its numbered parts when it was
+// split with --max-archive-size, otherwise the archive itself
+func ArchiveFiles(archivePath string) []string {
+	var parts []string
+	for n := 0; ; n++ {
+		part := archivePartName(archivePath, n)
+		if _, err := os.Stat(part); err != nil {
+			break
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return []string{archivePath}
+	}
+	return parts
+}
+
+// removeArchiveFiles removes an archive or all of its parts
+func removeArchiveFiles(archivePath string) {
+	for _, file := range ArchiveFiles(archivePath) {
+		os.Remove(file)
+	}
+}
+
+// archiveSize returns the total size of the files of an archive
+func archiveSize(files []string) (int64, error) {
+	var total int64
+	for _, file := range files {
+		stat, err := os.Stat(file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat archive: %w", err)
+		}
+		total += stat.Size()
+	}
+	return total, nil
+}
+
+// remoteArchiveFiles returns the remote paths the local archive files are uploaded to
+func remoteArchiveFiles(files []string, remoteTempDir string) []string {
+	remote := make([]string, len(files))
+	for i, file := range files {
+		remote[i] = path.Join(remoteTempDir, filepath.Base(file))
+	}
+	return remote
+}
@@ -0,0 +1,80 @@
+package migrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      int
+		size      int64
+		wantParts []int64
+	}{
+		{"smaller than a part", 5, 10, []int64{5}},
+		{"exact multiple", 20, 10, []int64{10, 10}},
+		{"remainder", 25, 10, []int64{10, 10, 5}},
+		{"empty stream", 0, 10, []int64{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := filepath.Join(t.TempDir(), "data.tar.gz")
+			data := bytes.Repeat([]byte("x"), tt.data)
+
+			w := newSplitWriter(base, tt.size)
+			// Uneven writes cross part boundaries
+			for _, chunk := range [][]byte{data[:tt.data/3], data[tt.data/3:]} {
+				if _, err := w.Write(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("second Close() error = %v", err)
+			}
+
+			files := ArchiveFiles(base)
+			if !slices.Equal(files, w.parts) {
+				t.Errorf("ArchiveFiles() = %v, want %v", files, w.parts)
+			}
+			var sizes []int64
+			var joined []byte
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes = append(sizes, int64(len(content)))
+				joined = append(joined, content...)
+			}
+			if !slices.Equal(sizes, tt.wantParts) {
+				t.Errorf("part sizes = %v, want %v", sizes, tt.wantParts)
+			}
+			if !bytes.Equal(joined, data) {
+				t.Error("concatenated parts differ from the written data")
+			}
+		})
+	}
+}
+
+func TestArchiveFiles_Unsplit(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "data.tar.gz")
+	if files := ArchiveFiles(archive); !slices.Equal(files, []string{archive}) {
+		t.Errorf("ArchiveFiles() = %v, want [%s]", files, archive)
+	}
+}
+
+func TestRemoteArchiveFiles(t *testing.T) {
+	files := []string{"/tmp/local/data.tar.gz.part000", "/tmp/local/data.tar.gz.part001"}
+	want := []string{"/tmp/remote/data.tar.gz.part000", "/tmp/remote/data.tar.gz.part001"}
+	if got := remoteArchiveFiles(files, "/tmp/remote"); !slices.Equal(got, want) {
+		t.Errorf("remoteArchiveFiles() = %v, want %v", got, want)
+	}
+}
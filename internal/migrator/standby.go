@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Standby keeps a warm copy of the volumes on a standby host: it runs the migration every
+// config.StandbyInterval until ctx is cancelled. Each run connects afresh and, with --incremental,
+// only sends what changed since the previous one. A failed run is logged and retried at the next
+// interval; only an invalid configuration ends the loop early.
+func Standby(ctx context.Context, config *Config) error {
+	for run := 1; ; run++ {
+		// NewMigrator fills in defaults such as a fresh temp directory, so each run gets its own copy
+		runConfig := *config
+		m, err := NewMigrator(ctx, &runConfig)
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+		fields := log.WithField("run", run)
+		fields.Info("Starting standby sync")
+		err = m.Migrate()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fields.WithError(err).Error("Standby sync failed, retrying at the next interval")
+		}
+
+		next := started.Add(config.StandbyInterval)
+		fields.WithFields(logrus.Fields{
+			"duration": time.Since(started).Round(time.Second),
+			"next_run": next.Format(time.RFC3339),
+		}).Info("Waiting for the next standby sync")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
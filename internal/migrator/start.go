@@ -0,0 +1,205 @@
+package migrator
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/ssh"
+)
+
+// DefaultHealthTimeout is how long --start-remote waits for the recreated containers to come up
+const DefaultHealthTimeout = 2 * time.Minute
+
+// healthPollInterval is how often the recreated containers are checked while waiting for them
+const healthPollInterval = 2 * time.Second
+
+// portDialTimeout bounds each attempt to connect to a published port
+const portDialTimeout = 2 * time.Second
+
+// containerStateFormat prints the status of a container and, when it has a health check, its health
+const containerStateFormat = "{{.State.Status}} {{if .State.Health}}{{.State.Health.Status}}{{end}}"
+
+// startRecreatedContainers recreates the source containers on the remote host on top of the
+// migrated volumes and waits for them to come up; volumes lists every volume of the run and
+// succeeded the migrated ones
+func (m *Migrator) startRecreatedContainers(volumes []docker.VolumeInfo, succeeded []string) error {
+	specs, err := m.sourceContainerSpecs()
+	if err != nil {
+		return err
+	}
+	host, err := ssh.HostName(m.config.RemoteHost)
+	if err != nil {
+		return err
+	}
+	remoteNames := migratedVolumeNames(volumes, succeeded)
+
+	log.Info("=== Phase 3.5: Start Remote Containers ===")
+	for _, spec := range specs {
+		if err := startRemoteContainer(m.sshClient, spec, remoteNames); err != nil {
+			return err
+		}
+	}
+
+	timeout := m.config.HealthTimeout
+	if timeout == 0 {
+		timeout = DefaultHealthTimeout
+	}
+	return WaitForRemoteContainers(m.sshClient, specs, host, timeout, healthPollInterval)
+}
+
+// startRemoteContainer creates and starts a container like spec on the remote host.
+// A remote container of the same name is started as it is instead, since replacing it could lose its data.
+func startRemoteContainer(remote RemoteExecutor, spec *docker.ContainerSpec, remoteNames map[string]string) error {
+	fields := log.WithFields(logrus.Fields{"container": spec.Name, "image": spec.Image})
+	if _, err := remote.RunDockerCommand("container", "inspect", shell.ShellEscape(spec.Name)); err == nil {
+		fields.Warn("Remote container already exists, starting it instead of recreating it")
+		if _, err := remote.RunDockerCommand("start", shell.ShellEscape(spec.Name)); err != nil {
+			return fmt.Errorf("failed to start remote container %s: %w", spec.Name, err)
+		}
+		return nil
+	}
+
+	args, warnings := buildRunArgs(spec, remoteNames)
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+	fields.Info("Creating remote container")
+	if _, err := remote.RunDockerCommand(args...); err != nil {
+		return fmt.Errorf("failed to create remote container %s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// WaitForRemoteContainers polls the remote containers until each one is up or the timeout passes.
+// A container with a health check is up once it reports healthy; one without is up once it runs
+// and its published TCP ports accept connections on host. A container that exits or turns
+// unhealthy fails at once.
+func WaitForRemoteContainers(remote RemoteExecutor, specs []*docker.ContainerSpec, host string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	pending := slices.Clone(specs)
+	waiting := make(map[string]string) // Why each pending container is not up yet
+
+	for {
+		var still []*docker.ContainerSpec
+		for _, spec := range pending {
+			up, reason, err := remoteContainerUp(remote, spec, host)
+			if err != nil {
+				return err
+			}
+			if up {
+				log.WithField("container", spec.Name).Info("Remote container is up")
+				continue
+			}
+			waiting[spec.Name] = reason
+			still = append(still, spec)
+		}
+		pending = still
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			var details []string
+			for _, spec := range pending {
+				details = append(details, spec.Name+" ("+waiting[spec.Name]+")")
+			}
+			return fmt.Errorf("remote containers not up after %s: %s", timeout, strings.Join(details, ", "))
+		}
+		log.WithField("containers", len(pending)).Debug("Waiting for remote containers to come up")
+		time.Sleep(interval)
+	}
+}
+
+// remoteContainerUp checks a remote container once, returning why it is not up yet.
+// The error is set when it will not come up at all.
+func remoteContainerUp(remote RemoteExecutor, spec *docker.ContainerSpec, host string) (bool, string, error) {
+	output, err := remote.RunDockerCommand("container", "inspect", "--format", shell.ShellEscape(containerStateFormat), shell.ShellEscape(spec.Name))
+	if err != nil {
+		return false, "", fmt.Errorf("failed to inspect remote container %s: %w", spec.Name, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return false, "", fmt.Errorf("failed to inspect remote container %s: empty state", spec.Name)
+	}
+	status, health := fields[0], ""
+	if len(fields) > 1 {
+		health = fields[1]
+	}
+
+	switch status {
+	case "running":
+	case "exited", "dead":
+		return false, "", fmt.Errorf("remote container %s %s; see docker logs %s on the remote host", spec.Name, status, spec.Name)
+	default:
+		return false, status, nil // created or restarting
+	}
+
+	switch health {
+	case "healthy":
+		return true, "", nil
+	case "unhealthy":
+		return false, "", fmt.Errorf("remote container %s is unhealthy", spec.Name)
+	case "":
+	default:
+		return false, "health check " + health, nil
+	}
+
+	// Host networking publishes no ports of its own to check
+	if spec.NetworkMode == "host" {
+		return true, "", nil
+	}
+	for _, port := range spec.Ports {
+		address, ok := publishedAddress(port, host)
+		if !ok {
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", address, portDialTimeout)
+		if err != nil {
+			return false, "port " + address + " not reachable", nil
+		}
+		conn.Close()
+	}
+	return true, "", nil
+}
+
+// publishedAddress returns the address a published [ip:][host:]container[/udp] port is reached at
+// from this machine. UDP ports, ports published on a random host port and ports bound to the
+// remote loopback interface cannot be checked from here.
+func publishedAddress(port, host string) (string, bool) {
+	if strings.HasSuffix(port, "/udp") {
+		return "", false
+	}
+	ip := ""
+	if strings.HasPrefix(port, "[") {
+		end := strings.Index(port, "]")
+		if end == -1 {
+			return "", false
+		}
+		ip, port = port[1:end], strings.TrimPrefix(port[end+1:], ":")
+	}
+	parts := strings.Split(port, ":")
+	switch {
+	case len(parts) == 3 && ip == "":
+		ip = parts[0]
+		parts = parts[1:]
+	case len(parts) != 2:
+		return "", false
+	}
+
+	if parts[0] == "" {
+		return "", false
+	}
+	if ip != "" {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.IsLoopback() {
+			return "", false
+		}
+		host = ip
+	}
+	return net.JoinHostPort(host, parts[0]), true
+}
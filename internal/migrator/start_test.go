@@ -0,0 +1,126 @@
+package migrator
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestPublishedAddress(t *testing.T) {
+	tests := []struct {
+		port     string
+		expected string
+		ok       bool
+	}{
+		{"8080:80", "remote.example:8080", true},
+		{"10.0.0.5:8080:80", "10.0.0.5:8080", true},
+		{"[2001:db8::1]:8080:80", "[2001:db8::1]:8080", true},
+		{"127.0.0.1:8080:80", "", false},
+		{"[::1]:8080:80", "", false},
+		{"53:53/udp", "", false},
+		{"80", "", false},
+		{"10.0.0.5::80", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.port, func(t *testing.T) {
+			address, ok := publishedAddress(tt.port, "remote.example")
+			if address != tt.expected || ok != tt.ok {
+				t.Errorf("publishedAddress(%q) = %q, %v, want %q, %v", tt.port, address, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWaitForRemoteContainers(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, openPort, _ := net.SplitHostPort(listener.Addr().String())
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		state   string
+		ports   []string
+		network string
+		wantErr string
+	}{
+		{"healthy", "running healthy", nil, "", ""},
+		{"running with a reachable port", "running ", []string{openPort + ":80"}, "", ""},
+		{"host networking", "running ", []string{closedPort + ":80"}, "host", ""},
+		{"unreachable port times out", "running ", []string{closedPort + ":80"}, "", "not reachable"},
+		{"health check still starting", "running starting", nil, "", "health check starting"},
+		{"restarting", "restarting ", nil, "", "(restarting)"},
+		{"exited", "exited ", nil, "", "web exited"},
+		{"unhealthy", "running unhealthy", nil, "", "unhealthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{responses: map[string]fakeResponse{
+				"container inspect --format": {output: tt.state + "\n"},
+			}}
+			specs := []*docker.ContainerSpec{{Name: "web", Ports: tt.ports, NetworkMode: tt.network}}
+
+			err := WaitForRemoteContainers(remote, specs, "127.0.0.1", 10*time.Millisecond, time.Millisecond)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected the container to be up, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStartRemoteContainer(t *testing.T) {
+	spec := &docker.ContainerSpec{
+		Name:   "web",
+		Image:  "nginx",
+		Mounts: []docker.MountInfo{{Type: "volume", Name: "static", Destination: "/data"}},
+	}
+
+	t.Run("creates a missing container", func(t *testing.T) {
+		remote := &fakeRemote{responses: map[string]fakeResponse{
+			"container inspect": {err: errors.New("no such container")},
+		}}
+		if err := startRemoteContainer(remote, spec, map[string]string{"static": "static-renamed"}); err != nil {
+			t.Fatal(err)
+		}
+		want := "run -d --name web -v 'static-renamed:/data' nginx"
+		if !slices.Contains(remote.commands, want) {
+			t.Errorf("expected %q, got: %v", want, remote.commands)
+		}
+	})
+
+	t.Run("starts an existing container", func(t *testing.T) {
+		remote := &fakeRemote{responses: map[string]fakeResponse{
+			"container inspect": {output: `[{"Name": "/web"}]`},
+		}}
+		if err := startRemoteContainer(remote, spec, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(remote.commands, "start web") || slices.ContainsFunc(remote.commands, func(cmd string) bool {
+			return strings.HasPrefix(cmd, "run ")
+		}) {
+			t.Errorf("expected the existing container to be started, got: %v", remote.commands)
+		}
+	})
+}
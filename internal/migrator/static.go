@@ -0,0 +1,236 @@
+package migrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/shell"
+)
+
+// EmbeddedHelperBinary selects the busybox binary compiled into the tool (builds with -tags embedbusybox)
+const EmbeddedHelperBinary = "embedded"
+
+// staticHelperRepository names the images built from a static busybox binary, tagged with its hash
+const staticHelperRepository = "volume-migrator-helper"
+
+// busyboxApplets are the commands the export, import, verify and remap scripts run in the helper
+var busyboxApplets = []string{
+	"sh", "tar", "gzip", "du", "find", "stat", "sha256sum", "xargs", "chown", "sed", "head",
+	"cat", "ls", "mkdir", "rm", "cp", "ln", "mv", "chmod", "test", "[", "grep", "dirname",
+}
+
+// elfArchitectures maps ELF machine types to the uname names docker info reports
+var elfArchitectures = map[elf.Machine]string{
+	elf.EM_X86_64:  "x86_64",
+	elf.EM_AARCH64: "aarch64",
+	elf.EM_386:     "i386",
+	elf.EM_ARM:     "armv7l",
+	elf.EM_PPC64:   "ppc64le",
+	elf.EM_S390:    "s390x",
+	elf.EM_RISCV:   "riscv64",
+}
+
+// staticHelper is a minimal helper image built with docker import from a static busybox binary
+type staticHelper struct {
+	Image        string // volume-migrator-helper:<hash>, identical on both hosts
+	Architecture string // Architecture the binary runs on
+	rootfs       []byte // Image filesystem as a tar archive
+}
+
+// loadHelperBinary reads the static busybox binary from path, or the embedded one
+func loadHelperBinary(binaryPath string) ([]byte, error) {
+	if binaryPath == EmbeddedHelperBinary {
+		if len(embeddedBusybox) == 0 {
+			return nil, fmt.Errorf("this build has no embedded helper binary (build with -tags embedbusybox) - pass --helper-binary with the path to a static busybox instead")
+		}
+		return embeddedBusybox, nil
+	}
+	data, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read helper binary: %w", err)
+	}
+	return data, nil
+}
+
+// binaryArchitecture returns the architecture of a static ELF executable
+func binaryArchitecture(binary []byte) (string, error) {
+	f, err := elf.NewFile(bytes.NewReader(binary))
+	if err != nil {
+		return "", fmt.Errorf("helper binary is not a Linux executable: %w", err)
+	}
+	defer f.Close()
+
+	if f.Type != elf.ET_EXEC && f.Type != elf.ET_DYN {
+		return "", fmt.Errorf("helper binary is not an executable (ELF type %s)", f.Type)
+	}
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			return "", fmt.Errorf("helper binary is dynamically linked: a static busybox is required, since the helper image has no libraries")
+		}
+	}
+	arch, ok := elfArchitectures[f.Machine]
+	if !ok {
+		return "", fmt.Errorf("unsupported helper binary architecture %s", f.Machine)
+	}
+	return arch, nil
+}
+
+// buildHelperRootfs returns a tar archive holding /bin/busybox, one symlink per applet and /tmp
+func buildHelperRootfs(binary []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	modTime := time.Unix(0, 0)
+
+	headers := []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime},
+		{Name: "tmp/", Typeflag: tar.TypeDir, Mode: 01777, ModTime: modTime},
+	}
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(binary)), ModTime: modTime}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(binary); err != nil {
+		return nil, err
+	}
+	for _, applet := range busyboxApplets {
+		link := &tar.Header{Name: "bin/" + applet, Typeflag: tar.TypeSymlink, Linkname: "busybox", Mode: 0777, ModTime: modTime}
+		if err := tw.WriteHeader(link); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newStaticHelper prepares the helper image for the binary at binaryPath (or the embedded one)
+func newStaticHelper(binaryPath string) (*staticHelper, error) {
+	binary, err := loadHelperBinary(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	arch, err := binaryArchitecture(binary)
+	if err != nil {
+		return nil, err
+	}
+	rootfs, err := buildHelperRootfs(binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build helper image filesystem: %w", err)
+	}
+
+	sum := sha256.Sum256(binary)
+	return &staticHelper{
+		Image:        staticHelperRepository + ":" + hex.EncodeToString(sum[:])[:12],
+		Architecture: arch,
+		rootfs:       rootfs,
+	}, nil
+}
+
+// checkArchitecture fails when the binary cannot run on a host of the given architecture
+func (h *staticHelper) checkArchitecture(side, arch string) error {
+	if arch != h.Architecture {
+		return fmt.Errorf("helper binary is built for %s but the %s host is %s", h.Architecture, side, arch)
+	}
+	return nil
+}
+
+// writeRootfs writes the image filesystem archive to tempDir for docker import or upload
+func (h *staticHelper) writeRootfs(tempDir string) (string, error) {
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	rootfsPath := filepath.Join(tempDir, "helper-rootfs.tar")
+	if err := os.WriteFile(rootfsPath, h.rootfs, 0644); err != nil {
+		return "", fmt.Errorf("failed to write helper image filesystem: %w", err)
+	}
+	return rootfsPath, nil
+}
+
+// ensureLocal imports the helper image into the local image store unless it is already there
+func (h *staticHelper) ensureLocal(dockerClient DockerRunner, tempDir string) error {
+	if _, err := dockerClient.ExecCommand("image", "inspect", h.Image); err == nil {
+		return nil
+	}
+
+	rootfsPath, err := h.writeRootfs(tempDir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(rootfsPath)
+
+	log.WithField("helper_image", h.Image).Info("Building helper image from static binary locally")
+	if _, err := dockerClient.ExecCommand("import", rootfsPath, h.Image); err != nil {
+		return fmt.Errorf("failed to import helper image locally: %w", err)
+	}
+	return nil
+}
+
+// ensureRemote uploads the helper image filesystem and imports it on the remote host unless it is already there
+func (h *staticHelper) ensureRemote(sshClient RemoteExecutor, tempDir, remoteTempDir string) error {
+	if _, err := sshClient.RunDockerCommand("image inspect " + shell.ShellEscape(h.Image)); err == nil {
+		return nil
+	}
+
+	localPath, err := h.writeRootfs(tempDir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(localPath)
+
+	remotePath := path.Join(remoteTempDir, "helper-rootfs.tar")
+	log.WithFields(logrus.Fields{
+		"helper_image": h.Image,
+		"size":         len(h.rootfs),
+	}).Info("Uploading static helper binary to remote host")
+	if err := sshClient.CreateDirectory(remoteTempDir); err != nil {
+		return fmt.Errorf("failed to create remote temp directory: %w", err)
+	}
+	if err := sshClient.TransferFile(localPath, remotePath, false); err != nil {
+		return fmt.Errorf("failed to upload helper binary: %w", err)
+	}
+	defer func() {
+		if err := sshClient.RemoveFile(remotePath); err != nil {
+			log.WithError(err).Warn("Failed to remove helper image filesystem from remote host")
+		}
+	}()
+
+	if _, err := sshClient.RunDockerCommand(fmt.Sprintf("import %s %s", shell.ShellEscape(remotePath), shell.ShellEscape(h.Image))); err != nil {
+		return fmt.Errorf("failed to import helper image on remote host: %w", err)
+	}
+	return nil
+}
+
+// prepareStaticHelper builds the helper image from --helper-binary on both hosts and returns its name
+func (m *Migrator) prepareStaticHelper() (string, error) {
+	helper, err := newStaticHelper(m.config.HelperBinary)
+	if err != nil {
+		return "", err
+	}
+	if err := helper.checkArchitecture("local", m.localEnv.Architecture); err != nil {
+		return "", err
+	}
+	if err := helper.checkArchitecture("remote", m.remoteEnv.Architecture); err != nil {
+		return "", err
+	}
+	if err := helper.ensureLocal(m.dockerClient, m.config.TempDir); err != nil {
+		return "", err
+	}
+	if err := helper.ensureRemote(m.sshClient, m.config.TempDir, m.config.RemoteTempDir); err != nil {
+		return "", err
+	}
+	return helper.Image, nil
+}
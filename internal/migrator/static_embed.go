@@ -0,0 +1,10 @@
+//go:build embedbusybox
+
+package migrator
+
+import _ "embed"
+
+// embeddedBusybox is the static busybox fetched by make busybox
+//
+//go:embed assets/busybox
+var embeddedBusybox []byte
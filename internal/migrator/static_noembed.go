@@ -0,0 +1,6 @@
+//go:build !embedbusybox
+
+package migrator
+
+// embeddedBusybox is empty unless the tool is built with -tags embedbusybox
+var embeddedBusybox []byte
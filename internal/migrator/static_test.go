@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testELF returns a minimal 64-bit little-endian ELF executable header for machine,
+// with a PT_INTERP program header when dynamic is set
+func testELF(t *testing.T, machine elf.Machine, dynamic bool) []byte {
+	t.Helper()
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     64,
+		Ehsize:    64,
+		Phentsize: 56,
+		Phnum:     1,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	prog := elf.Prog64{Type: uint32(elf.PT_LOAD)}
+	if dynamic {
+		prog.Type = uint32(elf.PT_INTERP)
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, prog); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBinaryArchitecture(t *testing.T) {
+	tests := []struct {
+		name    string
+		binary  []byte
+		want    string
+		wantErr string
+	}{
+		{name: "static amd64", binary: testELF(t, elf.EM_X86_64, false), want: "x86_64"},
+		{name: "static arm64", binary: testELF(t, elf.EM_AARCH64, false), want: "aarch64"},
+		{name: "dynamic", binary: testELF(t, elf.EM_X86_64, true), wantErr: "dynamically linked"},
+		{name: "not ELF", binary: []byte("#!/bin/sh\n"), wantErr: "not a Linux executable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := binaryArchitecture(tt.binary)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("binaryArchitecture() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("binaryArchitecture() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildHelperRootfs(t *testing.T) {
+	binary := testELF(t, elf.EM_X86_64, false)
+	rootfs, err := buildHelperRootfs(binary)
+	if err != nil {
+		t.Fatalf("buildHelperRootfs() error = %v", err)
+	}
+
+	entries := make(map[string]*tar.Header)
+	tr := tar.NewReader(bytes.NewReader(rootfs))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[h.Name] = h
+	}
+
+	if h := entries["bin/busybox"]; h == nil || h.Size != int64(len(binary)) || h.Mode != 0755 {
+		t.Errorf("bin/busybox entry = %+v, want executable of %d bytes", h, len(binary))
+	}
+	for _, applet := range []string{"sh", "tar", "du", "sha256sum"} {
+		if h := entries["bin/"+applet]; h == nil || h.Typeflag != tar.TypeSymlink || h.Linkname != "busybox" {
+			t.Errorf("bin/%s entry = %+v, want symlink to busybox", applet, h)
+		}
+	}
+	if h := entries["tmp/"]; h == nil || h.Mode != 01777 {
+		t.Errorf("tmp/ entry = %+v, want world-writable sticky directory", h)
+	}
+}
+
+func TestStaticHelper_CheckArchitecture(t *testing.T) {
+	helper := &staticHelper{Image: staticHelperRepository + ":abc", Architecture: "x86_64"}
+	if err := helper.checkArchitecture("remote", "x86_64"); err != nil {
+		t.Errorf("checkArchitecture() error = %v", err)
+	}
+	if err := helper.checkArchitecture("remote", "aarch64"); err == nil || !strings.Contains(err.Error(), "remote host is aarch64") {
+		t.Errorf("checkArchitecture() error = %v, want architecture mismatch", err)
+	}
+}
+
+func TestLoadHelperBinary_NotEmbedded(t *testing.T) {
+	if len(embeddedBusybox) > 0 {
+		t.Skip("built with an embedded helper binary")
+	}
+	if _, err := loadHelperBinary(EmbeddedHelperBinary); err == nil || !strings.Contains(err.Error(), "-tags embedbusybox") {
+		t.Errorf("loadHelperBinary(embedded) error = %v, want build tag hint", err)
+	}
+}
@@ -0,0 +1,55 @@
+package migrator
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/ssh"
+	"volume-migrator/internal/utils"
+)
+
+// volumeTransfer records the upload of one volume archive
+type volumeTransfer struct {
+	Volume   string
+	Bytes    int64
+	Duration time.Duration
+}
+
+// rate returns the average throughput in bytes/second
+func (t volumeTransfer) rate() float64 {
+	if t.Duration <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Duration.Seconds()
+}
+
+// totalTransfer sums the bytes and time spent uploading every volume
+func totalTransfer(transfers []volumeTransfer) volumeTransfer {
+	total := volumeTransfer{Volume: "total"}
+	for _, t := range transfers {
+		total.Bytes += t.Bytes
+		total.Duration += t.Duration
+	}
+	return total
+}
+
+// transferFields returns the log fields describing a transfer
+func transferFields(t volumeTransfer) logrus.Fields {
+	return logrus.Fields{
+		"volume":     t.Volume,
+		"size":       utils.FormatBytes(t.Bytes),
+		"duration":   t.Duration.Round(time.Second).String(),
+		"throughput": ssh.FormatRate(t.rate()),
+	}
+}
+
+// logTransferSummary logs the throughput of each volume and of the whole run
+func logTransferSummary(transfers []volumeTransfer) {
+	if len(transfers) == 0 {
+		return
+	}
+	for _, t := range transfers {
+		log.WithFields(transferFields(t)).Info("Transfer summary")
+	}
+	log.WithFields(transferFields(totalTransfer(transfers))).Info("Transfer summary")
+}
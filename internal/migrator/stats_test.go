@@ -0,0 +1,27 @@
+package migrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTotalTransfer(t *testing.T) {
+	transfers := []volumeTransfer{
+		{Volume: "vol1", Bytes: 30 * 1024 * 1024, Duration: 2 * time.Second},
+		{Volume: "vol2", Bytes: 10 * 1024 * 1024, Duration: 2 * time.Second},
+	}
+
+	total := totalTransfer(transfers)
+	if total.Bytes != 40*1024*1024 || total.Duration != 4*time.Second {
+		t.Errorf("totalTransfer() = %+v, want 40 MB in 4s", total)
+	}
+	if rate := total.rate(); rate != 10*1024*1024 {
+		t.Errorf("total rate = %f, want %d", rate, 10*1024*1024)
+	}
+	if rate := transfers[0].rate(); rate != 15*1024*1024 {
+		t.Errorf("vol1 rate = %f, want %d", rate, 15*1024*1024)
+	}
+	if rate := (volumeTransfer{Bytes: 1}).rate(); rate != 0 {
+		t.Errorf("expected zero rate without duration, got %f", rate)
+	}
+}
@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"volume-migrator/internal/shell"
+)
+
+// driverStatusFormat is the docker info template printing the storage driver's status pairs
+const driverStatusFormat = "{{json .DriverStatus}}"
+
+// caseInsensitiveFilesystems are the filesystem types, as stat -f names them, that fold the case
+// of file names, so names differing only in case land on the same file
+var caseInsensitiveFilesystems = map[string]bool{
+	"msdos":   true,
+	"vfat":    true,
+	"exfat":   true,
+	"hfs":     true,
+	"hfsplus": true,
+	"cifs":    true,
+	"smb2":    true,
+	"smb3":    true,
+	"v9fs":    true, // WSL mounts of Windows drives
+}
+
+// minNameMax is the longest file name, in bytes, Linux filesystems usually allow
+const minNameMax = 255
+
+// StorageInfo describes where the remote Docker engine keeps volume data
+type StorageInfo struct {
+	Driver            string // Storage driver, such as overlay2
+	BackingFilesystem string // Filesystem under the storage driver, as docker info reports it
+	DType             string // Whether the backing filesystem supports d_type, "true" or "false"; empty if unreported
+	Filesystem        string // Type of the filesystem holding the Docker root, as stat -f names it
+	NameMax           int    // Longest file name on that filesystem in bytes, 0 if unknown
+}
+
+// RemoteStorage inspects the remote storage driver and the filesystem holding dockerRoot
+func RemoteStorage(sshClient RemoteExecutor, driver, dockerRoot string) (StorageInfo, error) {
+	info := StorageInfo{Driver: driver}
+
+	output, err := sshClient.RunDockerCommand("info --format " + shell.ShellEscape(driverStatusFormat))
+	if err != nil {
+		return info, fmt.Errorf("failed to read remote storage driver status: %w", err)
+	}
+	var status [][]string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &status); err == nil {
+		for _, pair := range status {
+			if len(pair) != 2 {
+				continue
+			}
+			switch pair[0] {
+			case "Backing Filesystem":
+				info.BackingFilesystem = pair[1]
+			case "Supports d_type":
+				info.DType = pair[1]
+			}
+		}
+	}
+
+	root := shell.ShellEscape(dockerRoot)
+	output, err = sshClient.RunCommand(fmt.Sprintf("stat -f -c %%T %s; getconf NAME_MAX %s", root, root))
+	if err != nil {
+		return info, fmt.Errorf("failed to inspect remote filesystem of %s: %w", dockerRoot, err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	info.Filesystem = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		info.NameMax, _ = strconv.Atoi(strings.TrimSpace(lines[1]))
+	}
+	return info, nil
+}
+
+// StorageIssues reports remote storage setups known to break containers or extracted volume data.
+// None of them is fatal: the data may not be affected.
+func StorageIssues(info StorageInfo) []CompatIssue {
+	var issues []CompatIssue
+
+	if info.Driver == "overlay2" && info.BackingFilesystem == "xfs" && info.DType == "false" {
+		issues = append(issues, CompatIssue{Message: "remote overlay2 storage driver runs on xfs without d_type support (formatted with ftype=0): " +
+			"containers started on the migrated volumes may see missing or corrupted files; reformat with mkfs.xfs -n ftype=1"})
+	}
+
+	if info.Driver == "btrfs" || info.Filesystem == "btrfs" {
+		issues = append(issues, CompatIssue{Message: "remote Docker root is on btrfs: copy-on-write fragments database files rewritten in place " +
+			"(consider chattr +C on their volume directories), and free space reported by df may be inaccurate"})
+	}
+
+	if caseInsensitiveFilesystems[info.Filesystem] {
+		issues = append(issues, CompatIssue{Message: fmt.Sprintf(
+			"remote Docker root is on a case-insensitive %s filesystem: files whose names differ only in case overwrite each other when extracted",
+			info.Filesystem)})
+	}
+
+	if info.NameMax > 0 && info.NameMax < minNameMax {
+		issues = append(issues, CompatIssue{Message: fmt.Sprintf(
+			"remote Docker root %s filesystem allows file names of at most %d bytes: longer names fail to extract",
+			info.Filesystem, info.NameMax)})
+	}
+
+	return issues
+}
@@ -0,0 +1,53 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoteStorage(t *testing.T) {
+	remote := &fakeRemote{
+		responses: map[string]fakeResponse{"info": {output: `[["Backing Filesystem","xfs"],["Supports d_type","false"],["Using metacopy","false"]]` + "\n"}},
+		shell:     map[string]fakeResponse{"stat -f": {output: "xfs\n255\n"}},
+	}
+	got, err := RemoteStorage(remote, "overlay2", "/var/lib/docker")
+	if err != nil {
+		t.Fatalf("RemoteStorage() error = %v", err)
+	}
+	want := StorageInfo{Driver: "overlay2", BackingFilesystem: "xfs", DType: "false", Filesystem: "xfs", NameMax: 255}
+	if got != want {
+		t.Errorf("RemoteStorage() = %+v, want %+v", got, want)
+	}
+	if !strings.Contains(remote.ran[0], "NAME_MAX /var/lib/docker") {
+		t.Errorf("filesystem probe %q does not name the Docker root", remote.ran[0])
+	}
+}
+
+func TestStorageIssues(t *testing.T) {
+	tests := []struct {
+		name string
+		info StorageInfo
+		want []string // Substrings of the issues, in order
+	}{
+		{"overlay2 on ext4", StorageInfo{Driver: "overlay2", BackingFilesystem: "extfs", DType: "true", Filesystem: "ext2/ext3", NameMax: 255}, nil},
+		{"xfs without d_type", StorageInfo{Driver: "overlay2", BackingFilesystem: "xfs", DType: "false", Filesystem: "xfs", NameMax: 255}, []string{"ftype=0"}},
+		{"xfs with d_type", StorageInfo{Driver: "overlay2", BackingFilesystem: "xfs", DType: "true", Filesystem: "xfs"}, nil},
+		{"btrfs driver", StorageInfo{Driver: "btrfs", Filesystem: "btrfs"}, []string{"btrfs"}},
+		{"case-insensitive", StorageInfo{Driver: "overlay2", Filesystem: "v9fs"}, []string{"case-insensitive v9fs"}},
+		{"short names", StorageInfo{Driver: "overlay2", Filesystem: "ecryptfs", NameMax: 143}, []string{"at most 143 bytes"}},
+		{"nothing known", StorageInfo{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := StorageIssues(tt.info)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("StorageIssues() = %+v, want %d issues", issues, len(tt.want))
+			}
+			for i, issue := range issues {
+				if issue.Fatal || !strings.Contains(issue.Message, tt.want[i]) {
+					t.Errorf("issue %d = %+v, want a warning containing %q", i, issue, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	migerrors "volume-migrator/internal/errors"
+)
+
+const (
+	// TelemetryEnv turns telemetry on (1, true) or off (0, false), over the config file
+	TelemetryEnv = "VOLUME_MIGRATOR_TELEMETRY"
+	// TelemetryEndpointEnv sets the URL reports are posted to, over the config file
+	TelemetryEndpointEnv = "VOLUME_MIGRATOR_TELEMETRY_ENDPOINT"
+)
+
+// telemetryTimeout bounds posting a report, so an unreachable collector never delays the exit for long
+const telemetryTimeout = 5 * time.Second
+
+// TelemetrySettings is the telemetry section of the config file. Telemetry is off unless it is
+// enabled there or by TelemetryEnv, and an endpoint is set.
+type TelemetrySettings struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint"`
+}
+
+// Active reports whether runs should be reported
+func (s TelemetrySettings) Active() bool {
+	return s.Enabled && s.Endpoint != ""
+}
+
+// LoadTelemetrySettings reads the telemetry section of the config file at path, then applies
+// TelemetryEnv and TelemetryEndpointEnv. A missing config file leaves telemetry off.
+func LoadTelemetrySettings(path string) (TelemetrySettings, error) {
+	config, err := readConfigFile(path)
+	if err != nil {
+		return TelemetrySettings{}, err
+	}
+	settings := config.Telemetry
+
+	if value := os.Getenv(TelemetryEnv); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return TelemetrySettings{}, fmt.Errorf("invalid %s '%s': must be true or false", TelemetryEnv, value)
+		}
+		settings.Enabled = enabled
+	}
+	if endpoint := os.Getenv(TelemetryEndpointEnv); endpoint != "" {
+		settings.Endpoint = endpoint
+	}
+	return settings, nil
+}
+
+// TelemetryReport holds the anonymized statistics of one run. It never contains volume,
+// container or host names, paths or addresses.
+type TelemetryReport struct {
+	Version         string  `json:"version"`
+	OS              string  `json:"os"`
+	Arch            string  `json:"arch"`
+	Volumes         int     `json:"volumes"`          // Volumes selected for the run
+	Migrated        int     `json:"migrated"`         // Volumes uploaded
+	TotalBytes      int64   `json:"total_bytes"`      // Archive bytes uploaded
+	DurationSeconds float64 `json:"duration_seconds"` // Whole run
+	TransferSeconds float64 `json:"transfer_seconds"` // Spent uploading
+	Format          string  `json:"format"`
+	ImportMethod    string  `json:"import_method"`
+	Result          string  `json:"result"` // SUCCESS, or the --output json error code
+}
+
+// TelemetryReport describes the finished run: version is the build's, duration the time the
+// run took and err what Migrate returned
+func (m *Migrator) TelemetryReport(version string, duration time.Duration, err error) TelemetryReport {
+	total := totalTransfer(m.transfers)
+	report := TelemetryReport{
+		Version:         version,
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Volumes:         m.volumeCount,
+		Migrated:        len(m.transfers),
+		TotalBytes:      total.Bytes,
+		DurationSeconds: duration.Seconds(),
+		TransferSeconds: total.Duration.Seconds(),
+		Format:          m.config.ArchiveFormat,
+		ImportMethod:    m.config.ImportMethod,
+		Result:          "SUCCESS",
+	}
+	if report.Format == "" {
+		report.Format = FormatTarGz
+	}
+	if err != nil {
+		report.Result = migerrors.NewReport(err).Code
+	}
+	return report
+}
+
+// SendTelemetry posts report as JSON to endpoint
+func SendTelemetry(ctx context.Context, endpoint string, report TelemetryReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, telemetryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid telemetry endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
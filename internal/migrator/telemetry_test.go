@@ -0,0 +1,107 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	migerrors "volume-migrator/internal/errors"
+)
+
+func TestLoadTelemetrySettings(t *testing.T) {
+	dir := t.TempDir()
+	enabled := filepath.Join(dir, "enabled.json")
+	content := `{"profiles": {}, "telemetry": {"enabled": true, "endpoint": "https://telemetry.example.com/runs"}}`
+	if err := os.WriteFile(enabled, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		env      string
+		endpoint string
+		want     bool
+	}{
+		{"missing config file", filepath.Join(dir, "missing.json"), "", "", false},
+		{"enabled in config file", enabled, "", "", true},
+		{"disabled by env", enabled, "false", "", false},
+		{"enabled by env without endpoint", filepath.Join(dir, "missing.json"), "1", "", false},
+		{"enabled by env", filepath.Join(dir, "missing.json"), "true", "https://collector.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TelemetryEnv, tt.env)
+			t.Setenv(TelemetryEndpointEnv, tt.endpoint)
+			settings, err := LoadTelemetrySettings(tt.path)
+			if err != nil {
+				t.Fatalf("LoadTelemetrySettings() error = %v", err)
+			}
+			if settings.Active() != tt.want {
+				t.Errorf("Active() = %v, want %v (settings %+v)", settings.Active(), tt.want, settings)
+			}
+		})
+	}
+
+	t.Setenv(TelemetryEnv, "sometimes")
+	if _, err := LoadTelemetrySettings(enabled); err == nil {
+		t.Error("expected error for invalid env value")
+	}
+}
+
+func TestTelemetryReport(t *testing.T) {
+	m := &Migrator{
+		config:      &Config{RemoteHost: "deploy@prod.example.com"},
+		volumeCount: 3,
+		transfers: []volumeTransfer{
+			{Volume: "app_data", Bytes: 1000, Duration: 2 * time.Second},
+			{Volume: "app_db", Bytes: 500, Duration: time.Second},
+		},
+	}
+	report := m.TelemetryReport("1.2.0", time.Minute, migerrors.NewPartialMigrationError([]string{"app_data", "app_db"}, []string{"cache"}))
+
+	if report.Volumes != 3 || report.Migrated != 2 || report.TotalBytes != 1500 || report.TransferSeconds != 3 {
+		t.Errorf("unexpected statistics: %+v", report)
+	}
+	if report.Result != migerrors.CodePartialMigration || report.Format != FormatTarGz {
+		t.Errorf("unexpected result or format: %+v", report)
+	}
+
+	data, _ := json.Marshal(report)
+	for _, name := range []string{"app_data", "cache", "prod.example.com", "deploy"} {
+		if strings.Contains(string(data), name) {
+			t.Errorf("report contains %q: %s", name, data)
+		}
+	}
+}
+
+func TestSendTelemetry(t *testing.T) {
+	var received TelemetryReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := SendTelemetry(context.Background(), server.URL, TelemetryReport{Version: "1.2.0", Result: "SUCCESS"}); err != nil {
+		t.Fatalf("SendTelemetry() error = %v", err)
+	}
+	if received.Version != "1.2.0" || received.Result != "SUCCESS" {
+		t.Errorf("received %+v", received)
+	}
+
+	if err := SendTelemetry(context.Background(), server.URL+"/missing\x7f", TelemetryReport{}); err == nil {
+		t.Error("expected error for invalid endpoint")
+	}
+}
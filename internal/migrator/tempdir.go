@@ -0,0 +1,155 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/utils"
+)
+
+// remoteTempCandidates are the remote directories the temp directory may be created in when
+// --remote-temp-dir is not given, in order of preference when they have as much free space
+var remoteTempCandidates = []string{"/tmp", "/var/tmp", "/var", "/data", "/"}
+
+// chooseRemoteTempBase returns the candidate directory with the most free space among those the
+// SSH user can write to. /tmp is returned when none can be measured.
+func chooseRemoteTempBase(remote utils.RemoteCommandRunner) string {
+	spaces, err := utils.GetWritableRemoteDiskSpace(remote, remoteTempCandidates)
+	if err != nil {
+		log.WithError(err).Debug("Failed to measure remote temp directory candidates, using /tmp")
+		return remoteTempCandidates[0]
+	}
+	best := ""
+	for _, dir := range remoteTempCandidates {
+		info, ok := spaces[dir]
+		if !ok {
+			continue
+		}
+		log.WithFields(logrus.Fields{
+			"dir":       dir,
+			"available": utils.FormatBytes(int64(info.Available)),
+			"mount":     info.MountPoint,
+		}).Debug("Remote temp directory candidate")
+		if best == "" || info.Available > spaces[best].Available {
+			best = dir
+		}
+	}
+	if best == "" {
+		return remoteTempCandidates[0]
+	}
+	return best
+}
+
+// selectRemoteTempDir moves the default remote temp directory to the candidate with the most free
+// space. A --remote-temp-dir given on the command line is kept as it is.
+func (m *Migrator) selectRemoteTempDir() {
+	if !m.autoRemoteTempDir {
+		return
+	}
+	base := chooseRemoteTempBase(m.sshClient)
+	m.config.RemoteTempDir = path.Join(base, path.Base(m.config.RemoteTempDir))
+	log.WithField("remote_temp_dir", m.config.RemoteTempDir).Info("Selected remote temp directory with the most free space, set --remote-temp-dir to override")
+}
+
+// localTempCandidates are the local directories the temp directory may move to when the system
+// temp directory is unsuitable, the system temp directory first
+func localTempCandidates() []string {
+	candidates := []string{os.TempDir()}
+	if runtime.GOOS != "windows" {
+		candidates = append(candidates, "/var/tmp")
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		candidates = append(candidates, cache)
+	}
+	return candidates
+}
+
+// localTempSpace measures the filesystem of an existing local directory, failing when it cannot be written to
+func localTempSpace(dir string) (*utils.DiskSpaceInfo, error) {
+	probe, err := os.CreateTemp(dir, ".volume-migration-probe-*")
+	if err != nil {
+		return nil, err
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return utils.GetLocalDiskSpace(dir)
+}
+
+// unsuitableTempReason says why a filesystem should not hold the temp directory, empty when it can.
+// tmpfs holds the archives in memory, and a nearly full filesystem leaves no room for them.
+func unsuitableTempReason(info *utils.DiskSpaceInfo) string {
+	switch {
+	case info.Tmpfs:
+		return "on tmpfs"
+	case info.NearlyFull():
+		return fmt.Sprintf("nearly full (%s free)", utils.FormatBytes(int64(info.Available)))
+	}
+	return ""
+}
+
+// chooseLocalTempBase checks the filesystem of dir and, when it is unsuitable, returns why along
+// with the suitable candidate with the most free space. The candidate is empty when there is none.
+func chooseLocalTempBase(dir string, candidates []string, measure func(string) (*utils.DiskSpaceInfo, error)) (string, string) {
+	info, err := measure(dir)
+	if err != nil {
+		log.WithError(err).WithField("dir", dir).Debug("Failed to measure local temp directory")
+		return "", ""
+	}
+	reason := unsuitableTempReason(info)
+	if reason == "" {
+		return "", ""
+	}
+
+	best, bestAvailable := "", uint64(0)
+	for _, candidate := range candidates {
+		if candidate == dir {
+			continue
+		}
+		info, err := measure(candidate)
+		if err != nil || unsuitableTempReason(info) != "" {
+			continue
+		}
+		if best == "" || info.Available > bestAvailable {
+			best, bestAvailable = candidate, info.Available
+		}
+	}
+	return best, reason
+}
+
+// selectLocalTempDir moves the default local temp directory off the system temp directory when
+// that is on tmpfs or nearly full. A --temp-dir given on the command line is kept, with a warning
+// suggesting a better location.
+func (m *Migrator) selectLocalTempDir() {
+	candidates := localTempCandidates()
+	if !m.autoTempDir {
+		dir := utils.NearestExistingDir(m.config.TempDir)
+		better, reason := chooseLocalTempBase(dir, candidates, localTempSpace)
+		if reason == "" {
+			return
+		}
+		fields := log.WithFields(logrus.Fields{"temp_dir": m.config.TempDir, "reason": reason})
+		if better != "" {
+			fields = fields.WithField("suggested", better)
+		}
+		fields.Warn("Local temp directory is unsuitable for archives, consider another --temp-dir")
+		return
+	}
+
+	better, reason := chooseLocalTempBase(candidates[0], candidates, localTempSpace)
+	if reason == "" {
+		return
+	}
+	if better == "" {
+		log.WithFields(logrus.Fields{"temp_dir": m.config.TempDir, "reason": reason}).Warn("System temp directory is unsuitable for archives and no better location was found, set --temp-dir")
+		return
+	}
+	m.config.TempDir = filepath.Join(better, filepath.Base(m.config.TempDir))
+	log.WithFields(logrus.Fields{
+		"temp_dir": m.config.TempDir,
+		"reason":   "system temp directory is " + reason,
+	}).Info("Selected another local temp directory, set --temp-dir to override")
+}
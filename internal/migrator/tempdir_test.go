@@ -0,0 +1,115 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"volume-migrator/internal/utils"
+)
+
+// dfSection is the output of the candidate probe for one directory with available KiB free
+func dfSection(dir, mount string, available int) string {
+	return "== " + dir + "\n" +
+		"Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+		fmt.Sprintf("/dev/sda1        100000000   1000 %d      1%% %s\n", available, mount)
+}
+
+func TestChooseRemoteTempBase(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakeResponse
+		want     string
+	}{
+		{
+			name:     "most free space wins",
+			response: fakeResponse{output: dfSection("/tmp", "/tmp", 1000) + dfSection("/var/tmp", "/", 50000) + dfSection("/", "/", 50000)},
+			want:     "/var/tmp",
+		},
+		{
+			name:     "ties keep the candidate order",
+			response: fakeResponse{output: dfSection("/tmp", "/", 5000) + dfSection("/var/tmp", "/", 5000)},
+			want:     "/tmp",
+		},
+		{
+			name:     "unwritable candidates are left out",
+			response: fakeResponse{output: dfSection("/data", "/data", 9000)},
+			want:     "/data",
+		},
+		{
+			name:     "nothing writable falls back to /tmp",
+			response: fakeResponse{},
+			want:     "/tmp",
+		},
+		{
+			name:     "probe failure falls back to /tmp",
+			response: fakeResponse{err: errors.New("connection lost")},
+			want:     "/tmp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{shell: map[string]fakeResponse{"for d in": tt.response}}
+			if got := chooseRemoteTempBase(remote); got != tt.want {
+				t.Errorf("chooseRemoteTempBase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectRemoteTempDir(t *testing.T) {
+	remote := &fakeRemote{shell: map[string]fakeResponse{"for d in": {output: dfSection("/data", "/data", 9000)}}}
+
+	m := &Migrator{config: &Config{RemoteTempDir: "/tmp/volume-migration-1"}, sshClient: remote, autoRemoteTempDir: true}
+	m.selectRemoteTempDir()
+	if m.config.RemoteTempDir != "/data/volume-migration-1" {
+		t.Errorf("RemoteTempDir = %q, want /data/volume-migration-1", m.config.RemoteTempDir)
+	}
+
+	m = &Migrator{config: &Config{RemoteTempDir: "/srv/migration"}, sshClient: remote}
+	m.selectRemoteTempDir()
+	if m.config.RemoteTempDir != "/srv/migration" {
+		t.Errorf("RemoteTempDir = %q, want the given /srv/migration", m.config.RemoteTempDir)
+	}
+}
+
+func TestChooseLocalTempBase(t *testing.T) {
+	const gb = 1 << 30
+	filesystems := map[string]*utils.DiskSpaceInfo{
+		"/tmp":        {Total: 8 * gb, Available: 8 * gb, Tmpfs: true},
+		"/full":       {Total: 100 * gb, Available: 2 * gb},
+		"/var/tmp":    {Total: 100 * gb, Available: 40 * gb},
+		"/home/cache": {Total: 500 * gb, Available: 300 * gb},
+		"/small":      {Total: 10 * gb, Available: 5 * gb},
+	}
+	measure := func(dir string) (*utils.DiskSpaceInfo, error) {
+		if info, ok := filesystems[dir]; ok {
+			return info, nil
+		}
+		return nil, errors.New("not writable")
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		candidates []string
+		wantBetter string
+		wantReason string
+	}{
+		{"suitable dir is kept", "/var/tmp", []string{"/var/tmp", "/home/cache"}, "", ""},
+		{"tmpfs moves to the roomiest candidate", "/tmp", []string{"/tmp", "/var/tmp", "/home/cache"}, "/home/cache", "on tmpfs"},
+		{"nearly full moves", "/full", []string{"/full", "/small"}, "/small", "nearly full (2.0 GB free)"},
+		{"unsuitable and unwritable candidates are skipped", "/tmp", []string{"/tmp", "/full", "/missing"}, "", "on tmpfs"},
+		{"unmeasurable dir is kept", "/missing", []string{"/missing", "/var/tmp"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better, reason := chooseLocalTempBase(tt.dir, tt.candidates, measure)
+			if better != tt.wantBetter || reason != tt.wantReason {
+				t.Errorf("chooseLocalTempBase() = %q, %q, want %q, %q", better, reason, tt.wantBetter, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/ssh"
+)
+
+// manifestEntry describes one regular file of a volume
+type manifestEntry struct {
+	Size    int64
+	Hash    string // sha256, empty unless checksums were requested
+	ModTime int64  // Unix modification time, 0 unless modification times were requested
+}
+
+// fileManifest maps paths relative to the volume root to their entries
+type fileManifest map[string]manifestEntry
+
+// VerifyResult is the comparison of a local volume with its remote copy
+type VerifyResult struct {
+	Volume      string
+	LocalFiles  int
+	RemoteFiles int
+	LocalBytes  int64
+	RemoteBytes int64
+	Missing     []string // Files only on the local host
+	Extra       []string // Files only on the remote host
+	Different   []string // Files whose size (or checksum) differs
+}
+
+// OK reports whether the remote volume matches the local one
+func (r VerifyResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Different) == 0
+}
+
+// buildManifestScript returns the shell script listing the regular files under /data
+// Sizes are printed as "S <size> <path>" and, with checksums, hashes as "H <sha256>  <path>"
+func buildManifestScript(checksums bool) string {
+	script := "cd /data && find . -type f -exec stat -c 'S %s %n' {} +"
+	if checksums {
+		script += " && find . -type f -exec sha256sum {} + | sed 's/^/H /'"
+	}
+	return script
+}
+
+// manifestModTimeScript extends the manifest script with modification times as "M <mtime> <path>"
+const manifestModTimeScript = " && find . -type f -exec stat -c 'M %Y %n' {} +"
+
+// parseManifest parses the output of the manifest script
+func parseManifest(output string) (fileManifest, error) {
+	manifest := make(fileManifest)
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "S "):
+			fields := strings.SplitN(line[2:], " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("unexpected manifest line: %q", line)
+			}
+			size, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected file size in manifest line %q: %w", line, err)
+			}
+			entry := manifest[fields[1]]
+			entry.Size = size
+			manifest[fields[1]] = entry
+		case strings.HasPrefix(line, "H "):
+			fields := strings.SplitN(line[2:], "  ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("unexpected manifest line: %q", line)
+			}
+			entry := manifest[fields[1]]
+			entry.Hash = fields[0]
+			manifest[fields[1]] = entry
+		case strings.HasPrefix(line, "M "):
+			fields := strings.SplitN(line[2:], " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("unexpected manifest line: %q", line)
+			}
+			modTime, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected modification time in manifest line %q: %w", line, err)
+			}
+			entry := manifest[fields[1]]
+			entry.ModTime = modTime
+			manifest[fields[1]] = entry
+		default:
+			return nil, fmt.Errorf("unexpected manifest line: %q", line)
+		}
+	}
+	return manifest, nil
+}
+
+// compareManifests compares the files of a local volume with those of its remote copy
+func compareManifests(volume string, local, remote fileManifest) VerifyResult {
+	result := VerifyResult{Volume: volume, LocalFiles: len(local), RemoteFiles: len(remote)}
+
+	for path, l := range local {
+		result.LocalBytes += l.Size
+		r, ok := remote[path]
+		if !ok {
+			result.Missing = append(result.Missing, path)
+		} else if l != r {
+			result.Different = append(result.Different, path)
+		}
+	}
+	for path, r := range remote {
+		result.RemoteBytes += r.Size
+		if _, ok := local[path]; !ok {
+			result.Extra = append(result.Extra, path)
+		}
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	sort.Strings(result.Different)
+	return result
+}
+
+// VerifyVolumes compares each local volume in config.Volumes with the remote volume of the same name.
+// File counts and sizes are always compared; checksums adds a sha256 of every file on both hosts.
+// Uses RemoteHost, the SSH settings and HelperImage from the config.
+func VerifyVolumes(ctx context.Context, config *Config, checksums bool) ([]VerifyResult, error) {
+	return compareVolumes(ctx, config, checksums, false)
+}
+
+// compareVolumes compares each local volume in config.Volumes with the remote volume of the same
+// name, by size and optionally by checksum and modification time
+func compareVolumes(ctx context.Context, config *Config, checksums, modTimes bool) ([]VerifyResult, error) {
+	dockerClient, err := localDockerClient(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+	}
+
+	sshConfig, err := sshClientConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sshClient, err := ssh.NewClient(ctx, sshConfig)
+	if err != nil {
+		return nil, connectError(config.RemoteHost, err)
+	}
+	defer sshClient.Close()
+
+	image := resolveHelperImage(config.HelperImage, config.HelperRegistry, false)
+	script := buildManifestScript(checksums)
+	if modTimes {
+		script += manifestModTimeScript
+	}
+
+	var results []VerifyResult
+	for _, volume := range config.Volumes {
+		if !shell.ValidateVolumeName(volume) {
+			return nil, fmt.Errorf("invalid volume name '%s': must contain only alphanumeric characters, dashes, underscores, and dots", volume)
+		}
+		if exists, _ := VerifyVolumeExists(sshClient, volume); !exists {
+			return nil, migerrors.NewVolumeNotFoundError(volume, errors.New("does not exist on remote host"))
+		}
+
+		log.WithFields(logrus.Fields{
+			"volume":    volume,
+			"checksums": checksums,
+		}).Info("Comparing volume")
+
+		local, err := localFileManifest(dockerClient, volume, image, script)
+		if err != nil {
+			return nil, err
+		}
+		remote, err := remoteFileManifest(sshClient, volume, image, script)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, compareManifests(volume, local, remote))
+	}
+	return results, nil
+}
+
+// deepVerifyFailure describes a failed comparison, naming the first few differing files
+func deepVerifyFailure(result VerifyResult) error {
+	var files []string
+	files = append(files, result.Missing...)
+	files = append(files, result.Extra...)
+	files = append(files, result.Different...)
+	if len(files) > maxReportedFiles {
+		files = files[:maxReportedFiles]
+	}
+	return migerrors.NewVerificationError(fmt.Errorf("deep verification of %s failed: %d missing, %d extra, %d different files (%s)",
+		result.Volume, len(result.Missing), len(result.Extra), len(result.Different), strings.Join(files, ", ")))
+}
+
+// maxReportedFiles caps the file names listed in a deep verification failure
+const maxReportedFiles = 5
+
+// writeChecksumList writes the per-file hashes of a manifest in sha256sum format, sorted by path
+func writeChecksumList(path string, manifest fileManifest) error {
+	paths := make([]string, 0, len(manifest))
+	for p := range manifest {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", manifest[p].Hash, p)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checksum list: %w", err)
+	}
+	return nil
+}
+
+// localFileManifest runs the manifest script over a local volume in the helper image
+func localFileManifest(dockerClient DockerRunner, volume, image, script string) (fileManifest, error) {
+	output, err := dockerClient.ExecCommand("run", "--rm", "-v", volume+":/data:ro", image, "sh", "-c", script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local volume %s: %w", volume, err)
+	}
+	manifest, err := parseManifest(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local manifest of %s: %w", volume, err)
+	}
+	return manifest, nil
+}
+
+// remoteFileManifest runs the manifest script over a remote volume in the helper image
+func remoteFileManifest(sshClient RemoteExecutor, volume, image, script string) (fileManifest, error) {
+	output, err := sshClient.RunDockerCommand(fmt.Sprintf("run --rm -v %s:/data:ro %s sh -c %s",
+		volume, shell.ShellEscape(image), shell.ShellEscape(script)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote volume %s: %w", volume, err)
+	}
+	manifest, err := parseManifest(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote manifest of %s: %w", volume, err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,152 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/ssh"
+)
+
+func TestParseManifest(t *testing.T) {
+	output := "S 12 ./a.txt\nS 0 ./dir/with space.log\nH abc123  ./a.txt\nH e3b0  ./dir/with space.log\nM 1700000000 ./a.txt\n"
+
+	manifest, err := parseManifest(output)
+	if err != nil {
+		t.Fatalf("parseManifest failed: %v", err)
+	}
+	if len(manifest) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(manifest))
+	}
+	if e := manifest["./a.txt"]; e.Size != 12 || e.Hash != "abc123" || e.ModTime != 1700000000 {
+		t.Errorf("unexpected entry for ./a.txt: %+v", e)
+	}
+	if e := manifest["./dir/with space.log"]; e.Size != 0 || e.Hash != "e3b0" {
+		t.Errorf("unexpected entry for path with space: %+v", e)
+	}
+
+	for _, bad := range []string{"S x ./a\n", "garbage\n", "H onlyhash\n", "M never ./a\n"} {
+		if _, err := parseManifest(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCompareManifests(t *testing.T) {
+	local := fileManifest{
+		"./same":    {Size: 10, Hash: "h1"},
+		"./resized": {Size: 10},
+		"./changed": {Size: 5, Hash: "old"},
+		"./missing": {Size: 3},
+		"./touched": {Size: 1, ModTime: 100},
+	}
+	remote := fileManifest{
+		"./same":    {Size: 10, Hash: "h1"},
+		"./resized": {Size: 11},
+		"./changed": {Size: 5, Hash: "new"},
+		"./extra":   {Size: 7},
+		"./touched": {Size: 1, ModTime: 200},
+	}
+
+	result := compareManifests("vol", local, remote)
+	if result.OK() {
+		t.Fatal("expected differences")
+	}
+	if result.LocalFiles != 5 || result.RemoteFiles != 5 || result.LocalBytes != 29 || result.RemoteBytes != 34 {
+		t.Errorf("unexpected totals: %+v", result)
+	}
+	if strings.Join(result.Missing, ",") != "./missing" || strings.Join(result.Extra, ",") != "./extra" {
+		t.Errorf("unexpected missing/extra: %v %v", result.Missing, result.Extra)
+	}
+	if strings.Join(result.Different, ",") != "./changed,./resized,./touched" {
+		t.Errorf("unexpected different files: %v", result.Different)
+	}
+
+	if !compareManifests("vol", local, local).OK() {
+		t.Error("expected identical manifests to match")
+	}
+}
+
+func TestBuildManifestScript(t *testing.T) {
+	if script := buildManifestScript(false); strings.Contains(script, "sha256sum") {
+		t.Errorf("expected no checksums, got: %s", script)
+	}
+	if script := buildManifestScript(true); !strings.Contains(script, "sha256sum") {
+		t.Errorf("expected checksums, got: %s", script)
+	}
+}
+
+func TestWriteChecksumList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.sha256")
+	manifest := fileManifest{
+		"./b.txt":       {Size: 6, Hash: "bbbb"},
+		"./a dir/a.txt": {Size: 5, Hash: "aaaa"},
+	}
+	if err := writeChecksumList(path, manifest); err != nil {
+		t.Fatalf("writeChecksumList() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "aaaa  ./a dir/a.txt\nbbbb  ./b.txt\n"
+	if string(data) != want {
+		t.Errorf("checksum list = %q, want %q", data, want)
+	}
+}
+
+func TestDeepVerifyFailure(t *testing.T) {
+	result := VerifyResult{
+		Volume:    "data",
+		Missing:   []string{"./m1", "./m2", "./m3"},
+		Different: []string{"./d1", "./d2", "./d3"},
+	}
+	err := deepVerifyFailure(result)
+	want := "deep verification of data failed: 3 missing, 0 extra, 3 different files (./m1, ./m2, ./m3, ./d1, ./d2)"
+	if err == nil || err.Error() != want {
+		t.Errorf("deepVerifyFailure() = %v, want %q", err, want)
+	}
+	if code := migerrors.ExitCode(fmt.Errorf("failed to migrate volume data: %w", err)); code != migerrors.ExitVerification {
+		t.Errorf("ExitCode() = %d, want %d", code, migerrors.ExitVerification)
+	}
+}
+
+func TestConnectError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+		code string
+	}{
+		{"docker not accessible", fmt.Errorf("%w: permission denied", ssh.ErrDockerNotAccessible), migerrors.ExitDocker, migerrors.CodeDockerError},
+		{"authentication", fmt.Errorf("%w for user@host:22: ssh: unable to authenticate", migerrors.ErrSSHAuth), migerrors.ExitSSH, migerrors.CodeSSHAuthFailed},
+		{"unreachable", errors.New("failed to connect to host:22: connection refused"), migerrors.ExitSSH, migerrors.CodeSSHConnectionFailed},
+		{"already classified", migerrors.NewSSHConnectionError("user@host", errors.New("connection refused")), migerrors.ExitSSH, migerrors.CodeSSHConnectionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := connectError("user@host", tt.err)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("connectError() = %v, does not wrap %v", err, tt.err)
+			}
+			if code := migerrors.ExitCode(err); code != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", code, tt.want)
+			}
+			if report := migerrors.NewReport(err); report.Code != tt.code || report.ExitCode != tt.want {
+				t.Errorf("NewReport() = %+v, want code %s", report, tt.code)
+			}
+			if tt.want == migerrors.ExitSSH && !errors.Is(err, migerrors.ErrSSHConnection) {
+				t.Errorf("connectError() = %v, does not match ErrSSHConnection", err)
+			}
+			if strings.Count(err.Error(), "failed to connect to SSH host") > 1 {
+				t.Errorf("connectError() = %v, wrapped twice", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMinDockerVersion is the oldest engine supported on either host: docker system df -v and
+// Go templates for docker info and docker volume ls --format, which the migration relies on, came with 1.13
+const DefaultMinDockerVersion = "1.13"
+
+// serverVersionFormat is the docker version template printing the engine version
+const serverVersionFormat = "{{.Server.Version}}"
+
+// ValidateMinDockerVersion checks that a minimum engine version is empty (no minimum) or numeric, such as 20.10
+func ValidateMinDockerVersion(flag, version string) error {
+	if version == "" {
+		return nil
+	}
+	if len(versionComponents(version)) == 0 {
+		return fmt.Errorf("invalid %s '%s': must be a Docker version such as 20.10", flag, version)
+	}
+	return nil
+}
+
+// checkMinimumVersion fails when the engine on side ("local" or "remote") is older than minimum
+func checkMinimumVersion(side, version, minimum string) error {
+	if minimum == "" || compareDockerVersions(version, minimum) >= 0 {
+		return nil
+	}
+	reason := fmt.Sprintf("the %s engine must be at least %s (--min-%s-docker-version)", side, minimum, side)
+	if compareDockerVersions(version, DefaultMinDockerVersion) < 0 {
+		reason = fmt.Sprintf("docker system df -v and --format templates for docker info and volume ls need Docker %s or newer", DefaultMinDockerVersion)
+	}
+	return fmt.Errorf("%s Docker %s is too old: %s; upgrade Docker on the %s host or lower --min-%s-docker-version",
+		side, version, reason, side, side)
+}
+
+// localDockerVersion returns the local engine version
+func localDockerVersion(dockerClient DockerRunner) (string, error) {
+	output, err := dockerClient.ExecCommand("version", "--format", serverVersionFormat)
+	if err != nil {
+		return "", fmt.Errorf("failed to read local Docker version: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// remoteDockerVersion returns the remote engine version
+func remoteDockerVersion(sshClient RemoteExecutor) (string, error) {
+	output, err := sshClient.RunDockerCommand("version --format '" + serverVersionFormat + "'")
+	if err != nil {
+		return "", fmt.Errorf("failed to read remote Docker version: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
@@ -0,0 +1,54 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMinDockerVersion(t *testing.T) {
+	for _, version := range []string{"", "1.13", "20.10.7", "v24"} {
+		if err := ValidateMinDockerVersion("--min-remote-docker-version", version); err != nil {
+			t.Errorf("ValidateMinDockerVersion(%q) error = %v", version, err)
+		}
+	}
+	if err := ValidateMinDockerVersion("--min-remote-docker-version", "latest"); err == nil || !strings.Contains(err.Error(), "--min-remote-docker-version") {
+		t.Errorf("ValidateMinDockerVersion(latest) error = %v, want flag name in error", err)
+	}
+}
+
+func TestCheckMinimumVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		side     string
+		version  string
+		minimum  string
+		wantErr  bool
+		contains []string
+	}{
+		{name: "newer", side: "remote", version: "24.0.7", minimum: "20.10"},
+		{name: "equal", side: "local", version: "20.10.0", minimum: "20.10"},
+		{name: "no minimum", side: "remote", version: "1.12.6", minimum: ""},
+		{
+			name: "below configured minimum", side: "remote", version: "19.03.15", minimum: "20.10", wantErr: true,
+			contains: []string{"remote Docker 19.03.15 is too old", "at least 20.10", "--min-remote-docker-version"},
+		},
+		{
+			name: "below tool minimum", side: "local", version: "1.12.6", minimum: DefaultMinDockerVersion, wantErr: true,
+			contains: []string{"local Docker 1.12.6", "docker system df -v", "--min-local-docker-version"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMinimumVersion(tt.side, tt.version, tt.minimum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkMinimumVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("checkMinimumVersion() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
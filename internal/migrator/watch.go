@@ -0,0 +1,94 @@
+package migrator
+
+import (
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"volume-migrator/internal/docker"
+)
+
+// DefaultWatchInterval is how often --watch checks the migrated volumes for changes
+const DefaultWatchInterval = 10 * time.Second
+
+// watchManifestScript lists file sizes and modification times, enough to notice changes without reading the data
+var watchManifestScript = buildManifestScript(false) + manifestModTimeScript
+
+// recordWatchBaseline lists a volume's files right before it is exported, so --watch can tell
+// later changes from what was sent. Without a baseline the volume is synced again on the first check.
+func (m *Migrator) recordWatchBaseline(v docker.VolumeInfo) {
+	files, err := localFileManifest(m.dockerClient, v.Name, m.helperImage, watchManifestScript)
+	if err != nil {
+		log.WithError(err).WithField("volume", v.Name).Warn("Failed to list volume files, it will be synced again on the first check")
+		return
+	}
+	m.watchBaselines[v.Name] = files
+}
+
+// watchVolumes keeps the migrated volumes in sync until the run is interrupted: every interval,
+// each volume whose files changed since its last sync is migrated again as an incremental delta.
+// volumes lists every volume of the run and succeeded the migrated ones.
+func (m *Migrator) watchVolumes(volumes []docker.VolumeInfo, succeeded []string) {
+	interval := m.config.WatchInterval
+	if interval == 0 {
+		interval = DefaultWatchInterval
+	}
+	var watched []docker.VolumeInfo
+	for _, v := range volumes {
+		if slices.Contains(succeeded, v.Name) {
+			watched = append(watched, v)
+		}
+	}
+
+	log.Info("=== Phase 3.5: Watch Volumes ===")
+	log.WithFields(logrus.Fields{
+		"volumes":  len(watched),
+		"interval": interval,
+	}).Info("Watching volumes for changes, press Ctrl+C to stop")
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			log.Info("Stopped watching volumes")
+			return
+		case <-time.After(interval):
+		}
+		for _, v := range watched {
+			if m.ctx.Err() != nil {
+				break
+			}
+			m.syncVolumeChanges(v)
+		}
+	}
+}
+
+// syncVolumeChanges migrates a watched volume again when its files changed since the last sync.
+// Failures are logged and retried on the next check, since the volume keeps changing anyway.
+func (m *Migrator) syncVolumeChanges(v docker.VolumeInfo) {
+	files, err := localFileManifest(m.dockerClient, v.Name, m.helperImage, watchManifestScript)
+	if err != nil {
+		if m.ctx.Err() == nil {
+			log.WithError(err).WithField("volume", v.Name).Warn("Failed to check volume for changes")
+		}
+		return
+	}
+	changes := compareManifests(v.Name, files, m.watchBaselines[v.Name])
+	if changes.OK() {
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"volume":  v.Name,
+		"added":   len(changes.Missing),
+		"removed": len(changes.Extra),
+		"changed": len(changes.Different),
+	}).Info("Volume changed, syncing")
+	if err := m.migrateVolume(v); err != nil {
+		if m.ctx.Err() == nil {
+			log.WithError(err).WithField("volume", v.Name).Error("Failed to sync volume changes, retrying on the next check")
+		}
+		m.discardFailedVolume(v)
+		return
+	}
+	m.watchBaselines[v.Name] = files
+}
@@ -0,0 +1,80 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"volume-migrator/internal/docker"
+)
+
+func TestSyncVolumeChanges(t *testing.T) {
+	listCommand := "run --rm -v data:/data:ro tools sh -c " + watchManifestScript
+	files := "S 4 ./a.txt\nM 1700000000 ./a.txt\n"
+	errExport := errors.New("export failed")
+
+	tests := []struct {
+		name     string
+		baseline fileManifest
+		wantSync bool
+	}{
+		{"unchanged volume is left alone", fileManifest{"./a.txt": {Size: 4, ModTime: 1700000000}}, false},
+		{"touched file is synced", fileManifest{"./a.txt": {Size: 4, ModTime: 1600000000}}, true},
+		{"missing baseline is synced", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &fakeDocker{responses: map[string]fakeResponse{
+				"run":       {err: errExport},
+				listCommand: {output: files},
+			}}
+			m := &Migrator{
+				config:         &Config{Incremental: true, StateDir: t.TempDir(), TempDir: t.TempDir(), NoCleanup: true},
+				ctx:            context.Background(),
+				dockerClient:   local,
+				sshClient:      &fakeRemote{},
+				helperImage:    "tools",
+				watchBaselines: map[string]fileManifest{},
+			}
+			if tt.baseline != nil {
+				m.watchBaselines["data"] = tt.baseline
+			}
+
+			m.syncVolumeChanges(docker.VolumeInfo{Name: "data"})
+
+			synced := false
+			for _, cmd := range local.commands {
+				if cmd != listCommand && strings.HasPrefix(cmd, "run") {
+					synced = true
+				}
+			}
+			if synced != tt.wantSync {
+				t.Errorf("synced = %v, want %v (commands: %v)", synced, tt.wantSync, local.commands)
+			}
+			// The export fails, so the changes must still be pending on the next check
+			if baseline := m.watchBaselines["data"]; tt.wantSync && baseline["./a.txt"].ModTime == 1700000000 {
+				t.Errorf("baseline updated after a failed sync: %v", baseline)
+			}
+		})
+	}
+}
+
+func TestWatchVolumes_StopsWhenInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := &Migrator{config: &Config{WatchInterval: time.Hour}, ctx: ctx, dockerClient: &fakeDocker{}}
+
+	done := make(chan struct{})
+	go func() {
+		m.watchVolumes([]docker.VolumeInfo{{Name: "data"}}, []string{"data"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchVolumes did not return after the context was cancelled")
+	}
+}
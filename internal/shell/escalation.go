@@ -0,0 +1,67 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Escalation is a way of running docker with root privileges when the user cannot reach the daemon
+type Escalation struct {
+	Name           string   // "none", "sudo" or "doas"
+	Command        string   // Program prefixed to docker, empty for none
+	NonInteractive []string // Flags making the program fail instead of prompting for a password
+	StdinPassword  []string // Flags reading the password from stdin, nil if unsupported
+}
+
+// Supported escalation methods
+var (
+	EscalationNone = Escalation{Name: "none"}
+	EscalationSudo = Escalation{Name: "sudo", Command: "sudo", NonInteractive: []string{"-n"}, StdinPassword: []string{"-S", "-p", "''"}}
+	EscalationDoas = Escalation{Name: "doas", Command: "doas", NonInteractive: []string{"-n"}}
+)
+
+// EscalationAuto tries running docker directly, then with sudo, then with doas
+const EscalationAuto = "auto"
+
+// ParseEscalation returns the methods to try, in order, for an --escalation value:
+// auto (the default, also for ""), none, sudo or doas
+func ParseEscalation(name string) ([]Escalation, error) {
+	switch strings.ToLower(name) {
+	case "", EscalationAuto:
+		return []Escalation{EscalationNone, EscalationSudo, EscalationDoas}, nil
+	case EscalationNone.Name:
+		return []Escalation{EscalationNone}, nil
+	case EscalationSudo.Name:
+		return []Escalation{EscalationSudo}, nil
+	case EscalationDoas.Name:
+		return []Escalation{EscalationDoas}, nil
+	}
+	return nil, fmt.Errorf("invalid privilege escalation '%s': must be auto, none, sudo or doas", name)
+}
+
+// Required reports whether the method runs docker through another program
+func (e Escalation) Required() bool {
+	return e.Command != ""
+}
+
+// Args returns the program and flags to put before "docker", empty for none.
+// nonInteractive adds the flags that fail instead of prompting for a password.
+func (e Escalation) Args(nonInteractive bool) []string {
+	if !e.Required() {
+		return nil
+	}
+	args := []string{e.Command}
+	if nonInteractive {
+		args = append(args, e.NonInteractive...)
+	}
+	return args
+}
+
+// Prefix returns Args joined for a shell command line, followed by a space ("" for none)
+func (e Escalation) Prefix(nonInteractive bool) string {
+	args := e.Args(nonInteractive)
+	if len(args) == 0 {
+		return ""
+	}
+	return strings.Join(args, " ") + " "
+}
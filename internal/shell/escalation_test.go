@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEscalation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", input: "", want: []string{"none", "sudo", "doas"}},
+		{name: "auto", input: "auto", want: []string{"none", "sudo", "doas"}},
+		{name: "none", input: "none", want: []string{"none"}},
+		{name: "sudo", input: "sudo", want: []string{"sudo"}},
+		{name: "doas case-insensitive", input: "DOAS", want: []string{"doas"}},
+		{name: "su", input: "su", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods, err := ParseEscalation(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEscalation(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			var names []string
+			for _, m := range methods {
+				names = append(names, m.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseEscalation(%q) = %v, want %v", tt.input, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscalation_Prefix(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         Escalation
+		nonInteractive bool
+		want           string
+	}{
+		{name: "none", method: EscalationNone, nonInteractive: true, want: ""},
+		{name: "sudo", method: EscalationSudo, want: "sudo "},
+		{name: "sudo non-interactive", method: EscalationSudo, nonInteractive: true, want: "sudo -n "},
+		{name: "doas non-interactive", method: EscalationDoas, nonInteractive: true, want: "doas -n "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.method.Prefix(tt.nonInteractive); got != tt.want {
+				t.Errorf("Prefix(%v) = %q, want %q", tt.nonInteractive, got, tt.want)
+			}
+		})
+	}
+}
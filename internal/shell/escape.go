@@ -71,6 +71,35 @@ func ValidateVolumeName(name string) bool {
 	return true
 }
 
+// ValidateImageReference validates that a Docker image reference is safe to use in commands
+// Image references may contain alphanumeric characters and the separators used by
+// registries, repositories, tags and digests: dash, underscore, dot, slash, colon and @
+func ValidateImageReference(ref string) bool {
+	if ref == "" || len(ref) > 512 {
+		return false
+	}
+
+	// References must not start with a separator (could be parsed as a flag)
+	if ref[0] == '-' || ref[0] == '.' || ref[0] == '/' || ref[0] == ':' || ref[0] == '@' {
+		return false
+	}
+
+	if strings.Contains(ref, "..") {
+		return false
+	}
+
+	for _, r := range ref {
+		if !((r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') ||
+			r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '@') {
+			return false
+		}
+	}
+
+	return true
+}
+
 // SanitizePathForRemote ensures a remote path is safe
 // Prevents path traversal and ensures absolute paths
 func SanitizePathForRemote(path string) string {
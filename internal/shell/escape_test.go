@@ -165,6 +165,34 @@ func TestValidateVolumeName(t *testing.T) {
 	}
 }
 
+func TestValidateImageReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"plain name", "alpine", true},
+		{"name with tag", "alpine:3.19", true},
+		{"registry mirror", "registry.internal:5000/mirror/alpine:3.19", true},
+		{"digest", "alpine@sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b", true},
+		{"empty", "", false},
+		{"leading dash", "-alpine", false},
+		{"command injection", "alpine;rm -rf /", false},
+		{"command substitution", "$(whoami)", false},
+		{"space", "alpine tar", false},
+		{"path traversal", "../alpine", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateImageReference(tt.input)
+			if got != tt.want {
+				t.Errorf("ValidateImageReference(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSanitizePathForRemote(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -1,63 +1,144 @@
 package ssh
 
 import (
+	"bytes"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
+	migerrors "volume-migrator/internal/errors"
 )
 
-// getAuthMethods returns SSH authentication methods in priority order:
-// 1. SSH Agent (if available)
-// 2. Private keys from ~/.ssh/
-// 3. Custom key path (if provided)
-func getAuthMethods(customKeyPath string) ([]ssh.AuthMethod, error) {
-	var methods []ssh.AuthMethod
+// defaultKeyNames are the private keys tried from ~/.ssh/ when no custom key is given
+var defaultKeyNames = []string{
+	"id_rsa",
+	"id_ed25519",
+	"id_ecdsa",
+	"id_dsa",
+	"id_ed25519_sk",
+	"id_ecdsa_sk",
+}
 
-	// 1. Try SSH Agent
-	if agentMethods := trySSHAgent(); agentMethods != nil {
-		methods = append(methods, agentMethods)
+// getAuthMethods returns SSH authentication methods. All keys are offered through one publickey
+// method, since x/crypto only tries the first method of each type, in this order:
+// 1. Custom keys (--ssh-key), in the order given; keys the agent holds are signed by it
+// 2. Other SSH agent keys, unless identitiesOnly is set (as with OpenSSH's IdentitiesOnly)
+// 3. Private keys from ~/.ssh/, when no custom key is given
+// Every key offered counts against the server's MaxAuthTries, so identitiesOnly avoids
+// "too many authentication failures" when the agent holds many keys.
+// FIDO2 security keys (sk-*) can only sign through the agent, so they are checked against it instead
+func getAuthMethods(customKeyPaths []string, identitiesOnly bool) ([]ssh.AuthMethod, error) {
+	agentClient := connectSSHAgent()
+	var agentSigners []ssh.Signer
+	if agentClient != nil {
+		agentSigners, _ = agentClient.Signers()
 	}
 
-	// 2. Try custom key path if provided
-	if customKeyPath != "" {
-		if key, err := loadPrivateKey(customKeyPath); err == nil {
-			methods = append(methods, ssh.PublicKeys(key))
-		} else {
-			return nil, fmt.Errorf("failed to load custom key %s: %w", customKeyPath, err)
+	var signers []ssh.Signer
+	offered := make(map[string]bool)
+	add := func(signer ssh.Signer) {
+		key := string(signer.PublicKey().Marshal())
+		if !offered[key] {
+			offered[key] = true
+			signers = append(signers, signer)
 		}
-	} else {
-		// Try common private key locations
-		homeDir, err := os.UserHomeDir()
-		if err == nil {
-			keyPaths := []string{
-				"id_rsa",
-				"id_ed25519",
-				"id_ecdsa",
-				"id_dsa",
-			}
+	}
+
+	// 1. Custom keys, in order
+	needAgent := false
+	for _, path := range customKeyPaths {
+		signer, err := customKeySigner(path, agentClient, agentSigners)
+		if err != nil {
+			return nil, err
+		}
+		if signer == nil {
+			needAgent = true // Security key without a .pub file: let the agent try its keys
+			continue
+		}
+		add(signer)
+	}
+
+	// 2. Remaining agent keys
+	if !identitiesOnly || needAgent {
+		for _, signer := range agentSigners {
+			add(signer)
+		}
+	}
 
-			for _, keyName := range keyPaths {
+	// 3. Common private key locations
+	var securityKeyErr error
+	if len(customKeyPaths) == 0 {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			for _, keyName := range defaultKeyNames {
 				keyPath := filepath.Join(homeDir, ".ssh", keyName)
-				if key, err := loadPrivateKey(keyPath); err == nil {
-					methods = append(methods, ssh.PublicKeys(key))
+				key, err := loadPrivateKey(keyPath)
+				switch {
+				case err == nil:
+					add(key)
+				case errors.Is(err, errSecurityKey):
+					if signer := agentSignerFor(keyPath, agentSigners); signer != nil {
+						add(signer)
+					} else if securityKeyErr == nil {
+						securityKeyErr = checkSecurityKey(keyPath, agentClient)
+					}
 				}
 			}
 		}
 	}
 
-	if len(methods) == 0 {
-		return nil, fmt.Errorf("no SSH authentication methods available")
+	if len(signers) == 0 {
+		if securityKeyErr != nil {
+			return nil, securityKeyErr
+		}
+		return nil, errNoAuthMethods
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, nil
+}
+
+// customKeySigner returns the signer for a --ssh-key path: the key file itself or, for encrypted
+// and security keys, the agent key matching its .pub file. A nil signer without error means a
+// security key whose agent key cannot be identified, so the agent's keys should be offered.
+func customKeySigner(path string, agentClient agent.Agent, agentSigners []ssh.Signer) (ssh.Signer, error) {
+	key, err := loadPrivateKey(path)
+	if err == nil {
+		return key, nil
+	}
+	if signer := agentSignerFor(path, agentSigners); signer != nil {
+		return signer, nil
 	}
+	if errors.Is(err, errSecurityKey) {
+		return nil, checkSecurityKey(path, agentClient)
+	}
+	return nil, fmt.Errorf("failed to load custom key %s: %w", path, err)
+}
 
-	return methods, nil
+// agentSignerFor returns the agent signer for the public key in path + ".pub", or nil
+func agentSignerFor(path string, agentSigners []ssh.Signer) ssh.Signer {
+	data, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return nil
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil
+	}
+	for _, signer := range agentSigners {
+		if bytes.Equal(signer.PublicKey().Marshal(), publicKey.Marshal()) {
+			return signer
+		}
+	}
+	return nil
 }
 
-// trySSHAgent attempts to connect to SSH agent
-func trySSHAgent() ssh.AuthMethod {
+// connectSSHAgent connects to the SSH agent, returning nil when none is running
+func connectSSHAgent() agent.ExtendedAgent {
 	socket := os.Getenv("SSH_AUTH_SOCK")
 	if socket == "" {
 		return nil
@@ -68,8 +149,59 @@ func trySSHAgent() ssh.AuthMethod {
 		return nil
 	}
 
-	agentClient := agent.NewClient(conn)
-	return ssh.PublicKeysCallback(agentClient.Signers)
+	return agent.NewClient(conn)
+}
+
+// errNoAuthMethods is returned by getAuthMethods when no agent or key can be used
+var errNoAuthMethods = errors.New("no SSH authentication methods available")
+
+// errSecurityKey is returned by loadPrivateKey for FIDO2 security keys, which need the agent
+var errSecurityKey = errors.New("FIDO2 security keys can only be used through ssh-agent")
+
+// securityKeyTypes are the public key types of FIDO2 security keys
+var securityKeyTypes = []string{ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256}
+
+// isSecurityKey reports whether an OpenSSH private key file holds a FIDO2 security key
+// The key type is stored unencrypted in the key file, even for passphrase-protected keys
+func isSecurityKey(data []byte) bool {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return false
+	}
+	for _, keyType := range securityKeyTypes {
+		if bytes.Contains(block.Bytes, []byte(keyType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecurityKey verifies a FIDO2 security key can be used through the agent
+// When the public key (path + ".pub") is available it must be loaded in the agent
+func checkSecurityKey(path string, agentClient agent.Agent) error {
+	if agentClient == nil {
+		return fmt.Errorf("%s is a FIDO2 security key, which can only be used through ssh-agent: start an agent (eval $(ssh-agent)) and run 'ssh-add %s'", path, path)
+	}
+
+	data, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return nil // Cannot tell which agent key it is; let the agent try its keys
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil
+	}
+
+	keys, err := agentClient.List()
+	if err != nil {
+		return fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	for _, key := range keys {
+		if bytes.Equal(key.Marshal(), publicKey.Marshal()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("FIDO2 security key %s is not loaded in ssh-agent: run 'ssh-add %s' and touch the key when it blinks", path, path)
 }
 
 // loadPrivateKey loads a private key from a file
@@ -80,10 +212,17 @@ func loadPrivateKey(path string) (ssh.Signer, error) {
 	}
 
 	key, err := os.ReadFile(path)
+	if os.IsPermission(err) {
+		return nil, migerrors.NewPermissionError("read", path, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if isSecurityKey(key) {
+		return nil, errSecurityKey
+	}
+
 	// Try parsing without passphrase first
 	signer, err := ssh.ParsePrivateKey(key)
 	if err != nil {
@@ -116,7 +255,14 @@ func validateKeyPermissions(path string) error {
 	return nil
 }
 
+// HostName returns the host part of a [user@]host[:port] string, without brackets for IPv6
+func HostName(hostStr string) (string, error) {
+	_, host, _, err := parseHostPort(hostStr)
+	return host, err
+}
+
 // parseHostPort parses a host string in format "user@host:port" or "user@host"
+// IPv6 addresses are given bare (user@2001:db8::1) or in brackets (user@[2001:db8::1]:2222)
 func parseHostPort(hostStr string) (user, host, port string, err error) {
 	// Default values
 	port = "22"
@@ -127,18 +273,35 @@ func parseHostPort(hostStr string) (user, host, port string, err error) {
 		hostStr = hostStr[at+1:]
 	} else {
 		// Use current user if not specified
-		user = os.Getenv("USER")
-		if user == "" {
-			user = os.Getenv("USERNAME") // Windows
-		}
+		user = localUser()
 	}
 
 	// Check if port is specified
-	if colon := findColon(hostStr); colon != -1 {
-		host = hostStr[:colon]
-		port = hostStr[colon+1:]
-	} else {
+	// IPv6 literals take a port only in brackets ([2001:db8::1]:2222); a bare one has several colons
+	switch {
+	case strings.HasPrefix(hostStr, "["):
+		end := strings.Index(hostStr, "]")
+		if end == -1 {
+			err = fmt.Errorf("missing ']' in IPv6 address: %s", hostStr)
+			return
+		}
+		host = hostStr[1:end]
+		if rest := hostStr[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") || len(rest) == 1 {
+				err = fmt.Errorf("expected :port after IPv6 address, got: %s", rest)
+				return
+			}
+			port = rest[1:]
+		}
+	case strings.Count(hostStr, ":") > 1:
 		host = hostStr
+	default:
+		if colon := findColon(hostStr); colon != -1 {
+			host = hostStr[:colon]
+			port = hostStr[colon+1:]
+		} else {
+			host = hostStr
+		}
 	}
 
 	if user == "" {
@@ -1,8 +1,18 @@
 package ssh
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 func TestParseHostPort(t *testing.T) {
@@ -51,6 +61,37 @@ func TestParseHostPort(t *testing.T) {
 			wantHost: "localhost",
 			wantPort: "22",
 		},
+		{
+			name:     "bare IPv6",
+			input:    "root@2001:db8::1",
+			wantUser: "root",
+			wantHost: "2001:db8::1",
+			wantPort: "22",
+		},
+		{
+			name:     "bracketed IPv6 with port",
+			input:    "root@[2001:db8::1]:2222",
+			wantUser: "root",
+			wantHost: "2001:db8::1",
+			wantPort: "2222",
+		},
+		{
+			name:     "bracketed IPv6 without port",
+			input:    "root@[::1]",
+			wantUser: "root",
+			wantHost: "::1",
+			wantPort: "22",
+		},
+		{
+			name:        "unterminated IPv6 bracket",
+			input:       "root@[2001:db8::1:2222",
+			expectError: true,
+		},
+		{
+			name:        "IPv6 bracket with empty port",
+			input:       "root@[2001:db8::1]:",
+			expectError: true,
+		},
 		{
 			name:        "empty host",
 			input:       "user@",
@@ -228,3 +269,204 @@ func TestValidateKeyPermissions_NonExistentFile(t *testing.T) {
 		t.Error("Expected error for non-existent file, but got none")
 	}
 }
+
+func TestIsSecurityKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"ed25519 key", pem.EncodeToMemory(block), false},
+		{"sk-ed25519 key", pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("openssh-key-v1\x00" + ssh.KeyAlgoSKED25519)}), true},
+		{"sk-ecdsa key", pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("openssh-key-v1\x00" + ssh.KeyAlgoSKECDSA256)}), true},
+		{"other PEM type", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte(ssh.KeyAlgoSKED25519)}), false},
+		{"not PEM", []byte("test key content"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSecurityKey(tt.data); got != tt.want {
+				t.Errorf("isSecurityKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPrivateKey_SecurityKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id_ed25519_sk")
+	data := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("openssh-key-v1\x00" + ssh.KeyAlgoSKED25519)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	if _, err := loadPrivateKey(path); !errors.Is(err, errSecurityKey) {
+		t.Errorf("loadPrivateKey() error = %v, want errSecurityKey", err)
+	}
+}
+
+func TestCheckSecurityKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	withPub := filepath.Join(dir, "id_ed25519_sk")
+	if err := os.WriteFile(withPub+".pub", ssh.MarshalAuthorizedKey(publicKey), 0644); err != nil {
+		t.Fatalf("Failed to write public key: %v", err)
+	}
+	withoutPub := filepath.Join(dir, "id_ecdsa_sk")
+
+	loaded := agent.NewKeyring()
+	if err := loaded.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("Failed to add key to agent: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		agent   agent.Agent
+		wantErr string
+	}{
+		{"no agent", withPub, nil, "can only be used through ssh-agent"},
+		{"key not loaded", withPub, agent.NewKeyring(), "is not loaded in ssh-agent"},
+		{"key loaded", withPub, loaded, ""},
+		{"no public key file", withoutPub, agent.NewKeyring(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSecurityKey(tt.path, tt.agent)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkSecurityKey() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkSecurityKey() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeTestKey writes an ed25519 private key (encrypted when passphrase is set) and its .pub
+// file to dir, returning the key path and the raw private key
+func writeTestKey(t *testing.T, dir, name, passphrase string) (string, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	}
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert public key: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	if err := os.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(publicKey), 0644); err != nil {
+		t.Fatalf("Failed to write public key: %v", err)
+	}
+	return path, priv
+}
+
+func TestCustomKeySigner(t *testing.T) {
+	dir := t.TempDir()
+	plainPath, plainKey := writeTestKey(t, dir, "plain", "")
+	encryptedPath, encryptedKey := writeTestKey(t, dir, "encrypted", "secret")
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: encryptedKey}); err != nil {
+		t.Fatalf("Failed to add key to agent: %v", err)
+	}
+	agentSigners, err := keyring.Signers()
+	if err != nil {
+		t.Fatalf("Failed to list agent signers: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		signers []ssh.Signer
+		want    ed25519.PrivateKey
+		wantErr bool
+	}{
+		{"unencrypted key file", plainPath, nil, plainKey, false},
+		{"encrypted key held by agent", encryptedPath, agentSigners, encryptedKey, false},
+		{"encrypted key without agent", encryptedPath, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := customKeySigner(tt.path, keyring, tt.signers)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("customKeySigner() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("customKeySigner() unexpected error: %v", err)
+			}
+			want, _ := ssh.NewSignerFromKey(tt.want)
+			if !bytes.Equal(signer.PublicKey().Marshal(), want.PublicKey().Marshal()) {
+				t.Error("customKeySigner() returned the wrong key")
+			}
+		})
+	}
+}
+
+func TestGetAuthMethods_NoKeys(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := getAuthMethods(nil, false); !errors.Is(err, errNoAuthMethods) {
+		t.Errorf("getAuthMethods() error = %v, want errNoAuthMethods", err)
+	}
+}
+
+func TestGetAuthMethods_CustomKeys(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := t.TempDir()
+	first, _ := writeTestKey(t, dir, "first", "")
+	second, _ := writeTestKey(t, dir, "second", "")
+
+	methods, err := getAuthMethods([]string{first, second, first}, true)
+	if err != nil {
+		t.Fatalf("getAuthMethods() unexpected error: %v", err)
+	}
+	// All keys share one publickey method, since x/crypto tries only the first of each type
+	if len(methods) != 1 {
+		t.Errorf("getAuthMethods() returned %d methods, want 1", len(methods))
+	}
+
+	if _, err := getAuthMethods([]string{first, filepath.Join(dir, "missing")}, false); err == nil {
+		t.Error("getAuthMethods() expected error for a missing key, got nil")
+	}
+}
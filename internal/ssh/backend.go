@@ -0,0 +1,304 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"volume-migrator/internal/shell"
+)
+
+// Transfer backends selecting how files are uploaded (--transfer-backend)
+const (
+	TransferBackendAuto  = "auto"  // SFTP when the remote offers it, shell otherwise
+	TransferBackendSFTP  = "sftp"  // The SFTP subsystem of the SSH server
+	TransferBackendShell = "shell" // cat over an SSH exec session, for servers without SFTP
+)
+
+// TransferBackend moves files between this machine and remote paths. One is opened for each
+// transfer by the factory it was registered with.
+type TransferBackend interface {
+	// Put creates remotePath with the content of r, aborting when ctx ends
+	Put(ctx context.Context, remotePath string, r io.Reader) error
+	// Get writes the content of remotePath to w, aborting when ctx ends
+	Get(ctx context.Context, remotePath string, w io.Writer) error
+	// Exists reports whether remotePath exists
+	Exists(remotePath string) (bool, error)
+	// Remove deletes remotePath; a file that does not exist is not an error
+	Remove(remotePath string) error
+	// Size returns the size of a remote file, failing when it does not exist
+	Size(remotePath string) (int64, error)
+	MkdirAll(dir string) error
+}
+
+// TransferBackendFactory opens a backend over the client's connection. The backend should stop
+// using the connection once ctx ends. The returned function closes the backend.
+type TransferBackendFactory func(ctx context.Context, c *Client) (TransferBackend, func(), error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]TransferBackendFactory)
+)
+
+func init() {
+	RegisterTransferBackend(TransferBackendSFTP, openSFTPBackend)
+	RegisterTransferBackend(TransferBackendShell, func(ctx context.Context, c *Client) (TransferBackend, func(), error) {
+		return shellBackend{c}, func() {}, nil
+	})
+}
+
+// RegisterTransferBackend makes a backend selectable with --transfer-backend name. It is meant to
+// be called from an init function and panics when the name is empty, auto or already registered.
+func RegisterTransferBackend(name string, factory TransferBackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if name == "" || name == TransferBackendAuto {
+		panic("ssh: invalid transfer backend name " + strconv.Quote(name))
+	}
+	if _, ok := backends[name]; ok {
+		panic("ssh: transfer backend registered twice: " + name)
+	}
+	backends[name] = factory
+}
+
+// TransferBackends returns the names of the registered backends, sorted
+func TransferBackends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lookupTransferBackend returns the factory of a registered backend or, failing that, of an
+// external plugin of that name
+func lookupTransferBackend(name string) (TransferBackendFactory, error) {
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+	if ok {
+		return factory, nil
+	}
+	return lookupPluginBackend(name)
+}
+
+// ValidateTransferBackend checks that name is empty (auto), a registered transfer backend or an
+// external plugin found on PATH
+func ValidateTransferBackend(name string) error {
+	if name == "" || name == TransferBackendAuto {
+		return nil
+	}
+	if _, err := lookupTransferBackend(name); err != nil {
+		return fmt.Errorf("invalid transfer backend '%s': must be auto, one of %s, or a %s%s plugin on PATH",
+			name, strings.Join(TransferBackends(), ", "), PluginBackendPrefix, name)
+	}
+	return nil
+}
+
+// TransferBackend returns the backend uploads use, never auto
+func (c *Client) TransferBackend() string {
+	return c.backend
+}
+
+// probeTransferBackend picks SFTP when the remote offers an SFTP subsystem, shell otherwise.
+// The probed session is kept as the shared one for the first transfer.
+func (c *Client) probeTransferBackend() string {
+	if _, err := c.sftp.get(c.dialSFTP); err != nil {
+		return TransferBackendShell
+	}
+	return TransferBackendSFTP
+}
+
+// openBackend opens the connection's transfer backend until ctx ends.
+// The returned function closes the backend.
+func (c *Client) openBackend(ctx context.Context) (TransferBackend, func(), error) {
+	factory, err := lookupTransferBackend(c.TransferBackend())
+	if err != nil {
+		return nil, nil, err
+	}
+	return factory(ctx, c)
+}
+
+// openSFTPBackend uses the shared SFTP session, closing it when ctx ends
+func openSFTPBackend(ctx context.Context, c *Client) (TransferBackend, func(), error) {
+	sftpClient, closeSFTP, err := c.openSFTP(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sftpBackend{sftpClient, c}, closeSFTP, nil
+}
+
+// sftpBackend transfers through the connection's shared SFTP session
+type sftpBackend struct {
+	client *sftp.Client
+	c      *Client
+}
+
+// Put closes the whole session when ctx ends, since a stalled SFTP write cannot be interrupted otherwise
+func (b sftpBackend) Put(ctx context.Context, remotePath string, r io.Reader) error {
+	dstFile, err := b.client.Create(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	defer dstFile.Close()
+	defer context.AfterFunc(ctx, func() { b.c.dropSFTP(b.client) })()
+
+	_, err = io.Copy(dstFile, r)
+	return err
+}
+
+// Get closes the whole session when ctx ends, like Put
+func (b sftpBackend) Get(ctx context.Context, remotePath string, w io.Writer) error {
+	srcFile, err := b.client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer srcFile.Close()
+	defer context.AfterFunc(ctx, func() { b.c.dropSFTP(b.client) })()
+
+	_, err = io.Copy(w, srcFile)
+	return err
+}
+
+// PutAt continues an upload, closing the whole session when ctx ends like Put
+func (b sftpBackend) PutAt(ctx context.Context, remotePath string, offset int64, r io.Reader) error {
+	dstFile, err := b.client.OpenFile(remotePath, os.O_WRONLY)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer dstFile.Close()
+	defer context.AfterFunc(ctx, func() { b.c.dropSFTP(b.client) })()
+
+	if err := dstFile.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate remote file: %w", err)
+	}
+	if _, err := dstFile.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek remote file: %w", err)
+	}
+	_, err = io.Copy(dstFile, r)
+	return err
+}
+
+// GetFrom continues a download, closing the whole session when ctx ends like Get
+func (b sftpBackend) GetFrom(ctx context.Context, remotePath string, offset int64, w io.Writer) error {
+	srcFile, err := b.client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer srcFile.Close()
+	defer context.AfterFunc(ctx, func() { b.c.dropSFTP(b.client) })()
+
+	if _, err := srcFile.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek remote file: %w", err)
+	}
+	_, err = io.Copy(w, srcFile)
+	return err
+}
+
+func (b sftpBackend) Exists(remotePath string) (bool, error) {
+	if _, err := b.client.Stat(remotePath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b sftpBackend) Remove(remotePath string) error {
+	if err := b.client.Remove(remotePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file %s on remote host: %w", remotePath, err)
+	}
+	return nil
+}
+
+func (b sftpBackend) Size(remotePath string) (int64, error) {
+	info, err := b.client.Stat(remotePath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (b sftpBackend) MkdirAll(dir string) error {
+	return b.client.MkdirAll(dir)
+}
+
+// shellBackend transfers by piping the data through cat on the remote host
+type shellBackend struct {
+	c *Client
+}
+
+// Put is not bounded by the command timeout, as the file streams as stdin for as long as it takes
+func (b shellBackend) Put(ctx context.Context, remotePath string, r io.Reader) error {
+	_, err := b.c.runCommandContext(ctx, "cat > "+shell.ShellEscape(remotePath), r)
+	return err
+}
+
+// PutAt cuts the file to offset and appends to it, like Put not bounded by the command timeout
+func (b shellBackend) PutAt(ctx context.Context, remotePath string, offset int64, r io.Reader) error {
+	escapedPath := shell.ShellEscape(remotePath)
+	_, err := b.c.runCommandContext(ctx, fmt.Sprintf("truncate -s %d %s && cat >> %s", offset, escapedPath, escapedPath), r)
+	return err
+}
+
+// Get streams the output of cat to w, not bounded by the command timeout either
+func (b shellBackend) Get(ctx context.Context, remotePath string, w io.Writer) error {
+	return b.stream(ctx, "cat "+shell.ShellEscape(remotePath), w)
+}
+
+// GetFrom streams the file from offset on with tail, like Get
+func (b shellBackend) GetFrom(ctx context.Context, remotePath string, offset int64, w io.Writer) error {
+	return b.stream(ctx, fmt.Sprintf("tail -c +%d %s", offset+1, shell.ShellEscape(remotePath)), w)
+}
+
+// stream runs cmd with its output written to w until ctx ends
+func (b shellBackend) stream(ctx context.Context, cmd string, w io.Writer) error {
+	session, closeSession, err := b.c.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeSession()
+
+	var stderr bytes.Buffer
+	session.Stdout = w
+	session.Stderr = &stderr
+	if err := b.c.runSession(ctx, session, cmd); err != nil {
+		return fmt.Errorf("command failed: %w, stderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func (b shellBackend) Exists(remotePath string) (bool, error) {
+	output, err := b.c.RunCommand(fmt.Sprintf("test -e %s && echo exists || true", shell.ShellEscape(remotePath)))
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) == "exists", nil
+}
+
+func (b shellBackend) Remove(remotePath string) error {
+	return b.c.RemoveFile(remotePath)
+}
+
+func (b shellBackend) Size(remotePath string) (int64, error) {
+	output, err := b.c.RunCommand("wc -c < " + shell.ShellEscape(remotePath))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+}
+
+func (b shellBackend) MkdirAll(dir string) error {
+	return b.c.CreateDirectory(dir)
+}
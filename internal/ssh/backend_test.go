@@ -0,0 +1,157 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBackend is a transfer backend registered by the tests
+type fakeBackend struct{}
+
+func (fakeBackend) Put(ctx context.Context, remotePath string, r io.Reader) error { return nil }
+func (fakeBackend) Get(ctx context.Context, remotePath string, w io.Writer) error { return nil }
+func (fakeBackend) Exists(remotePath string) (bool, error)                        { return false, nil }
+func (fakeBackend) Remove(remotePath string) error                                { return nil }
+func (fakeBackend) Size(remotePath string) (int64, error)                         { return 0, nil }
+func (fakeBackend) MkdirAll(dir string) error                                     { return nil }
+
+func init() {
+	RegisterTransferBackend("fake", func(ctx context.Context, c *Client) (TransferBackend, func(), error) {
+		return fakeBackend{}, func() {}, nil
+	})
+}
+
+// writePlugin installs a plugin named name running script on a PATH holding nothing else
+func writePlugin(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PluginBackendPrefix+name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin:/usr/bin")
+}
+
+func TestValidateTransferBackend(t *testing.T) {
+	writePlugin(t, "bucket", "exit 0\n")
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"", false},
+		{TransferBackendAuto, false},
+		{TransferBackendSFTP, false},
+		{TransferBackendShell, false},
+		{"fake", false},
+		{"bucket", false},
+		{"rsync", true},
+		{"../bucket", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTransferBackend(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTransferBackend(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterTransferBackend_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterTransferBackend() of a registered name did not panic")
+		}
+	}()
+	RegisterTransferBackend(TransferBackendSFTP, openSFTPBackend)
+}
+
+func TestTransferBackends(t *testing.T) {
+	got := strings.Join(TransferBackends(), ",")
+	if got != "fake,sftp,shell" {
+		t.Errorf("TransferBackends() = %s, want fake,sftp,shell", got)
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	writePlugin(t, "bucket", "exit 0\n")
+
+	tests := []struct {
+		backend string
+		want    func(TransferBackend) bool
+	}{
+		{TransferBackendShell, func(b TransferBackend) bool { _, ok := b.(shellBackend); return ok }},
+		{"fake", func(b TransferBackend) bool { _, ok := b.(fakeBackend); return ok }},
+		{"bucket", func(b TransferBackend) bool { _, ok := b.(pluginBackend); return ok }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			client := &Client{backend: tt.backend}
+			backend, closeBackend, err := client.openBackend(client.context())
+			if err != nil {
+				t.Fatalf("openBackend() error = %v", err)
+			}
+			defer closeBackend()
+			if !tt.want(backend) {
+				t.Errorf("openBackend() = %T", backend)
+			}
+		})
+	}
+}
+
+func TestPluginBackend(t *testing.T) {
+	// The plugin keeps the remote files under $STORE
+	store := t.TempDir()
+	t.Setenv("STORE", store)
+	writePlugin(t, "dir", `file="$STORE/$(basename "$2")"
+case "$1" in
+put) cat > "$file" ;;
+get) cat "$file" ;;
+exists) if [ -e "$file" ]; then echo true; else echo false; fi ;;
+remove) rm -f "$file" ;;
+size) wc -c < "$file" ;;
+mkdir) [ "$VOLUME_MIGRATOR_REMOTE_HOST" = "host:22" ] ;;
+*) echo "unknown operation $1" >&2; exit 1 ;;
+esac
+`)
+
+	client := &Client{host: "host:22", backend: "dir"}
+	backend, closeBackend, err := client.openBackend(client.context())
+	if err != nil {
+		t.Fatalf("openBackend() error = %v", err)
+	}
+	defer closeBackend()
+	ctx := context.Background()
+
+	if err := backend.MkdirAll("/tmp/migration"); err != nil {
+		t.Errorf("MkdirAll() error = %v", err)
+	}
+	if exists, err := backend.Exists("/tmp/migration/a.tar.gz"); err != nil || exists {
+		t.Errorf("Exists() before Put = %v, %v, want false", exists, err)
+	}
+	if err := backend.Put(ctx, "/tmp/migration/a.tar.gz", strings.NewReader("archive")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if exists, err := backend.Exists("/tmp/migration/a.tar.gz"); err != nil || !exists {
+		t.Errorf("Exists() after Put = %v, %v, want true", exists, err)
+	}
+	if size, err := backend.Size("/tmp/migration/a.tar.gz"); err != nil || size != 7 {
+		t.Errorf("Size() = %d, %v, want 7", size, err)
+	}
+	var got bytes.Buffer
+	if err := backend.Get(ctx, "/tmp/migration/a.tar.gz", &got); err != nil || got.String() != "archive" {
+		t.Errorf("Get() = %q, %v, want archive", got.String(), err)
+	}
+	if err := backend.Remove("/tmp/migration/a.tar.gz"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if _, err := backend.Size("/tmp/migration/a.tar.gz"); err == nil {
+		t.Error("Size() after Remove succeeded, want error")
+	}
+}
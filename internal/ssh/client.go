@@ -3,50 +3,117 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	migerrors "volume-migrator/internal/errors"
 	"volume-migrator/internal/shell"
+	"volume-migrator/internal/utils"
 )
 
+// ErrDockerNotAccessible is returned by NewClient when the connection works but Docker cannot
+// be run on the remote host, directly or with any of the allowed escalation methods
+var ErrDockerNotAccessible = errors.New("docker not accessible on remote host")
+
 // Client wraps SSH client operations
 type Client struct {
 	client     *ssh.Client
 	config     *ssh.ClientConfig
 	host       string
-	remoteSudo bool
+	escalation shell.Escalation // How remote docker commands get root privileges
+	sudoPass   string           // Fed to sudo -S when passwordless sudo is not allowed
+	sudoPrompt func() (string, error)
+	forward    bool // Request agent forwarding on every session
 	ctx        context.Context
+
+	commandTimeout time.Duration // Bounds each command, 0 for none
+	stallTimeout   time.Duration // Aborts transfers that send no data for this long, 0 for never
+	stallRestarts  int           // Times a stalled transfer is restarted before it fails
+	onStallRestart func(remotePath string, restart, restarts int)
+	backend        string        // Transfer backend, sftp or shell
+	bufferSize     int           // Bytes per copy and SFTP packet of transfers, 0 for DefaultBufferSize
+	streams        int           // SFTP sessions a single large upload is split over, 0 or 1 for one
+	sftp           *sftpSession  // Shared by the transfers, and by the clients WithoutCancel returns
+	sessions       *sessionPool  // Bounds the command sessions running at once, shared like sftp
 }
 
 // ClientConfig holds SSH client configuration options
 type ClientConfig struct {
 	HostString            string
-	CustomKeyPath         string
+	CustomKeyPaths        []string // Keys tried in order before the agent's other keys
 	StrictHostKeyChecking bool
 	AcceptHostKey         bool
 	KnownHostsFile        string
+	Options               Options // Settings from --ssh-option
+	GSSAPI                bool    // Try gssapi-with-mic (Kerberos) before the other methods
+	ProxyCommand          string  // Command whose stdio carries the connection, with %h, %p and %r expanded
+
+	// Escalation lists the methods tried in order to run remote docker commands; nil tries none, sudo and doas
+	Escalation []shell.Escalation
+
+	// SudoPassword is asked for the remote sudo password when Docker needs sudo and sudo -n
+	// is refused; nil keeps failing with ErrDockerNotAccessible
+	SudoPassword func() (string, error)
+
+	// CommandTimeout bounds every remote command except those streaming a file as stdin; 0 for none
+	CommandTimeout time.Duration
+
+	// TransferStallTimeout aborts uploads, downloads and streamed commands that move no data for this long; 0 for never
+	TransferStallTimeout time.Duration
+
+	// TransferStallRestarts restarts a stalled upload or download this many times, resuming where
+	// it stopped when the backend can; 0 fails it at the first stall
+	TransferStallRestarts int
+
+	// OnStallRestart is told about each restart of a stalled transfer; nil for none
+	OnStallRestart func(remotePath string, restart, restarts int)
+
+	// TransferBackend selects how files are uploaded (see TransferBackendAuto and friends), empty for auto
+	TransferBackend string
+
+	// BufferSize is the size of each read and SFTP packet of transfers, 0 for DefaultBufferSize
+	BufferSize int
+
+	// ParallelStreams splits SFTP uploads of large files over this many sessions, 0 or 1 for one
+	ParallelStreams int
+
+	// MaxSessions bounds the commands run at once over the connection, 0 for DefaultMaxSessions
+	MaxSessions int
 }
 
 // NewClient creates a new SSH client and establishes connection
 func NewClient(ctx context.Context, cfg *ClientConfig) (*Client, error) {
 	hostStr := cfg.HostString
-	customKeyPath := cfg.CustomKeyPath
 	user, host, port, err := parseHostPort(hostStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid host string: %w", err)
 	}
 
-	authMethods, err := getAuthMethods(customKeyPath)
-	if err != nil {
+	authMethods, err := getAuthMethods(cfg.CustomKeyPaths, cfg.Options.IdentitiesOnly)
+	if err != nil && !(cfg.GSSAPI && errors.Is(err, errNoAuthMethods)) {
 		return nil, fmt.Errorf("failed to get auth methods: %w", err)
 	}
 
+	if cfg.GSSAPI {
+		method, err := gssapiAuthMethod(host)
+		if err != nil {
+			return nil, err
+		}
+		authMethods = append([]ssh.AuthMethod{method}, authMethods...)
+	}
+
 	// Create host key verifier
 	verifier, err := NewHostKeyVerifier(cfg.StrictHostKeyChecking, cfg.AcceptHostKey, cfg.KnownHostsFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create host key verifier: %w", err)
 	}
+	verifier.hashHostnames = cfg.Options.HashKnownHosts
 
 	hostKeyCallback, err := verifier.GetCallback()
 	if err != nil {
@@ -57,97 +124,317 @@ func NewClient(ctx context.Context, cfg *ClientConfig) (*Client, error) {
 		User:            user,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
-		Timeout:         30 * time.Second,
 	}
+	cfg.Options.apply(config)
 
 	// Connect to remote host
-	addr := fmt.Sprintf("%s:%s", host, port)
-	client, err := ssh.Dial("tcp", addr, config)
+	addr := net.JoinHostPort(host, port)
+	proxyCommand := ""
+	if cfg.ProxyCommand != "" {
+		proxyCommand = expandProxyCommand(cfg.ProxyCommand, user, host, port)
+	}
+	client, err := dialSSH(ctx, proxyCommand, addr, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		// golang.org/x/crypto/ssh reports rejected credentials only in the message
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			err = fmt.Errorf("%w: %w", migerrors.ErrSSHAuth, err)
+		}
+		return nil, migerrors.NewSSHConnectionError(hostStr, err)
 	}
 
 	sshClient := &Client{
-		client: client,
-		config: config,
-		host:   addr,
-		ctx:    ctx,
+		client:     client,
+		config:     config,
+		host:       addr,
+		sudoPrompt: cfg.SudoPassword,
+		ctx:        ctx,
+
+		commandTimeout: cfg.CommandTimeout,
+		stallTimeout:   cfg.TransferStallTimeout,
+		stallRestarts:  cfg.TransferStallRestarts,
+		onStallRestart: cfg.OnStallRestart,
+		backend:        cfg.TransferBackend,
+		bufferSize:     cfg.BufferSize,
+		streams:        cfg.ParallelStreams,
+		sftp:           &sftpSession{},
+		sessions:       newSessionPool(cfg.MaxSessions),
 	}
 
-	// Detect if remote Docker requires sudo
-	if err := sshClient.detectRemoteSudo(); err != nil {
+	if cfg.Options.ForwardAgent {
+		if err := forwardAgent(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+		sshClient.forward = true
+	}
+
+	methods := cfg.Escalation
+	if methods == nil {
+		methods, _ = shell.ParseEscalation(shell.EscalationAuto)
+	}
+
+	// Detect how remote Docker can be run
+	if err := sshClient.detectRemoteEscalation(methods); err != nil {
 		client.Close()
-		return nil, fmt.Errorf("failed to detect remote sudo: %w", err)
+		return nil, fmt.Errorf("failed to detect remote privilege escalation: %w", err)
+	}
+
+	if sshClient.backend == "" || sshClient.backend == TransferBackendAuto {
+		sshClient.backend = sshClient.probeTransferBackend()
+	}
+
+	if cfg.Options.ServerAliveInterval > 0 {
+		go sshClient.keepAlive(cfg.Options.ServerAliveInterval)
 	}
 
 	return sshClient, nil
 }
 
-// detectRemoteSudo detects if Docker commands on remote require sudo
-func (c *Client) detectRemoteSudo() error {
-	// Try without sudo
-	_, err := c.RunCommand("docker ps")
-	if err == nil {
-		c.remoteSudo = false
-		return nil
+// dialSSH connects to addr directly or, when proxyCommand is set, over the command's stdio
+func dialSSH(ctx context.Context, proxyCommand, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if proxyCommand == "" {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	conn, err := dialProxyCommand(ctx, proxyCommand, addr)
+	if err != nil {
+		return nil, err
 	}
 
-	// Try with sudo
-	_, err = c.RunCommand("sudo -n docker ps")
+	// Pipes have no deadlines, so the handshake is bounded by closing the connection
+	timer := time.AfterFunc(config.Timeout, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if !timer.Stop() {
+		return nil, fmt.Errorf("handshake through proxy command timed out after %s", config.Timeout)
+	}
 	if err != nil {
-		return fmt.Errorf("docker not accessible on remote host")
+		conn.Close()
+		return nil, fmt.Errorf("handshake through proxy command failed: %w", err)
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// keepAlive sends an OpenSSH keepalive request every interval so idle connections are not
+// dropped by firewalls, until the connection closes or the context is cancelled
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// forwardAgent serves agent requests from the remote host with the local ssh-agent, so remote
+// commands can authenticate onward without keys stored on the remote host
+func forwardAgent(client *ssh.Client) error {
+	agentClient := connectSSHAgent()
+	if agentClient == nil {
+		return fmt.Errorf("ForwardAgent=yes requires a running ssh-agent (SSH_AUTH_SOCK is unset or unreachable)")
+	}
+	if err := agent.ForwardToAgent(client, agentClient); err != nil {
+		return fmt.Errorf("failed to set up agent forwarding: %w", err)
+	}
+	return nil
+}
+
+// detectRemoteEscalation runs "docker ps" with each method, non-interactively, and keeps the first that works
+func (c *Client) detectRemoteEscalation(methods []shell.Escalation) error {
+	for _, method := range methods {
+		if _, err := c.RunCommand(method.Prefix(true) + "docker ps"); err == nil {
+			c.escalation = method
+			return nil
+		}
+	}
+	if c.sudoPrompt == nil {
+		return ErrDockerNotAccessible
+	}
+
+	// No method works without a password: ask once and feed it to the first that reads one from stdin
+	var method shell.Escalation
+	for _, m := range methods {
+		if m.StdinPassword != nil {
+			method = m
+			break
+		}
+	}
+	if method.StdinPassword == nil {
+		return fmt.Errorf("%w: a password can only be given to sudo", ErrDockerNotAccessible)
 	}
 
-	c.remoteSudo = true
+	password, err := c.sudoPrompt()
+	if err != nil {
+		return fmt.Errorf("failed to read %s password: %w", method.Name, err)
+	}
+	c.escalation = method
+	c.sudoPass = password
+	if _, err := c.RunDockerCommand("ps"); err != nil {
+		c.escalation = shell.EscalationNone
+		c.sudoPass = ""
+		return fmt.Errorf("%w: %s password rejected or %s not allowed to run docker", ErrDockerNotAccessible, method.Name, method.Name)
+	}
 	return nil
 }
 
 // RunCommand executes a command on the remote host
 func (c *Client) RunCommand(cmd string) (string, error) {
-	session, err := c.client.NewSession()
+	return c.runCommand(cmd, nil)
+}
+
+// runCommand executes a command on the remote host with stdin (nil for none), bounded by the command timeout
+func (c *Client) runCommand(cmd string, stdin io.Reader) (string, error) {
+	ctx, cancel := c.commandContext()
+	defer cancel()
+	return c.runCommandContext(ctx, cmd, stdin)
+}
+
+// runCommandContext executes a command on the remote host with stdin (nil for none) until ctx ends
+func (c *Client) runCommandContext(ctx context.Context, cmd string, stdin io.Reader) (string, error) {
+	session, closeSession, err := c.newSession(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", err
 	}
-	defer session.Close()
+	defer closeSession()
 
 	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	if err := session.Run(cmd); err != nil {
+	if err := c.runSession(ctx, session, cmd); err != nil {
 		return "", fmt.Errorf("command failed: %w, stderr: %s", err, stderr.String())
 	}
 
 	return stdout.String(), nil
 }
 
+// runSession runs cmd in session until it exits or ctx ends, by cancellation or a timeout.
+// The remote process is then sent SIGTERM and the session is closed, so the command does not
+// keep running on the remote host after Ctrl+C. The error is the cause ctx ended with.
+func (c *Client) runSession(ctx context.Context, session *ssh.Session, cmd string) (err error) {
+	start := time.Now()
+	defer func() { utils.TraceCommand(c.host, cmd, exitCode(err), time.Since(start)) }()
+
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Servers that do not support signals still end the command's channel on close
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+		<-done
+		return context.Cause(ctx)
+	}
+}
+
+// exitCode returns the exit status of a finished remote command, -1 when it did not exit normally
+func exitCode(err error) int {
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus()
+	default:
+		return -1
+	}
+}
+
+// commandContext returns the context bounding one command: the client's, limited by the command timeout
+func (c *Client) commandContext() (context.Context, context.CancelFunc) {
+	if c.commandTimeout <= 0 {
+		return context.WithCancel(c.context())
+	}
+	return context.WithTimeoutCause(c.context(), c.commandTimeout,
+		fmt.Errorf("timed out after %s (--command-timeout)", c.commandTimeout))
+}
+
+// context returns the context bounding the client's remote work
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithoutCancel returns a client sharing the connection whose commands are not interrupted when
+// the context is cancelled, for cleanup that must still run after Ctrl+C
+func (c *Client) WithoutCancel() *Client {
+	detached := *c
+	detached.ctx = context.WithoutCancel(c.context())
+	return &detached
+}
+
 // RunDockerCommand executes a Docker command on the remote host
-// Automatically adds sudo if required
+// Automatically adds sudo or doas if required
 func (c *Client) RunDockerCommand(args ...string) (string, error) {
-	cmd := "docker"
-	if c.remoteSudo {
-		cmd = "sudo docker"
+	cmd := c.dockerCommand(args...)
+	if c.sudoPass != "" {
+		return c.runCommand(cmd, strings.NewReader(c.sudoPass+"\n"))
+	}
+	return c.RunCommand(cmd)
+}
+
+// RunDockerCommandWithInput executes a Docker command on the remote host with stdin read from input
+// A sudo password is sent first: sudo -S reads a single line and passes the rest on to docker.
+// The command timeout does not apply, since input may be a long stream; the stall timeout does.
+func (c *Client) RunDockerCommandWithInput(input io.Reader, args ...string) (string, error) {
+	watch := c.watchStalls()
+	defer watch.stop()
+
+	cmd := c.dockerCommand(args...)
+	input = watch.reader(input)
+	if c.sudoPass != "" {
+		input = io.MultiReader(strings.NewReader(c.sudoPass+"\n"), input)
+	}
+	return c.runCommandContext(watch.ctx, cmd, input)
+}
+
+// dockerCommand builds the remote docker command line, with the escalation method if required
+// With a sudo password, sudo -S reads it from stdin and -p '' keeps the prompt out of stderr
+func (c *Client) dockerCommand(args ...string) string {
+	cmd := c.escalation.Prefix(false) + "docker"
+	if c.sudoPass != "" {
+		cmd = strings.Join(append(c.escalation.Args(false), c.escalation.StdinPassword...), " ") + " docker"
 	}
 
 	for _, arg := range args {
 		cmd += " " + arg
 	}
-
-	return c.RunCommand(cmd)
+	return cmd
 }
 
 // RunCommandWithOutput executes a command and captures stdout and stderr separately
 func (c *Client) RunCommandWithOutput(cmd string, stdout, stderr *bytes.Buffer) error {
-	session, err := c.client.NewSession()
+	ctx, cancel := c.commandContext()
+	defer cancel()
+
+	session, closeSession, err := c.newSession(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return err
 	}
-	defer session.Close()
+	defer closeSession()
 
 	session.Stdout = stdout
 	session.Stderr = stderr
-
-	return session.Run(cmd)
+	return c.runSession(ctx, session, cmd)
 }
 
 // CreateDirectory creates a directory on the remote host
@@ -194,13 +481,21 @@ func (c *Client) RemoveDirectory(path string) error {
 	return nil
 }
 
-// RequiresSudo returns whether remote Docker commands require sudo
-func (c *Client) RequiresSudo() bool {
-	return c.remoteSudo
+// RequiresEscalation returns whether remote Docker commands run through sudo or doas
+func (c *Client) RequiresEscalation() bool {
+	return c.escalation.Required()
 }
 
-// Close closes the SSH connection
+// Escalation returns how remote Docker commands get root privileges
+func (c *Client) Escalation() shell.Escalation {
+	return c.escalation
+}
+
+// Close closes the shared SFTP session and the SSH connection
 func (c *Client) Close() error {
+	if c.sftp != nil {
+		c.sftp.close()
+	}
 	if c.client != nil {
 		return c.client.Close()
 	}
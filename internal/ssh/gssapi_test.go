@@ -0,0 +1,155 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeGSSAPIClient satisfies ssh.GSSAPIClient without talking to a KDC
+type fakeGSSAPIClient struct{}
+
+func (fakeGSSAPIClient) InitSecContext(target string, token []byte, isGSSDelegCreds bool) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (fakeGSSAPIClient) GetMIC(micField []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (fakeGSSAPIClient) DeleteSecContext() error {
+	return nil
+}
+
+func TestGSSAPIAuthMethod(t *testing.T) {
+	original := newGSSAPIClient
+	defer func() { newGSSAPIClient = original }()
+
+	tests := []struct {
+		name      string
+		newClient func() (ssh.GSSAPIClient, error)
+		wantErr   bool
+	}{
+		{"client fails", func() (ssh.GSSAPIClient, error) { return nil, errors.New("no credentials cache") }, true},
+		{"client available", func() (ssh.GSSAPIClient, error) { return fakeGSSAPIClient{}, nil }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newGSSAPIClient = tt.newClient
+			method, err := gssapiAuthMethod("remote.example.com")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("gssapiAuthMethod() expected error, got nil")
+				}
+				return
+			}
+			if err != nil || method == nil {
+				t.Errorf("gssapiAuthMethod() = %v, %v; want an auth method", method, err)
+			}
+		})
+	}
+}
+
+func TestCredentialCachePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", "/tmp/krb5cc_1000", false},
+		{"/run/user/1000/krb5cc", "/run/user/1000/krb5cc", false},
+		{"FILE:/tmp/krb5cc_alice", "/tmp/krb5cc_alice", false},
+		{"KEYRING:persistent:1000", "", true},
+		{"KCM:", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := credentialCachePath(tt.name, 1000)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("credentialCachePath(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestKrb5ConfigPath(t *testing.T) {
+	tests := map[string]string{
+		"":                              "/etc/krb5.conf",
+		"/opt/krb5.conf":                "/opt/krb5.conf",
+		"/opt/krb5.conf:/etc/krb5.conf": "/opt/krb5.conf",
+	}
+	for env, want := range tests {
+		if got := krb5ConfigPath(env); got != want {
+			t.Errorf("krb5ConfigPath(%q) = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestKerberosClient(t *testing.T) {
+	sessionKey := types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: bytes.Repeat([]byte{7}, 32)}
+	var requested string
+	k := &kerberosClient{
+		client: client.NewWithPassword("alice", "EXAMPLE.COM", "secret", config.New()),
+		serviceTicket: func(spn string) (messages.Ticket, types.EncryptionKey, error) {
+			requested = spn
+			return messages.Ticket{
+				TktVNO:  5,
+				Realm:   "EXAMPLE.COM",
+				SName:   types.NewPrincipalName(nametype.KRB_NT_SRV_HST, spn),
+				EncPart: types.EncryptedData{EType: etypeID.AES256_CTS_HMAC_SHA1_96, Cipher: []byte("ticket")},
+			}, sessionKey, nil
+		},
+	}
+
+	token, needContinue, err := k.InitSecContext("host@remote.example.com", nil, false)
+	if err != nil {
+		t.Fatalf("InitSecContext() error = %v", err)
+	}
+	if needContinue {
+		t.Error("InitSecContext() needContinue = true, want the context established by the AP-REQ")
+	}
+	if requested != "host/remote.example.com" {
+		t.Errorf("InitSecContext() requested a ticket for %q, want host/remote.example.com", requested)
+	}
+
+	var apReq spnego.KRB5Token
+	if err := apReq.Unmarshal(token); err != nil || !apReq.IsAPReq() {
+		t.Fatalf("InitSecContext() token is not a KRB5 AP-REQ: %v", err)
+	}
+	if err := apReq.APReq.DecryptAuthenticator(sessionKey); err != nil {
+		t.Fatalf("AP-REQ authenticator does not decrypt with the session key: %v", err)
+	}
+	if got := apReq.APReq.Authenticator.CName.PrincipalNameString(); got != "alice" {
+		t.Errorf("AP-REQ authenticator client = %q, want alice", got)
+	}
+
+	micField := []byte("session binding")
+	micBytes, err := k.GetMIC(micField)
+	if err != nil {
+		t.Fatalf("GetMIC() error = %v", err)
+	}
+	var mic gssapi.MICToken
+	if err := mic.Unmarshal(micBytes, false); err != nil {
+		t.Fatalf("GetMIC() token does not parse: %v", err)
+	}
+	mic.Payload = micField
+	if ok, err := mic.Verify(sessionKey, keyusage.GSSAPI_INITIATOR_SIGN); !ok {
+		t.Errorf("GetMIC() token does not verify with the session key: %v", err)
+	}
+
+	// A reply the server sends anyway completes the context
+	if _, needContinue, err := k.InitSecContext("host@remote.example.com", []byte{0}, false); err != nil || needContinue {
+		t.Errorf("InitSecContext(reply) = %v, %v; want the context complete", needContinue, err)
+	}
+}
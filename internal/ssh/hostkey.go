@@ -1,10 +1,12 @@
 package ssh
 
 import (
+	"bufio"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/knownhosts"
@@ -15,6 +17,7 @@ type HostKeyVerifier struct {
 	knownHostsPath string
 	strictChecking bool
 	acceptNewKeys  bool
+	hashHostnames  *bool // nil follows the existing file (see shouldHashHostnames)
 }
 
 // NewHostKeyVerifier creates a new host key verifier with the specified security settings.
@@ -134,16 +137,51 @@ func (v *HostKeyVerifier) createKnownHostsFile() error {
 	return file.Close()
 }
 
+// shouldHashHostnames reports whether new entries are written hashed, as with OpenSSH's HashKnownHosts.
+// Unless set explicitly, entries are hashed when the file already contains hashed entries,
+// so a file maintained with HashKnownHosts=yes does not end up with mixed formats.
+func (v *HostKeyVerifier) shouldHashHostnames() bool {
+	if v.hashHostnames != nil {
+		return *v.hashHostnames
+	}
+
+	file, err := os.Open(v.knownHostsPath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "|1|") {
+			return true
+		}
+	}
+	return false
+}
+
+// knownHostsLine formats a known_hosts entry for hostname (host:port as passed to the callback)
+// Non-22 ports are written as [host]:port, both in plain and hashed form
+func knownHostsLine(hostname string, key ssh.PublicKey, hash bool) string {
+	address := knownhosts.Normalize(hostname)
+	if hash {
+		address = knownhosts.HashHostname(address)
+	}
+	// Format: hostname keytype base64key
+	return knownhosts.Line([]string{address}, key)
+}
+
 // addHostKey adds a host key to known_hosts
 func (v *HostKeyVerifier) addHostKey(hostname string, key ssh.PublicKey) error {
+	hash := v.shouldHashHostnames()
+
 	file, err := os.OpenFile(v.knownHostsPath, os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to open known_hosts: %w", err)
 	}
 	defer file.Close()
 
-	// Format: hostname keytype base64key
-	line := knownhosts.Line([]string{hostname}, key)
+	line := knownHostsLine(hostname, key, hash)
 	if _, err := file.WriteString(line + "\n"); err != nil {
 		return fmt.Errorf("failed to write to known_hosts: %w", err)
 	}
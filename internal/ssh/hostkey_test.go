@@ -1,9 +1,16 @@
 package ssh
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func TestNewHostKeyVerifier(t *testing.T) {
@@ -202,3 +209,103 @@ func TestGetCallback_NonStrictCreatesFile(t *testing.T) {
 		t.Error("GetCallback() should have created known_hosts file in non-strict mode")
 	}
 }
+
+// testHostKey generates a throwaway ed25519 host key
+func testHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	return key
+}
+
+func TestKnownHostsLine(t *testing.T) {
+	key := testHostKey(t)
+
+	tests := []struct {
+		name     string
+		hostname string
+		hash     bool
+		prefix   string
+	}{
+		{"default port", "example.com:22", false, "example.com "},
+		{"custom port", "example.com:2222", false, "[example.com]:2222 "},
+		{"hashed", "example.com:2222", true, "|1|"},
+		{"IPv6 default port", "[2001:db8::1]:22", false, "2001:db8::1 "},
+		{"IPv6 custom port", "[2001:db8::1]:2222", false, "[2001:db8::1]:2222 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := knownHostsLine(tt.hostname, key, tt.hash)
+			if !strings.HasPrefix(line, tt.prefix) {
+				t.Errorf("knownHostsLine() = %q, want prefix %q", line, tt.prefix)
+			}
+			if tt.hash && strings.Contains(line, "example.com") {
+				t.Errorf("hashed line leaks the hostname: %q", line)
+			}
+		})
+	}
+}
+
+func TestShouldHashHostnames(t *testing.T) {
+	tmpDir := t.TempDir()
+	plain := filepath.Join(tmpDir, "plain")
+	hashed := filepath.Join(tmpDir, "hashed")
+	os.WriteFile(plain, []byte(knownHostsLine("a.example.com:22", testHostKey(t), false)+"\n"), 0600)
+	os.WriteFile(hashed, []byte(knownHostsLine("b.example.com:22", testHostKey(t), true)+"\n"), 0600)
+	yes, no := true, false
+
+	tests := []struct {
+		name    string
+		path    string
+		setting *bool
+		want    bool
+	}{
+		{"plain file", plain, nil, false},
+		{"hashed file", hashed, nil, true},
+		{"missing file", filepath.Join(tmpDir, "missing"), nil, false},
+		{"forced on", plain, &yes, true},
+		{"forced off", hashed, &no, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &HostKeyVerifier{knownHostsPath: tt.path, hashHostnames: tt.setting}
+			if got := v.shouldHashHostnames(); got != tt.want {
+				t.Errorf("shouldHashHostnames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddHostKey_HashedEntryMatches(t *testing.T) {
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	yes := true
+	v := &HostKeyVerifier{knownHostsPath: knownHostsPath, hashHostnames: &yes}
+	if err := v.createKnownHostsFile(); err != nil {
+		t.Fatalf("createKnownHostsFile() error: %v", err)
+	}
+
+	key := testHostKey(t)
+	if err := v.addHostKey("example.com:2222", key); err != nil {
+		t.Fatalf("addHostKey() error: %v", err)
+	}
+
+	callback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		t.Fatalf("knownhosts.New() error: %v", err)
+	}
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 2222}
+	if err := callback("example.com:2222", remote, key); err != nil {
+		t.Errorf("hashed entry did not match: %v", err)
+	}
+	if err := callback("example.com:22", remote, key); err == nil {
+		t.Error("entry for port 2222 should not match port 22")
+	}
+}
@@ -0,0 +1,126 @@
+package ssh
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Options holds the ssh_config-style settings given with --ssh-option
+// Empty algorithm lists keep the library defaults
+type Options struct {
+	Ciphers             []string
+	MACs                []string
+	KeyExchanges        []string
+	HostKeyAlgorithms   []string
+	ConnectTimeout      time.Duration // 0 keeps the default of 30 seconds
+	ServerAliveInterval time.Duration // 0 disables keepalives
+	HashKnownHosts      *bool         // nil hashes new known_hosts entries only if the file already has hashed ones
+	ForwardAgent        bool          // Forward the local ssh-agent to remote commands
+	IdentitiesOnly      bool          // Offer only the --ssh-key keys, not every agent key
+}
+
+// defaultConnectTimeout is used when no ConnectTimeout option is given
+const defaultConnectTimeout = 30 * time.Second
+
+// ParseOptions parses Key=Value settings as in ssh_config: keys are case-insensitive,
+// algorithm lists are comma-separated and replace the defaults, and times are in seconds.
+// Supported keys: Ciphers, MACs, KexAlgorithms, HostKeyAlgorithms, ConnectTimeout, ServerAliveInterval, HashKnownHosts, ForwardAgent, IdentitiesOnly.
+func ParseOptions(settings []string) (Options, error) {
+	var opts Options
+	supported := ssh.SupportedAlgorithms()
+	insecure := ssh.InsecureAlgorithms()
+
+	for _, setting := range settings {
+		key, value, ok := strings.Cut(setting, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return Options{}, fmt.Errorf("invalid SSH option '%s': expected Key=Value", setting)
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "ciphers":
+			opts.Ciphers, err = parseAlgorithms(value, supported.Ciphers, insecure.Ciphers)
+		case "macs":
+			opts.MACs, err = parseAlgorithms(value, supported.MACs, insecure.MACs)
+		case "kexalgorithms":
+			opts.KeyExchanges, err = parseAlgorithms(value, supported.KeyExchanges, insecure.KeyExchanges)
+		case "hostkeyalgorithms":
+			opts.HostKeyAlgorithms, err = parseAlgorithms(value, supported.HostKeys, insecure.HostKeys)
+		case "connecttimeout":
+			opts.ConnectTimeout, err = parseSeconds(value)
+		case "serveraliveinterval":
+			opts.ServerAliveInterval, err = parseSeconds(value)
+		case "hashknownhosts":
+			var hash bool
+			hash, err = parseYesNo(value)
+			opts.HashKnownHosts = &hash
+		case "forwardagent":
+			opts.ForwardAgent, err = parseYesNo(value)
+		case "identitiesonly":
+			opts.IdentitiesOnly, err = parseYesNo(value)
+		default:
+			return Options{}, fmt.Errorf("unsupported SSH option '%s' (supported: Ciphers, MACs, KexAlgorithms, HostKeyAlgorithms, ConnectTimeout, ServerAliveInterval, HashKnownHosts, ForwardAgent, IdentitiesOnly)", key)
+		}
+		if err != nil {
+			return Options{}, fmt.Errorf("invalid SSH option %s: %w", key, err)
+		}
+	}
+	return opts, nil
+}
+
+// parseAlgorithms splits a comma-separated algorithm list, rejecting names the library does not implement
+func parseAlgorithms(value string, supported, insecure []string) ([]string, error) {
+	var algorithms []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(supported, name) && !slices.Contains(insecure, name) {
+			return nil, fmt.Errorf("unknown algorithm '%s' (supported: %s)", name, strings.Join(supported, ","))
+		}
+		algorithms = append(algorithms, name)
+	}
+	if len(algorithms) == 0 {
+		return nil, fmt.Errorf("empty algorithm list")
+	}
+	return algorithms, nil
+}
+
+// parseSeconds parses a positive number of seconds
+func parseSeconds(value string) (time.Duration, error) {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("'%s' must be a positive number of seconds", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// parseYesNo parses an ssh_config yes/no value
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("'%s' must be yes or no", value)
+}
+
+// apply sets the options on an ssh.ClientConfig
+func (o Options) apply(config *ssh.ClientConfig) {
+	config.Ciphers = o.Ciphers
+	config.MACs = o.MACs
+	config.KeyExchanges = o.KeyExchanges
+	config.HostKeyAlgorithms = o.HostKeyAlgorithms
+	config.Timeout = defaultConnectTimeout
+	if o.ConnectTimeout > 0 {
+		config.Timeout = o.ConnectTimeout
+	}
+}
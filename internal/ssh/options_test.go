@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions([]string{
+		"Ciphers=aes256-gcm@openssh.com,aes128-ctr",
+		"macs=hmac-sha2-256",
+		"KexAlgorithms = curve25519-sha256",
+		"HostKeyAlgorithms=ssh-ed25519",
+		"ConnectTimeout=10",
+		"ServerAliveInterval=60",
+		"HashKnownHosts=Yes",
+		"ForwardAgent=yes",
+	})
+	if err != nil {
+		t.Fatalf("ParseOptions failed: %v", err)
+	}
+
+	if strings.Join(opts.Ciphers, ",") != "aes256-gcm@openssh.com,aes128-ctr" {
+		t.Errorf("unexpected ciphers: %v", opts.Ciphers)
+	}
+	if strings.Join(opts.MACs, ",") != "hmac-sha2-256" || strings.Join(opts.KeyExchanges, ",") != "curve25519-sha256" {
+		t.Errorf("unexpected MACs or key exchanges: %v %v", opts.MACs, opts.KeyExchanges)
+	}
+	if strings.Join(opts.HostKeyAlgorithms, ",") != "ssh-ed25519" {
+		t.Errorf("unexpected host key algorithms: %v", opts.HostKeyAlgorithms)
+	}
+	if opts.ConnectTimeout != 10*time.Second || opts.ServerAliveInterval != time.Minute {
+		t.Errorf("unexpected durations: %v %v", opts.ConnectTimeout, opts.ServerAliveInterval)
+	}
+	if opts.HashKnownHosts == nil || !*opts.HashKnownHosts {
+		t.Errorf("expected HashKnownHosts yes, got %v", opts.HashKnownHosts)
+	}
+	if !opts.ForwardAgent {
+		t.Error("expected ForwardAgent yes")
+	}
+}
+
+func TestParseOptions_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		setting     string
+		errContains string
+	}{
+		{"missing value", "Ciphers", "expected Key=Value"},
+		{"empty value", "Ciphers=", "expected Key=Value"},
+		{"unknown key", "ProxyJump=bastion", "unsupported SSH option"},
+		{"unknown cipher", "Ciphers=rot13", "unknown algorithm 'rot13'"},
+		{"empty list", "MACs=,", "empty algorithm list"},
+		{"negative timeout", "ConnectTimeout=-1", "positive number of seconds"},
+		{"non-numeric interval", "ServerAliveInterval=1m", "positive number of seconds"},
+		{"invalid hash setting", "HashKnownHosts=true", "must be yes or no"},
+		{"invalid forward setting", "ForwardAgent=1", "must be yes or no"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOptions([]string{tt.setting})
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("ParseOptions(%q) error = %v, want error containing %q", tt.setting, err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestOptionsApply(t *testing.T) {
+	config := &ssh.ClientConfig{}
+	Options{}.apply(config)
+	if config.Timeout != defaultConnectTimeout || config.Ciphers != nil {
+		t.Errorf("expected defaults, got timeout %v ciphers %v", config.Timeout, config.Ciphers)
+	}
+
+	Options{Ciphers: []string{"aes128-ctr"}, ConnectTimeout: 5 * time.Second}.apply(config)
+	if config.Timeout != 5*time.Second || len(config.Ciphers) != 1 {
+		t.Errorf("expected options applied, got timeout %v ciphers %v", config.Timeout, config.Ciphers)
+	}
+}
@@ -0,0 +1,174 @@
+package ssh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"volume-migrator/internal/utils"
+)
+
+// MaxParallelStreams bounds --parallel-streams, each stream being an SFTP session of its own
+const MaxParallelStreams = 16
+
+// parallelMinRange is the smallest range of a file given its own stream; smaller files use fewer streams
+const parallelMinRange = 16 * 1024 * 1024
+
+// ValidateParallelStreams checks a --parallel-streams count, 0 and 1 meaning a single stream
+func ValidateParallelStreams(streams int) error {
+	if streams < 0 || streams > MaxParallelStreams {
+		return fmt.Errorf("invalid parallel streams %d: must be between 0 and %d (0 or 1 for a single stream)", streams, MaxParallelStreams)
+	}
+	return nil
+}
+
+// parallelStreamCount returns how many streams upload a file of size bytes, at most streams and
+// with each range at least parallelMinRange bytes
+func parallelStreamCount(size int64, streams int) int {
+	return int(max(min(int64(streams), size/parallelMinRange), 1))
+}
+
+// streamRange returns the offset and length of the i-th of count ranges splitting size bytes
+func streamRange(size int64, count, i int) (int64, int64) {
+	rangeSize := (size + int64(count) - 1) / int64(count)
+	offset := int64(i) * rangeSize
+	return offset, min(rangeSize, size-offset)
+}
+
+// sftpOpener opens an SFTP session that is closed when ctx ends, as Client.newSFTP does
+type sftpOpener func(ctx context.Context) (*sftp.Client, func(), error)
+
+// transferFileParallel uploads a file of size bytes over several SFTP sessions at once, each
+// writing its own range of the remote file on a session of its own, then checks the sha256 of the remote file. Every
+// session is a channel with its own flow-control window, so together they fill links whose
+// latency keeps a single one from doing so.
+func (c *Client) transferFileParallel(localPath, remotePath string, size int64, streams int, showProgress bool) error {
+	watch := c.watchStalls()
+	defer watch.stop()
+
+	srcFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer srcFile.Close()
+
+	// Checksummed while uploading, from the page cache the streams fill
+	checksum := make(chan string, 1)
+	go func() {
+		hash := sha256.New()
+		io.Copy(hash, io.NewSectionReader(srcFile, 0, size))
+		checksum <- hex.EncodeToString(hash.Sum(nil))
+	}()
+
+	var mu sync.Mutex
+	var progress *ProgressReader
+	if showProgress {
+		description := fmt.Sprintf("Uploading %s (%d streams)", filepath.Base(localPath), streams)
+		bar := utils.NewProgressBar(size, description)
+		progress = newProgressReader(nil, bar, description)
+		defer bar.Finish()
+	}
+	// Stall watching is done here, as the EOF of one stream must not end it for the others
+	wrap := func(r io.Reader) io.Reader {
+		return readFunc(func(p []byte) (int, error) {
+			n, err := r.Read(p)
+			if n > 0 {
+				now := time.Now()
+				watch.touch(now)
+				if progress != nil {
+					mu.Lock()
+					progress.bar.Add(n)
+					progress.record(n, now)
+					mu.Unlock()
+				}
+			}
+			return n, err
+		})
+	}
+
+	if err := uploadParallel(watch.ctx, c.newSFTP, srcFile, remotePath, size, streams, wrap); err != nil {
+		c.removePartial(remotePath)
+		return fmt.Errorf("failed to transfer file: %w", transferError(watch.ctx, err))
+	}
+
+	if err := c.verifyChecksum(remotePath, <-checksum, "parallel upload"); err != nil {
+		c.removePartial(remotePath)
+		return err
+	}
+	return nil
+}
+
+// uploadParallel creates remotePath and writes size bytes of src to it over streams sessions,
+// each copying its range through wrap. The first failure closes the other sessions.
+func uploadParallel(ctx context.Context, open sftpOpener, src io.ReaderAt, remotePath string, size int64, streams int, wrap func(io.Reader) io.Reader) error {
+	sftpClient, closeSFTP, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeSFTP()
+	if err := sftpClient.MkdirAll(path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+	dstFile, err := sftpClient.Create(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	dstFile.Close()
+
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		offset, length := streamRange(size, streams, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := uploadRange(streamCtx, open, io.NewSectionReader(src, offset, length), remotePath, offset, wrap); err != nil {
+				cancel(fmt.Errorf("stream %d/%d: %w", i+1, streams, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	// Sessions closed by the first failure fail the other streams too, so only the first is reported
+	return context.Cause(streamCtx)
+}
+
+// uploadRange writes r to remotePath starting at offset, over a session of its own
+func uploadRange(ctx context.Context, open sftpOpener, r io.Reader, remotePath string, offset int64, wrap func(io.Reader) io.Reader) error {
+	sftpClient, closeSFTP, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeSFTP()
+
+	dstFile, err := sftpClient.OpenFile(remotePath, os.O_WRONLY)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer dstFile.Close()
+	if _, err := dstFile.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek remote file: %w", err)
+	}
+	_, err = io.Copy(dstFile, wrap(r))
+	return err
+}
+
+// readFunc adapts a function to io.Reader
+type readFunc func(p []byte) (int, error)
+
+func (f readFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
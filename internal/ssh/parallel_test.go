@@ -0,0 +1,152 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+func TestParallelStreamCount(t *testing.T) {
+	tests := []struct {
+		size    int64
+		streams int
+		want    int
+	}{
+		{100 * parallelMinRange, 4, 4},
+		{3 * parallelMinRange, 4, 3},
+		{parallelMinRange - 1, 4, 1},
+		{100 * parallelMinRange, 1, 1},
+		{0, 4, 1},
+	}
+	for _, tt := range tests {
+		if got := parallelStreamCount(tt.size, tt.streams); got != tt.want {
+			t.Errorf("parallelStreamCount(%d, %d) = %d, want %d", tt.size, tt.streams, got, tt.want)
+		}
+	}
+}
+
+func TestStreamRange(t *testing.T) {
+	var next int64
+	for i := 0; i < 3; i++ {
+		offset, length := streamRange(10, 3, i)
+		if offset != next {
+			t.Errorf("range %d starts at %d, want %d", i, offset, next)
+		}
+		next = offset + length
+	}
+	if next != 10 {
+		t.Errorf("ranges end at %d, want 10", next)
+	}
+	if offset, length := streamRange(10, 3, 2); offset != 8 || length != 2 {
+		t.Errorf("streamRange(10, 3, 2) = %d, %d, want 8, 2", offset, length)
+	}
+}
+
+func TestValidateParallelStreams(t *testing.T) {
+	for _, streams := range []int{0, 1, MaxParallelStreams} {
+		if err := ValidateParallelStreams(streams); err != nil {
+			t.Errorf("ValidateParallelStreams(%d) = %v, want nil", streams, err)
+		}
+	}
+	for _, streams := range []int{-1, MaxParallelStreams + 1} {
+		if err := ValidateParallelStreams(streams); err == nil {
+			t.Errorf("ValidateParallelStreams(%d) succeeded, want error", streams)
+		}
+	}
+}
+
+// pipeClient connects an SFTP client to an in-process server serving the local filesystem.
+// The server stops when the client closes.
+func pipeClient(t *testing.T) (*sftp.Client, error) {
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client's Close waits for its receive loop, which ends once the server side is closed
+	go func() {
+		server.Serve()
+		server.Close()
+	}()
+	return sftp.NewClientPipe(clientRead, clientWrite)
+}
+
+// pipeSFTP opens sessions to an in-process SFTP server, counting them in opened
+func pipeSFTP(t *testing.T, opened *atomic.Int32) sftpOpener {
+	return func(ctx context.Context) (*sftp.Client, func(), error) {
+		opened.Add(1)
+		client, err := pipeClient(t)
+		if err != nil {
+			return nil, nil, err
+		}
+		stop := context.AfterFunc(ctx, func() { client.Close() })
+		return client, func() {
+			stop()
+			client.Close()
+		}, nil
+	}
+}
+
+func TestUploadParallel(t *testing.T) {
+	data := make([]byte, 1<<20+123)
+	rand.Read(data)
+	remotePath := filepath.Join(t.TempDir(), "sub", "archive.tar.gz")
+
+	var opened atomic.Int32
+	var read atomic.Int64
+	wrap := func(r io.Reader) io.Reader {
+		return readFunc(func(p []byte) (int, error) {
+			n, err := r.Read(p)
+			read.Add(int64(n))
+			return n, err
+		})
+	}
+	err := uploadParallel(context.Background(), pipeSFTP(t, &opened), bytes.NewReader(data), remotePath, int64(len(data)), 4, wrap)
+	if err != nil {
+		t.Fatalf("uploadParallel() error = %v", err)
+	}
+
+	got, err := os.ReadFile(remotePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("uploaded file differs from the source (%d bytes, want %d)", len(got), len(data))
+	}
+	if opened.Load() != 5 {
+		t.Errorf("opened %d sessions, want one to create the file and one per stream", opened.Load())
+	}
+	if read.Load() != int64(len(data)) {
+		t.Errorf("read %d bytes through wrap, want %d", read.Load(), len(data))
+	}
+}
+
+func TestUploadParallel_StreamFailure(t *testing.T) {
+	data := make([]byte, 1<<20)
+	remotePath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	errDisk := errors.New("disk read error")
+
+	var opened, streams atomic.Int32
+	wrap := func(r io.Reader) io.Reader {
+		if streams.Add(1) == 2 {
+			return readFunc(func(p []byte) (int, error) { return 0, errDisk })
+		}
+		return r
+	}
+	err := uploadParallel(context.Background(), pipeSFTP(t, &opened), bytes.NewReader(data), remotePath, int64(len(data)), 3, wrap)
+	if !errors.Is(err, errDisk) {
+		t.Errorf("uploadParallel() error = %v, want %v", err, errDisk)
+	}
+}
@@ -0,0 +1,116 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PluginBackendPrefix names the executables providing external transfer backends:
+// --transfer-backend name runs volume-migrator-backend-name found on PATH
+const PluginBackendPrefix = "volume-migrator-backend-"
+
+// Environment variables telling a plugin which remote host it transfers to
+const (
+	PluginHostEnv = "VOLUME_MIGRATOR_REMOTE_HOST" // host:port of the SSH connection
+	PluginUserEnv = "VOLUME_MIGRATOR_REMOTE_USER" // SSH user of the connection
+)
+
+// lookupPluginBackend returns a factory running the plugin executable of name
+func lookupPluginBackend(name string) (TransferBackendFactory, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid transfer backend plugin name '%s'", name)
+	}
+	executable, err := exec.LookPath(PluginBackendPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("transfer backend plugin not found: %w", err)
+	}
+	return func(ctx context.Context, c *Client) (TransferBackend, func(), error) {
+		return pluginBackend{executable: executable, c: c}, func() {}, nil
+	}, nil
+}
+
+// pluginBackend runs a plugin executable once per operation, as
+// "<plugin> put|get|exists|remove|size|mkdir <path>". put reads the file from stdin and get
+// writes it to stdout; exists prints true or false and size the size in bytes. A non-zero exit
+// status fails the operation with the plugin's stderr.
+type pluginBackend struct {
+	executable string
+	c          *Client
+}
+
+// run runs one operation of the plugin until ctx ends, returning its stdout when stdout is nil
+func (b pluginBackend) run(ctx context.Context, op, remotePath string, stdin io.Reader, stdout io.Writer) (string, error) {
+	cmd := exec.CommandContext(ctx, b.executable, op, remotePath)
+	cmd.Env = append(os.Environ(), PluginHostEnv+"="+b.c.host)
+	if b.c.config != nil {
+		cmd.Env = append(cmd.Env, PluginUserEnv+"="+b.c.config.User)
+	}
+
+	var output, stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = &output
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		return "", fmt.Errorf("transfer backend plugin %s %s failed: %w, stderr: %s", op, remotePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return output.String(), nil
+}
+
+// runBounded runs an operation that moves no file data, bounded by the command timeout
+func (b pluginBackend) runBounded(op, remotePath string) (string, error) {
+	ctx, cancel := b.c.commandContext()
+	defer cancel()
+	return b.run(ctx, op, remotePath, nil, nil)
+}
+
+func (b pluginBackend) Put(ctx context.Context, remotePath string, r io.Reader) error {
+	_, err := b.run(ctx, "put", remotePath, r, nil)
+	return err
+}
+
+func (b pluginBackend) Get(ctx context.Context, remotePath string, w io.Writer) error {
+	_, err := b.run(ctx, "get", remotePath, nil, w)
+	return err
+}
+
+func (b pluginBackend) Exists(remotePath string) (bool, error) {
+	output, err := b.runBounded("exists", remotePath)
+	if err != nil {
+		return false, err
+	}
+	exists, err := strconv.ParseBool(strings.TrimSpace(output))
+	if err != nil {
+		return false, fmt.Errorf("transfer backend plugin exists %s printed %q, want true or false", remotePath, strings.TrimSpace(output))
+	}
+	return exists, nil
+}
+
+func (b pluginBackend) Remove(remotePath string) error {
+	_, err := b.runBounded("remove", remotePath)
+	return err
+}
+
+func (b pluginBackend) Size(remotePath string) (int64, error) {
+	output, err := b.runBounded("size", remotePath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+}
+
+func (b pluginBackend) MkdirAll(dir string) error {
+	_, err := b.runBounded("mkdir", dir)
+	return err
+}
@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// expandProxyCommand substitutes the OpenSSH tokens %h (host), %p (port), %r (user) and %%
+func expandProxyCommand(command, user, host, port string) string {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' || i == len(command)-1 {
+			b.WriteByte(command[i])
+			continue
+		}
+		i++
+		switch command[i] {
+		case 'h':
+			b.WriteString(host)
+		case 'p':
+			b.WriteString(port)
+		case 'r':
+			b.WriteString(user)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(command[i])
+		}
+	}
+	return b.String()
+}
+
+// proxyAddr is the address reported for proxied connections: the SSH target, so known_hosts
+// lookups use the real host name
+type proxyAddr string
+
+func (a proxyAddr) Network() string { return "proxy" }
+func (a proxyAddr) String() string  { return string(a) }
+
+// proxyConn is a net.Conn over the stdin and stdout of a ProxyCommand process
+type proxyConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	addr   proxyAddr
+}
+
+// dialProxyCommand starts command with sh -c and returns a connection over its stdio
+// The command's stderr is passed through, as OpenSSH does, so proxy errors are visible
+func dialProxyCommand(ctx context.Context, command, addr string) (net.Conn, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy command stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy command stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start proxy command: %w", err)
+	}
+
+	return &proxyConn{cmd: cmd, stdin: stdin, stdout: stdout, addr: proxyAddr(addr)}, nil
+}
+
+func (c *proxyConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *proxyConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+
+// Close ends the proxy command: stdin is closed first so well-behaved commands can exit
+func (c *proxyConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *proxyConn) LocalAddr() net.Addr  { return proxyAddr("proxy-command") }
+func (c *proxyConn) RemoteAddr() net.Addr { return c.addr }
+
+// Deadlines are not supported on pipes; the handshake timeout is enforced by dialSSH instead
+func (c *proxyConn) SetDeadline(t time.Time) error      { return nil }
+func (c *proxyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *proxyConn) SetWriteDeadline(t time.Time) error { return nil }
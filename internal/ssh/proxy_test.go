@@ -0,0 +1,63 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestExpandProxyCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"no tokens", "nc bastion 22", "nc bastion 22"},
+		{"host and port", "nc %h %p", "nc db.internal 2222"},
+		{"user", "ssh -W %h:%p %r@bastion", "ssh -W db.internal:2222 deploy@bastion"},
+		{"escaped percent", "echo 100%%", "echo 100%"},
+		{"unknown token", "echo %x", "echo %x"},
+		{"trailing percent", "echo %", "echo %"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandProxyCommand(tt.command, "deploy", "db.internal", "2222"); got != tt.want {
+				t.Errorf("expandProxyCommand(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialProxyCommand(t *testing.T) {
+	conn, err := dialProxyCommand(context.Background(), "cat", "db.internal:2222")
+	if err != nil {
+		t.Fatalf("dialProxyCommand() error: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.RemoteAddr().String() != "db.internal:2222" {
+		t.Errorf("RemoteAddr() = %s, want the SSH target", conn.RemoteAddr())
+	}
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Read() = %q, %v; want the echoed data", buf, err)
+	}
+}
+
+func TestDialSSH_ProxyCommandFails(t *testing.T) {
+	config := &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second}
+
+	_, err := dialSSH(context.Background(), "exit 1", "db.internal:22", config)
+	if err == nil || !strings.Contains(err.Error(), "proxy command") {
+		t.Errorf("dialSSH() error = %v, want proxy command failure", err)
+	}
+}
@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultStallRestarts is how many times --transfer-stall-restarts restarts a stalled transfer
+const DefaultStallRestarts = 3
+
+// ResumableBackend is implemented by transfer backends that can continue an interrupted transfer
+// where it stopped. Transfers over other backends restart from the beginning.
+type ResumableBackend interface {
+	// PutAt writes r to remotePath from offset on, keeping its first offset bytes and dropping the rest
+	PutAt(ctx context.Context, remotePath string, offset int64, r io.Reader) error
+	// GetFrom writes the content of remotePath from offset on to w
+	GetFrom(ctx context.Context, remotePath string, offset int64, w io.Writer) error
+}
+
+// ValidateStallRestarts checks a --transfer-stall-restarts count
+func ValidateStallRestarts(restarts int) error {
+	if restarts < 0 {
+		return fmt.Errorf("invalid transfer stall restarts %d: must not be negative", restarts)
+	}
+	return nil
+}
+
+// restartStalled runs transfer, running it again while it fails by stalling, up to the client's
+// stall restarts. Restarts are asked to resume, and reported to the client's OnStallRestart.
+func (c *Client) restartStalled(remotePath string, transfer func(resume bool) error) error {
+	err := transfer(false)
+	for restart := 1; restart <= c.stallRestarts && errors.Is(err, ErrTransferStalled) && c.context().Err() == nil; restart++ {
+		if c.onStallRestart != nil {
+			c.onStallRestart(remotePath, restart, c.stallRestarts)
+		}
+		err = transfer(true)
+	}
+	return err
+}
+
+// resumeOffset returns where an interrupted upload of size bytes to remotePath continues: the size
+// of what was written so far, which writes in order make a prefix of the file. An unreadable or
+// oversized remote file starts over.
+func resumeOffset(backend TransferBackend, remotePath string, size int64) int64 {
+	written, err := backend.Size(remotePath)
+	if err != nil || written < 0 || written > size {
+		return 0
+	}
+	return written
+}
@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateStallRestarts(t *testing.T) {
+	if err := ValidateStallRestarts(DefaultStallRestarts); err != nil {
+		t.Errorf("ValidateStallRestarts(%d) = %v, want nil", DefaultStallRestarts, err)
+	}
+	if err := ValidateStallRestarts(-1); err == nil {
+		t.Error("ValidateStallRestarts(-1) succeeded, want error")
+	}
+}
+
+func TestRestartStalled(t *testing.T) {
+	stalled := fmt.Errorf("failed to transfer file: %w", ErrTransferStalled)
+	failed := errors.New("permission denied")
+
+	tests := []struct {
+		name     string
+		restarts int
+		results  []error // Of each run, the last repeating
+		wantRuns int
+		wantErr  error
+	}{
+		{"success", 2, []error{nil}, 1, nil},
+		{"resumes after a stall", 2, []error{stalled, nil}, 2, nil},
+		{"gives up after the restarts", 2, []error{stalled}, 3, ErrTransferStalled},
+		{"no restarts", 0, []error{stalled}, 1, ErrTransferStalled},
+		{"other failures are not restarted", 2, []error{failed}, 1, failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []string
+			c := &Client{stallRestarts: tt.restarts, onStallRestart: func(remotePath string, restart, restarts int) {
+				reported = append(reported, fmt.Sprintf("%s %d/%d", remotePath, restart, restarts))
+			}}
+
+			var resumed []bool
+			err := c.restartStalled("/tmp/a.tar.gz", func(resume bool) error {
+				resumed = append(resumed, resume)
+				return tt.results[min(len(resumed), len(tt.results))-1]
+			})
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("restartStalled() = %v, want %v", err, tt.wantErr)
+			}
+			if len(resumed) != tt.wantRuns {
+				t.Fatalf("transfer ran %d times, want %d", len(resumed), tt.wantRuns)
+			}
+			for i, resume := range resumed {
+				if resume != (i > 0) {
+					t.Errorf("run %d resume = %v, want %v", i, resume, i > 0)
+				}
+			}
+			if len(reported) != tt.wantRuns-1 || (len(reported) > 0 && reported[0] != fmt.Sprintf("/tmp/a.tar.gz 1/%d", tt.restarts)) {
+				t.Errorf("reported restarts %q", reported)
+			}
+		})
+	}
+}
+
+func TestRestartStalled_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{ctx: ctx, stallRestarts: 3}
+	runs := 0
+	c.restartStalled("/tmp/a.tar.gz", func(resume bool) error {
+		runs++
+		cancel()
+		return ErrTransferStalled
+	})
+	if runs != 1 {
+		t.Errorf("transfer ran %d times after cancellation, want 1", runs)
+	}
+}
+
+func TestSFTPBackend_Resume(t *testing.T) {
+	client, err := pipeClient(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	backend := sftpBackend{client, &Client{}}
+	ctx := context.Background()
+	remotePath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	content := "hello migrated world"
+
+	// An upload interrupted past a partly written packet continues after what arrived
+	if err := backend.Put(ctx, remotePath, strings.NewReader(content[:8]+"XX")); err != nil {
+		t.Fatal(err)
+	}
+	if got := resumeOffset(backend, remotePath, 8); got != 0 {
+		t.Errorf("resumeOffset() of a file larger than the upload = %d, want 0", got)
+	}
+	if err := backend.PutAt(ctx, remotePath, 8, strings.NewReader(content[8:])); err != nil {
+		t.Fatalf("PutAt() error = %v", err)
+	}
+	if data, _ := os.ReadFile(remotePath); string(data) != content {
+		t.Errorf("resumed upload = %q, want %q", data, content)
+	}
+	if got := resumeOffset(backend, remotePath, int64(len(content))); got != int64(len(content)) {
+		t.Errorf("resumeOffset() = %d, want %d", got, len(content))
+	}
+	if got := resumeOffset(backend, remotePath+".missing", 10); got != 0 {
+		t.Errorf("resumeOffset() of a missing file = %d, want 0", got)
+	}
+
+	var buf bytes.Buffer
+	if err := backend.GetFrom(ctx, remotePath, 6, &buf); err != nil {
+		t.Fatalf("GetFrom() error = %v", err)
+	}
+	if buf.String() != content[6:] {
+		t.Errorf("GetFrom() = %q, want %q", buf.String(), content[6:])
+	}
+}
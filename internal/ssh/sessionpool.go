@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// DefaultMaxSessions is how many command sessions a connection runs at once. OpenSSH allows 10
+// sessions per connection by default, which leaves room for SFTP sessions.
+const DefaultMaxSessions = 8
+
+// sessionRetryInterval is how long a session the server refused waits before it is asked for again
+const sessionRetryInterval = 100 * time.Millisecond
+
+// sessionPool bounds the command sessions a connection runs at once, multiplexed over its one TCP
+// connection. Commands beyond the bound wait for a session to end.
+type sessionPool struct {
+	slots chan struct{}
+}
+
+// newSessionPool returns a pool of size sessions, DefaultMaxSessions when size is 0
+func newSessionPool(size int) *sessionPool {
+	if size <= 0 {
+		size = DefaultMaxSessions
+	}
+	return &sessionPool{slots: make(chan struct{}, size)}
+}
+
+// acquire waits for a free session until ctx ends
+func (p *sessionPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// release frees a session taken by acquire
+func (p *sessionPool) release() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+// inUse returns how many sessions are taken
+func (p *sessionPool) inUse() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.slots)
+}
+
+// ValidateMaxSessions checks a --max-sessions count, 0 meaning DefaultMaxSessions
+func ValidateMaxSessions(sessions int) error {
+	if sessions < 0 {
+		return fmt.Errorf("invalid max sessions %d: must not be negative", sessions)
+	}
+	return nil
+}
+
+// newSession opens a session from the pool, requesting agent forwarding when it is enabled.
+// When the pool is full, or the server refuses the session because its own MaxSessions is
+// reached, it waits for another session to end until ctx does. The returned function closes
+// the session and gives it back to the pool.
+func (c *Client) newSession(ctx context.Context) (*ssh.Session, func(), error) {
+	for {
+		if err := c.sessions.acquire(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to create session: %w", err)
+		}
+		session, err := c.client.NewSession()
+		if err == nil {
+			if c.forward {
+				if err := agent.RequestAgentForwarding(session); err != nil {
+					session.Close()
+					c.sessions.release()
+					return nil, nil, fmt.Errorf("failed to request agent forwarding: %w", err)
+				}
+			}
+			return session, func() {
+				session.Close()
+				c.sessions.release()
+			}, nil
+		}
+		c.sessions.release()
+
+		// Without another session of ours to end, waiting would not help
+		var openErr *ssh.OpenChannelError
+		if !errors.As(err, &openErr) || openErr.Reason != ssh.Prohibited || c.sessions.inUse() == 0 {
+			return nil, nil, fmt.Errorf("failed to create session: %w", err)
+		}
+		select {
+		case <-time.After(sessionRetryInterval):
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("failed to create session: %w", context.Cause(ctx))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionPool(t *testing.T) {
+	pool := newSessionPool(2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := pool.acquire(ctx); err != nil {
+			t.Fatalf("acquire() %d error = %v", i, err)
+		}
+	}
+	if pool.inUse() != 2 {
+		t.Errorf("inUse() = %d, want 2", pool.inUse())
+	}
+
+	// A full pool queues until a session is released
+	acquired := make(chan error, 1)
+	go func() { acquired <- pool.acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquire() on a full pool returned before a release")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("queued acquire() error = %v", err)
+	}
+
+	// The wait ends with the context
+	cancelled, cancel := context.WithCancelCause(ctx)
+	errStop := errors.New("stopped")
+	cancel(errStop)
+	if err := pool.acquire(cancelled); !errors.Is(err, errStop) {
+		t.Errorf("acquire() with a cancelled context = %v, want %v", err, errStop)
+	}
+}
+
+func TestSessionPool_Default(t *testing.T) {
+	if size := cap(newSessionPool(0).slots); size != DefaultMaxSessions {
+		t.Errorf("newSessionPool(0) size = %d, want %d", size, DefaultMaxSessions)
+	}
+	// Clients built without a pool are not bounded
+	var pool *sessionPool
+	if err := pool.acquire(context.Background()); err != nil {
+		t.Errorf("nil pool acquire() = %v", err)
+	}
+	pool.release()
+}
@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpSession is the SFTP client shared by the transfers of a connection. It is opened on first
+// use and again after it was closed, so one lost or aborted session does not fail later transfers.
+type sftpSession struct {
+	mu     sync.Mutex
+	client *sftp.Client
+	closed bool // The connection was closed, nothing is opened anymore
+}
+
+// get returns the shared client, opening one with open when there is none
+func (s *sftpSession) get(open func() (*sftp.Client, error)) (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("failed to create SFTP client: connection closed")
+	}
+	if s.client != nil {
+		return s.client, nil
+	}
+	client, err := open()
+	if err != nil {
+		return nil, err
+	}
+	s.client = client
+	// A session the server or the connection ends is forgotten as well
+	go func() {
+		client.Wait()
+		s.drop(client)
+	}()
+	return client, nil
+}
+
+// drop closes client and, when it is the shared one, forgets it so the next transfer opens a new one
+func (s *sftpSession) drop(client *sftp.Client) {
+	s.mu.Lock()
+	if s.client == client {
+		s.client = nil
+	}
+	s.mu.Unlock()
+	client.Close()
+}
+
+// close closes the shared client for good
+func (s *sftpSession) close() {
+	s.mu.Lock()
+	client := s.client
+	s.client, s.closed = nil, true
+	s.mu.Unlock()
+	if client != nil {
+		client.Close()
+	}
+}
+
+// dialSFTP opens a new SFTP session over the connection
+func (c *Client) dialSFTP() (*sftp.Client, error) {
+	sftpClient, err := sftp.NewClient(c.client, sftp.MaxPacketUnchecked(c.transferBufferSize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	return sftpClient, nil
+}
+
+// openSFTP returns the connection's shared SFTP session, which is closed when ctx ends to abort
+// any transfer in progress. The returned function stops watching ctx and leaves the session open.
+func (c *Client) openSFTP(ctx context.Context) (*sftp.Client, func(), error) {
+	if c.sftp == nil {
+		return c.newSFTP(ctx)
+	}
+	sftpClient, err := c.sftp.get(c.dialSFTP)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.dropSFTP(sftpClient) })
+	return sftpClient, func() { stop() }, nil
+}
+
+// newSFTP opens an SFTP session of its own that is closed when ctx ends, for transfers running
+// alongside the shared session. The returned function closes the session.
+func (c *Client) newSFTP(ctx context.Context) (*sftp.Client, func(), error) {
+	sftpClient, err := c.dialSFTP()
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { sftpClient.Close() })
+	return sftpClient, func() {
+		stop()
+		sftpClient.Close()
+	}, nil
+}
+
+// dropSFTP closes an SFTP session opened by openSFTP, so the shared session is opened again next time
+func (c *Client) dropSFTP(sftpClient *sftp.Client) {
+	if c.sftp == nil {
+		sftpClient.Close()
+		return
+	}
+	c.sftp.drop(sftpClient)
+}
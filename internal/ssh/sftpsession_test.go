@@ -0,0 +1,53 @@
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+func TestSFTPSession(t *testing.T) {
+	opened := 0
+	open := func() (*sftp.Client, error) {
+		opened++
+		return pipeClient(t)
+	}
+	var s sftpSession
+
+	first, err := s.get(open)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if again, _ := s.get(open); again != first || opened != 1 {
+		t.Fatalf("second get() opened %d sessions, want the first one reused", opened)
+	}
+
+	s.drop(first)
+	second, err := s.get(open)
+	if err != nil || second == first || opened != 2 {
+		t.Fatalf("get() after drop = %v, opened %d, want a new session", err, opened)
+	}
+
+	// A session closed behind the cache's back, as by a lost connection, is replaced as well
+	second.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		third, err := s.get(open)
+		if err != nil {
+			t.Fatalf("get() error = %v", err)
+		}
+		if third != second {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("closed session still shared")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.close()
+	if _, err := s.get(open); err == nil {
+		t.Error("get() after close succeeded, want error")
+	}
+}
@@ -0,0 +1,266 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIncludeDepth bounds nested Include directives, as OpenSSH does
+const maxIncludeDepth = 16
+
+// ConfigHost is a Host entry of an OpenSSH client config file (~/.ssh/config)
+type ConfigHost struct {
+	Alias         string
+	HostName      string
+	User          string
+	Port          string
+	IdentityFiles []string
+}
+
+// Remote returns the host in the user@host[:port] format of --remote, falling back to the
+// alias for a missing HostName and to the local user for a missing User
+func (h ConfigHost) Remote() string {
+	user := h.User
+	if user == "" {
+		user = localUser()
+	}
+	host := h.HostName
+	if host == "" {
+		host = h.Alias
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if h.Port != "" && h.Port != "22" {
+		host += ":" + h.Port
+	}
+	return user + "@" + host
+}
+
+// configBlock is a Host (or Match) section and the settings given in it
+type configBlock struct {
+	patterns []string // Empty for the settings before the first Host line
+	match    bool     // Match sections are not evaluated and never apply
+	settings [][2]string
+}
+
+// DefaultConfigPath returns the user's OpenSSH client config file (~/.ssh/config)
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ssh", "config")
+	}
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// LoadConfigHosts returns the named hosts of the OpenSSH client config file at path, sorted by alias.
+// Wildcard and negated patterns are not hosts of their own, but their settings apply to the hosts
+// they match. As in ssh, the first value of each setting wins, Include is followed and Match
+// sections are skipped. A missing file yields no hosts.
+func LoadConfigHosts(configPath string) ([]ConfigHost, error) {
+	var blocks []*configBlock
+	if err := readConfigFile(configPath, filepath.Dir(configPath), &blocks, 0); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return configHosts(blocks), nil
+}
+
+// readConfigFile parses the file at configPath, appending its sections to blocks
+// Relative Include paths are resolved against dir
+func readConfigFile(configPath, dir string, blocks *[]*configBlock, depth int) error {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if len(*blocks) == 0 {
+		*blocks = append(*blocks, &configBlock{})
+	}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		keyword, args := parseConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+		switch keyword {
+		case "host":
+			*blocks = append(*blocks, &configBlock{patterns: args})
+		case "match":
+			*blocks = append(*blocks, &configBlock{match: true})
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: too many nested Include directives", configPath, line)
+			}
+			for _, pattern := range args {
+				if err := includeConfigFiles(pattern, dir, blocks, depth+1); err != nil {
+					return err
+				}
+			}
+		default:
+			if len(args) > 0 {
+				current := (*blocks)[len(*blocks)-1]
+				current.settings = append(current.settings, [2]string{keyword, args[0]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// includeConfigFiles reads every file matching an Include pattern, in lexical order
+func includeConfigFiles(pattern, dir string, blocks *[]*configBlock, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid Include pattern %s: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	for _, match := range matches {
+		if err := readConfigFile(match, dir, blocks, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseConfigLine splits a config line into its lowercased keyword and arguments
+// Arguments may be quoted, and the keyword may be separated from them by '='
+func parseConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end == -1 {
+		return strings.ToLower(line), nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimSpace(line[end:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing == -1 {
+				arg, rest = rest[1:], ""
+			} else {
+				arg, rest = rest[1:closing+1], rest[closing+2:]
+			}
+		} else if space := strings.IndexAny(rest, " \t"); space != -1 {
+			arg, rest = rest[:space], rest[space:]
+		} else {
+			arg, rest = rest, ""
+		}
+		args = append(args, arg)
+		rest = strings.TrimSpace(rest)
+	}
+	return keyword, args
+}
+
+// configHosts resolves the settings of every named host
+func configHosts(blocks []*configBlock) []ConfigHost {
+	seen := make(map[string]bool)
+	var hosts []ConfigHost
+	for _, block := range blocks {
+		for _, pattern := range block.patterns {
+			if seen[pattern] || strings.ContainsAny(pattern, "*?!") {
+				continue
+			}
+			seen[pattern] = true
+			hosts = append(hosts, resolveHost(pattern, blocks))
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Alias < hosts[j].Alias })
+	return hosts
+}
+
+// resolveHost collects the settings of alias from every section that applies to it
+func resolveHost(alias string, blocks []*configBlock) ConfigHost {
+	host := ConfigHost{Alias: alias}
+	for _, block := range blocks {
+		if !block.appliesTo(alias) {
+			continue
+		}
+		for _, setting := range block.settings {
+			value := setting[1]
+			switch setting[0] {
+			case "hostname":
+				if host.HostName == "" {
+					host.HostName = strings.ReplaceAll(value, "%h", alias)
+				}
+			case "user":
+				if host.User == "" {
+					host.User = value
+				}
+			case "port":
+				if host.Port == "" {
+					host.Port = value
+				}
+			case "identityfile":
+				// Unlike other settings, every IdentityFile is tried in order
+				host.IdentityFiles = append(host.IdentityFiles, expandHome(value))
+			}
+		}
+	}
+	return host
+}
+
+// appliesTo reports whether the section's settings apply to alias
+// A matching negated pattern excludes the host even if another pattern matches it
+func (b *configBlock) appliesTo(alias string) bool {
+	if b.match {
+		return false
+	}
+	if len(b.patterns) == 0 {
+		return true
+	}
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "!"), alias); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(p string) string {
+	if !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[2:])
+}
+
+// localUser returns the name of the user running the tool
+func localUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return os.Getenv("USERNAME") // Windows
+}
@@ -0,0 +1,124 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigLine(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantKeyword string
+		wantArgs    []string
+	}{
+		{"", "", nil},
+		{"  # comment", "", nil},
+		{"Host web db", "host", []string{"web", "db"}},
+		{"HostName=10.0.0.5", "hostname", []string{"10.0.0.5"}},
+		{"Port = 2222", "port", []string{"2222"}},
+		{`IdentityFile "~/.ssh/my key"`, "identityfile", []string{"~/.ssh/my key"}},
+		{"\tUser\tdeploy", "user", []string{"deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			keyword, args := parseConfigLine(tt.line)
+			if keyword != tt.wantKeyword || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("parseConfigLine(%q) = %q, %q, want %q, %q", tt.line, keyword, args, tt.wantKeyword, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestLoadConfigHosts(t *testing.T) {
+	dir := t.TempDir()
+	config := `User fallback
+
+Host prod-db
+    HostName db.example.com
+    Port 2222
+    IdentityFile /keys/db
+
+Host staging *.internal
+    HostName %h.example.net
+    User deploy
+
+Match host prod-db
+    User ignored
+
+Include conf.d/*.conf
+
+Host * !staging
+    User admin
+    IdentityFile /keys/default
+`
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "conf.d"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	included := "Host v6\n    HostName 2001:db8::1\n"
+	if err := os.WriteFile(filepath.Join(dir, "conf.d", "v6.conf"), []byte(included), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := LoadConfigHosts(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigHost{
+		{Alias: "prod-db", HostName: "db.example.com", User: "fallback", Port: "2222", IdentityFiles: []string{"/keys/db", "/keys/default"}},
+		{Alias: "staging", HostName: "staging.example.net", User: "fallback"},
+		{Alias: "v6", HostName: "2001:db8::1", User: "fallback", IdentityFiles: []string{"/keys/default"}},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("LoadConfigHosts() =\n%+v\nwant\n%+v", hosts, want)
+	}
+}
+
+func TestLoadConfigHosts_Missing(t *testing.T) {
+	hosts, err := LoadConfigHosts(filepath.Join(t.TempDir(), "config"))
+	if err != nil || hosts != nil {
+		t.Errorf("expected no hosts and no error for a missing file, got %v, %v", hosts, err)
+	}
+}
+
+func TestLoadConfigHosts_IncludeLoop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("Include config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigHosts(path); err == nil {
+		t.Error("expected an error for recursive includes")
+	}
+}
+
+func TestConfigHostRemote(t *testing.T) {
+	t.Setenv("USER", "me")
+	tests := []struct {
+		name string
+		host ConfigHost
+		want string
+	}{
+		{"alias only", ConfigHost{Alias: "web"}, "me@web"},
+		{"full entry", ConfigHost{Alias: "db", HostName: "db.example.com", User: "deploy", Port: "2222"}, "deploy@db.example.com:2222"},
+		{"default port", ConfigHost{Alias: "db", HostName: "10.0.0.5", User: "deploy", Port: "22"}, "deploy@10.0.0.5"},
+		{"IPv6", ConfigHost{Alias: "v6", HostName: "2001:db8::1", User: "root", Port: "2222"}, "root@[2001:db8::1]:2222"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.host.Remote(); got != tt.want {
+				t.Errorf("Remote() = %q, want %q", got, tt.want)
+			}
+			if _, _, _, err := parseHostPort(tt.host.Remote()); err != nil {
+				t.Errorf("Remote() = %q does not parse as --remote: %v", tt.host.Remote(), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Signatures use the OpenSSH SSHSIG format (PROTOCOL.sshsig), so they can also be
+// checked with: ssh-keygen -Y verify -n <namespace> -f allowed_signers -I <id> -s file.sig
+const (
+	sshsigMagic   = "SSHSIG"
+	sshsigVersion = 1
+	sshsigHash    = "sha512"
+	sshsigPEMType = "SSH SIGNATURE"
+)
+
+// sshsigBlob is the wire format of a signature, after the magic preamble
+type sshsigBlob struct {
+	Version   uint32
+	PublicKey []byte
+	Namespace string
+	Reserved  string
+	Hash      string
+	Signature []byte
+}
+
+// sshsigSignedData is the wire format of what the key actually signs, after the magic preamble
+type sshsigSignedData struct {
+	Namespace string
+	Reserved  string
+	Hash      string
+	Digest    []byte
+}
+
+// SigningKey signs and verifies files with an SSH key
+type SigningKey struct {
+	signer ssh.Signer
+}
+
+// LoadSigningKey loads the private key at path, or the ssh-agent key matching path + ".pub"
+// for passphrase-protected and FIDO2 keys
+func LoadSigningKey(path string) (*SigningKey, error) {
+	signer, err := loadPrivateKey(path)
+	if err == nil {
+		return &SigningKey{signer: signer}, nil
+	}
+	if agentClient := connectSSHAgent(); agentClient != nil {
+		if agentSigners, agentErr := agentClient.Signers(); agentErr == nil {
+			if signer := agentSignerFor(path, agentSigners); signer != nil {
+				return &SigningKey{signer: signer}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to load signing key %s: %w", path, err)
+}
+
+// Fingerprint returns the SHA256 fingerprint of the public key
+func (k *SigningKey) Fingerprint() string {
+	return ssh.FingerprintSHA256(k.signer.PublicKey())
+}
+
+// Sign returns an armored signature of message in namespace
+func (k *SigningKey) Sign(namespace string, message []byte) ([]byte, error) {
+	signed := sshsigMessage(namespace, message)
+
+	var signature *ssh.Signature
+	var err error
+	// RSA keys must sign with SHA-2, never the legacy ssh-rsa (SHA-1) algorithm
+	if algorithmSigner, ok := k.signer.(ssh.AlgorithmSigner); ok && k.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = k.signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	blob := ssh.Marshal(sshsigBlob{
+		Version:   sshsigVersion,
+		PublicKey: k.signer.PublicKey().Marshal(),
+		Namespace: namespace,
+		Hash:      sshsigHash,
+		Signature: ssh.Marshal(signature),
+	})
+	return pem.EncodeToMemory(&pem.Block{Type: sshsigPEMType, Bytes: append([]byte(sshsigMagic), blob...)}), nil
+}
+
+// Verify checks an armored signature of message in namespace against the key's public half
+func (k *SigningKey) Verify(namespace string, message, armored []byte) error {
+	return VerifySignature(k.signer.PublicKey(), namespace, message, armored)
+}
+
+// LoadPublicKey reads a public key in authorized_keys format, such as a .pub file
+func LoadPublicKey(path string) (ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// VerifySignature checks an armored SSHSIG signature of message in namespace made by publicKey
+func VerifySignature(publicKey ssh.PublicKey, namespace string, message, armored []byte) error {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != sshsigPEMType {
+		return errors.New("not an SSH signature")
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(sshsigMagic)) {
+		return errors.New("not an SSH signature")
+	}
+
+	var blob sshsigBlob
+	if err := ssh.Unmarshal(block.Bytes[len(sshsigMagic):], &blob); err != nil {
+		return fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if blob.Version != sshsigVersion {
+		return fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+	if blob.Namespace != namespace {
+		return fmt.Errorf("signature is for namespace '%s', not '%s'", blob.Namespace, namespace)
+	}
+	if blob.Hash != sshsigHash {
+		return fmt.Errorf("unsupported signature hash '%s'", blob.Hash)
+	}
+	if !bytes.Equal(blob.PublicKey, publicKey.Marshal()) {
+		return errors.New("signature was made by a different key")
+	}
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &signature); err != nil {
+		return fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if err := publicKey.Verify(sshsigMessage(namespace, message), &signature); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	return nil
+}
+
+// sshsigMessage returns the data signed for message in namespace
+func sshsigMessage(namespace string, message []byte) []byte {
+	digest := sha512.Sum512(message)
+	return append([]byte(sshsigMagic), ssh.Marshal(sshsigSignedData{
+		Namespace: namespace,
+		Hash:      sshsigHash,
+		Digest:    digest[:],
+	})...)
+}
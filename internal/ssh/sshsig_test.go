@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testSigningKey(t *testing.T) *SigningKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SigningKey{signer: signer}
+}
+
+func TestSigningKey_SignVerify(t *testing.T) {
+	key := testSigningKey(t)
+	message := []byte(`{"archives":[]}`)
+
+	armored, err := key.Sign("volume-migrator", message)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if !strings.HasPrefix(string(armored), "-----BEGIN SSH SIGNATURE-----\n") {
+		t.Errorf("signature is not armored: %s", armored)
+	}
+	if err := key.Verify("volume-migrator", message, armored); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       *SigningKey
+		namespace string
+		message   []byte
+		armored   []byte
+		wantErr   string
+	}{
+		{"tampered message", key, "volume-migrator", []byte(`{"archives":[{}]}`), armored, "verification failed"},
+		{"other namespace", key, "file", message, armored, "namespace"},
+		{"other key", testSigningKey(t), "volume-migrator", message, armored, "different key"},
+		{"not a signature", key, "volume-migrator", message, []byte("garbage"), "not an SSH signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.key.Verify(tt.namespace, tt.message, tt.armored)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
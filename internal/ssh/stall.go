@@ -0,0 +1,115 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stallWatch cancels its context when the readers it wraps deliver no data for the stall timeout.
+// Watching ends at the first EOF, so a command may keep running after its input is consumed.
+type stallWatch struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timeout time.Duration
+	last    atomic.Int64 // UnixNano of the last read returning data
+	done    chan struct{}
+	once    sync.Once
+}
+
+// watchStalls starts watching for stalls with the client's transfer stall timeout.
+// Without one the watch only carries the client's context.
+func (c *Client) watchStalls() *stallWatch {
+	ctx, cancel := context.WithCancelCause(c.context())
+	w := &stallWatch{ctx: ctx, cancel: cancel, timeout: c.stallTimeout, done: make(chan struct{})}
+	if w.timeout <= 0 {
+		w.finish()
+		return w
+	}
+
+	w.touch(time.Now())
+	go w.run()
+	return w
+}
+
+// run cancels the context once no data was read for the timeout
+func (w *stallWatch) run() {
+	ticker := time.NewTicker(stallCheckInterval(w.timeout))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if w.stalled(now) {
+				w.cancel(fmt.Errorf("%w: no data moved for %s (--transfer-stall-timeout)", ErrTransferStalled, w.timeout))
+				return
+			}
+		}
+	}
+}
+
+// ErrTransferStalled is the cause of a transfer aborted by the stall timeout
+var ErrTransferStalled = errors.New("transfer stalled")
+
+// stallCheckInterval is how often a watch with timeout checks for progress
+func stallCheckInterval(timeout time.Duration) time.Duration {
+	if interval := timeout / 4; interval > 0 {
+		return interval
+	}
+	return timeout
+}
+
+// touch records progress at now
+func (w *stallWatch) touch(now time.Time) {
+	w.last.Store(now.UnixNano())
+}
+
+// stalled reports whether no data was read for the timeout before now
+func (w *stallWatch) stalled(now time.Time) bool {
+	return now.Sub(time.Unix(0, w.last.Load())) >= w.timeout
+}
+
+// finish stops watching without cancelling the context
+func (w *stallWatch) finish() {
+	w.once.Do(func() { close(w.done) })
+}
+
+// stop stops watching and releases the context
+func (w *stallWatch) stop() {
+	w.finish()
+	w.cancel(nil)
+}
+
+// reader wraps r so reads count as progress and EOF ends the watch
+func (w *stallWatch) reader(r io.Reader) io.Reader {
+	if w.timeout <= 0 {
+		return r
+	}
+	return &stallReader{Reader: r, watch: w}
+}
+
+// stallReader reports the reads of an io.Reader to a stallWatch
+type stallReader struct {
+	io.Reader
+	watch *stallWatch
+}
+
+// Read implements io.Reader, recording progress
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.watch.touch(time.Now())
+	}
+	if err == io.EOF {
+		r.watch.finish()
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchStalls(t *testing.T) {
+	t.Run("stalled reader cancels", func(t *testing.T) {
+		client := &Client{stallTimeout: 20 * time.Millisecond}
+		watch := client.watchStalls()
+		defer watch.stop()
+
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		watch.reader(pr) // nothing is ever written
+
+		select {
+		case <-watch.ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("expected the watch to cancel a stalled transfer")
+		}
+		if !errors.Is(context.Cause(watch.ctx), ErrTransferStalled) {
+			t.Errorf("cause = %v, want ErrTransferStalled", context.Cause(watch.ctx))
+		}
+	})
+
+	t.Run("EOF ends the watch", func(t *testing.T) {
+		client := &Client{stallTimeout: 20 * time.Millisecond}
+		watch := client.watchStalls()
+		defer watch.stop()
+
+		if _, err := io.ReadAll(watch.reader(strings.NewReader("data"))); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if err := watch.ctx.Err(); err != nil {
+			t.Errorf("expected a finished transfer not to be cancelled, got: %v", err)
+		}
+	})
+
+	t.Run("no timeout", func(t *testing.T) {
+		watch := (&Client{}).watchStalls()
+		defer watch.stop()
+
+		r := strings.NewReader("data")
+		if watch.reader(r) != io.Reader(r) {
+			t.Error("expected the reader to be returned unwrapped without a stall timeout")
+		}
+	})
+}
+
+func TestCommandContext(t *testing.T) {
+	client := &Client{commandTimeout: time.Millisecond}
+	ctx, cancel := client.commandContext()
+	defer cancel()
+
+	<-ctx.Done()
+	if cause := context.Cause(ctx); cause == nil || !strings.Contains(cause.Error(), "--command-timeout") {
+		t.Errorf("cause = %v, want a --command-timeout error", cause)
+	}
+}
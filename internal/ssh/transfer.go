@@ -1,19 +1,45 @@
 package ssh
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/pkg/sftp"
 	"github.com/schollz/progressbar/v3"
+	"volume-migrator/internal/shell"
+	"volume-migrator/internal/utils"
 )
 
+// rateInterval is how often the transfer rate shown in the progress bar is refreshed
+const rateInterval = time.Second
+
 // ProgressReader wraps an io.Reader with a progress bar
+// It also measures the transfer rate and shows it in the bar description
 type ProgressReader struct {
 	io.Reader
-	bar *progressbar.ProgressBar
+	bar         *progressbar.ProgressBar
+	description string
+
+	start       time.Time // First read
+	windowStart time.Time // Start of the current rate window
+	total       int64     // Bytes read since start
+	window      int64     // Bytes read since windowStart
+	current     float64   // Rate over the last complete window, bytes/second
+}
+
+// newProgressReader creates a ProgressReader reporting to bar, whose description is description
+func newProgressReader(r io.Reader, bar *progressbar.ProgressBar, description string) *ProgressReader {
+	return &ProgressReader{Reader: r, bar: bar, description: description}
 }
 
 // Read implements io.Reader interface with progress tracking
@@ -21,18 +47,97 @@ func (pr *ProgressReader) Read(p []byte) (int, error) {
 	n, err := pr.Reader.Read(p)
 	if err == nil || err == io.EOF {
 		pr.bar.Add(n)
+		pr.record(n, time.Now())
 	}
 	return n, err
 }
 
-// TransferFile uploads a file to the remote host via SFTP with progress tracking
-func (c *Client) TransferFile(localPath, remotePath string, showProgress bool) error {
-	// Open SFTP session
-	sftpClient, err := sftp.NewClient(c.client)
+// record accounts for n bytes read at now, refreshing the rates once per rateInterval
+func (pr *ProgressReader) record(n int, now time.Time) {
+	if pr.start.IsZero() {
+		pr.start, pr.windowStart = now, now
+	}
+	pr.total += int64(n)
+	pr.window += int64(n)
+
+	elapsed := now.Sub(pr.windowStart)
+	if elapsed < rateInterval {
+		return
+	}
+	pr.current = float64(pr.window) / elapsed.Seconds()
+	pr.window, pr.windowStart = 0, now
+	pr.bar.Describe(fmt.Sprintf("%s (%s, avg %s)", pr.description, FormatRate(pr.current), FormatRate(pr.averageRate(now))))
+}
+
+// averageRate returns the mean rate in bytes/second from the first read until now
+func (pr *ProgressReader) averageRate(now time.Time) float64 {
+	elapsed := now.Sub(pr.start).Seconds()
+	if pr.start.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return float64(pr.total) / elapsed
+}
+
+// FormatRate formats a transfer rate in bytes/second as MB/s (1 MB = 1024 * 1024 bytes)
+func FormatRate(bytesPerSecond float64) string {
+	return fmt.Sprintf("%.1f MB/s", bytesPerSecond/(1024*1024))
+}
+
+// Limits of --buffer-size. The default is the SFTP packet size every server supports; the maximum
+// is the largest data packet OpenSSH's sftp-server accepts.
+const (
+	DefaultBufferSize = 32 * 1024
+	MinBufferSize     = 1024
+	MaxBufferSize     = 255 * 1024
+)
+
+// ValidateBufferSize checks a --buffer-size in bytes
+func ValidateBufferSize(size int64) error {
+	if size < MinBufferSize || size > MaxBufferSize {
+		return fmt.Errorf("invalid buffer size %d: must be between 1KB and 255KB", size)
+	}
+	return nil
+}
+
+// transferBufferSize returns the size of each copy and SFTP packet of transfers
+func (c *Client) transferBufferSize() int {
+	if c.bufferSize <= 0 {
+		return DefaultBufferSize
+	}
+	return c.bufferSize
+}
+
+// transferError reports a failed copy by the cause of ctx ending, such as cancellation or a stall, when it did
+func transferError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// removePartial removes a partially written remote file after a failed upload. It runs even when
+// the upload failed because the context was cancelled, so Ctrl+C leaves no truncated files behind.
+// Removal is best effort: it cannot succeed when the connection itself was lost.
+func (c *Client) removePartial(remotePath string) {
+	detached := c.WithoutCancel()
+	backend, closeBackend, err := detached.openBackend(detached.context())
 	if err != nil {
-		return fmt.Errorf("failed to create SFTP client: %w", err)
+		return
+	}
+	defer closeBackend()
+	backend.Remove(remotePath)
+}
+
+// TransferFile uploads a file to the remote host over the transfer backend with progress tracking.
+// Large files are split over parallel SFTP sessions when the client has several streams.
+func (c *Client) TransferFile(localPath, remotePath string, showProgress bool) error {
+	if c.streams > 1 && c.TransferBackend() == TransferBackendSFTP {
+		if stat, err := os.Stat(localPath); err == nil {
+			if streams := parallelStreamCount(stat.Size(), c.streams); streams > 1 {
+				return c.transferFileParallel(localPath, remotePath, stat.Size(), streams, showProgress)
+			}
+		}
 	}
-	defer sftpClient.Close()
 
 	// Open local file
 	srcFile, err := os.Open(localPath)
@@ -47,56 +152,297 @@ func (c *Client) TransferFile(localPath, remotePath string, showProgress bool) e
 		return fmt.Errorf("failed to stat local file: %w", err)
 	}
 
+	// Create progress bar if requested; restarts set it back to where they resume
+	var progress *ProgressReader
+	if showProgress {
+		description := fmt.Sprintf("Uploading %s", filepath.Base(localPath))
+		bar := utils.NewProgressBar(stat.Size(), description)
+		progress = newProgressReader(nil, bar, description)
+		defer bar.Finish()
+	}
+
+	err = c.restartStalled(remotePath, func(resume bool) error {
+		return c.putFile(srcFile, remotePath, stat.Size(), progress, resume)
+	})
+	if err != nil {
+		c.removePartial(remotePath)
+		return err
+	}
+	return nil
+}
+
+// putFile uploads size bytes of src to remotePath over a newly opened backend. When resume is set
+// and the backend is resumable, it continues after what the remote file already holds, and the
+// whole file is checked with sha256 afterwards.
+func (c *Client) putFile(src *os.File, remotePath string, size int64, progress *ProgressReader, resume bool) error {
+	watch := c.watchStalls()
+	defer watch.stop()
+
+	backend, closeBackend, err := c.openBackend(watch.ctx)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
 	// Ensure remote directory exists
-	remoteDir := filepath.Dir(remotePath)
-	if err := sftpClient.MkdirAll(remoteDir); err != nil {
+	remoteDir := path.Dir(remotePath)
+	if err := backend.MkdirAll(remoteDir); err != nil {
 		return fmt.Errorf("failed to create remote directory: %w", err)
 	}
 
-	// Create remote file
-	dstFile, err := sftpClient.Create(remotePath)
+	var offset int64
+	resumable, canResume := backend.(ResumableBackend)
+	if resume && canResume {
+		offset = resumeOffset(backend, remotePath, size)
+	}
+
+	var reader io.Reader = io.NewSectionReader(src, offset, size-offset)
+	if progress != nil {
+		progress.bar.Set64(offset)
+		progress.Reader = reader
+		reader = progress
+	}
+
+	// Copy file
+	if offset > 0 {
+		err = resumable.PutAt(watch.ctx, remotePath, offset, watch.reader(reader))
+	} else {
+		err = backend.Put(watch.ctx, remotePath, watch.reader(reader))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create remote file: %w", err)
+		return fmt.Errorf("failed to transfer file: %w", transferError(watch.ctx, err))
+	}
+
+	if offset > 0 {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, io.NewSectionReader(src, 0, size)); err != nil {
+			return fmt.Errorf("failed to read local file: %w", err)
+		}
+		return c.verifyChecksum(remotePath, hex.EncodeToString(hash.Sum(nil)), "resumed upload")
+	}
+	return nil
+}
+
+// StreamFileToDocker runs a Docker command on the remote host with the local file as its stdin,
+// so the content is consumed as it arrives instead of being stored on the remote disk
+func (c *Client) StreamFileToDocker(localPath string, showProgress bool, args ...string) error {
+	srcFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer srcFile.Close()
+
+	stat, err := srcFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
 	}
-	defer dstFile.Close()
 
-	// Create progress bar if requested
 	var reader io.Reader = srcFile
 	if showProgress {
-		bar := progressbar.DefaultBytes(
-			stat.Size(),
-			fmt.Sprintf("Uploading %s", filepath.Base(localPath)),
-		)
-		reader = &ProgressReader{Reader: srcFile, bar: bar}
+		description := fmt.Sprintf("Streaming %s", filepath.Base(localPath))
+		bar := utils.NewProgressBar(stat.Size(), description)
+		reader = newProgressReader(srcFile, bar, description)
 		defer bar.Finish()
 	}
 
-	// Copy file
-	if _, err := io.Copy(dstFile, reader); err != nil {
-		return fmt.Errorf("failed to transfer file: %w", err)
+	if _, err := c.RunDockerCommandWithInput(reader, args...); err != nil {
+		return fmt.Errorf("failed to stream file: %w", err)
 	}
-
 	return nil
 }
 
-// DownloadFile downloads a file from the remote host via SFTP with progress tracking
-func (c *Client) DownloadFile(remotePath, localPath string, showProgress bool) error {
-	// Open SFTP session
-	sftpClient, err := sftp.NewClient(c.client)
+// chunkAttempts is the number of times a single chunk upload is tried before giving up
+const chunkAttempts = 3
+
+// TransferFileChunked uploads a file in parts of at most chunkSize bytes, then reassembles
+// and verifies it (sha256) on the remote host. Parts that already exist on the remote with
+// the expected size are skipped, so an interrupted transfer resumes at chunk granularity.
+// The checksum is computed from the chunks as they are uploaded, so the file is read only once.
+// Files no larger than chunkSize (or chunkSize <= 0) are uploaded with TransferFile.
+func (c *Client) TransferFileChunked(localPath, remotePath string, chunkSize int64, showProgress bool) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+	if chunkSize <= 0 || stat.Size() <= chunkSize {
+		return c.TransferFile(localPath, remotePath, showProgress)
+	}
+
+	backend, closeBackend, err := c.openBackend(c.context())
 	if err != nil {
-		return fmt.Errorf("failed to create SFTP client: %w", err)
+		return err
 	}
-	defer sftpClient.Close()
+	defer func() { closeBackend() }()
 
-	// Open remote file
-	srcFile, err := sftpClient.Open(remotePath)
+	srcFile, err := os.Open(localPath)
 	if err != nil {
-		return fmt.Errorf("failed to open remote file: %w", err)
+		return fmt.Errorf("failed to open local file: %w", err)
 	}
 	defer srcFile.Close()
 
-	// Get file info for size
-	stat, err := srcFile.Stat()
+	// Ensure remote directory exists
+	if err := backend.MkdirAll(path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+
+	// One reader is shared by every chunk so the rate covers the whole file
+	var progress *ProgressReader
+	if showProgress {
+		description := fmt.Sprintf("Uploading %s", filepath.Base(localPath))
+		bar := utils.NewProgressBar(stat.Size(), description)
+		progress = newProgressReader(nil, bar, description)
+		defer bar.Finish()
+	}
+
+	hash := sha256.New()
+	count := chunkCount(stat.Size(), chunkSize)
+	parts := make([]string, count)
+	stalls := 0
+	for i := 0; i < count; i++ {
+		offset := int64(i) * chunkSize
+		length := chunkSize
+		if offset+length > stat.Size() {
+			length = stat.Size() - offset
+		}
+		parts[i] = chunkPath(remotePath, i)
+
+		// Retry individual chunks so a dropped connection doesn't restart the whole file
+		state := hashState(hash)
+		for attempt := 1; ; attempt++ {
+			if progress != nil {
+				progress.bar.Set64(offset) // discard progress of a failed attempt
+			}
+			restoreHash(hash, state) // and what it hashed
+			err = c.uploadChunk(backend, io.NewSectionReader(srcFile, offset, length), parts[i], length, progress, hash)
+			if err == nil {
+				break
+			}
+			// A stall closed the SFTP session, so the part is restarted over a new one and
+			// does not count as an attempt; cancellation ends the run
+			if errors.Is(err, ErrTransferStalled) && stalls < c.stallRestarts && c.context().Err() == nil {
+				stalls++
+				if c.onStallRestart != nil {
+					c.onStallRestart(parts[i], stalls, c.stallRestarts)
+				}
+				closeBackend()
+				if backend, closeBackend, err = c.openBackend(c.context()); err != nil {
+					closeBackend = func() {}
+					return err
+				}
+				attempt--
+				continue
+			}
+			if errors.Is(err, ErrTransferStalled) || c.context().Err() != nil {
+				return fmt.Errorf("failed to transfer chunk %d/%d: %w", i+1, count, err)
+			}
+			if attempt == chunkAttempts {
+				return fmt.Errorf("failed to transfer chunk %d/%d after %d attempts: %w", i+1, count, attempt, err)
+			}
+		}
+	}
+
+	return c.assembleChunks(parts, remotePath, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// uploadChunk uploads one part, skipping it when the remote part already has the expected size.
+// The part's data is written to hash as it is read; a skipped part is read for the hash alone.
+// A stalled part closes an SFTP session, since it cannot be used again.
+func (c *Client) uploadChunk(backend TransferBackend, section *io.SectionReader, remotePart string, length int64, progress *ProgressReader, hash io.Writer) error {
+	if size, err := backend.Size(remotePart); err == nil && size == length {
+		if _, err := io.Copy(hash, section); err != nil {
+			return fmt.Errorf("failed to read local file: %w", err)
+		}
+		if progress != nil {
+			progress.bar.Add64(length)
+		}
+		return nil
+	}
+
+	watch := c.watchStalls()
+	defer watch.stop()
+
+	var reader io.Reader = io.TeeReader(section, hash)
+	if progress != nil {
+		progress.Reader = reader
+		reader = progress
+	}
+	if err := backend.Put(watch.ctx, remotePart, watch.reader(reader)); err != nil {
+		c.removePartial(remotePart)
+		return fmt.Errorf("failed to transfer file: %w", transferError(watch.ctx, err))
+	}
+	return nil
+}
+
+// hashState saves the state of a hash, so the data of a failed chunk attempt can be taken out again
+// The standard library hashes all implement encoding.BinaryMarshaler.
+func hashState(h hash.Hash) []byte {
+	state, _ := h.(encoding.BinaryMarshaler).MarshalBinary()
+	return state
+}
+
+// restoreHash resets a hash to a state saved by hashState
+func restoreHash(h hash.Hash, state []byte) {
+	h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+}
+
+// assembleChunks concatenates the uploaded parts into remotePath, checks its sha256 and removes the parts
+func (c *Client) assembleChunks(parts []string, remotePath, checksum string) error {
+	escapedParts := make([]string, len(parts))
+	for i, part := range parts {
+		escapedParts[i] = shell.ShellEscape(part)
+	}
+	escapedPath := shell.ShellEscape(remotePath)
+
+	cmd := fmt.Sprintf("cat %s > %s && sha256sum %s",
+		strings.Join(escapedParts, " "), escapedPath, escapedPath)
+	output, err := c.RunCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble chunks on remote host: %w", err)
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 || fields[0] != checksum {
+		return fmt.Errorf("checksum mismatch after reassembly of %s: expected %s, got %q", remotePath, checksum, strings.TrimSpace(output))
+	}
+
+	if _, err := c.RunCommand("rm -f " + strings.Join(escapedParts, " ")); err != nil {
+		return fmt.Errorf("failed to remove chunks on remote host: %w", err)
+	}
+	return nil
+}
+
+// verifyChecksum checks that the sha256 of remotePath is checksum, after the upload named by what
+func (c *Client) verifyChecksum(remotePath, checksum, what string) error {
+	output, err := c.RunCommand("sha256sum " + shell.ShellEscape(remotePath))
+	if err != nil {
+		return fmt.Errorf("failed to verify %s on remote host: %w", remotePath, err)
+	}
+	if fields := strings.Fields(output); len(fields) == 0 || fields[0] != checksum {
+		return fmt.Errorf("checksum mismatch after %s of %s: expected %s, got %q", what, remotePath, checksum, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// chunkCount returns the number of parts needed to split size bytes into chunks of chunkSize
+func chunkCount(size, chunkSize int64) int {
+	return int((size + chunkSize - 1) / chunkSize)
+}
+
+// chunkPath returns the remote path of the i-th part of remotePath
+func chunkPath(remotePath string, i int) string {
+	return fmt.Sprintf("%s.part%04d", remotePath, i)
+}
+
+// DownloadFile downloads a file from the remote host over the transfer backend with progress tracking
+func (c *Client) DownloadFile(remotePath, localPath string, showProgress bool) error {
+	backend, closeBackend, err := c.openBackend(c.context())
+	if err != nil {
+		return err
+	}
+
+	// Get remote size for the progress bar
+	size, err := backend.Size(remotePath)
+	closeBackend()
 	if err != nil {
 		return fmt.Errorf("failed to stat remote file: %w", err)
 	}
@@ -114,20 +460,68 @@ func (c *Client) DownloadFile(remotePath, localPath string, showProgress bool) e
 	}
 	defer dstFile.Close()
 
-	// Create progress bar if requested
-	var reader io.Reader = srcFile
+	// Create progress bar if requested; restarts set it back to where they resume
+	var progress *ProgressReader
 	if showProgress {
-		bar := progressbar.DefaultBytes(
-			stat.Size(),
-			fmt.Sprintf("Downloading %s", filepath.Base(remotePath)),
-		)
-		reader = &ProgressReader{Reader: srcFile, bar: bar}
+		description := fmt.Sprintf("Downloading %s", filepath.Base(remotePath))
+		bar := utils.NewProgressBar(size, description)
+		progress = newProgressReader(nil, bar, description)
 		defer bar.Finish()
 	}
 
-	// Copy file
-	if _, err := io.Copy(dstFile, reader); err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+	return c.restartStalled(remotePath, func(resume bool) error {
+		return c.getFile(remotePath, dstFile, progress, resume)
+	})
+}
+
+// getFile downloads remotePath into dst over a newly opened backend. When resume is set and the
+// backend is resumable, it continues after what dst already holds; otherwise dst starts over.
+func (c *Client) getFile(remotePath string, dst *os.File, progress *ProgressReader, resume bool) error {
+	watch := c.watchStalls()
+	defer watch.stop()
+
+	backend, closeBackend, err := c.openBackend(watch.ctx)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	var offset int64
+	resumable, canResume := backend.(ResumableBackend)
+	if resume && canResume {
+		if info, err := dst.Stat(); err == nil {
+			offset = info.Size()
+		}
+	}
+	if err := dst.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate local file: %w", err)
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek local file: %w", err)
+	}
+
+	// The backend writes into a pipe, so progress and stalls are tracked as for uploads
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeReader.Close()
+	go func() {
+		if offset > 0 {
+			pipeWriter.CloseWithError(resumable.GetFrom(watch.ctx, remotePath, offset, pipeWriter))
+		} else {
+			pipeWriter.CloseWithError(backend.Get(watch.ctx, remotePath, pipeWriter))
+		}
+	}()
+
+	var reader io.Reader = pipeReader
+	if progress != nil {
+		progress.bar.Set64(offset)
+		progress.Reader = pipeReader
+		reader = progress
+	}
+
+	// Copy file; hiding the file's ReadFrom makes the copy use the buffer
+	buffer := make([]byte, c.transferBufferSize())
+	if _, err := io.CopyBuffer(struct{ io.Writer }{dst}, watch.reader(reader), buffer); err != nil {
+		return fmt.Errorf("failed to download file: %w", transferError(watch.ctx, err))
 	}
 
 	return nil
@@ -135,35 +529,22 @@ func (c *Client) DownloadFile(remotePath, localPath string, showProgress bool) e
 
 // FileExists checks if a file exists on the remote host
 func (c *Client) FileExists(remotePath string) (bool, error) {
-	sftpClient, err := sftp.NewClient(c.client)
-	if err != nil {
-		return false, fmt.Errorf("failed to create SFTP client: %w", err)
-	}
-	defer sftpClient.Close()
-
-	_, err = sftpClient.Stat(remotePath)
+	backend, closeBackend, err := c.openBackend(c.context())
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
 		return false, err
 	}
+	defer closeBackend()
 
-	return true, nil
+	return backend.Exists(remotePath)
 }
 
 // GetFileSize returns the size of a remote file
 func (c *Client) GetFileSize(remotePath string) (int64, error) {
-	sftpClient, err := sftp.NewClient(c.client)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create SFTP client: %w", err)
-	}
-	defer sftpClient.Close()
-
-	stat, err := sftpClient.Stat(remotePath)
+	backend, closeBackend, err := c.openBackend(c.context())
 	if err != nil {
 		return 0, err
 	}
+	defer closeBackend()
 
-	return stat.Size(), nil
+	return backend.Size(remotePath)
 }
@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+func TestChunkCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int64
+		chunkSize int64
+		expected  int
+	}{
+		{"exact multiple", 4096, 1024, 4},
+		{"partial last chunk", 4097, 1024, 5},
+		{"smaller than chunk", 10, 1024, 1},
+		{"empty file", 0, 1024, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := chunkCount(tt.size, tt.chunkSize); result != tt.expected {
+				t.Errorf("chunkCount(%d, %d) = %d, want %d", tt.size, tt.chunkSize, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestChunkPath(t *testing.T) {
+	if got := chunkPath("/tmp/vm/data.tar.gz", 0); got != "/tmp/vm/data.tar.gz.part0000" {
+		t.Errorf("chunkPath(0) = %q", got)
+	}
+	if got := chunkPath("/tmp/vm/data.tar.gz", 12); got != "/tmp/vm/data.tar.gz.part0012" {
+		t.Errorf("chunkPath(12) = %q", got)
+	}
+}
+
+func TestRestoreHash(t *testing.T) {
+	hash := sha256.New()
+	hash.Write([]byte("hello "))
+	state := hashState(hash)
+
+	hash.Write([]byte("failed attempt"))
+	restoreHash(hash, state)
+	hash.Write([]byte("world"))
+
+	// sha256 of "hello world"
+	if got, want := hex.EncodeToString(hash.Sum(nil)), "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"; got != want {
+		t.Errorf("hash after restore = %s, want %s", got, want)
+	}
+}
+
+func TestProgressReaderRates(t *testing.T) {
+	bar := progressbar.DefaultBytesSilent(-1, "Uploading a.tar.gz")
+	pr := newProgressReader(nil, bar, "Uploading a.tar.gz")
+	start := time.Unix(1700000000, 0)
+
+	pr.record(1024*1024, start)
+	if pr.current != 0 {
+		t.Errorf("expected no current rate before a full window, got %f", pr.current)
+	}
+
+	pr.record(2*1024*1024, start.Add(time.Second))
+	if pr.current != 3*1024*1024 {
+		t.Errorf("current rate = %f, want %d", pr.current, 3*1024*1024)
+	}
+
+	pr.record(1024*1024, start.Add(3*time.Second))
+	if pr.current != 512*1024 {
+		t.Errorf("current rate = %f, want %d", pr.current, 512*1024)
+	}
+	if avg := pr.averageRate(start.Add(4 * time.Second)); avg != 1024*1024 {
+		t.Errorf("average rate = %f, want %d", avg, 1024*1024)
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected string
+	}{
+		{0, "0.0 MB/s"},
+		{1024 * 1024, "1.0 MB/s"},
+		{12.5 * 1024 * 1024, "12.5 MB/s"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatRate(tt.rate); result != tt.expected {
+			t.Errorf("FormatRate(%f) = %q, want %q", tt.rate, result, tt.expected)
+		}
+	}
+}
+
+func TestValidateBufferSize(t *testing.T) {
+	tests := []struct {
+		size    int64
+		wantErr bool
+	}{
+		{DefaultBufferSize, false},
+		{MinBufferSize, false},
+		{MaxBufferSize, false},
+		{MinBufferSize - 1, true},
+		{MaxBufferSize + 1, true},
+		{0, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateBufferSize(tt.size); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateBufferSize(%d) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTransferBufferSize(t *testing.T) {
+	if got := (&Client{}).transferBufferSize(); got != DefaultBufferSize {
+		t.Errorf("transferBufferSize() unset = %d, want %d", got, DefaultBufferSize)
+	}
+	if got := (&Client{bufferSize: 256 * 1024}).transferBufferSize(); got != 256*1024 {
+		t.Errorf("transferBufferSize() = %d, want %d", got, 256*1024)
+	}
+}
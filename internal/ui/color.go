@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/manifoldco/promptui"
+)
+
+// noColor disables ANSI colors and styles in the selector and prompts
+var noColor bool
+
+// SetNoColor turns off ANSI colors and styles in the selector and prompts
+func SetNoColor(disabled bool) {
+	noColor = disabled
+}
+
+// templateFuncs returns the template functions for prompt templates: promptui's color
+// functions, or functions printing their argument unchanged when colors are off
+func templateFuncs() template.FuncMap {
+	if !noColor {
+		return promptui.FuncMap
+	}
+	plain := make(template.FuncMap, len(promptui.FuncMap))
+	for name := range promptui.FuncMap {
+		plain[name] = func(v interface{}) string { return fmt.Sprint(v) }
+	}
+	return plain
+}
+
+// promptTemplates returns the templates of text prompts, nil for promptui's defaults
+// The defaults style their icons outside the template functions, so they are replaced when colors are off
+func promptTemplates() *promptui.PromptTemplates {
+	if !noColor {
+		return nil
+	}
+	return &promptui.PromptTemplates{
+		Prompt:  "? {{ . }}: ",
+		Confirm: "? {{ . }}? [y/N] ",
+		Valid:   "✔ {{ . }}: ",
+		Invalid: "✗ {{ . }}: ",
+		Success: "{{ . }}: ",
+		FuncMap: templateFuncs(),
+	}
+}
@@ -0,0 +1,23 @@
+package ui
+
+import (
+	"errors"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Confirm asks a yes/no question, defaulting to no
+func Confirm(label string) (bool, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+		Templates: promptTemplates(),
+	}
+	if _, err := prompt.Run(); err != nil {
+		if errors.Is(err, promptui.ErrAbort) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/ssh"
+)
+
+// SelectHost presents a searchable list of hosts to pick the migration target from
+func SelectHost(hosts []ssh.ConfigHost) (ssh.ConfigHost, error) {
+	if len(hosts) == 0 {
+		return ssh.ConfigHost{}, errors.New("no hosts to select")
+	}
+
+	prompt := promptui.Select{
+		Label: "Select the remote host (type / to search)",
+		Items: hosts,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}",
+			Active:   "→ {{ .Alias | cyan }} {{ .Remote | faint }}",
+			Inactive: "  {{ .Alias }} {{ .Remote | faint }}",
+			Selected: "Remote host: {{ .Alias | green }} ({{ .Remote }})",
+			FuncMap:  templateFuncs(),
+		},
+		Size: 10,
+		Searcher: func(input string, index int) bool {
+			host := hosts[index]
+			input = strings.ToLower(strings.TrimSpace(input))
+			return strings.Contains(strings.ToLower(host.Alias), input) ||
+				strings.Contains(strings.ToLower(host.HostName), input)
+		},
+	}
+
+	idx, _, err := prompt.Run()
+	if err != nil {
+		if err == promptui.ErrInterrupt {
+			return ssh.ConfigHost{}, fmt.Errorf("host selection %w", migerrors.ErrCancelled)
+		}
+		return ssh.ConfigHost{}, fmt.Errorf("host selection failed: %w", err)
+	}
+	return hosts[idx], nil
+}
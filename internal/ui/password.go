@@ -0,0 +1,15 @@
+package ui
+
+import (
+	"github.com/manifoldco/promptui"
+)
+
+// PromptPassword asks for a password without echoing it
+func PromptPassword(label string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		Mask:      '*',
+		Templates: promptTemplates(),
+	}
+	return prompt.Run()
+}
@@ -3,13 +3,21 @@ package ui
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/manifoldco/promptui"
 	"volume-migrator/internal/docker"
+	migerrors "volume-migrator/internal/errors"
 	"volume-migrator/internal/utils"
 )
 
+// anonymousTag marks anonymous volumes (and their remote name, if renamed) in selector rows
+const anonymousTag = `{{ if .Anonymous }} {{ "[anonymous" | yellow }}{{ if .TargetName }}{{ " → " | yellow }}{{ .TargetName | yellow }}{{ end }}{{ "]" | yellow }}{{ end }}`
+
+// projectTag prefixes selector rows with the compose project/service of the volume
+const projectTag = `{{ if .Project }}{{ .Project | faint }}{{ "/" | faint }}{{ .Service | faint }} {{ end }}`
+
 // SelectVolumes presents an interactive UI for selecting volumes to migrate
 func SelectVolumes(volumes []docker.VolumeInfo) ([]docker.VolumeInfo, error) {
 	if len(volumes) == 0 {
@@ -19,9 +27,8 @@ func SelectVolumes(volumes []docker.VolumeInfo) ([]docker.VolumeInfo, error) {
 	// Display summary
 	fmt.Printf("\nDiscovered %d volume(s)\n\n", len(volumes))
 
-	// Create a copy of volumes for selection
-	selectionItems := make([]docker.VolumeInfo, len(volumes))
-	copy(selectionItems, volumes)
+	// Create a copy of volumes for selection, grouped by compose project
+	selectionItems := groupByProject(volumes)
 
 	// All volumes start as selected by default
 	for i := range selectionItems {
@@ -43,15 +50,18 @@ func SelectVolumes(volumes []docker.VolumeInfo) ([]docker.VolumeInfo, error) {
 		// Create prompt templates
 		templates := &promptui.SelectTemplates{
 			Label:    "{{ . }}",
-			Active:   "→ [{{ if .Selected }}✓{{ else }} {{ end }}] {{ .Name | cyan }} ({{ .Container }}) {{ .MountPath }} {{ .Size }}",
-			Inactive: "  [{{ if .Selected }}✓{{ else }} {{ end }}] {{ .Name }} ({{ .Container }}) {{ .MountPath }} {{ .Size }}",
+			Active:   "→ [{{ if .Selected }}✓{{ else }} {{ end }}] {{ .Name | cyan }}" + anonymousTag + " ({{ .Container }}) {{ .MountPath }} {{ .Size }}",
+			Inactive: "  [{{ if .Selected }}✓{{ else }} {{ end }}] " + projectTag + "{{ .Name }}" + anonymousTag + " ({{ .Container }}) {{ .MountPath }} {{ .Size }}",
 			Selected: "{{ .Name | green }}",
 			Details: `
 --------- Volume Details ---------
-{{ "Name:" | faint }}	{{ .Name }}
-{{ "Container:" | faint }}	{{ .Container }}
-{{ "Mount Path:" | faint }}	{{ .MountPath }}
+{{ "Name:" | faint }}	{{ .Name }}{{ if .Anonymous }} (anonymous){{ end }}
+{{ if .TargetName }}{{ "Remote Name:" | faint }}	{{ .TargetName }}
+{{ end }}{{ "Container:" | faint }}	{{ .Container }}
+{{ if .Project }}{{ "Project:" | faint }}	{{ .Project }} ({{ .Service }})
+{{ end }}{{ "Mount Path:" | faint }}	{{ .MountPath }}
 {{ "Size:" | faint }}	{{ .Size }}`,
+			FuncMap: templateFuncs(),
 		}
 
 		// Create select prompt
@@ -73,7 +83,7 @@ func SelectVolumes(volumes []docker.VolumeInfo) ([]docker.VolumeInfo, error) {
 		if err != nil {
 			// Check if user wants to quit
 			if err == promptui.ErrInterrupt {
-				return nil, errors.New("selection cancelled by user")
+				return nil, fmt.Errorf("selection %w", migerrors.ErrCancelled)
 			}
 			return nil, fmt.Errorf("selection failed: %w", err)
 		}
@@ -90,25 +100,40 @@ func SelectVolumes(volumes []docker.VolumeInfo) ([]docker.VolumeInfo, error) {
 		}
 
 		// Ask if user wants to continue or confirm
+		label := fmt.Sprintf("Selected %d volume(s). Continue selecting (c), Confirm (y), or Cancel (n)?", selectedCount)
+		project := selectionItems[idx].Project
+		if project != "" {
+			label = fmt.Sprintf("Selected %d volume(s). Continue selecting (c), Apply to whole project %s (p), Confirm (y), or Cancel (n)?", selectedCount, project)
+		}
 		confirmPrompt := promptui.Prompt{
-			Label:     fmt.Sprintf("Selected %d volume(s). Continue selecting (c), Confirm (y), or Cancel (n)?", selectedCount),
+			Label:     label,
 			IsConfirm: false,
 			Default:   "c",
+			Templates: promptTemplates(),
 		}
 
 		response, err := confirmPrompt.Run()
 		if err != nil {
 			if err == promptui.ErrInterrupt {
-				return nil, errors.New("selection cancelled by user")
+				return nil, fmt.Errorf("selection %w", migerrors.ErrCancelled)
 			}
 			continue
 		}
 
 		response = strings.ToLower(strings.TrimSpace(response))
+		if response == "p" && project != "" {
+			// Give every volume of the stack the state just chosen for this one
+			for i := range selectionItems {
+				if selectionItems[i].Project == project {
+					selectionItems[i].Selected = selectionItems[idx].Selected
+				}
+			}
+			continue
+		}
 		if response == "y" || response == "yes" {
 			break
 		} else if response == "n" || response == "no" {
-			return nil, errors.New("selection cancelled by user")
+			return nil, fmt.Errorf("selection %w", migerrors.ErrCancelled)
 		}
 		// Continue loop for "c" or other responses
 	}
@@ -139,10 +164,20 @@ func DisplayVolumeTable(volumes []docker.VolumeInfo) {
 	fmt.Printf("\n%-25s %-20s %-25s %s\n", "VOLUME NAME", "CONTAINER", "MOUNT PATH", "SIZE")
 	fmt.Println(strings.Repeat("-", 95))
 
-	// Print volumes
-	for _, v := range volumes {
+	// Print volumes, with a header line for each compose project
+	project := ""
+	for i, v := range groupByProject(volumes) {
+		if (i == 0 && v.Project != "") || (i > 0 && v.Project != project) {
+			header := v.Project
+			if header == "" {
+				header = "no project"
+			}
+			fmt.Printf("[%s]\n", header)
+		}
+		project = v.Project
+
 		fmt.Printf("%-25s %-20s %-25s %s\n",
-			truncate(v.Name, 25),
+			truncate(displayName(v), 25),
 			truncate(v.Container, 20),
 			truncate(v.MountPath, 25),
 			v.Size,
@@ -151,6 +186,60 @@ func DisplayVolumeTable(volumes []docker.VolumeInfo) {
 	fmt.Println()
 }
 
+// DisplayUnmigratedMounts lists the container mounts that are not volumes, whose data is not migrated
+func DisplayUnmigratedMounts(mounts []docker.UnmigratedMount) {
+	if len(mounts) == 0 {
+		return
+	}
+
+	fmt.Printf("Not migrated (bind/tmpfs):\n")
+	fmt.Printf("%-20s %-8s %-25s %s\n", "CONTAINER", "TYPE", "MOUNT PATH", "SOURCE")
+	fmt.Println(strings.Repeat("-", 95))
+	for _, m := range mounts {
+		fmt.Printf("%-20s %-8s %-25s %s\n",
+			truncate(m.Container, 20),
+			m.Type,
+			truncate(m.Destination, 25),
+			m.Source,
+		)
+	}
+	fmt.Println()
+}
+
+// groupByProject returns a copy of volumes ordered by compose project and service
+// Volumes without a project come last
+func groupByProject(volumes []docker.VolumeInfo) []docker.VolumeInfo {
+	grouped := make([]docker.VolumeInfo, len(volumes))
+	copy(grouped, volumes)
+
+	sort.SliceStable(grouped, func(i, j int) bool {
+		a, b := grouped[i], grouped[j]
+		if (a.Project == "") != (b.Project == "") {
+			return a.Project != ""
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Name < b.Name
+	})
+	return grouped
+}
+
+// displayName returns the name shown in the volume table
+// Anonymous volumes are shown by their remote name when renamed, or a short id otherwise
+func displayName(v docker.VolumeInfo) string {
+	if !v.Anonymous {
+		return v.Name
+	}
+	if v.TargetName != "" {
+		return v.TargetName + " (anon)"
+	}
+	return truncate(v.Name, 12) + " (anon)"
+}
+
 // truncate truncates a string to the specified length
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
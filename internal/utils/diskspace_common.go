@@ -2,30 +2,98 @@ package utils
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
-	"volume-migrator/internal/ssh"
+	migerrors "volume-migrator/internal/errors"
+	"volume-migrator/internal/shell"
 )
 
 // DiskSpaceInfo holds disk space information
 type DiskSpaceInfo struct {
-	Total     uint64
-	Available uint64
-	Used      uint64
+	Total      uint64
+	Available  uint64
+	Used       uint64
+	MountPoint string // Filesystem mount point (remote only, empty if unknown)
+	Tmpfs      bool   // Memory-backed filesystem (local Linux only)
+}
+
+// NearlyFull reports whether less than a tenth of the filesystem is free
+func (d *DiskSpaceInfo) NearlyFull() bool {
+	return d.Available < d.Total/10
+}
+
+// RemoteCommandRunner runs shell commands on the remote host, as *ssh.Client does
+type RemoteCommandRunner interface {
+	RunCommand(cmd string) (string, error)
 }
 
 // GetRemoteDiskSpace returns disk space information for a remote path via SSH
-func GetRemoteDiskSpace(sshClient *ssh.Client, remotePath string) (*DiskSpaceInfo, error) {
+func GetRemoteDiskSpace(sshClient RemoteCommandRunner, remotePath string) (*DiskSpaceInfo, error) {
 	// Use df -k to get disk space in kilobytes
 	// -P flag ensures POSIX output format (single line per filesystem)
-	cmd := fmt.Sprintf("df -Pk %s", remotePath)
+	// Walk up to the closest existing ancestor so directories that are not created yet can be checked
+	cmd := fmt.Sprintf(`p=%s; while [ ! -e "$p" ]; do p=$(dirname "$p"); done; df -Pk "$p"`, shell.ShellEscape(remotePath))
 	output, err := sshClient.RunCommand(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get remote disk space: %w", err)
 	}
 
-	// Parse df output
+	return parseDFOutput(output)
+}
+
+// GetWritableRemoteDiskSpace measures several remote directories with a single command, leaving
+// out those that do not exist or that the SSH user cannot write to. The result is keyed by directory.
+func GetWritableRemoteDiskSpace(sshClient RemoteCommandRunner, dirs []string) (map[string]*DiskSpaceInfo, error) {
+	escaped := make([]string, len(dirs))
+	for i, dir := range dirs {
+		escaped[i] = shell.ShellEscape(dir)
+	}
+	cmd := fmt.Sprintf(`for d in %s; do if [ -d "$d" ] && [ -w "$d" ]; then echo "== $d"; df -Pk "$d"; fi; done`, strings.Join(escaped, " "))
+	output, err := sshClient.RunCommand(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote disk space: %w", err)
+	}
+	return parseDFSections(output)
+}
+
+// parseDFSections parses `df -Pk` outputs each preceded by a "== <dir>" line
+func parseDFSections(output string) (map[string]*DiskSpaceInfo, error) {
+	spaces := make(map[string]*DiskSpaceInfo)
+	dir, section := "", ""
+	flush := func() error {
+		if dir == "" {
+			return nil
+		}
+		info, err := parseDFOutput(section)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		spaces[dir] = info
+		return nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := strings.CutPrefix(line, "== "); ok {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			dir, section = name, ""
+			continue
+		}
+		section += line + "\n"
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return spaces, nil
+}
+
+// parseDFOutput parses the output of `df -Pk` for a single path
+func parseDFOutput(output string) (*DiskSpaceInfo, error) {
 	// Expected format:
 	// Filesystem     1024-blocks      Used Available Capacity Mounted on
 	// /dev/sda1        10000000   5000000   4500000      53% /
@@ -56,14 +124,66 @@ func GetRemoteDiskSpace(sshClient *ssh.Client, remotePath string) (*DiskSpaceInf
 		return nil, fmt.Errorf("failed to parse available size: %w", err)
 	}
 
+	var mountPoint string
+	if len(fields) >= 6 {
+		mountPoint = strings.Join(fields[5:], " ")
+	}
+
 	// Convert KB to bytes
 	return &DiskSpaceInfo{
-		Total:     totalKB * 1024,
-		Available: availableKB * 1024,
-		Used:      usedKB * 1024,
+		Total:      totalKB * 1024,
+		Available:  availableKB * 1024,
+		Used:       usedKB * 1024,
+		MountPoint: mountPoint,
 	}, nil
 }
 
+// InodeInfo holds the inode counts of a filesystem
+type InodeInfo struct {
+	Total uint64 // 0 for filesystems allocating inodes on demand, such as btrfs
+	Free  uint64
+}
+
+// GetRemoteInodes returns the inode counts of the filesystem holding a remote path via SSH,
+// walking up to the closest existing ancestor like GetRemoteDiskSpace
+func GetRemoteInodes(sshClient RemoteCommandRunner, remotePath string) (*InodeInfo, error) {
+	cmd := fmt.Sprintf(`d=%s; while [ ! -e "$d" ]; do d=$(dirname "$d"); done; df -Pi "$d"`, shell.ShellEscape(remotePath))
+	output, err := sshClient.RunCommand(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote inode counts: %w", err)
+	}
+	return parseDFInodes(output)
+}
+
+// parseDFInodes parses the output of `df -Pi` for a single path. Filesystems without a fixed
+// inode table show 0 or - as their counts.
+func parseDFInodes(output string) (*InodeInfo, error) {
+	// Expected format:
+	// Filesystem      Inodes  IUsed   IFree IUse% Mounted on
+	// /dev/sda1      6553600 350000 6203600    6% /
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected df output: %s", output)
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("unexpected df output format: %s", lines[1])
+	}
+	if fields[1] == "-" {
+		return &InodeInfo{}, nil
+	}
+
+	total, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse total inodes: %w", err)
+	}
+	free, err := strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse free inodes: %w", err)
+	}
+	return &InodeInfo{Total: total, Free: free}, nil
+}
+
 // CalculateRequiredSpace estimates required space for volume export
 // Uses conservative estimate assuming minimal compression for safety
 func CalculateRequiredSpace(volumeSizeBytes int64) int64 {
@@ -79,13 +199,45 @@ func CalculateRequiredSpace(volumeSizeBytes int64) int64 {
 	return int64(float64(volumeSizeBytes) * buffer)
 }
 
+// PeakSpaceRequirement returns the peak temporary space needed when volumes are processed
+// `concurrency` at a time and each volume's archive is deleted before the next one starts.
+// requirements holds the estimated space for each volume; the result is the sum of the
+// `concurrency` largest entries.
+func PeakSpaceRequirement(requirements []int64, concurrency int) int64 {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sorted := make([]int64, len(requirements))
+	copy(sorted, requirements)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	var peak int64
+	for i := 0; i < len(sorted) && i < concurrency; i++ {
+		peak += sorted[i]
+	}
+	return peak
+}
+
+// NearestExistingDir returns path, or its closest ancestor that exists
+// Used to measure free space for directories that have not been created yet
+func NearestExistingDir(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
 // ValidateDiskSpace checks if there's sufficient disk space for the operation
 func ValidateDiskSpace(location string, required, available uint64) error {
 	if available < required {
-		return fmt.Errorf("insufficient disk space on %s: required %d bytes (%s), available %d bytes (%s)",
-			location,
-			required, FormatBytes(int64(required)),
-			available, FormatBytes(int64(available)))
+		return migerrors.NewDiskSpaceError(location, int64(required), int64(available), nil)
 	}
 	return nil
 }
@@ -105,3 +257,26 @@ func FormatBytes(bytes int64) string {
 
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// ParseSize parses a human-readable size such as "512MB", "2GB", "1.5T" or "1024" into bytes
+// Units are binary (1 KB = 1024 bytes); a missing unit means bytes
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if idx := strings.IndexByte("KMGTP", s[n-1]); idx >= 0 {
+			for i := 0; i <= idx; i++ {
+				multiplier *= 1024
+			}
+			s = s[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size '%s': must be a number with an optional unit (B, KB, MB, GB, TB)", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
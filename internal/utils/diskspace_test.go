@@ -1,7 +1,12 @@
 package utils
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+
+	migerrors "volume-migrator/internal/errors"
 )
 
 func TestCalculateRequiredSpace(t *testing.T) {
@@ -67,6 +72,90 @@ func TestCalculateRequiredSpace_BufferPercentage(t *testing.T) {
 	}
 }
 
+func TestPeakSpaceRequirement(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements []int64
+		concurrency  int
+		expected     int64
+	}{
+		{
+			name:         "sequential uses largest volume",
+			requirements: []int64{100, 500, 200},
+			concurrency:  1,
+			expected:     500,
+		},
+		{
+			name:         "concurrency sums largest volumes",
+			requirements: []int64{100, 500, 200},
+			concurrency:  2,
+			expected:     700,
+		},
+		{
+			name:         "concurrency above volume count",
+			requirements: []int64{100, 500},
+			concurrency:  5,
+			expected:     600,
+		},
+		{
+			name:         "invalid concurrency treated as sequential",
+			requirements: []int64{100, 500},
+			concurrency:  0,
+			expected:     500,
+		},
+		{
+			name:         "no volumes",
+			requirements: nil,
+			concurrency:  1,
+			expected:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := PeakSpaceRequirement(tt.requirements, tt.concurrency)
+			if result != tt.expected {
+				t.Errorf("PeakSpaceRequirement(%v, %d) = %d, want %d", tt.requirements, tt.concurrency, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPeakSpaceRequirement_DoesNotReorderInput(t *testing.T) {
+	requirements := []int64{100, 500, 200}
+	PeakSpaceRequirement(requirements, 1)
+
+	if requirements[0] != 100 || requirements[1] != 500 || requirements[2] != 200 {
+		t.Errorf("PeakSpaceRequirement modified its input: %v", requirements)
+	}
+}
+
+func TestNearestExistingDir(t *testing.T) {
+	base := t.TempDir()
+	existing := filepath.Join(base, "existing")
+	if err := os.Mkdir(existing, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"existing directory", existing, existing},
+		{"missing child", filepath.Join(existing, "missing"), existing},
+		{"missing nested children", filepath.Join(existing, "a", "b", "c"), existing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := NearestExistingDir(tt.path); result != tt.expected {
+				t.Errorf("NearestExistingDir(%q) = %q, want %q", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestValidateDiskSpace_SufficientSpace(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -141,6 +230,10 @@ func TestValidateDiskSpace_InsufficientSpace(t *testing.T) {
 			if err != nil && err.Error() == "" {
 				t.Error("Error message should not be empty")
 			}
+			var diskErr *migerrors.DiskSpaceError
+			if !errors.As(err, &diskErr) || diskErr.Required != int64(tt.required) || !errors.Is(err, migerrors.ErrDiskSpace) {
+				t.Errorf("ValidateDiskSpace() = %#v, want a DiskSpaceError", err)
+			}
 		})
 	}
 }
@@ -291,3 +384,208 @@ func BenchmarkCalculateRequiredSpace(b *testing.B) {
 		})
 	}
 }
+
+func TestParseDFOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		expected  *DiskSpaceInfo
+		expectErr bool
+	}{
+		{
+			name: "standard output",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sda1        10000000   5000000   4500000      53% /\n",
+			expected: &DiskSpaceInfo{
+				Total:      10000000 * 1024,
+				Used:       5000000 * 1024,
+				Available:  4500000 * 1024,
+				MountPoint: "/",
+			},
+		},
+		{
+			name: "mount point with spaces",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sdb1        2000   1000   1000      50% /mnt/docker data\n",
+			expected: &DiskSpaceInfo{
+				Total:      2000 * 1024,
+				Used:       1000 * 1024,
+				Available:  1000 * 1024,
+				MountPoint: "/mnt/docker data",
+			},
+		},
+		{
+			name: "missing mount point",
+			output: "Filesystem 1024-blocks Used Available\n" +
+				"/dev/sda1 2000 1000 1000\n",
+			expected: &DiskSpaceInfo{
+				Total:     2000 * 1024,
+				Used:      1000 * 1024,
+				Available: 1000 * 1024,
+			},
+		},
+		{
+			name:      "header only",
+			output:    "Filesystem     1024-blocks      Used Available Capacity Mounted on\n",
+			expectErr: true,
+		},
+		{
+			name: "non-numeric values",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sda1        abc   5000000   4500000      53% /\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseDFOutput(tt.output)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("parseDFOutput() expected error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDFOutput() unexpected error: %v", err)
+			}
+			if *result != *tt.expected {
+				t.Errorf("parseDFOutput() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1024", 1024, false},
+		{"512B", 512, false},
+		{"1KB", 1024, false},
+		{"2GB", 2 * 1024 * 1024 * 1024, false},
+		{"2G", 2 * 1024 * 1024 * 1024, false},
+		{"2GiB", 2 * 1024 * 1024 * 1024, false},
+		{"1.5mb", 1536 * 1024, false},
+		{" 10 MB ", 10 * 1024 * 1024, false},
+		{"1T", 1024 * 1024 * 1024 * 1024, false},
+		{"", 0, true},
+		{"GB", 0, true},
+		{"-1GB", 0, true},
+		{"ten", 0, true},
+		{"inf", 0, true},
+		{"1XB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDFSections(t *testing.T) {
+	output := "== /tmp\n" +
+		"Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+		"tmpfs              2000000   1000000   1000000      50% /tmp\n" +
+		"== /var/tmp\n" +
+		"Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+		"/dev/sda1        10000000   5000000   4500000      53% /\n"
+
+	spaces, err := parseDFSections(output)
+	if err != nil {
+		t.Fatalf("parseDFSections() error = %v", err)
+	}
+	if len(spaces) != 2 {
+		t.Fatalf("parseDFSections() = %d directories, want 2", len(spaces))
+	}
+	if spaces["/tmp"].Available != 1000000*1024 || spaces["/tmp"].MountPoint != "/tmp" {
+		t.Errorf("/tmp = %+v", spaces["/tmp"])
+	}
+	if spaces["/var/tmp"].Available != 4500000*1024 || spaces["/var/tmp"].MountPoint != "/" {
+		t.Errorf("/var/tmp = %+v", spaces["/var/tmp"])
+	}
+
+	if spaces, err := parseDFSections(""); err != nil || len(spaces) != 0 {
+		t.Errorf("parseDFSections(\"\") = %v, %v, want no directories", spaces, err)
+	}
+	if _, err := parseDFSections("== /tmp\ndf: /tmp: error\n"); err == nil {
+		t.Error("parseDFSections() of a failed df succeeded, want error")
+	}
+}
+
+func TestDiskSpaceInfoNearlyFull(t *testing.T) {
+	tests := []struct {
+		total, available uint64
+		want             bool
+	}{
+		{1000, 500, false},
+		{1000, 100, false},
+		{1000, 99, true},
+		{0, 0, false},
+	}
+	for _, tt := range tests {
+		info := &DiskSpaceInfo{Total: tt.total, Available: tt.available}
+		if got := info.NearlyFull(); got != tt.want {
+			t.Errorf("NearlyFull() of %d/%d = %v, want %v", tt.available, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestParseDFInodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		expected  *InodeInfo
+		expectErr bool
+	}{
+		{
+			name: "standard output",
+			output: "Filesystem      Inodes  IUsed   IFree IUse% Mounted on\n" +
+				"/dev/sda1      6553600 350000 6203600    6% /\n",
+			expected: &InodeInfo{Total: 6553600, Free: 6203600},
+		},
+		{
+			name: "inodes allocated on demand",
+			output: "Filesystem     Inodes IUsed IFree IUse% Mounted on\n" +
+				"/dev/sdb1           0     0     0     - /var/lib/docker\n",
+			expected: &InodeInfo{},
+		},
+		{
+			name: "no counts",
+			output: "Filesystem     Inodes IUsed IFree IUse% Mounted on\n" +
+				"overlay             -     -     -     - /\n",
+			expected: &InodeInfo{},
+		},
+		{
+			name:      "empty output",
+			output:    "",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDFInodes(tt.output)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("parseDFInodes() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDFInodes() error = %v", err)
+			}
+			if *got != *tt.expected {
+				t.Errorf("parseDFInodes() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -23,5 +23,6 @@ func GetLocalDiskSpace(path string) (*DiskSpaceInfo, error) {
 		Total:     total,
 		Available: available,
 		Used:      used,
+		Tmpfs:     isTmpfs(&stat),
 	}, nil
 }
@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	migerrors "volume-migrator/internal/errors"
 )
 
 var log = logrus.New()
@@ -36,3 +40,78 @@ func SetVerbose(verbose bool) {
 		log.SetLevel(logrus.InfoLevel)
 	}
 }
+
+// SetNoColor turns off ANSI colors in console log output, which logrus otherwise uses
+// whenever stdout is a terminal
+func SetNoColor(noColor bool) {
+	log.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+		DisableColors: noColor,
+	})
+}
+
+// NoColor reports whether colors are turned off, by the --no-color flag or by a NO_COLOR
+// environment variable with any non-empty value (https://no-color.org)
+func NoColor(flag bool) bool {
+	return flag || os.Getenv("NO_COLOR") != ""
+}
+
+// SetTrace enables the Trace level, which adds every executed docker and SSH command line,
+// its exit code and duration to the Debug output. It has no effect when trace is false.
+func SetTrace(trace bool) {
+	if trace {
+		log.SetLevel(logrus.TraceLevel)
+	}
+}
+
+// SetLogFile appends every log entry to the file at path as well, without colors, and returns
+// a function closing it. Console output is unchanged.
+func SetLogFile(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if os.IsPermission(err) {
+		return nil, migerrors.NewPermissionError("write", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	log.AddHook(&fileHook{
+		out:       file,
+		formatter: &logrus.TextFormatter{FullTimestamp: true, DisableColors: true},
+	})
+	return file.Close, nil
+}
+
+// fileHook writes log entries to a file with its own formatter
+type fileHook struct {
+	out       io.Writer
+	formatter logrus.Formatter
+}
+
+// Levels implements logrus.Hook; the logger's level already filters entries
+func (h *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *fileHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.out.Write(line)
+	return err
+}
+
+// TraceCommand records an executed command at Trace level: the host it ran on, its command
+// line, exit code (-1 when it did not exit normally, e.g. it was killed or never started) and duration
+func TraceCommand(host, command string, exitCode int, duration time.Duration) {
+	if !log.IsLevelEnabled(logrus.TraceLevel) {
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"host":      host,
+		"command":   command,
+		"exit_code": exitCode,
+		"duration":  duration.Round(time.Millisecond),
+	}).Trace("Executed command")
+}
@@ -1,7 +1,12 @@
 package utils
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -104,6 +109,99 @@ func TestLogger_Configuration(t *testing.T) {
 	}
 }
 
+func TestSetTrace(t *testing.T) {
+	logger := GetLogger()
+	defer SetVerbose(false)
+
+	SetVerbose(true)
+	SetTrace(false)
+	if logger.Level != logrus.DebugLevel {
+		t.Errorf("after SetTrace(false), level = %v, want the level to be unchanged", logger.Level)
+	}
+	SetTrace(true)
+	if logger.Level != logrus.TraceLevel {
+		t.Errorf("after SetTrace(true), level = %v, want TraceLevel", logger.Level)
+	}
+}
+
+func TestNoColor(t *testing.T) {
+	tests := []struct {
+		name string
+		flag bool
+		env  string
+		want bool
+	}{
+		{"default", false, "", false},
+		{"flag", true, "", true},
+		{"environment", false, "1", true},
+		{"both", true, "1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.env)
+			if got := NoColor(tt.flag); got != tt.want {
+				t.Errorf("NoColor(%v) with NO_COLOR=%q = %v, want %v", tt.flag, tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetNoColor(t *testing.T) {
+	logger := GetLogger()
+	defer SetNoColor(false)
+
+	SetNoColor(true)
+	formatter, ok := logger.Formatter.(*logrus.TextFormatter)
+	if !ok {
+		t.Fatalf("expected a TextFormatter, got %T", logger.Formatter)
+	}
+	if !formatter.DisableColors || !formatter.FullTimestamp {
+		t.Errorf("expected colors disabled and full timestamps, got %+v", formatter)
+	}
+}
+
+func TestSetLogFile_TraceCommand(t *testing.T) {
+	logger := GetLogger()
+	out := logger.Out
+	logger.SetOutput(io.Discard)
+	defer func() {
+		logger.SetOutput(out)
+		logger.ReplaceHooks(make(logrus.LevelHooks))
+		SetVerbose(false)
+	}()
+
+	path := filepath.Join(t.TempDir(), "migration.log")
+	closeLog, err := SetLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	TraceCommand("local", "docker ps", 0, time.Second) // below the level, not recorded
+	SetTrace(true)
+	TraceCommand("host:22", "docker volume rm data", 1, 1500*time.Millisecond)
+	if err := closeLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if strings.Contains(content, "docker ps") {
+		t.Errorf("expected commands below the Trace level to be skipped, got:\n%s", content)
+	}
+	for _, want := range []string{`command="docker volume rm data"`, "exit_code=1", "duration=1.5s", "host=\"host:22\""} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %s in log file, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "\x1b[") {
+		t.Error("expected no color escapes in the log file")
+	}
+}
+
 func BenchmarkGetLogger(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		GetLogger()
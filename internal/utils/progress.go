@@ -1,15 +1,57 @@
 package utils
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
 )
 
+// DefaultPlainProgressInterval is how often plain progress prints a line for each active bar
+const DefaultPlainProgressInterval = 10 * time.Second
+
+// plainInterval is how often bars print a plain line, 0 while they are drawn in place
+var plainInterval time.Duration
+
+// SetPlainProgress makes the bars of NewProgressBar print a timestamped line at most every
+// interval instead of redrawing themselves, for CI logs and journals; 0 draws them again
+func SetPlainProgress(interval time.Duration) {
+	plainInterval = interval
+}
+
+// PlainProgress reports whether progress is shown as plain lines: when --progress=plain asks for
+// it, or when stderr is not a terminal that could redraw a bar
+func PlainProgress(flag bool) bool {
+	return flag || !term.IsTerminal(int(os.Stderr.Fd()))
+}
+
 // NewProgressBar creates a new progress bar for tracking byte-based operations.
-// The max parameter specifies the total number of bytes, and description
+// The max parameter specifies the total number of bytes, -1 when unknown, and description
 // provides a label for the progress bar. Returns a progress bar configured
-// for displaying byte-sized progress (e.g., "10 MB / 100 MB").
+// for displaying byte-sized progress (e.g., "10 MB / 100 MB"), or printing it as
+// plain lines after SetPlainProgress.
 func NewProgressBar(max int64, description string) *progressbar.ProgressBar {
-	return progressbar.DefaultBytes(max, description)
+	if plainInterval == 0 {
+		return progressbar.DefaultBytes(max, description)
+	}
+	writer := &plainProgressWriter{out: os.Stderr}
+	if max < 0 {
+		// Bars without a known size render on every update, whatever their throttle
+		writer.interval = plainInterval
+	}
+	return progressbar.NewOptions64(max,
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionSetWriter(writer),
+		progressbar.OptionSetTheme(progressbar.Theme{}),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionThrottle(plainInterval),
+	)
 }
 
 // NewSpinner creates a spinner for indeterminate operations where progress
@@ -23,3 +65,39 @@ func NewSpinner(description string) *progressbar.ProgressBar {
 		progressbar.OptionSpinnerType(14),
 	)
 }
+
+var (
+	// ansiEscape matches the escape sequences a bar may clear its line with
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	// barTimes matches the [elapsed:remaining] times at the end of a rendered bar
+	barTimes = regexp.MustCompile(`\[([^:\]]+):([^\]]+)\]$`)
+)
+
+// plainProgressWriter turns each rendering of a bar into a timestamped line without control
+// characters, such as "2024-05-01T10:00:00Z Uploading app.tar.gz 45% (450/1000 MB, 12 MB/s) ETA 45s".
+// The blank renderings that clear the line are dropped.
+type plainProgressWriter struct {
+	out      io.Writer
+	interval time.Duration // Minimum time between lines, 0 to print every rendering
+	last     time.Time
+}
+
+// Write implements io.Writer
+func (w *plainProgressWriter) Write(p []byte) (int, error) {
+	line := plainProgressLine(string(p))
+	if line == "" || time.Since(w.last) < w.interval {
+		return len(p), nil
+	}
+	w.last = time.Now()
+	fmt.Fprintf(w.out, "%s %s\n", w.last.Format(time.RFC3339), line)
+	return len(p), nil
+}
+
+// plainProgressLine removes the control characters and spinner from a rendered bar and names its remaining time
+func plainProgressLine(rendered string) string {
+	line := ansiEscape.ReplaceAllString(rendered, "")
+	line = strings.Join(strings.Fields(strings.ReplaceAll(line, "\r", " ")), " ")
+	// Bars without a known size lead with a spinner, ASCII or braille
+	line = strings.TrimLeft(line, "|/-\\⠀⠁⠂⠃⠄⠅⠆⠇⠈⠉⠊⠋⠌⠍⠎⠏⠐⠑⠒⠓⠔⠕⠖⠗⠘⠙⠚⠛⠜⠝⠞⠟⠠⠡⠢⠣⠤⠥⠦⠧⠨⠩⠪⠫⠬⠭⠮⠯⠰⠱⠲⠳⠴⠵⠶⠷⠸⠹⠺⠻⠼⠽⠾⠿ ")
+	return barTimes.ReplaceAllString(line, "ETA $2")
+}
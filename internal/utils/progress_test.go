@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlainProgressLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		rendered string
+		want     string
+	}{
+		{
+			name:     "bar",
+			rendered: "\rUploading app.tar.gz  30% (32/105 MB, 12 MB/s) [2s:5s]",
+			want:     "Uploading app.tar.gz 30% (32/105 MB, 12 MB/s) ETA 5s",
+		},
+		{
+			name:     "spinner",
+			rendered: "\r⠋ Exporting app (5.0 MB, 50 MB/s) [3s] ",
+			want:     "Exporting app (5.0 MB, 50 MB/s) [3s]",
+		},
+		{
+			name:     "ASCII spinner",
+			rendered: "\r/ Exporting app (7.3 MB, 60 MB/s) [0s] ",
+			want:     "Exporting app (7.3 MB, 60 MB/s) [0s]",
+		},
+		{
+			name:     "ANSI codes",
+			rendered: "\x1b[2K\rImporting app  50% (1/2 GB, 80 MB/s) [10s:10s]",
+			want:     "Importing app 50% (1/2 GB, 80 MB/s) ETA 10s",
+		},
+		{
+			name:     "clearing the line",
+			rendered: "\r          \r",
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plainProgressLine(tt.rendered); got != tt.want {
+				t.Errorf("plainProgressLine(%q) = %q, want %q", tt.rendered, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainProgressWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &plainProgressWriter{out: &out, interval: time.Hour}
+
+	w.Write([]byte("\r   \r"))
+	w.Write([]byte("\rExporting app (1.0 MB, 1 MB/s) [1s] "))
+	w.Write([]byte("\rExporting app (2.0 MB, 1 MB/s) [2s] "))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1 within the interval: %q", len(lines), out.String())
+	}
+	timestamp, line, _ := strings.Cut(lines[0], " ")
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		t.Errorf("line does not start with a timestamp: %q", lines[0])
+	}
+	if line != "Exporting app (1.0 MB, 1 MB/s) [1s]" {
+		t.Errorf("line = %q", line)
+	}
+}
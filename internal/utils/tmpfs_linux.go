@@ -0,0 +1,11 @@
+package utils
+
+import "syscall"
+
+// tmpfsMagic is the filesystem type statfs reports for tmpfs
+const tmpfsMagic = 0x01021994
+
+// isTmpfs reports whether stat describes a tmpfs filesystem
+func isTmpfs(stat *syscall.Statfs_t) bool {
+	return int64(stat.Type) == tmpfsMagic
+}
@@ -0,0 +1,10 @@
+//go:build unix && !linux
+
+package utils
+
+import "syscall"
+
+// isTmpfs reports whether stat describes a tmpfs filesystem; only detected on Linux
+func isTmpfs(stat *syscall.Statfs_t) bool {
+	return false
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// wslMountRoot is where WSL mounts the Windows drives (the automount root of /etc/wsl.conf)
+const wslMountRoot = "/mnt/"
+
+// windowsPathRegex matches absolute Windows paths such as C:\Temp or D:/data
+var windowsPathRegex = regexp.MustCompile(`^([A-Za-z]):([\\/].*)?$`)
+
+var (
+	wslOnce sync.Once
+	isWSL2  bool
+)
+
+// IsWSL2 reports whether the process runs inside a WSL2 distribution
+// The result is read from the kernel release once and cached.
+func IsWSL2() bool {
+	wslOnce.Do(func() {
+		release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+		isWSL2 = err == nil && isWSL2Release(string(release))
+	})
+	return isWSL2
+}
+
+// isWSL2Release reports whether a kernel release string belongs to a WSL2 kernel
+// (e.g. "5.15.153.1-microsoft-standard-WSL2"); WSL1 reports "...-Microsoft" and has no Docker support
+func isWSL2Release(release string) bool {
+	return strings.Contains(strings.ToLower(release), "microsoft-standard")
+}
+
+// WSLPath translates a Windows path (C:\Temp\x) into its WSL mount (/mnt/c/Temp/x)
+// Paths that are not Windows paths are returned unchanged.
+func WSLPath(path string) string {
+	matches := windowsPathRegex.FindStringSubmatch(path)
+	if matches == nil {
+		return path
+	}
+	rest := strings.ReplaceAll(matches[2], `\`, "/")
+	return wslMountRoot + strings.ToLower(matches[1]) + strings.TrimSuffix(rest, "/")
+}
@@ -0,0 +1,44 @@
+package utils
+
+import "testing"
+
+func TestIsWSL2Release(t *testing.T) {
+	tests := []struct {
+		release string
+		want    bool
+	}{
+		{"5.15.153.1-microsoft-standard-WSL2\n", true},
+		{"4.19.128-microsoft-standard", true},
+		{"4.4.0-19041-Microsoft", false}, // WSL1
+		{"6.8.0-45-generic", false},
+	}
+
+	for _, tt := range tests {
+		if got := isWSL2Release(tt.release); got != tt.want {
+			t.Errorf("isWSL2Release(%q) = %v, want %v", tt.release, got, tt.want)
+		}
+	}
+}
+
+func TestWSLPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"backslashes", `C:\Users\me\Temp`, "/mnt/c/Users/me/Temp"},
+		{"forward slashes", "D:/data/backups/", "/mnt/d/data/backups"},
+		{"drive root", `E:\`, "/mnt/e"},
+		{"bare drive", "F:", "/mnt/f"},
+		{"linux path", "/tmp/migration", "/tmp/migration"},
+		{"relative path", "backups", "backups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WSLPath(tt.path); got != tt.want {
+				t.Errorf("WSLPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+//go:build integration
+
+// Package integration runs real migrations against a target container running sshd and
+// Docker-in-Docker. It needs a local Docker daemon that can start privileged containers:
+//
+//	make test-integration
+package integration
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"volume-migrator/internal/migrator"
+)
+
+// targetImage is the image built from ./target
+const targetImage = "volume-migrator-integration-target"
+
+// targetStartTimeout bounds how long sshd and the inner Docker daemon may take to come up
+const targetStartTimeout = 90 * time.Second
+
+// seedScript fills a volume with the cases a migration must preserve: nested directories,
+// empty and binary files, names with spaces, symlinks, ownership and modes
+const seedScript = `set -e
+mkdir -p /data/nested/deep
+echo hello > /data/a.txt
+: > /data/empty
+head -c 1048576 /dev/urandom > /data/nested/random.bin
+echo spaced > "/data/nested/deep/with space.txt"
+ln -s ../a.txt /data/nested/link
+chown 1234:5678 /data/a.txt
+chmod 0640 /data/a.txt
+chmod 0700 /data/nested/deep`
+
+// contentScript lists a volume's entries with type, owner and mode, the hash of every file
+// and the target of every symlink, sorted so both hosts produce comparable output
+const contentScript = `cd /data
+find . -mindepth 1 -exec stat -c '%n %F %u:%g %a' {} + | sort
+find . -type f -exec sha256sum {} + | sort
+find . -type l -exec sh -c 'echo "$1 -> $(readlink "$1")"' _ {} \; | sort`
+
+// target is a running migration target
+type target struct {
+	container  string
+	remote     string // user@host:port for --remote
+	keyPath    string
+	knownHosts string
+}
+
+func TestMigrate(t *testing.T) {
+	tgt := startTarget(t)
+	binary := buildMigrator(t)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"archive import", nil},
+		{"streamed import", []string{"--import-method", "stream"}},
+		{"docker cp import", []string{"--import-method", "cp"}},
+		{"deep verify", []string{"--deep-verify"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume := fmt.Sprintf("vm-integration-%d-%d", time.Now().Unix(), i)
+			seedVolume(t, volume)
+			t.Cleanup(func() { docker(t, "exec", tgt.container, "docker", "volume", "rm", "-f", volume) })
+
+			args := append([]string{
+				volume, "--by-volume",
+				"--remote", tgt.remote,
+				"--ssh-key", tgt.keyPath,
+				"--known-hosts-file", tgt.knownHosts,
+				"--accept-host-key",
+				"--progress=false",
+				"--yes",
+			}, tt.args...)
+			cmd := exec.Command(binary, args...)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("migration failed: %v\n%s", err, output)
+			}
+
+			local := docker(t, "run", "--rm", "-v", volume+":/data:ro", migrator.DefaultHelperImage, "sh", "-c", contentScript)
+			remote := docker(t, "exec", tgt.container, "docker", "run", "--rm", "-v", volume+":/data:ro", migrator.DefaultHelperImage, "sh", "-c", contentScript)
+			if !strings.Contains(local, "./nested/random.bin") {
+				t.Fatalf("seeded volume is missing files:\n%s", local)
+			}
+			if local != remote {
+				t.Errorf("remote volume differs from the source\nlocal:\n%s\nremote:\n%s", local, remote)
+			}
+		})
+	}
+}
+
+// buildMigrator compiles the command under test
+func buildMigrator(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "volume-migrator")
+	cmd := exec.Command("go", "build", "-o", binary, "./cmd/volume-migrator")
+	cmd.Dir = filepath.Join("..", "..")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build volume-migrator: %v\n%s", err, output)
+	}
+	return binary
+}
+
+// startTarget builds and starts the target container, authorizing a fresh key for root
+func startTarget(t *testing.T) *target {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not installed")
+	}
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	authorizedKey := writeKey(t, keyPath)
+
+	docker(t, "build", "-t", targetImage, "target")
+	container := strings.TrimSpace(docker(t, "run", "-d", "--rm", "--privileged",
+		"-p", "127.0.0.1::22", "-e", "AUTHORIZED_KEY="+authorizedKey, targetImage))
+	t.Cleanup(func() { exec.Command("docker", "rm", "-f", container).Run() })
+
+	// docker port prints the mapping as 127.0.0.1:<port>
+	address := strings.TrimSpace(strings.Split(docker(t, "port", container, "22/tcp"), "\n")[0])
+
+	deadline := time.Now().Add(targetStartTimeout)
+	for exec.Command("docker", "exec", container, "docker", "info").Run() != nil {
+		if time.Now().After(deadline) {
+			logs, _ := exec.Command("docker", "logs", container).CombinedOutput()
+			t.Fatalf("target did not start within %s:\n%s", targetStartTimeout, logs)
+		}
+		time.Sleep(time.Second)
+	}
+
+	return &target{
+		container:  container,
+		remote:     "root@" + address,
+		keyPath:    keyPath,
+		knownHosts: filepath.Join(dir, "known_hosts"),
+	}
+}
+
+// writeKey writes a new ed25519 private key to path and returns its authorized_keys line
+func writeKey(t *testing.T, path string) string {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "volume-migrator integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic)))
+}
+
+// seedVolume creates a local volume filled by seedScript and removes it when the test ends
+func seedVolume(t *testing.T, volume string) {
+	t.Helper()
+	docker(t, "volume", "create", volume)
+	t.Cleanup(func() { exec.Command("docker", "volume", "rm", "-f", volume).Run() })
+	docker(t, "run", "--rm", "-v", volume+":/data", migrator.DefaultHelperImage, "sh", "-c", seedScript)
+}
+
+// docker runs a local docker command and returns its stdout, failing the test on error
+func docker(t *testing.T, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("docker %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String()
+}
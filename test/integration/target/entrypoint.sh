@@ -0,0 +1,12 @@
+#!/bin/sh
+# Authorizes $AUTHORIZED_KEY for root, starts sshd and hands over to the dind entrypoint
+set -e
+
+mkdir -p /root/.ssh
+printf '%s\n' "$AUTHORIZED_KEY" > /root/.ssh/authorized_keys
+chmod 700 /root/.ssh
+chmod 600 /root/.ssh/authorized_keys
+
+/usr/sbin/sshd
+
+exec dockerd-entrypoint.sh "$@"